@@ -23,7 +23,9 @@ func main() {
 
 	logsOptions := logs.NewOptions()
 	command := newCommand(logsOptions)
-	logsOptions.AddFlags(command.Flags())
+	// the logging flags apply to all subcommands, for example --logging-format=json
+	logs.AddFlags(command.PersistentFlags(), logs.SkipLoggingConfigurationFlags())
+	logsOptions.AddFlags(command.PersistentFlags())
 	code := cli.Run(command)
 	os.Exit(code)
 
@@ -37,10 +39,10 @@ func newCommand(logsOptions *logs.Options) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "Hub Cluster Deploy",
 		Short: "Hub Cluster Deploy",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return logsOptions.ValidateAndApply()
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := logsOptions.ValidateAndApply(); err != nil {
-				fmt.Fprintf(os.Stderr, "%v\n", err)
-			}
 			os.Exit(1)
 		},
 	}
@@ -52,6 +54,12 @@ func newCommand(logsOptions *logs.Options) *cobra.Command {
 	}
 
 	cmd.AddCommand(hubcontroller.NewController())
+	cmd.AddCommand(hubcontroller.NewWebhook())
+	cmd.AddCommand(hubcontroller.NewRender())
+	cmd.AddCommand(hubcontroller.NewExport())
+	cmd.AddCommand(hubcontroller.NewStatus())
+	cmd.AddCommand(hubcontroller.NewUninstall())
+	cmd.AddCommand(hubcontroller.NewDiagnose())
 
 	return cmd
 }
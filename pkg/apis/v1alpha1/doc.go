@@ -0,0 +1,4 @@
+// Package v1alpha1 contains the API types maintained by the hub cluster controller.
+// +k8s:deepcopy-gen=package
+// +groupName=hub-of-hubs.open-cluster-management.io
+package v1alpha1
@@ -0,0 +1,46 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	GroupName     = "hub-of-hubs.open-cluster-management.io"
+	GroupVersion  = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+	schemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	// Install is a function which adds this version to a scheme
+	Install = schemeBuilder.AddToScheme
+
+	// SchemeGroupVersion generated code relies on this name
+	// Deprecated
+	SchemeGroupVersion = GroupVersion
+	// AddToScheme exists solely to keep the old generators creating valid code
+	// DEPRECATED
+	AddToScheme = schemeBuilder.AddToScheme
+)
+
+// Resource generated code relies on this being here, but it logically belongs to the group
+// DEPRECATED
+func Resource(resource string) schema.GroupResource {
+	return schema.GroupResource{Group: GroupName, Resource: resource}
+}
+
+// ManagedHubInventoriesResource is the resource of the ManagedHubInventory
+var ManagedHubInventoriesResource = GroupVersion.WithResource("managedhubinventories")
+
+// MultiClusterHubOverridesResource is the resource of the MultiClusterHubOverride
+var MultiClusterHubOverridesResource = GroupVersion.WithResource("multiclusterhuboverrides")
+
+// Adds the list of known types to api.Scheme.
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(GroupVersion,
+		&ManagedHubInventory{},
+		&ManagedHubInventoryList{},
+		&MultiClusterHubOverride{},
+		&MultiClusterHubOverrideList{},
+	)
+	metav1.AddToGroupVersion(scheme, GroupVersion)
+	return nil
+}
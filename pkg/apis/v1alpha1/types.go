@@ -0,0 +1,165 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ManagedHubInventory aggregates the state of every managed hub in the fleet, so that the fleet
+// state can be inspected with a single get. It is maintained by the hub cluster controller as a
+// singleton named managed-hubs.
+type ManagedHubInventory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Status holds the state of the managed hubs.
+	// +optional
+	Status ManagedHubInventoryStatus `json:"status,omitempty"`
+}
+
+// ManagedHubInventoryName is the name of the singleton ManagedHubInventory
+const ManagedHubInventoryName = "managed-hubs"
+
+// ManagedHubInventoryStatus is the aggregated state of the managed hubs.
+type ManagedHubInventoryStatus struct {
+	// Total is the number of managed hubs.
+	Total int32 `json:"total"`
+
+	// Installed is the number of managed hubs whose hub is installed.
+	Installed int32 `json:"installed"`
+
+	// Degraded is the number of managed hubs whose hub is degraded.
+	Degraded int32 `json:"degraded"`
+
+	// Hubs is the list of managed hubs, sorted by name.
+	// +optional
+	Hubs []ManagedHub `json:"hubs,omitempty"`
+}
+
+// ManagedHubPhase is the installation phase of a managed hub.
+type ManagedHubPhase string
+
+const (
+	// ManagedHubPending means the installation of the hub is not started yet.
+	ManagedHubPending ManagedHubPhase = "Pending"
+	// ManagedHubInstalling means the hub is being installed.
+	ManagedHubInstalling ManagedHubPhase = "Installing"
+	// ManagedHubInstalled means the hub is installed and running.
+	ManagedHubInstalled ManagedHubPhase = "Installed"
+	// ManagedHubDegraded means the hub can not be installed.
+	ManagedHubDegraded ManagedHubPhase = "Degraded"
+)
+
+// ManagedHub is the state of a single managed hub.
+type ManagedHub struct {
+	// Name is the name of the managed cluster.
+	Name string `json:"name"`
+
+	// Version is the version of the installed hub.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// InstalledCSV is the CSV of the hub operator installed on the managed cluster.
+	// +optional
+	InstalledCSV string `json:"installedCSV,omitempty"`
+
+	// CurrentCSV is the latest CSV of the channel known to the operator subscription, it differs from
+	// the installed CSV while an upgrade is pending.
+	// +optional
+	CurrentCSV string `json:"currentCSV,omitempty"`
+
+	// Phase is the installation phase of the hub.
+	Phase ManagedHubPhase `json:"phase"`
+
+	// LastError is the most recent error reported for the hub installation.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// LastErrorTime is the time the hub installation started failing with the last error.
+	// +optional
+	LastErrorTime *metav1.Time `json:"lastErrorTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ManagedHubInventoryList is a collection of ManagedHubInventory.
+type ManagedHubInventoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// Items is a list of ManagedHubInventory.
+	Items []ManagedHubInventory `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MultiClusterHubOverride customizes the MultiClusterHub installed on a managed hub. It is created in
+// the managed cluster namespace and referenced by the hoh-mch-override annotation of the
+// ManagedCluster, in place of the raw JSON of the mch annotation.
+type MultiClusterHubOverride struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec holds the fields of the MultiClusterHub spec to install.
+	Spec MultiClusterHubOverrideSpec `json:"spec"`
+}
+
+// MultiClusterHubOverrideSpec is the subset of the MultiClusterHub spec customizable per managed hub.
+type MultiClusterHubOverrideSpec struct {
+	// AvailabilityConfig is the availability of the hub components, High or Basic.
+	// +optional
+	// +kubebuilder:validation:Enum=High;Basic
+	AvailabilityConfig string `json:"availabilityConfig,omitempty"`
+
+	// ImagePullSecret is the name of the secret to pull the hub images with.
+	// +optional
+	ImagePullSecret string `json:"imagePullSecret,omitempty"`
+
+	// NodeSelector selects the nodes of the hub components.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are the tolerations of the hub components.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// CustomCAConfigmap is the name of the ConfigMap of the custom CA of the hub.
+	// +optional
+	CustomCAConfigmap string `json:"customCAConfigmap,omitempty"`
+
+	// DisableUpdateClusterImageSets disables the updates of the cluster image sets.
+	// +optional
+	DisableUpdateClusterImageSets bool `json:"disableUpdateClusterImageSets,omitempty"`
+
+	// EnableClusterBackup enables the backup of the hub.
+	// +optional
+	EnableClusterBackup bool `json:"enableClusterBackup,omitempty"`
+
+	// EnableClusterProxyAddon enables the cluster proxy addon.
+	// +optional
+	EnableClusterProxyAddon bool `json:"enableClusterProxyAddon,omitempty"`
+
+	// SeparateCertificateManagement installs the certificate manager in its own namespace.
+	// +optional
+	SeparateCertificateManagement bool `json:"separateCertificateManagement,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MultiClusterHubOverrideList is a collection of MultiClusterHubOverride.
+type MultiClusterHubOverrideList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// Items is a list of MultiClusterHubOverride.
+	Items []MultiClusterHubOverride `json:"items"`
+}
@@ -0,0 +1,109 @@
+package cluster
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// AGENT_ADDON_NAME is the name of the ManagedClusterAddOn representing the multicluster-global-hub
+// agent of the managed hubs, so the agents show up in the addon status of the managed clusters and
+// can be selected by the addon predicates of the placements.
+const AGENT_ADDON_NAME = AGENT_NAME
+
+// ensureAddOn creates the ManagedClusterAddOn of the agent of the managed hub, and reports the
+// availability of the agent from the feedback of its manifestwork. The agent does not maintain a
+// lease, its health is checked by the controller.
+func (c *agentController) ensureAddOn(ctx context.Context, managedCluster *clusterv1.ManagedCluster,
+	agent *workv1.ManifestWork) error {
+	addOn, err := c.addOnLister.ManagedClusterAddOns(managedCluster.Name).Get(AGENT_ADDON_NAME)
+	if errors.IsNotFound(err) {
+		// the lister only caches the addons with the managed-by label, an existing addon created by
+		// hand or before the label was set is adopted
+		addOn, err = c.addOnClient.ManagedClusterAddOns(managedCluster.Name).Get(ctx, AGENT_ADDON_NAME, metav1.GetOptions{})
+		if err == nil {
+			addOn, err = c.adoptAddOn(ctx, addOn)
+		}
+	}
+	if errors.IsNotFound(err) {
+		addOn, err = c.addOnClient.ManagedClusterAddOns(managedCluster.Name).Create(ctx, &addonv1alpha1.ManagedClusterAddOn{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      AGENT_ADDON_NAME,
+				Namespace: managedCluster.Name,
+				Labels:    map[string]string{MANAGED_BY_LABEL: MANAGED_BY_VALUE, BACKUP_LABEL: BACKUP_VALUE},
+			},
+			Spec: addonv1alpha1.ManagedClusterAddOnSpec{InstallNamespace: AGENT_NAMESPACE},
+		}, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	updated := addOn.DeepCopy()
+	updated.Status.HealthCheck.Mode = addonv1alpha1.HealthCheckModeCustomized
+	meta.SetStatusCondition(&updated.Status.Conditions, agentAvailableCondition(agent))
+	if equality.Semantic.DeepEqual(addOn.Status, updated.Status) {
+		return nil
+	}
+	_, err = c.addOnClient.ManagedClusterAddOns(managedCluster.Name).UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+// adoptAddOn adds the managed-by and backup labels to an existing addon of the agent, so it is cached
+// by the lister from then on.
+func (c *agentController) adoptAddOn(ctx context.Context,
+	addOn *addonv1alpha1.ManagedClusterAddOn) (*addonv1alpha1.ManagedClusterAddOn, error) {
+	if addOn.Labels[MANAGED_BY_LABEL] == MANAGED_BY_VALUE && addOn.Labels[BACKUP_LABEL] == BACKUP_VALUE {
+		return addOn, nil
+	}
+	adopted := addOn.DeepCopy()
+	if adopted.Labels == nil {
+		adopted.Labels = map[string]string{}
+	}
+	adopted.Labels[MANAGED_BY_LABEL] = MANAGED_BY_VALUE
+	adopted.Labels[BACKUP_LABEL] = BACKUP_VALUE
+	return c.addOnClient.ManagedClusterAddOns(addOn.Namespace).Update(ctx, adopted, metav1.UpdateOptions{})
+}
+
+// agentAvailableCondition returns the Available condition of the addon from the feedback of the agent
+// manifestwork.
+func agentAvailableCondition(agent *workv1.ManifestWork) metav1.Condition {
+	if agent == nil {
+		return metav1.Condition{
+			Type:    addonv1alpha1.ManagedClusterAddOnConditionAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AgentNotInstalled",
+			Message: "The agent is installed once the MultiClusterHub is running",
+		}
+	}
+	replicas, ok := GetFeedbackInteger(agent, "Deployment", AGENT_AVAILABLE_REPLICAS_FEEDBACK)
+	switch {
+	case !ok:
+		return metav1.Condition{
+			Type:    addonv1alpha1.ManagedClusterAddOnConditionAvailable,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "AgentStatusUnknown",
+			Message: "The status of the agent is not reported yet",
+		}
+	case replicas > 0:
+		return metav1.Condition{
+			Type:    addonv1alpha1.ManagedClusterAddOnConditionAvailable,
+			Status:  metav1.ConditionTrue,
+			Reason:  "AgentAvailable",
+			Message: "The agent is available",
+		}
+	default:
+		return metav1.Condition{
+			Type:    addonv1alpha1.ManagedClusterAddOnConditionAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AgentUnavailable",
+			Message: "The agent has no available replica",
+		}
+	}
+}
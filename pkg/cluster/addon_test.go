@@ -0,0 +1,85 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestEnsureAddOn(t *testing.T) {
+	replicas := int64(1)
+	available := CreateAgentManifestwork("cluster1", DefaultHubConfig())
+	available.Status.ResourceStatus.Manifests = []workv1.ManifestCondition{{
+		ResourceMeta: workv1.ManifestResourceMeta{Kind: "Deployment"},
+		StatusFeedbacks: workv1.StatusFeedbackResult{Values: []workv1.FeedbackValue{{
+			Name:  AGENT_AVAILABLE_REPLICAS_FEEDBACK,
+			Value: workv1.FieldValue{Type: workv1.Integer, Integer: &replicas},
+		}}},
+	}}
+	cases := []struct {
+		name     string
+		agent    *workv1.ManifestWork
+		expected metav1.ConditionStatus
+	}{
+		{name: "agent not installed", agent: nil, expected: metav1.ConditionFalse},
+		{name: "agent status unknown", agent: CreateAgentManifestwork("cluster1", DefaultHubConfig()), expected: metav1.ConditionUnknown},
+		{name: "agent available", agent: available, expected: metav1.ConditionTrue},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addOnClient := addonfake.NewSimpleClientset()
+			ctrl := &agentController{
+				addOnClient: addOnClient.AddonV1alpha1(),
+				addOnLister: addonlisterv1alpha1.NewManagedClusterAddOnLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+			}
+			if err := ctrl.ensureAddOn(context.TODO(), newManagedCluster("cluster1"), c.agent); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			addOn, err := addOnClient.AddonV1alpha1().ManagedClusterAddOns("cluster1").Get(context.TODO(), AGENT_ADDON_NAME, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("expected the addon to be created: %v", err)
+			}
+			if addOn.Spec.InstallNamespace != AGENT_NAMESPACE || addOn.Status.HealthCheck.Mode != addonv1alpha1.HealthCheckModeCustomized {
+				t.Errorf("expected the addon of the agent with a customized health check, got %v", addOn)
+			}
+			if cond := meta.FindStatusCondition(addOn.Status.Conditions, addonv1alpha1.ManagedClusterAddOnConditionAvailable); cond == nil ||
+				cond.Status != c.expected {
+				t.Errorf("expected the addon availability %s, got %v", c.expected, addOn.Status.Conditions)
+			}
+		})
+	}
+}
+
+func TestEnsureAddOnAdoptsExistingAddOn(t *testing.T) {
+	// the addon created by hand has no managed-by label, so it is not in the lister
+	existing := &addonv1alpha1.ManagedClusterAddOn{
+		ObjectMeta: metav1.ObjectMeta{Name: AGENT_ADDON_NAME, Namespace: "cluster1", Labels: map[string]string{"team": "fleet"}},
+		Spec:       addonv1alpha1.ManagedClusterAddOnSpec{InstallNamespace: AGENT_NAMESPACE},
+	}
+	addOnClient := addonfake.NewSimpleClientset(existing)
+	ctrl := &agentController{
+		addOnClient: addOnClient.AddonV1alpha1(),
+		addOnLister: addonlisterv1alpha1.NewManagedClusterAddOnLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+	}
+	if err := ctrl.ensureAddOn(context.TODO(), newManagedCluster("cluster1"), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addOn, err := addOnClient.AddonV1alpha1().ManagedClusterAddOns("cluster1").Get(context.TODO(), AGENT_ADDON_NAME, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addOn.Labels[MANAGED_BY_LABEL] != MANAGED_BY_VALUE || addOn.Labels[BACKUP_LABEL] != BACKUP_VALUE || addOn.Labels["team"] != "fleet" {
+		t.Errorf("expected the existing addon to be adopted with its labels kept, got %v", addOn.Labels)
+	}
+	if cond := meta.FindStatusCondition(addOn.Status.Conditions, addonv1alpha1.ManagedClusterAddOnConditionAvailable); cond == nil ||
+		cond.Status != metav1.ConditionFalse {
+		t.Errorf("expected the availability of the adopted addon to be reported, got %v", addOn.Status.Conditions)
+	}
+}
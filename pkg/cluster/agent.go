@@ -0,0 +1,319 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// HOH_HUB_CLUSTER_AGENT is the suffix of the manifestwork installing the multicluster-global-hub
+// agent on the managed hubs, it is created once the MultiClusterHub is running.
+const HOH_HUB_CLUSTER_AGENT = "hoh-hub-cluster-agent"
+
+const (
+	// AGENT_NAMESPACE is the namespace of the multicluster-global-hub agent on the managed hubs
+	AGENT_NAMESPACE = "open-cluster-management-global-hub-agent"
+	// AGENT_NAME is the name of the deployment and service account of the agent
+	AGENT_NAME = "multicluster-global-hub-agent"
+
+	// AGENT_TRANSPORT_SECRET is the name of the transport credentials of the agent on the managed hubs
+	AGENT_TRANSPORT_SECRET = "multicluster-global-hub-transport"
+	// AGENT_CA_BUNDLE is the name of the ConfigMap of the CA bundle of the hub of hubs on the managed
+	// hubs, the agents verify the TLS connections to the hub of hubs with it
+	AGENT_CA_BUNDLE = "multicluster-global-hub-ca-bundle"
+
+	defaultAgentImage = "quay.io/stolostron/multicluster-global-hub-agent:latest"
+)
+
+// AGENT_AVAILABLE_REPLICAS_FEEDBACK is the name of the status feedback value of the available
+// replicas of the agent deployment
+const AGENT_AVAILABLE_REPLICAS_FEEDBACK = "availableReplicas"
+
+// SECRET_HASH_ANNOTATION is the hash of the secrets and CA bundle mounted in the agent, set on its
+// pod template so the agent is restarted when they are rotated
+const SECRET_HASH_ANNOTATION = "hub-of-hubs.open-cluster-management.io/secret-hash"
+
+// agentVolume is a secret or ConfigMap propagated to the agent namespace of the managed hub and
+// mounted in the agent
+type agentVolume struct {
+	name      string
+	mountPath string
+	configMap bool
+	manifest  workv1.Manifest
+	hash      string
+}
+
+// CreateAgentManifestwork returns the agent manifestwork installing the multicluster-global-hub agent
+// of the hub configuration on the managed hub. The agent syncs the managed hub with the hub of hubs
+// through the transport of the configuration.
+func CreateAgentManifestwork(namespace string, config *HubConfig) *workv1.ManifestWork {
+	return newAgentManifestwork(namespace, config, nil)
+}
+
+// desiredAgentManifestWork renders the agent manifestwork of the managed cluster, with the transport
+// credentials and the CA bundle of the hub configuration copied from the controller namespace.
+func (c *clusterController) desiredAgentManifestWork(managedCluster *clusterv1.ManagedCluster,
+	config *HubConfig) (*workv1.ManifestWork, error) {
+	var volumes []agentVolume
+	if config.TransportSecret != "" {
+		manifest, hash, err := c.propagatedSecret(config.TransportSecret, AGENT_TRANSPORT_SECRET, AGENT_NAMESPACE)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, agentVolume{
+			name:      AGENT_TRANSPORT_SECRET,
+			mountPath: "/var/run/secrets/transport",
+			manifest:  manifest,
+			hash:      hash,
+		})
+	}
+	if config.CABundleConfigMap != "" {
+		manifest, hash, err := c.propagatedConfigMap(config.CABundleConfigMap, AGENT_CA_BUNDLE, AGENT_NAMESPACE)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, agentVolume{
+			name:      AGENT_CA_BUNDLE,
+			mountPath: "/var/run/secrets/ca-bundle",
+			configMap: true,
+			manifest:  manifest,
+			hash:      hash,
+		})
+	}
+	return placeManifestWork(managedCluster, newAgentManifestwork(managedCluster.Name, config, volumes)), nil
+}
+
+// newAgentManifestwork returns the agent manifestwork installing the agent with the given volumes
+func newAgentManifestwork(namespace string, config *HubConfig, volumes []agentVolume) *workv1.ManifestWork {
+	manifests := []workv1.Manifest{}
+	for _, volume := range volumes {
+		manifests = append(manifests, volume.manifest)
+	}
+	return &workv1.ManifestWork{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: workv1.GroupVersion.String(),
+			Kind:       "ManifestWork",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      WorkNaming{}.Name(namespace, HOH_HUB_CLUSTER_AGENT),
+			Namespace: namespace,
+			Labels: map[string]string{
+				MANAGED_BY_LABEL:      MANAGED_BY_VALUE,
+				MANAGED_CLUSTER_LABEL: namespace,
+				BACKUP_LABEL:          BACKUP_VALUE,
+				WORK_TYPE_LABEL:       HOH_HUB_CLUSTER_AGENT,
+			},
+		},
+		Spec: workv1.ManifestWorkSpec{
+			Workload: workv1.ManifestsTemplate{
+				Manifests: append(append([]workv1.Manifest{
+					{RawExtension: runtime.RawExtension{
+						Raw: []byte(`{
+	"apiVersion": "v1",
+	"kind": "Namespace",
+	"metadata": {
+		"name": "open-cluster-management-global-hub-agent"
+	}
+}`),
+					}},
+					{RawExtension: runtime.RawExtension{
+						Raw: []byte(`{
+	"apiVersion": "v1",
+	"kind": "ServiceAccount",
+	"metadata": {
+		"name": "multicluster-global-hub-agent",
+		"namespace": "open-cluster-management-global-hub-agent"
+	}
+}`),
+					}},
+				}, append(agentRBACManifests(), manifests...)...), workv1.Manifest{
+					RawExtension: runtime.RawExtension{Raw: agentDeploymentManifest(namespace, config, volumes)},
+				}),
+			},
+			ManifestConfigs: []workv1.ManifestConfigOption{
+				{
+					ResourceIdentifier: workv1.ResourceIdentifier{
+						Group:     "apps",
+						Resource:  "deployments",
+						Name:      AGENT_NAME,
+						Namespace: AGENT_NAMESPACE,
+					},
+					FeedbackRules: []workv1.FeedbackRule{
+						{
+							Type: workv1.JSONPathsType,
+							JsonPaths: []workv1.JsonPath{
+								{
+									Name: AGENT_AVAILABLE_REPLICAS_FEEDBACK,
+									Path: ".status.availableReplicas",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// AGENT_CLUSTER_ROLE is the name of the ClusterRole and ClusterRoleBinding of the agent on the
+// managed hubs. The binding is not named after the agent, so the binding to cluster-admin of the
+// previous versions is removed by the work agent rather than updated, as its role is immutable.
+const AGENT_CLUSTER_ROLE = "open-cluster-management:multicluster-global-hub-agent"
+
+// agentRBACManifests returns the ClusterRole of the agent and its binding to the agent serviceaccount.
+// The agent syncs the policies, placements and applications of the managed hub with the hub of hubs
+// and reports the managed clusters and the status of the hub, it is granted these resources only.
+func agentRBACManifests() []workv1.Manifest {
+	return []workv1.Manifest{
+		{RawExtension: runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{
+	"apiVersion": "rbac.authorization.k8s.io/v1",
+	"kind": "ClusterRole",
+	"metadata": {
+		"name": %q
+	},
+	"rules": [
+		{
+			"apiGroups": [""],
+			"resources": ["namespaces", "configmaps", "secrets", "events"],
+			"verbs": ["get", "list", "watch", "create", "update", "patch", "delete"]
+		},
+		{
+			"apiGroups": ["coordination.k8s.io"],
+			"resources": ["leases"],
+			"verbs": ["get", "list", "watch", "create", "update", "patch", "delete"]
+		},
+		{
+			"apiGroups": ["policy.open-cluster-management.io"],
+			"resources": ["policies", "policies/status", "placementbindings", "policyautomations"],
+			"verbs": ["get", "list", "watch", "create", "update", "patch", "delete"]
+		},
+		{
+			"apiGroups": ["apps.open-cluster-management.io"],
+			"resources": ["placementrules", "placementrules/status", "subscriptions", "subscriptions/status",
+				"subscriptionreports", "channels"],
+			"verbs": ["get", "list", "watch", "create", "update", "patch", "delete"]
+		},
+		{
+			"apiGroups": ["app.k8s.io"],
+			"resources": ["applications"],
+			"verbs": ["get", "list", "watch", "create", "update", "patch", "delete"]
+		},
+		{
+			"apiGroups": ["cluster.open-cluster-management.io"],
+			"resources": ["placements", "placements/status", "placementdecisions", "placementdecisions/status",
+				"managedclustersets", "managedclustersetbindings"],
+			"verbs": ["get", "list", "watch", "create", "update", "patch", "delete"]
+		},
+		{
+			"apiGroups": ["cluster.open-cluster-management.io"],
+			"resources": ["managedclusters"],
+			"verbs": ["get", "list", "watch", "update", "patch"]
+		},
+		{
+			"apiGroups": ["internal.open-cluster-management.io"],
+			"resources": ["managedclusterinfos"],
+			"verbs": ["get", "list", "watch"]
+		},
+		{
+			"apiGroups": [%q],
+			"resources": ["multiclusterhubs"],
+			"verbs": ["get", "list", "watch"]
+		},
+		{
+			"apiGroups": ["apiextensions.k8s.io"],
+			"resources": ["customresourcedefinitions"],
+			"verbs": ["get", "list", "watch"]
+		}
+	]
+}`, AGENT_CLUSTER_ROLE, MultiClusterHubsResource.Group))}},
+		{RawExtension: runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{
+	"apiVersion": "rbac.authorization.k8s.io/v1",
+	"kind": "ClusterRoleBinding",
+	"metadata": {
+		"name": %q
+	},
+	"roleRef": {
+		"apiGroup": "rbac.authorization.k8s.io",
+		"kind": "ClusterRole",
+		"name": %q
+	},
+	"subjects": [
+		{
+			"kind": "ServiceAccount",
+			"name": %q,
+			"namespace": %q
+		}
+	]
+}`, AGENT_CLUSTER_ROLE, AGENT_CLUSTER_ROLE, AGENT_NAME, AGENT_NAMESPACE))}},
+	}
+}
+
+// agentDeploymentManifest renders the deployment of the agent from the image and transport of the hub
+// configuration, the managed cluster name identifies the managed hub on the hub of hubs. The given
+// volumes are mounted in the agent.
+func agentDeploymentManifest(managedClusterName string, config *HubConfig, agentVolumes []agentVolume) []byte {
+	image := config.AgentImage
+	if image == "" {
+		image = defaultAgentImage
+	}
+	args := []string{"--leaf-hub-name=" + managedClusterName}
+	if config.AgentBootstrapServer != "" {
+		args = append(args, "--kafka-bootstrap-server="+config.AgentBootstrapServer)
+	}
+	labels := map[string]interface{}{"name": AGENT_NAME}
+	container := map[string]interface{}{
+		"name":            AGENT_NAME,
+		"image":           image,
+		"imagePullPolicy": "Always",
+		"args":            args,
+	}
+	podSpec := map[string]interface{}{
+		"serviceAccountName": AGENT_NAME,
+		"containers":         []interface{}{container},
+	}
+	podMeta := map[string]interface{}{"labels": labels}
+	if len(agentVolumes) > 0 {
+		volumes, mounts, hash := []interface{}{}, []interface{}{}, sha256.New()
+		for _, volume := range agentVolumes {
+			source := map[string]interface{}{"name": volume.name}
+			if volume.configMap {
+				source["configMap"] = map[string]interface{}{"name": volume.name}
+			} else {
+				source["secret"] = map[string]interface{}{"secretName": volume.name}
+			}
+			volumes = append(volumes, source)
+			mounts = append(mounts, map[string]interface{}{
+				"name":      volume.name,
+				"mountPath": volume.mountPath,
+				"readOnly":  true,
+			})
+			hash.Write([]byte(volume.hash))
+		}
+		podSpec["volumes"] = volumes
+		container["volumeMounts"] = mounts
+		podMeta["annotations"] = map[string]interface{}{SECRET_HASH_ANNOTATION: fmt.Sprintf("%x", hash.Sum(nil))}
+	}
+	// marshaling generic JSON values does not fail
+	raw, _ := json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      AGENT_NAME,
+			"namespace": AGENT_NAMESPACE,
+		},
+		"spec": map[string]interface{}{
+			"replicas": 1,
+			"selector": map[string]interface{}{"matchLabels": labels},
+			"template": map[string]interface{}{
+				"metadata": podMeta,
+				"spec":     podSpec,
+			},
+		},
+	}, "", "\t")
+	return raw
+}
@@ -0,0 +1,145 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	addonclientv1alpha1 "open-cluster-management.io/api/client/addon/clientset/versioned/typed/addon/v1alpha1"
+	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	clusterclientv1 "open-cluster-management.io/api/client/cluster/clientset/versioned/typed/cluster/v1"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	workclientv1 "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// agentController applies the manifestworks installing the multicluster-global-hub agent, and the
+// observability if configured, on the managed hubs once the MultiClusterHub reports Running.
+type agentController struct {
+	*clusterController
+	addOnClient addonclientv1alpha1.AddonV1alpha1Interface
+	addOnLister addonlisterv1alpha1.ManagedClusterAddOnLister
+}
+
+// NewAgentController creates a new multicluster-global-hub agent controller
+func NewAgentController(
+	clusterclient clusterclientv1.ClusterV1Interface,
+	workclient workclientv1.WorkV1Interface,
+	addOnClient addonclientv1alpha1.AddonV1alpha1Interface,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	workInformer workinformerv1.ManifestWorkInformer,
+	addOnInformer addoninformerv1alpha1.ManagedClusterAddOnInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	secretInformer corev1informers.SecretInformer,
+	overrideInformer informers.GenericInformer,
+	restoreInformer informers.GenericInformer,
+	options ControllerOptions,
+	recorder events.Recorder,
+	clusterRecorder record.EventRecorder) factory.Controller {
+	c := &agentController{
+		clusterController: newClusterController("AgentController", clusterclient, workclient,
+			clusterInformer, workInformer, configMapInformer, secretInformer, overrideInformer, restoreInformer, options,
+			HubConditionAgentParked, recorder, clusterRecorder),
+		addOnClient: addOnClient,
+		addOnLister: addOnInformer.Lister(),
+	}
+	c.reconcile = c.reconcileAgent
+	c.ownedWorks = []string{HOH_HUB_CLUSTER_AGENT, HOH_HUB_CLUSTER_OBSERVABILITY}
+	return c.newFactory(clusterInformer, workInformer, configMapInformer, HOH_HUB_CLUSTER_MCH, HOH_HUB_CLUSTER_AGENT,
+		HOH_HUB_CLUSTER_OBSERVABILITY).
+		// rotate the transport credentials of all agents and the observability secrets when they are
+		// changed
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				return factory.DefaultQueueKey
+			},
+			func(obj interface{}) bool {
+				accessor, err := objectMeta(obj)
+				if err != nil {
+					return false
+				}
+				config := c.hubConfig.get()
+				switch accessor.GetName() {
+				case config.TransportSecret, config.ObservabilityWriteSecret, config.ObservabilityStorageSecret:
+					return accessor.GetName() != ""
+				}
+				return false
+			}, secretInformer.Informer()).
+		// and the CA bundle when it is rotated
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				return factory.DefaultQueueKey
+			},
+			func(obj interface{}) bool {
+				accessor, err := objectMeta(obj)
+				return err == nil && accessor.GetName() == c.hubConfig.get().CABundleConfigMap
+			}, configMapInformer.Informer()).
+		// recreate the addon of the agent when it is deleted
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return accessor.GetNamespace()
+			},
+			func(obj interface{}) bool {
+				accessor, err := objectMeta(obj)
+				return err == nil && accessor.GetName() == AGENT_ADDON_NAME && c.ownsCluster(accessor.GetNamespace())
+			}, addOnInformer.Informer()).
+		ToController(c.name, recorder)
+}
+
+func (c *agentController) reconcileAgent(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster) error {
+	// the addon reports the status of the agent, it is maintained even if the hub is paused
+	agent, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_AGENT)
+	if err != nil {
+		return err
+	}
+	if err := c.ensureAddOn(ctx, managedCluster, agent); err != nil {
+		return err
+	}
+
+	if c.paused(ctx, managedCluster) || !c.inMaintenanceWindow(ctx, syncCtx, managedCluster) {
+		return nil
+	}
+	subscription, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_SUBSCRIPTION)
+	if err != nil {
+		return err
+	}
+	if c.upgrading(ctx, syncCtx, managedCluster, subscription) {
+		return nil
+	}
+	mch, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_MCH)
+	if err != nil {
+		return err
+	}
+	// the agent syncs the resources of the MultiClusterHub, it is installed once the hub is running.
+	// The manifestwork is synced again when the feedback of the mch manifestwork changes.
+	if GetFeedbackValue(mch, "MultiClusterHub", MCH_PHASE_FEEDBACK) != MCH_PHASE_RUNNING {
+		return nil
+	}
+
+	config := c.hubConfig.get()
+	desired, err := c.desiredAgentManifestWork(managedCluster, config)
+	if err != nil {
+		return err
+	}
+	if !c.waveOpen(ctx, syncCtx, managedCluster, desired) {
+		return nil
+	}
+	agent, err = c.applyManifestWork(ctx, managedCluster, desired)
+	if err != nil {
+		return err
+	}
+	if IsResourceMissing(agent, "Deployment") {
+		return c.repairManifestWork(ctx, syncCtx, managedCluster, agent, "Deployment")
+	}
+	return c.applyObservability(ctx, syncCtx, managedCluster, config)
+}
@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+// newTestAgentController returns a test controller reconciling the agent manifestwork
+func newTestAgentController(t *testing.T, managedClusters []*clusterv1.ManagedCluster, works []*workv1.ManifestWork) *testController {
+	ctrl := newTestController(t, managedClusters, works)
+	agent := &agentController{
+		clusterController: ctrl.clusterController,
+		addOnClient:       addonfake.NewSimpleClientset().AddonV1alpha1(),
+		addOnLister:       addonlisterv1alpha1.NewManagedClusterAddOnLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+	}
+	ctrl.reconcile = agent.reconcileAgent
+	ctrl.parkedCondition = HubConditionAgentParked
+	return ctrl
+}
+
+func TestReconcileAgent(t *testing.T) {
+	cases := []struct {
+		name     string
+		phase    string
+		expected bool
+	}{
+		{name: "mch installing", phase: "Installing", expected: false},
+		{name: "mch running", phase: MCH_PHASE_RUNNING, expected: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := newManagedCluster("cluster1")
+			mch, err := CreateMCHManifestwork("cluster1", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			mch = withFeedback(mch, "MultiClusterHub", map[string]string{MCH_PHASE_FEEDBACK: c.phase})
+			SetManagedClusterUID(mch, managedCluster)
+			ctrl := newTestAgentController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{mch})
+
+			syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+			if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			agent, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+				Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_AGENT, metav1.GetOptions{})
+			if !c.expected {
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the agent manifestwork not to be created, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected the agent manifestwork to be created: %v", err)
+			}
+			manifests := agent.Spec.Workload.Manifests
+			if deployment := string(manifests[len(manifests)-1].Raw); !strings.Contains(deployment, "--leaf-hub-name=cluster1") {
+				t.Errorf("expected the agent to be named after the managed cluster, got %s", deployment)
+			}
+		})
+	}
+}
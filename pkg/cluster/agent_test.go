@@ -0,0 +1,161 @@
+package cluster
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// agentPodAnnotations returns the pod template annotations of the agent deployment of the manifestwork
+func agentPodAnnotations(t *testing.T, agent *workv1.ManifestWork) map[string]string {
+	manifests := agent.Spec.Workload.Manifests
+	deployment := struct {
+		Spec struct {
+			Template struct {
+				Metadata struct {
+					Annotations map[string]string `json:"annotations"`
+				} `json:"metadata"`
+			} `json:"template"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(manifests[len(manifests)-1].Raw, &deployment); err != nil {
+		t.Fatal(err)
+	}
+	return deployment.Spec.Template.Metadata.Annotations
+}
+
+func TestDesiredAgentManifestWorkTransportSecret(t *testing.T) {
+	config := DefaultHubConfig()
+	config.TransportSecret = "kafka-credentials"
+	managedCluster := newManagedCluster("cluster1")
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	c := &clusterController{
+		options:      ControllerOptions{ConfigNamespace: "test"},
+		secretLister: corev1listers.NewSecretLister(indexer).Secrets("test"),
+	}
+	if _, err := c.desiredAgentManifestWork(managedCluster, config); err == nil {
+		t.Errorf("expected an error for the missing transport secret")
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka-credentials", Namespace: "test"},
+		Data:       map[string][]byte{"ca.crt": []byte("ca")},
+	}
+	if err := indexer.Add(secret); err != nil {
+		t.Fatal(err)
+	}
+	agent, err := c.desiredAgentManifestWork(managedCluster, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	found := false
+	for _, manifest := range agent.Spec.Workload.Manifests {
+		if strings.Contains(string(manifest.Raw), `"name":"`+AGENT_TRANSPORT_SECRET+`","namespace":"`+AGENT_NAMESPACE+`"`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the transport secret to be copied to the agent namespace")
+	}
+	hash := agentPodAnnotations(t, agent)[SECRET_HASH_ANNOTATION]
+	if hash == "" {
+		t.Fatalf("expected the agent to be annotated with the secret hash")
+	}
+
+	// the agent is rolled out when the credentials are rotated
+	rotated := secret.DeepCopy()
+	rotated.Data["ca.crt"] = []byte("rotated")
+	if err := indexer.Update(rotated); err != nil {
+		t.Fatal(err)
+	}
+	agent, err = c.desiredAgentManifestWork(managedCluster, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rotatedHash := agentPodAnnotations(t, agent)[SECRET_HASH_ANNOTATION]; rotatedHash == hash {
+		t.Errorf("expected the secret hash to change when the secret is rotated")
+	}
+}
+
+func TestDesiredAgentManifestWorkCABundle(t *testing.T) {
+	config := DefaultHubConfig()
+	config.CABundleConfigMap = "global-hub-ca"
+	c := &clusterController{
+		hubConfig: newHubConfigLoader(newConfigMapLister(t, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "global-hub-ca", Namespace: "test"},
+			Data:       map[string]string{"ca-bundle.crt": "ca"},
+		})),
+	}
+	agent, err := c.desiredAgentManifestWork(newManagedCluster("cluster1"), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manifests := agent.Spec.Workload.Manifests
+	found := false
+	for _, manifest := range manifests {
+		if strings.Contains(string(manifest.Raw), `"kind":"ConfigMap"`) &&
+			strings.Contains(string(manifest.Raw), `"name":"`+AGENT_CA_BUNDLE+`","namespace":"`+AGENT_NAMESPACE+`"`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the CA bundle to be copied to the agent namespace")
+	}
+	if deployment := string(manifests[len(manifests)-1].Raw); !strings.Contains(deployment, "/var/run/secrets/ca-bundle") {
+		t.Errorf("expected the CA bundle to be mounted in the agent, got %s", deployment)
+	}
+	if agentPodAnnotations(t, agent)[SECRET_HASH_ANNOTATION] == "" {
+		t.Errorf("expected the agent to be annotated with the CA bundle hash")
+	}
+
+	config.CABundleConfigMap = "missing-ca"
+	if _, err := c.desiredAgentManifestWork(newManagedCluster("cluster1"), config); err == nil {
+		t.Errorf("expected an error for the missing CA bundle")
+	}
+}
+
+func TestAgentRBACManifests(t *testing.T) {
+	manifests := CreateAgentManifestwork("cluster1", DefaultHubConfig()).Spec.Workload.Manifests
+	var role *rbacv1.ClusterRole
+	var binding *rbacv1.ClusterRoleBinding
+	for _, manifest := range manifests {
+		object := &metav1.TypeMeta{}
+		if err := json.Unmarshal(manifest.Raw, object); err != nil {
+			t.Fatal(err)
+		}
+		switch object.Kind {
+		case "ClusterRole":
+			role = &rbacv1.ClusterRole{}
+			if err := json.Unmarshal(manifest.Raw, role); err != nil {
+				t.Fatal(err)
+			}
+		case "ClusterRoleBinding":
+			binding = &rbacv1.ClusterRoleBinding{}
+			if err := json.Unmarshal(manifest.Raw, binding); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if role == nil || role.Name != AGENT_CLUSTER_ROLE || len(role.Rules) == 0 {
+		t.Fatalf("expected the agent ClusterRole, got %v", role)
+	}
+	for _, rule := range role.Rules {
+		for _, value := range append(append(rule.APIGroups, rule.Resources...), rule.Verbs...) {
+			if value == "*" {
+				t.Errorf("expected the agent ClusterRole to list its resources and verbs, got %v", rule)
+			}
+		}
+	}
+	if binding == nil || binding.RoleRef.Name != AGENT_CLUSTER_ROLE || len(binding.Subjects) != 1 ||
+		binding.Subjects[0].Name != AGENT_NAME || binding.Subjects[0].Namespace != AGENT_NAMESPACE {
+		t.Errorf("expected the agent serviceaccount to be bound to the agent ClusterRole, got %v", binding)
+	}
+}
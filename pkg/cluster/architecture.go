@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// ARCHITECTURE_CLAIM is the ClusterClaim reporting the CPU architecture of the nodes of the managed
+// cluster, such as amd64, arm64, s390x or ppc64le. The managed clusters without the claim are
+// handled as amd64.
+const ARCHITECTURE_CLAIM = "architecture.hub-of-hubs.open-cluster-management.io"
+
+// ARCHITECTURE_AMD64 is the architecture of the builds of the catalogSource configuration
+const ARCHITECTURE_AMD64 = "amd64"
+
+// architectureAliases maps the architecture names reported by the kernel to the ones of the builds
+var architectureAliases = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+}
+
+// ArchitectureCatalog is the catalog of the hub builds of a CPU architecture.
+type ArchitectureCatalog struct {
+	// CatalogSource is the catalog source of the builds of the architecture
+	CatalogSource string `json:"catalogSource"`
+	// Channels are the channels with a build of the architecture, all channels have a build if empty
+	Channels []string `json:"channels,omitempty"`
+}
+
+// Architecture returns the CPU architecture of the managed cluster from its architecture claim.
+func Architecture(managedCluster *clusterv1.ManagedCluster) string {
+	for _, claim := range managedCluster.Status.ClusterClaims {
+		if claim.Name != ARCHITECTURE_CLAIM || claim.Value == "" {
+			continue
+		}
+		if architecture, ok := architectureAliases[claim.Value]; ok {
+			return architecture
+		}
+		return claim.Value
+	}
+	return ARCHITECTURE_AMD64
+}
+
+// UnsupportedArchitectureError is returned when the configured hub has no build for the architecture
+// of a managed cluster, the subscription manifestwork is not updated until a build is configured.
+type UnsupportedArchitectureError struct {
+	Architecture string
+	Channel      string
+}
+
+func (e *UnsupportedArchitectureError) Error() string {
+	return fmt.Sprintf("the channel %s has no build for the %s architecture, configure it in %s",
+		e.Channel, e.Architecture, HUB_CONFIG_ARCHITECTURE_CATALOGS_KEY)
+}
+
+// architectureCatalogSource returns the catalog source of the builds of the architecture of the
+// managed cluster for the configured channel, or an empty string for the builds of the catalogSource
+// configuration.
+func architectureCatalogSource(managedCluster *clusterv1.ManagedCluster, config *HubConfig) (string, error) {
+	architecture := Architecture(managedCluster)
+	catalog, ok := config.ArchitectureCatalogs[architecture]
+	if !ok {
+		if architecture == ARCHITECTURE_AMD64 {
+			return "", nil
+		}
+		return "", &UnsupportedArchitectureError{Architecture: architecture, Channel: config.Channel}
+	}
+	if len(catalog.Channels) > 0 && !sets.NewString(catalog.Channels...).Has(config.Channel) {
+		return "", &UnsupportedArchitectureError{Architecture: architecture, Channel: config.Channel}
+	}
+	return catalog.CatalogSource, nil
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+// withArchitecture returns the managed cluster with the given architecture claim
+func withArchitecture(managedCluster *clusterv1.ManagedCluster, architecture string) *clusterv1.ManagedCluster {
+	managedCluster.Status.ClusterClaims = append(managedCluster.Status.ClusterClaims,
+		clusterv1.ManagedClusterClaim{Name: ARCHITECTURE_CLAIM, Value: architecture})
+	return managedCluster
+}
+
+func TestCatalogSourceByArchitecture(t *testing.T) {
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_ARCHITECTURE_CATALOGS_KEY: `{
+			"arm64": {"catalogSource": "redhat-operators-arm64"},
+			"s390x": {"catalogSource": "redhat-operators-s390x", "channels": ["release-2.5"]}
+		}`,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cases := []struct {
+		name         string
+		architecture string
+		expected     string
+		unsupported  bool
+	}{
+		{name: "no claim", expected: defaultCatalogSource},
+		{name: "amd64", architecture: "x86_64", expected: defaultCatalogSource},
+		{name: "arm64", architecture: "aarch64", expected: "redhat-operators-arm64"},
+		{name: "channel without build", architecture: "s390x", unsupported: true},
+		{name: "architecture without build", architecture: "ppc64le", unsupported: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := newManagedCluster("cluster1")
+			if c.architecture != "" {
+				withArchitecture(managedCluster, c.architecture)
+			}
+			source, err := CatalogSource(managedCluster, config)
+			var unsupported *UnsupportedArchitectureError
+			if errors.As(err, &unsupported) != c.unsupported {
+				t.Fatalf("expected unsupported %v, got %v", c.unsupported, err)
+			}
+			if source != c.expected {
+				t.Errorf("expected the catalog source %q, got %q", c.expected, source)
+			}
+		})
+	}
+
+	if _, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_ARCHITECTURE_CATALOGS_KEY: `{"arm64": {"channels": ["release-2.4"]}}`,
+	})); err == nil {
+		t.Errorf("expected an architecture catalog without catalog source to be rejected")
+	}
+}
+
+func TestReconcileSkipsUnsupportedArchitecture(t *testing.T) {
+	managedCluster := withArchitecture(newManagedCluster("cluster1"), "ppc64le")
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{managedCluster})
+
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	works, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(works.Items) != 0 {
+		t.Errorf("expected the subscription manifestwork not to be created, got %d manifestworks", len(works.Items))
+	}
+	updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cond := meta.FindStatusCondition(updated.Status.Conditions, HubConditionArchitectureUnsupported); cond == nil ||
+		cond.Status != metav1.ConditionTrue || !strings.Contains(cond.Message, "ppc64le") {
+		t.Errorf("expected the unsupported architecture to be reported, got %v", updated.Status.Conditions)
+	}
+	if events := recordedEvents(ctrl.clusterRecorder.(*record.FakeRecorder)); len(events) != 1 ||
+		!strings.Contains(events[0], EventReasonUnsupportedArchitecture) {
+		t.Errorf("expected an %s event, got %v", EventReasonUnsupportedArchitecture, events)
+	}
+}
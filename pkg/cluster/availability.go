@@ -0,0 +1,70 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// HOH_SIZE_LABEL presets the availability of the hub of the managed cluster by its size, small
+// managed clusters such as edge clusters get a Basic hub and large managed clusters a High one.
+const HOH_SIZE_LABEL = "hoh-size"
+
+// sizes of the HOH_SIZE_LABEL
+const (
+	HOH_SIZE_SMALL = "small"
+	HOH_SIZE_LARGE = "large"
+)
+
+// NODE_COUNT_CLAIM is the ClusterClaim reporting the number of nodes of the managed cluster, the hub
+// gets a Basic availability if it does not exceed the basicAvailabilityMaxNodes configuration.
+const NODE_COUNT_CLAIM = "nodecount.hub-of-hubs.open-cluster-management.io"
+
+// availability configurations of the MultiClusterHub
+const (
+	AVAILABILITY_HIGH  = "High"
+	AVAILABILITY_BASIC = "Basic"
+)
+
+// AvailabilityConfig returns the availability preset of the hub of the managed cluster, from its size
+// label or else its node count claim, or an empty string if there is no preset. The HyperShift hosted
+// clusters get a Basic hub unless labeled otherwise, their few worker nodes also run the workloads.
+func AvailabilityConfig(managedCluster *clusterv1.ManagedCluster, config *HubConfig) string {
+	switch managedCluster.Labels[HOH_SIZE_LABEL] {
+	case HOH_SIZE_SMALL:
+		return AVAILABILITY_BASIC
+	case HOH_SIZE_LARGE:
+		return AVAILABILITY_HIGH
+	}
+	if IsHyperShift(managedCluster) {
+		return AVAILABILITY_BASIC
+	}
+	if config.BasicAvailabilityMaxNodes <= 0 {
+		return ""
+	}
+	for _, claim := range managedCluster.Status.ClusterClaims {
+		if claim.Name != NODE_COUNT_CLAIM {
+			continue
+		}
+		nodes, err := strconv.Atoi(claim.Value)
+		if err != nil {
+			return ""
+		}
+		if nodes <= config.BasicAvailabilityMaxNodes {
+			return AVAILABILITY_BASIC
+		}
+		return AVAILABILITY_HIGH
+	}
+	return ""
+}
+
+// availabilityMCH returns the MultiClusterHub setting the availability preset of the managed cluster,
+// to be merged onto the default one, or an empty string if there is no preset.
+func availabilityMCH(managedCluster *clusterv1.ManagedCluster, config *HubConfig) string {
+	availability := AvailabilityConfig(managedCluster, config)
+	if availability == "" {
+		return ""
+	}
+	return fmt.Sprintf(`{"spec":{"availabilityConfig":%q}}`, availability)
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func TestAvailabilityConfig(t *testing.T) {
+	config := DefaultHubConfig()
+	config.BasicAvailabilityMaxNodes = 3
+	cases := []struct {
+		name     string
+		labels   map[string]string
+		nodes    string
+		config   *HubConfig
+		expected string
+	}{
+		{
+			name:     "no preset",
+			config:   config,
+			expected: "",
+		},
+		{
+			name:     "small",
+			labels:   map[string]string{HOH_SIZE_LABEL: HOH_SIZE_SMALL},
+			nodes:    "10",
+			config:   config,
+			expected: AVAILABILITY_BASIC,
+		},
+		{
+			name:     "large",
+			labels:   map[string]string{HOH_SIZE_LABEL: HOH_SIZE_LARGE},
+			config:   config,
+			expected: AVAILABILITY_HIGH,
+		},
+		{
+			name:     "few nodes",
+			nodes:    "3",
+			config:   config,
+			expected: AVAILABILITY_BASIC,
+		},
+		{
+			name:     "many nodes",
+			nodes:    "4",
+			config:   config,
+			expected: AVAILABILITY_HIGH,
+		},
+		{
+			name:     "node count preset disabled",
+			nodes:    "1",
+			config:   DefaultHubConfig(),
+			expected: "",
+		},
+		{
+			name:     "invalid node count",
+			nodes:    "three",
+			config:   config,
+			expected: "",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := newManagedCluster("cluster1")
+			managedCluster.Labels = c.labels
+			if c.nodes != "" {
+				managedCluster.Status.ClusterClaims = []clusterv1.ManagedClusterClaim{{Name: NODE_COUNT_CLAIM, Value: c.nodes}}
+			}
+			if availability := AvailabilityConfig(managedCluster, c.config); availability != c.expected {
+				t.Errorf("expected the availability %q, got %q", c.expected, availability)
+			}
+		})
+	}
+}
+
+func TestDesiredMCHManifestWorkAvailability(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	managedCluster.Labels = map[string]string{HOH_SIZE_LABEL: HOH_SIZE_SMALL}
+	work, _, err := (&clusterController{}).desiredMCHManifestWork(managedCluster, DefaultHubConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mch := string(work.Spec.Workload.Manifests[0].Raw); !strings.Contains(mch, `"availabilityConfig":"Basic"`) {
+		t.Errorf("expected a Basic multiclusterhub, got %s", mch)
+	}
+
+	// the user defined mch takes precedence over the preset
+	managedCluster.Annotations = map[string]string{HOH_MCH_ANNOTATION: `{"spec":{"availabilityConfig":"High"}}`}
+	work, _, err = (&clusterController{}).desiredMCHManifestWork(managedCluster, DefaultHubConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mch := string(work.Spec.Workload.Manifests[0].Raw); !strings.Contains(mch, `"availabilityConfig":"High"`) {
+		t.Errorf("expected a High multiclusterhub, got %s", mch)
+	}
+}
@@ -0,0 +1,136 @@
+package cluster
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// HOH_RETRY_ANNOTATION can be set or changed on a parked managed cluster to retry it manually.
+const HOH_RETRY_ANNOTATION = "hoh-retry"
+
+const (
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 5 * time.Minute
+)
+
+// clusterBackoff tracks the failed syncs of each managed cluster to retry them with an exponential
+// backoff, and parks a cluster once its retry budget is exhausted, like a circuit breaker. A parked
+// cluster is not synced until its desired state changes or the cooldown elapses, then it is retried
+// once and parked again if it still fails. The state is kept in memory, so the budget is reset on
+// restart.
+type clusterBackoff struct {
+	lock       sync.Mutex
+	maxRetries int
+	// cooldown is the time after which a parked cluster is retried once, it is only retried on a
+	// change if 0
+	cooldown time.Duration
+	clusters map[string]*backoffState
+}
+
+type backoffState struct {
+	failures int
+	// desiredState identifies the desired state the failures happened for
+	desiredState string
+	// parkedAt is the time the cluster was parked
+	parkedAt time.Time
+}
+
+func newClusterBackoff(maxRetries int, cooldown time.Duration) *clusterBackoff {
+	return &clusterBackoff{
+		maxRetries: maxRetries,
+		cooldown:   cooldown,
+		clusters:   map[string]*backoffState{},
+	}
+}
+
+// parked returns true if the retry budget of the cluster is exhausted for its current desired
+// state. The backoff of the cluster is reset if its desired state changed, and a single retry is
+// allowed once the cooldown elapsed.
+func (b *clusterBackoff) parked(managedCluster *clusterv1.ManagedCluster) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state, ok := b.clusters[managedCluster.Name]
+	if !ok {
+		return false
+	}
+	if state.desiredState != desiredStateKey(managedCluster) {
+		delete(b.clusters, managedCluster.Name)
+		return false
+	}
+	if b.maxRetries <= 0 || state.failures < b.maxRetries {
+		return false
+	}
+	if b.cooldown > 0 && time.Since(state.parkedAt) >= b.cooldown {
+		state.failures = b.maxRetries - 1
+		return false
+	}
+	return true
+}
+
+// failed records a failed sync of the cluster, it returns the delay before the cluster should be
+// retried, or true if the cluster is parked.
+func (b *clusterBackoff) failed(managedCluster *clusterv1.ManagedCluster) (time.Duration, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state, ok := b.clusters[managedCluster.Name]
+	if !ok {
+		state = &backoffState{desiredState: desiredStateKey(managedCluster)}
+		b.clusters[managedCluster.Name] = state
+	}
+	state.failures++
+	if b.maxRetries > 0 && state.failures >= b.maxRetries {
+		state.parkedAt = time.Now()
+		return 0, true
+	}
+
+	return retryDelay(state.failures), false
+}
+
+// retrying returns the backoff delay of the cluster if its last syncs failed.
+func (b *clusterBackoff) retrying(name string) (time.Duration, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	state, ok := b.clusters[name]
+	if !ok {
+		return 0, false
+	}
+	return retryDelay(state.failures), true
+}
+
+// retryDelay returns the exponential backoff delay after the given number of failures
+func retryDelay(failures int) time.Duration {
+	if failures > 16 {
+		return retryMaxDelay
+	}
+	if d := retryBaseDelay << (failures - 1); d < retryMaxDelay {
+		return d
+	}
+	return retryMaxDelay
+}
+
+// succeeded resets the backoff of the cluster.
+func (b *clusterBackoff) succeeded(name string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.clusters, name)
+}
+
+// desiredStateKey identifies the desired state of the hub on the managed cluster, that is the spec
+// of the managed cluster, the user defined mch or its override, the catalog source and image
+// repository overrides and the manual retry annotation.
+func desiredStateKey(managedCluster *clusterv1.ManagedCluster) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(managedCluster.Annotations[HOH_MCH_ANNOTATION]))
+	hash.Write([]byte(managedCluster.Annotations[HOH_MCH_OVERRIDE_ANNOTATION]))
+	hash.Write([]byte(managedCluster.Annotations[HOH_CATALOG_SOURCE_ANNOTATION]))
+	hash.Write([]byte(managedCluster.Annotations[HOH_IMAGE_REPOSITORY_ANNOTATION]))
+	hash.Write([]byte(managedCluster.Annotations[HOSTING_CLUSTER_NAME_ANNOTATION]))
+	return fmt.Sprintf("%d/%x/%s", managedCluster.Generation, hash.Sum64(),
+		managedCluster.Annotations[HOH_RETRY_ANNOTATION])
+}
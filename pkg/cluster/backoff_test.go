@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func TestClusterBackoff(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	backoff := newClusterBackoff(3, 0)
+
+	expectedDelays := []time.Duration{5 * time.Second, 10 * time.Second}
+	for _, expected := range expectedDelays {
+		if backoff.parked(managedCluster) {
+			t.Fatalf("expected the cluster not to be parked")
+		}
+		delay, parked := backoff.failed(managedCluster)
+		if parked || delay != expected {
+			t.Fatalf("expected delay %s, got %s (parked %v)", expected, delay, parked)
+		}
+	}
+	if _, parked := backoff.failed(managedCluster); !parked {
+		t.Fatalf("expected the cluster to be parked")
+	}
+	if !backoff.parked(managedCluster) {
+		t.Fatalf("expected the cluster to be parked")
+	}
+
+	// the cluster is unparked once the retry annotation is set
+	managedCluster.Annotations = map[string]string{HOH_RETRY_ANNOTATION: "1"}
+	if backoff.parked(managedCluster) {
+		t.Fatalf("expected the cluster not to be parked")
+	}
+	if delay, _ := backoff.failed(managedCluster); delay != retryBaseDelay {
+		t.Fatalf("expected the backoff to be reset, got delay %s", delay)
+	}
+
+	backoff.succeeded("cluster1")
+	if delay, _ := backoff.failed(managedCluster); delay != retryBaseDelay {
+		t.Fatalf("expected the backoff to be reset, got delay %s", delay)
+	}
+}
+
+func TestClusterBackoffMaxDelay(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	backoff := newClusterBackoff(0, 0)
+
+	var delay time.Duration
+	for i := 0; i < 100; i++ {
+		var parked bool
+		delay, parked = backoff.failed(managedCluster)
+		if parked {
+			t.Fatalf("expected the cluster never to be parked without a retry budget")
+		}
+	}
+	if delay != retryMaxDelay {
+		t.Errorf("expected delay %s, got %s", retryMaxDelay, delay)
+	}
+}
+
+func TestClusterBackoffCooldown(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	backoff := newClusterBackoff(2, time.Hour)
+
+	backoff.failed(managedCluster)
+	if _, parked := backoff.failed(managedCluster); !parked {
+		t.Fatalf("expected the cluster to be parked")
+	}
+	if !backoff.parked(managedCluster) {
+		t.Fatalf("expected the cluster to stay parked during the cooldown")
+	}
+
+	// the cluster is retried once after the cooldown, and parked again on the next failure
+	backoff.clusters["cluster1"].parkedAt = time.Now().Add(-time.Hour)
+	if backoff.parked(managedCluster) {
+		t.Fatalf("expected the cluster to be retried after the cooldown")
+	}
+	if _, parked := backoff.failed(managedCluster); !parked {
+		t.Fatalf("expected the cluster to be parked again after a failed retry")
+	}
+	if !backoff.parked(managedCluster) {
+		t.Fatalf("expected the cooldown to start again")
+	}
+}
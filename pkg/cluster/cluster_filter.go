@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// managedClusterChanged returns true if the managed cluster changed between the old and new objects of
+// an update event other than by the HubReconcileError condition, which the controllers write on every
+// failed reconcile. Its update would otherwise requeue the managed hub at once and retry it without
+// waiting for its backoff delay. The resyncs of the informer, with the same resource version, are kept.
+func managedClusterChanged(old, new *clusterv1.ManagedCluster) bool {
+	if old.ResourceVersion == new.ResourceVersion {
+		return true
+	}
+	old, new = old.DeepCopy(), new.DeepCopy()
+	for _, managedCluster := range []*clusterv1.ManagedCluster{old, new} {
+		managedCluster.ResourceVersion = ""
+		managedCluster.ManagedFields = nil
+		meta.RemoveStatusCondition(&managedCluster.Status.Conditions, HubConditionReconcileError)
+	}
+	return !equality.Semantic.DeepEqual(old, new)
+}
+
+// clusterEventHandler enqueues the managed hubs owned by the controller into the queue. The creations
+// and deletions of the managed clusters always trigger a sync, the updates unless only their
+// HubReconcileError condition changed.
+func (c *clusterController) clusterEventHandler(queue workqueue.Interface) cache.ResourceEventHandler {
+	enqueue := func(obj interface{}) {
+		accessor, err := objectMeta(obj)
+		if err != nil {
+			return
+		}
+		c.enqueued(accessor.GetName())
+		c.coalescer.clusterChanged(accessor.GetName())
+		queue.Add(accessor.GetName())
+	}
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			accessor, err := objectMeta(obj)
+			if err != nil {
+				return false
+			}
+			return IsManagedHub(accessor) && c.ownsCluster(accessor.GetName())
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: enqueue,
+			UpdateFunc: func(old, new interface{}) {
+				oldCluster, ok := old.(*clusterv1.ManagedCluster)
+				newCluster, ok2 := new.(*clusterv1.ManagedCluster)
+				if ok && ok2 && !managedClusterChanged(oldCluster, newCluster) {
+					return
+				}
+				enqueue(new)
+			},
+			DeleteFunc: enqueue,
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestClusterEventHandler(t *testing.T) {
+	ctrl := newTestController(t, nil, nil)
+	managedCluster := newManagedCluster("cluster1")
+	managedCluster.ResourceVersion = "1"
+	disabled := newManagedCluster("cluster2")
+	disabled.Labels = map[string]string{HOH_LABEL: HOH_LABEL_DISABLED}
+
+	queue := workqueue.New()
+	defer queue.ShutDown()
+	handler := ctrl.clusterEventHandler(queue)
+	expectQueued := func(expected ...string) {
+		t.Helper()
+		if queue.Len() != len(expected) {
+			t.Fatalf("expected %v to be queued, got %d keys", expected, queue.Len())
+		}
+		for _, name := range expected {
+			key, _ := queue.Get()
+			if key != name {
+				t.Errorf("expected %s to be queued, got %v", name, key)
+			}
+			queue.Done(key)
+		}
+	}
+
+	handler.OnAdd(managedCluster)
+	handler.OnAdd(disabled)
+	expectQueued("cluster1")
+
+	// the reconcile error reported by the controllers does not trigger a sync
+	failed := managedCluster.DeepCopy()
+	failed.ResourceVersion = "2"
+	meta.SetStatusCondition(&failed.Status.Conditions,
+		ctrl.reconcileErrorCondition(errors.New("failed to apply the manifestwork")))
+	handler.OnUpdate(managedCluster, failed)
+	expectQueued()
+
+	// the other changes and the resyncs of the informer do
+	labeled := failed.DeepCopy()
+	labeled.ResourceVersion = "3"
+	labeled.Labels = map[string]string{"environment": "dev"}
+	handler.OnUpdate(failed, labeled)
+	expectQueued("cluster1")
+	available := labeled.DeepCopy()
+	available.ResourceVersion = "4"
+	meta.SetStatusCondition(&available.Status.Conditions, metav1.Condition{
+		Type: HubConditionInstalled, Status: metav1.ConditionTrue, Reason: "Installed",
+	})
+	handler.OnUpdate(labeled, available)
+	expectQueued("cluster1")
+	handler.OnUpdate(available, available)
+	expectQueued("cluster1")
+
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "cluster1", Obj: available})
+	expectQueued("cluster1")
+}
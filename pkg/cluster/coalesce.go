@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// eventCoalescer collapses the bursts of manifestwork events of a managed hub into one sync. The work
+// agents update the status of the manifestworks frequently, so a managed hub synced less than the
+// coalescing window ago is requeued at the end of the window, and the events received meanwhile are
+// deduplicated by the queue. The changes of the managed cluster itself are never delayed.
+type eventCoalescer struct {
+	lock sync.Mutex
+	// lastSync is the start of the last sync of each managed hub
+	lastSync map[string]time.Time
+	// clusterChanges holds the managed hubs queued by a change of their managed cluster
+	clusterChanges sets.String
+}
+
+func newEventCoalescer() *eventCoalescer {
+	return &eventCoalescer{
+		lastSync:       map[string]time.Time{},
+		clusterChanges: sets.NewString(),
+	}
+}
+
+// clusterChanged records the managed hub is queued by a change of its managed cluster
+func (e *eventCoalescer) clusterChanged(name string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.clusterChanges.Insert(name)
+}
+
+// delay returns the time to wait before syncing the managed hub, or 0 if it is synced now
+func (e *eventCoalescer) delay(name string, window time.Duration, now time.Time) time.Duration {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if last, ok := e.lastSync[name]; ok && window > 0 && !e.clusterChanges.Has(name) {
+		if remaining := last.Add(window).Sub(now); remaining > 0 {
+			return remaining
+		}
+	}
+	e.lastSync[name] = now
+	e.clusterChanges.Delete(name)
+	return 0
+}
+
+// forget drops the state of a deleted managed hub
+func (e *eventCoalescer) forget(name string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	delete(e.lastSync, name)
+	e.clusterChanges.Delete(name)
+}
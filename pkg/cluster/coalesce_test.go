@@ -0,0 +1,72 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func TestEventCoalescer(t *testing.T) {
+	coalescer := newEventCoalescer()
+	now := time.Now()
+	window := 5 * time.Second
+
+	if delay := coalescer.delay("cluster1", window, now); delay != 0 {
+		t.Errorf("expected the first sync not to be delayed, got %s", delay)
+	}
+	if delay := coalescer.delay("cluster1", window, now.Add(2*time.Second)); delay != 3*time.Second {
+		t.Errorf("expected the sync to be delayed to the end of the window, got %s", delay)
+	}
+	if delay := coalescer.delay("cluster1", window, now.Add(window)); delay != 0 {
+		t.Errorf("expected the sync at the end of the window not to be delayed, got %s", delay)
+	}
+
+	// the changes of the managed cluster are synced right away
+	coalescer.clusterChanged("cluster1")
+	if delay := coalescer.delay("cluster1", window, now.Add(window+time.Second)); delay != 0 {
+		t.Errorf("expected the cluster change not to be delayed, got %s", delay)
+	}
+	if delay := coalescer.delay("cluster1", 0, now.Add(window+time.Second)); delay != 0 {
+		t.Errorf("expected no delay without window, got %s", delay)
+	}
+}
+
+func TestSyncCoalescesWorkEvents(t *testing.T) {
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{newManagedCluster("cluster1")}, nil)
+	ctrl.options.EventCoalescingWindow = 50 * time.Millisecond
+	syncs := 0
+	ctrl.reconcile = func(ctx context.Context, syncCtx factory.SyncContext, managedCluster *clusterv1.ManagedCluster) error {
+		syncs++
+		return nil
+	}
+
+	// a burst of three manifestwork events is synced once, then once more at the end of the window
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+	for i := 0; i < 3; i++ {
+		if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if syncs != 1 {
+		t.Errorf("expected 1 sync, got %d", syncs)
+	}
+	if key, _ := syncCtx.Queue().Get(); key != "cluster1" {
+		t.Fatalf("expected the managed hub to be requeued, got %v", key)
+	}
+	syncCtx.Queue().Done("cluster1")
+	if syncCtx.Queue().Len() != 0 {
+		t.Errorf("expected the requeues to be collapsed, got %d", syncCtx.Queue().Len())
+	}
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if syncs != 2 {
+		t.Errorf("expected 2 syncs, got %d", syncs)
+	}
+}
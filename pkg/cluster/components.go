@@ -0,0 +1,72 @@
+package cluster
+
+import (
+	"encoding/json"
+	"sort"
+
+	"k8s.io/klog/v2"
+)
+
+// MCH profiles of the hub configuration
+const (
+	// MCHProfileFull installs all the components of the MultiClusterHub
+	MCHProfileFull = "full"
+	// MCHProfileLeaf disables the components a hub managed by the hub of hubs does not need, as its
+	// clusters are searched, deployed to and viewed from the hub of hubs
+	MCHProfileLeaf = "leaf"
+)
+
+// leafDisabledComponents are the components of the MultiClusterHub disabled by the leaf profile
+var leafDisabledComponents = []string{
+	"app-lifecycle",
+	"cluster-backup",
+	"console",
+	"insights",
+	"search",
+}
+
+// profileComponents returns the components of the MultiClusterHub enabled or disabled by the profile
+func profileComponents(profile string) map[string]bool {
+	components := map[string]bool{}
+	if profile == MCHProfileLeaf {
+		for _, name := range leafDisabledComponents {
+			components[name] = false
+		}
+	}
+	return components
+}
+
+// componentsMCH returns the MultiClusterHub enabling or disabling the components of the hub
+// configuration with spec.overrides.components, to be merged onto the default one, or an empty string
+// if no component is configured. The components of the user defined MultiClusterHubs replace them.
+func componentsMCH(config *HubConfig) string {
+	if len(config.MCHComponents) == 0 {
+		return ""
+	}
+	mch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"overrides": map[string]interface{}{"components": renderComponents(config.MCHComponents)},
+		},
+	})
+	if err != nil {
+		// the configuration is parsed from json, it can always be marshalled back
+		klog.Errorf("Failed to render the components of the hubs: %v", err)
+		return ""
+	}
+	return string(mch)
+}
+
+// renderComponents renders the components of spec.overrides.components, in a stable order so the
+// manifestworks are not updated on resync
+func renderComponents(toggles map[string]bool) []interface{} {
+	names := make([]string, 0, len(toggles))
+	for name := range toggles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	components := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		components = append(components, map[string]interface{}{"name": name, "enabled": toggles[name]})
+	}
+	return components
+}
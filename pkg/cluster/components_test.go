@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestComponentsMCH(t *testing.T) {
+	if mch := componentsMCH(DefaultHubConfig()); mch != "" {
+		t.Errorf("expected no components by default, got %s", mch)
+	}
+
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_MCH_PROFILE_KEY:    MCHProfileLeaf,
+		HUB_CONFIG_MCH_COMPONENTS_KEY: `{"console":true,"volsync":false}`,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rendered, _, err := RenderMCH(newManagedCluster("cluster1"), true, componentsMCH(config))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	type component struct {
+		Name    string `json:"name"`
+		Enabled bool   `json:"enabled"`
+	}
+	mch := struct {
+		Spec struct {
+			Overrides struct {
+				Components []component `json:"components"`
+			} `json:"overrides"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(rendered, &mch); err != nil {
+		t.Fatal(err)
+	}
+	// the configured components are applied on top of the profile, in a stable order
+	expected := []component{
+		{Name: "app-lifecycle"},
+		{Name: "cluster-backup"},
+		{Name: "console", Enabled: true},
+		{Name: "insights"},
+		{Name: "search"},
+		{Name: "volsync"},
+	}
+	if !reflect.DeepEqual(mch.Spec.Overrides.Components, expected) {
+		t.Errorf("expected the components %v, got %v", expected, mch.Spec.Overrides.Components)
+	}
+
+	for _, data := range []map[string]string{
+		{HUB_CONFIG_MCH_PROFILE_KEY: "minimal"},
+		{HUB_CONFIG_MCH_COMPONENTS_KEY: `["search"]`},
+	} {
+		if _, err := ParseHubConfig(newHubConfigMap(data)); err == nil {
+			t.Errorf("expected %v to be rejected", data)
+		}
+	}
+}
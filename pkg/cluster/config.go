@@ -0,0 +1,528 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// HUB_CONFIG_NAME is the name of the ConfigMap in the controller namespace configuring the hubs.
+// Changes are picked up without restarting the controller.
+const HUB_CONFIG_NAME = "hub-cluster-controller-config"
+
+// keys of the hub configuration ConfigMap
+const (
+	// HUB_CONFIG_CHANNEL_KEY is the channel of the operator subscription
+	HUB_CONFIG_CHANNEL_KEY = "channel"
+	// HUB_CONFIG_STARTING_CSV_KEY is the starting CSV of the operator subscription, it is only
+	// pinned when the channel is configured if the starting CSV is configured as well
+	HUB_CONFIG_STARTING_CSV_KEY = "startingCSV"
+	// HUB_CONFIG_FLAVOR_KEY is the product flavor of the hubs without hoh-flavor annotation, acm for
+	// the downstream operator or community for the community operator
+	HUB_CONFIG_FLAVOR_KEY = "flavor"
+	// HUB_CONFIG_COMMUNITY_CHANNEL_KEY is the channel of the operator subscription of the hubs of the
+	// community flavor
+	HUB_CONFIG_COMMUNITY_CHANNEL_KEY = "communityChannel"
+	// HUB_CONFIG_COMMUNITY_CATALOG_SOURCE_KEY is the catalog source of the operator subscription of
+	// the hubs of the community flavor without hoh-catalog-source annotation
+	HUB_CONFIG_COMMUNITY_CATALOG_SOURCE_KEY = "communityCatalogSource"
+	// HUB_CONFIG_MCH_KEY is the MultiClusterHub installed on the managed hubs without mch annotation
+	HUB_CONFIG_MCH_KEY = "mch"
+	// HUB_CONFIG_EXCLUDED_CLUSTERS_KEY is a comma or whitespace separated list of managed clusters
+	// to not install a hub on
+	HUB_CONFIG_EXCLUDED_CLUSTERS_KEY = "excludedClusters"
+	// HUB_CONFIG_PAUSED_KEY freezes the creation and updates of the manifestworks of all managed
+	// hubs when true, for change freezes and incident containment
+	HUB_CONFIG_PAUSED_KEY = "paused"
+	// HUB_CONFIG_MAX_CONCURRENT_INSTALLS_KEY caps the number of hubs installing at once across the
+	// fleet, the installs are not capped if unset or 0
+	HUB_CONFIG_MAX_CONCURRENT_INSTALLS_KEY = "maxConcurrentInstalls"
+	// HUB_CONFIG_BASIC_AVAILABILITY_MAX_NODES_KEY is the number of nodes up to which the hubs get a
+	// Basic availability, as reported by the node count claim of the managed clusters
+	HUB_CONFIG_BASIC_AVAILABILITY_MAX_NODES_KEY = "basicAvailabilityMaxNodes"
+	// HUB_CONFIG_MAX_MINOR_VERSIONS_KEY is the number of minor versions the managed hubs may span
+	// before a version drift is reported, the spread is not checked if unset or 0
+	HUB_CONFIG_MAX_MINOR_VERSIONS_KEY = "maxMinorVersions"
+	// HUB_CONFIG_MAX_RELEASES_BEHIND_KEY is the number of minor releases a managed hub may lag the
+	// channel of the subscription before a version drift is reported, the lag is not checked if
+	// unset or 0
+	HUB_CONFIG_MAX_RELEASES_BEHIND_KEY = "maxReleasesBehind"
+	// HUB_CONFIG_DISABLE_HUB_SELF_MANAGEMENT_KEY is the disableHubSelfManagement enforced on the
+	// MultiClusterHub of all managed hubs, true by default
+	HUB_CONFIG_DISABLE_HUB_SELF_MANAGEMENT_KEY = "disableHubSelfManagement"
+	// HUB_CONFIG_NODE_SELECTOR_KEY is the json node selector of the hub components of all managed
+	// hubs, such as the infrastructure nodes
+	HUB_CONFIG_NODE_SELECTOR_KEY = "nodeSelector"
+	// HUB_CONFIG_TOLERATIONS_KEY is the json list of tolerations of the hub components of all
+	// managed hubs, such as the taints of the infrastructure nodes
+	HUB_CONFIG_TOLERATIONS_KEY = "tolerations"
+	// HUB_CONFIG_MCH_PROFILE_KEY is the profile of the MultiClusterHub of all managed hubs, full or
+	// leaf to disable the components a managed hub does not need
+	HUB_CONFIG_MCH_PROFILE_KEY = "mchProfile"
+	// HUB_CONFIG_MCH_COMPONENTS_KEY is the json map of the components of the MultiClusterHub of all
+	// managed hubs to enable or disable, on top of the profile
+	HUB_CONFIG_MCH_COMPONENTS_KEY = "mchComponents"
+	// HUB_CONFIG_RESOURCE_PROFILES_KEY is the json map of the resource profiles of the managed hubs
+	// selected by their hoh-resource-profile annotation, they replace the built-in profiles of the
+	// same name
+	HUB_CONFIG_RESOURCE_PROFILES_KEY = "resourceProfiles"
+	// HUB_CONFIG_NAMESPACE_LABELS_KEY is the json map of the labels of the namespace the hubs are
+	// installed into, such as openshift.io/cluster-monitoring or the pod security admission labels
+	HUB_CONFIG_NAMESPACE_LABELS_KEY = "namespaceLabels"
+	// HUB_CONFIG_NAMESPACE_ANNOTATIONS_KEY is the json map of the annotations of the namespace the
+	// hubs are installed into
+	HUB_CONFIG_NAMESPACE_ANNOTATIONS_KEY = "namespaceAnnotations"
+	// HUB_CONFIG_IMAGE_PULL_SECRET_KEY is the name of the image pull secret of the MultiClusterHub of
+	// the managed hubs without hoh-image-pull-secret annotation
+	HUB_CONFIG_IMAGE_PULL_SECRET_KEY = "imagePullSecret"
+	// HUB_CONFIG_PROPAGATE_IMAGE_PULL_SECRET_KEY copies the image pull secret of the MultiClusterHub
+	// from the controller namespace to the managed hubs when true
+	HUB_CONFIG_PROPAGATE_IMAGE_PULL_SECRET_KEY = "propagateImagePullSecret"
+	// HUB_CONFIG_CATALOG_SOURCE_KEY is the catalog source of the operator subscription of the managed
+	// hubs without hoh-catalog-source annotation, such as a catalog of pre-release builds
+	HUB_CONFIG_CATALOG_SOURCE_KEY = "catalogSource"
+	// HUB_CONFIG_CATALOG_SOURCE_IMAGE_KEY is the index image of the catalog source installed with the
+	// operator subscription on the managed hubs whose default catalogs are disabled, as reported by
+	// their marketplace claim
+	HUB_CONFIG_CATALOG_SOURCE_IMAGE_KEY = "catalogSourceImage"
+	// HUB_CONFIG_IMAGE_REPOSITORY_KEY is the image repository of the hub components of the managed
+	// hubs without hoh-image-repository annotation, such as a mirror
+	HUB_CONFIG_IMAGE_REPOSITORY_KEY = "imageRepository"
+	// HUB_CONFIG_ARCHITECTURE_CATALOGS_KEY is the json map of the catalogs of the hub builds by the
+	// CPU architecture of the managed clusters, such as arm64, s390x or ppc64le
+	HUB_CONFIG_ARCHITECTURE_CATALOGS_KEY = "architectureCatalogs"
+	// HUB_CONFIG_AGENT_IMAGE_KEY is the image of the multicluster-global-hub agent installed on the
+	// managed hubs
+	HUB_CONFIG_AGENT_IMAGE_KEY = "agentImage"
+	// HUB_CONFIG_AGENT_BOOTSTRAP_SERVER_KEY is the kafka bootstrap server of the hub of hubs the
+	// multicluster-global-hub agents sync with
+	HUB_CONFIG_AGENT_BOOTSTRAP_SERVER_KEY = "agentBootstrapServer"
+	// HUB_CONFIG_TRANSPORT_SECRET_KEY is the name of the secret of the controller namespace holding
+	// the transport credentials of the agents, such as the kafka certificates, SASL credentials or
+	// REST tokens
+	HUB_CONFIG_TRANSPORT_SECRET_KEY = "transportSecret"
+	// HUB_CONFIG_CA_BUNDLE_CONFIGMAP_KEY is the name of the ConfigMap of the controller namespace
+	// holding the CA bundle of the hub of hubs, the agents verify the TLS connections with it
+	HUB_CONFIG_CA_BUNDLE_CONFIGMAP_KEY = "caBundleConfigMap"
+	// HUB_CONFIG_TRUSTED_CA_BUNDLE_CONFIGMAP_KEY is the name of the ConfigMap of the controller
+	// namespace holding the trust bundle of the hub operator, such as the CAs of the internal
+	// registries it pulls from
+	HUB_CONFIG_TRUSTED_CA_BUNDLE_CONFIGMAP_KEY = "trustedCABundleConfigMap"
+	// HUB_CONFIG_HYPERSHIFT_CATALOG_SOURCE_KEY is the catalog source of the operator subscription of
+	// the HyperShift hosted clusters, which are not installed unless it is configured
+	HUB_CONFIG_HYPERSHIFT_CATALOG_SOURCE_KEY = "hyperShiftCatalogSource"
+	// HUB_CONFIG_HYPERSHIFT_CATALOG_SOURCE_NAMESPACE_KEY is the namespace of the catalog source of the
+	// HyperShift hosted clusters, openshift-marketplace by default
+	HUB_CONFIG_HYPERSHIFT_CATALOG_SOURCE_NAMESPACE_KEY = "hyperShiftCatalogSourceNamespace"
+	// HUB_CONFIG_KLUSTERLET_ADDONS_KEY is the json map of the addons enabled on the local-cluster of
+	// the managed hubs managing themselves, merged onto the default ones
+	HUB_CONFIG_KLUSTERLET_ADDONS_KEY = "klusterletAddons"
+	// HUB_CONFIG_OBSERVABILITY_WRITE_SECRET_KEY is the name of the secret of the controller namespace
+	// holding the remote write endpoint of the hub of hubs, the observability of the managed hubs is
+	// enabled to export their metrics to it when set
+	HUB_CONFIG_OBSERVABILITY_WRITE_SECRET_KEY = "observabilityWriteSecret"
+	// HUB_CONFIG_OBSERVABILITY_STORAGE_SECRET_KEY is the name of the secret of the controller
+	// namespace holding the object storage configuration of the metrics of the managed hubs
+	HUB_CONFIG_OBSERVABILITY_STORAGE_SECRET_KEY = "observabilityStorageSecret"
+	// HUB_CONFIG_MANAGED_SERVICE_ACCOUNT_KEY provisions a ManagedServiceAccount per managed hub when
+	// true, the controller reads the health of the managed hubs with its token through the
+	// cluster-proxy
+	HUB_CONFIG_MANAGED_SERVICE_ACCOUNT_KEY = "managedServiceAccount"
+)
+
+const (
+	defaultChannel     = "release-2.4"
+	defaultStartingCSV = "advanced-cluster-management.v2.4.1"
+	// defaultCatalogSource is the catalog source of the released builds
+	defaultCatalogSource = "redhat-operators"
+	// defaultCommunityChannel and defaultCommunityCatalogSource are the channel and catalog source of
+	// the released builds of the community operator
+	defaultCommunityChannel       = "community-2.5"
+	defaultCommunityCatalogSource = "community-operators"
+)
+
+// HubConfig is the configuration of the hubs installed on the managed clusters.
+type HubConfig struct {
+	Channel     string
+	StartingCSV string
+	// Flavor is the product flavor of the hubs without hoh-flavor annotation
+	Flavor string
+	// CommunityChannel and CommunityCatalogSource replace the channel and catalog source of the
+	// operator subscription of the hubs of the community flavor
+	CommunityChannel       string
+	CommunityCatalogSource string
+	// DefaultMCH is the MultiClusterHub installed on the managed hubs without mch annotation, the
+	// built-in MultiClusterHub is installed if empty
+	DefaultMCH       string
+	ExcludedClusters sets.String
+	// Paused freezes the creation and updates of the manifestworks of all managed hubs
+	Paused bool
+	// MaxConcurrentInstalls caps the number of hubs installing at once, 0 for no cap
+	MaxConcurrentInstalls int
+	// BasicAvailabilityMaxNodes is the number of nodes up to which the hubs get a Basic availability,
+	// the availability is not preset by the node count if 0
+	BasicAvailabilityMaxNodes int
+	// MaxMinorVersions and MaxReleasesBehind are the version drift tolerated across the fleet, the
+	// drift is not checked if 0
+	MaxMinorVersions  int
+	MaxReleasesBehind int
+	// DisableHubSelfManagement is enforced on the MultiClusterHub of all managed hubs, the user
+	// defined values are reported as conflicts
+	DisableHubSelfManagement bool
+	// NodeSelector and Tolerations place the hub components of all managed hubs, they are merged
+	// onto the default MultiClusterHub
+	NodeSelector map[string]string
+	Tolerations  []corev1.Toleration
+	// MCHComponents are the components of the MultiClusterHub enabled or disabled on all managed
+	// hubs, from the profile and the components of the configuration
+	MCHComponents map[string]bool
+	// ResourceProfiles are the resource profiles of the managed hubs by name, the built-in ones
+	// unless replaced
+	ResourceProfiles map[string]ResourceProfile
+	// NamespaceLabels and NamespaceAnnotations are set on the namespace the hubs are installed into
+	NamespaceLabels      map[string]string
+	NamespaceAnnotations map[string]string
+	// ImagePullSecret is the image pull secret of the managed hubs without hoh-image-pull-secret
+	// annotation
+	ImagePullSecret string
+	// PropagateImagePullSecret copies the image pull secret from the controller namespace to the
+	// managed hubs with their MultiClusterHub
+	PropagateImagePullSecret bool
+	// CatalogSource is the catalog source of the operator subscription
+	CatalogSource string
+	// CatalogSourceImage is the index image of the catalog source installed on the managed hubs whose
+	// default catalogs are disabled, no catalog source is installed if empty
+	CatalogSourceImage string
+	// ImageRepository overrides the image repository of the hub components if not empty
+	ImageRepository string
+	// ArchitectureCatalogs are the catalogs of the hub builds by CPU architecture, the managed clusters
+	// of the other architectures than amd64 are only installed from these catalogs
+	ArchitectureCatalogs map[string]ArchitectureCatalog
+	// AgentImage is the image of the multicluster-global-hub agent, the default one if empty
+	AgentImage string
+	// AgentBootstrapServer is the kafka bootstrap server the agents sync with
+	AgentBootstrapServer string
+	// TransportSecret is the secret of the controller namespace copied to the agents as their
+	// transport credentials, the agents have no credentials if empty
+	TransportSecret string
+	// CABundleConfigMap is the ConfigMap of the controller namespace copied to the agents as the CA
+	// bundle of the hub of hubs
+	CABundleConfigMap string
+	// TrustedCABundleConfigMap is the ConfigMap of the controller namespace copied to the install
+	// namespace and mounted in the hub operator as its trust bundle
+	TrustedCABundleConfigMap string
+	// HyperShiftCatalogSource is the catalog source of the operator subscription of the HyperShift
+	// hosted clusters, they are skipped if empty
+	HyperShiftCatalogSource string
+	// HyperShiftCatalogSourceNamespace is the namespace of the catalog source of the HyperShift hosted
+	// clusters
+	HyperShiftCatalogSourceNamespace string
+	// KlusterletAddons enables or disables the addons of the local-cluster of the managed hubs
+	// managing themselves, the other addons keep their default
+	KlusterletAddons map[string]bool
+	// ObservabilityWriteSecret and ObservabilityStorageSecret are the secrets of the controller
+	// namespace copied to the observability of the managed hubs, it is not enabled if empty
+	ObservabilityWriteSecret   string
+	ObservabilityStorageSecret string
+	// ManagedServiceAccount provisions a ManagedServiceAccount per managed hub to read its health
+	ManagedServiceAccount bool
+	// Generation is the resource version of the ConfigMap the configuration is parsed from, it is
+	// empty for the default configuration
+	Generation string
+}
+
+// DefaultHubConfig returns the configuration used when the hub configuration ConfigMap does not exist.
+func DefaultHubConfig() *HubConfig {
+	return &HubConfig{
+		Channel:          defaultChannel,
+		StartingCSV:      defaultStartingCSV,
+		CatalogSource:    defaultCatalogSource,
+		ExcludedClusters: sets.NewString(),
+
+		ResourceProfiles:       builtinResourceProfiles(),
+		Flavor:                 FlavorACM,
+		CommunityChannel:       defaultCommunityChannel,
+		CommunityCatalogSource: defaultCommunityCatalogSource,
+
+		HyperShiftCatalogSourceNamespace: MARKETPLACE_NAMESPACE,
+
+		DisableHubSelfManagement: true,
+	}
+}
+
+// ParseHubConfig parses the hub configuration from the ConfigMap, a nil ConfigMap returns the
+// default configuration.
+func ParseHubConfig(configMap *corev1.ConfigMap) (*HubConfig, error) {
+	config := DefaultHubConfig()
+	if configMap == nil {
+		return config, nil
+	}
+	config.Generation = configMap.ResourceVersion
+
+	if channel := configMap.Data[HUB_CONFIG_CHANNEL_KEY]; channel != "" {
+		config.Channel = channel
+		config.StartingCSV = configMap.Data[HUB_CONFIG_STARTING_CSV_KEY]
+	} else if startingCSV := configMap.Data[HUB_CONFIG_STARTING_CSV_KEY]; startingCSV != "" {
+		config.StartingCSV = startingCSV
+	}
+	switch flavor := configMap.Data[HUB_CONFIG_FLAVOR_KEY]; flavor {
+	case "":
+	case FlavorACM, FlavorCommunity:
+		config.Flavor = flavor
+	default:
+		return nil, fmt.Errorf("invalid %s %q, expected %s or %s", HUB_CONFIG_FLAVOR_KEY, flavor, FlavorACM, FlavorCommunity)
+	}
+	if channel := configMap.Data[HUB_CONFIG_COMMUNITY_CHANNEL_KEY]; channel != "" {
+		config.CommunityChannel = channel
+	}
+	if source := configMap.Data[HUB_CONFIG_COMMUNITY_CATALOG_SOURCE_KEY]; source != "" {
+		config.CommunityCatalogSource = source
+	}
+
+	if mch := configMap.Data[HUB_CONFIG_MCH_KEY]; mch != "" {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(mch), &fields); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_MCH_KEY, err)
+		}
+		if _, ok := fields["spec"].(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("invalid %s: the multiclusterhub has no spec", HUB_CONFIG_MCH_KEY)
+		}
+		config.DefaultMCH = mch
+	}
+
+	if paused := configMap.Data[HUB_CONFIG_PAUSED_KEY]; paused != "" {
+		value, err := strconv.ParseBool(paused)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_PAUSED_KEY, err)
+		}
+		config.Paused = value
+	}
+
+	if msa := configMap.Data[HUB_CONFIG_MANAGED_SERVICE_ACCOUNT_KEY]; msa != "" {
+		value, err := strconv.ParseBool(msa)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_MANAGED_SERVICE_ACCOUNT_KEY, err)
+		}
+		config.ManagedServiceAccount = value
+	}
+
+	if maxInstalls := configMap.Data[HUB_CONFIG_MAX_CONCURRENT_INSTALLS_KEY]; maxInstalls != "" {
+		value, err := strconv.Atoi(maxInstalls)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid %s %q, expected a non-negative integer",
+				HUB_CONFIG_MAX_CONCURRENT_INSTALLS_KEY, maxInstalls)
+		}
+		config.MaxConcurrentInstalls = value
+	}
+
+	if maxNodes := configMap.Data[HUB_CONFIG_BASIC_AVAILABILITY_MAX_NODES_KEY]; maxNodes != "" {
+		value, err := strconv.Atoi(maxNodes)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid %s %q, expected a non-negative integer",
+				HUB_CONFIG_BASIC_AVAILABILITY_MAX_NODES_KEY, maxNodes)
+		}
+		config.BasicAvailabilityMaxNodes = value
+	}
+
+	for key, field := range map[string]*int{
+		HUB_CONFIG_MAX_MINOR_VERSIONS_KEY:  &config.MaxMinorVersions,
+		HUB_CONFIG_MAX_RELEASES_BEHIND_KEY: &config.MaxReleasesBehind,
+	} {
+		if data := configMap.Data[key]; data != "" {
+			value, err := strconv.Atoi(data)
+			if err != nil || value < 0 {
+				return nil, fmt.Errorf("invalid %s %q, expected a non-negative integer", key, data)
+			}
+			*field = value
+		}
+	}
+
+	if disable := configMap.Data[HUB_CONFIG_DISABLE_HUB_SELF_MANAGEMENT_KEY]; disable != "" {
+		value, err := strconv.ParseBool(disable)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_DISABLE_HUB_SELF_MANAGEMENT_KEY, err)
+		}
+		config.DisableHubSelfManagement = value
+	}
+
+	if nodeSelector := configMap.Data[HUB_CONFIG_NODE_SELECTOR_KEY]; nodeSelector != "" {
+		if err := json.Unmarshal([]byte(nodeSelector), &config.NodeSelector); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_NODE_SELECTOR_KEY, err)
+		}
+	}
+
+	if tolerations := configMap.Data[HUB_CONFIG_TOLERATIONS_KEY]; tolerations != "" {
+		if err := json.Unmarshal([]byte(tolerations), &config.Tolerations); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_TOLERATIONS_KEY, err)
+		}
+	}
+
+	switch profile := configMap.Data[HUB_CONFIG_MCH_PROFILE_KEY]; profile {
+	case "", MCHProfileFull, MCHProfileLeaf:
+		config.MCHComponents = profileComponents(profile)
+	default:
+		return nil, fmt.Errorf("invalid %s %q, expected %s or %s", HUB_CONFIG_MCH_PROFILE_KEY, profile, MCHProfileFull, MCHProfileLeaf)
+	}
+	if components := configMap.Data[HUB_CONFIG_MCH_COMPONENTS_KEY]; components != "" {
+		var toggles map[string]bool
+		if err := json.Unmarshal([]byte(components), &toggles); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_MCH_COMPONENTS_KEY, err)
+		}
+		for name, enabled := range toggles {
+			config.MCHComponents[name] = enabled
+		}
+	}
+
+	if profiles := configMap.Data[HUB_CONFIG_RESOURCE_PROFILES_KEY]; profiles != "" {
+		var configured map[string]ResourceProfile
+		if err := json.Unmarshal([]byte(profiles), &configured); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_RESOURCE_PROFILES_KEY, err)
+		}
+		for name, profile := range configured {
+			config.ResourceProfiles[name] = profile
+		}
+	}
+
+	if namespaceLabels := configMap.Data[HUB_CONFIG_NAMESPACE_LABELS_KEY]; namespaceLabels != "" {
+		if err := json.Unmarshal([]byte(namespaceLabels), &config.NamespaceLabels); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_NAMESPACE_LABELS_KEY, err)
+		}
+		if err := validation.ValidateLabels(config.NamespaceLabels, field.NewPath(HUB_CONFIG_NAMESPACE_LABELS_KEY)); len(err) > 0 {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_NAMESPACE_LABELS_KEY, err.ToAggregate())
+		}
+	}
+
+	if namespaceAnnotations := configMap.Data[HUB_CONFIG_NAMESPACE_ANNOTATIONS_KEY]; namespaceAnnotations != "" {
+		if err := json.Unmarshal([]byte(namespaceAnnotations), &config.NamespaceAnnotations); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_NAMESPACE_ANNOTATIONS_KEY, err)
+		}
+		if err := apimachineryvalidation.ValidateAnnotations(config.NamespaceAnnotations, field.NewPath(HUB_CONFIG_NAMESPACE_ANNOTATIONS_KEY)); len(err) > 0 {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_NAMESPACE_ANNOTATIONS_KEY, err.ToAggregate())
+		}
+	}
+
+	if source := configMap.Data[HUB_CONFIG_CATALOG_SOURCE_KEY]; source != "" {
+		config.CatalogSource = source
+	}
+	config.CatalogSourceImage = configMap.Data[HUB_CONFIG_CATALOG_SOURCE_IMAGE_KEY]
+	config.ImageRepository = configMap.Data[HUB_CONFIG_IMAGE_REPOSITORY_KEY]
+	config.AgentImage = configMap.Data[HUB_CONFIG_AGENT_IMAGE_KEY]
+	config.AgentBootstrapServer = configMap.Data[HUB_CONFIG_AGENT_BOOTSTRAP_SERVER_KEY]
+	config.TransportSecret = configMap.Data[HUB_CONFIG_TRANSPORT_SECRET_KEY]
+	config.CABundleConfigMap = configMap.Data[HUB_CONFIG_CA_BUNDLE_CONFIGMAP_KEY]
+	config.TrustedCABundleConfigMap = configMap.Data[HUB_CONFIG_TRUSTED_CA_BUNDLE_CONFIGMAP_KEY]
+	config.HyperShiftCatalogSource = configMap.Data[HUB_CONFIG_HYPERSHIFT_CATALOG_SOURCE_KEY]
+	if namespace := configMap.Data[HUB_CONFIG_HYPERSHIFT_CATALOG_SOURCE_NAMESPACE_KEY]; namespace != "" {
+		config.HyperShiftCatalogSourceNamespace = namespace
+	}
+	config.ObservabilityWriteSecret = configMap.Data[HUB_CONFIG_OBSERVABILITY_WRITE_SECRET_KEY]
+	config.ObservabilityStorageSecret = configMap.Data[HUB_CONFIG_OBSERVABILITY_STORAGE_SECRET_KEY]
+	if config.ObservabilityWriteSecret != "" && config.ObservabilityStorageSecret == "" {
+		return nil, fmt.Errorf("invalid %s: the observability requires %s",
+			HUB_CONFIG_OBSERVABILITY_WRITE_SECRET_KEY, HUB_CONFIG_OBSERVABILITY_STORAGE_SECRET_KEY)
+	}
+
+	if catalogs := configMap.Data[HUB_CONFIG_ARCHITECTURE_CATALOGS_KEY]; catalogs != "" {
+		if err := json.Unmarshal([]byte(catalogs), &config.ArchitectureCatalogs); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_ARCHITECTURE_CATALOGS_KEY, err)
+		}
+		for architecture, catalog := range config.ArchitectureCatalogs {
+			if catalog.CatalogSource == "" {
+				return nil, fmt.Errorf("invalid %s: no catalog source for the %s architecture",
+					HUB_CONFIG_ARCHITECTURE_CATALOGS_KEY, architecture)
+			}
+		}
+	}
+
+	if addons := configMap.Data[HUB_CONFIG_KLUSTERLET_ADDONS_KEY]; addons != "" {
+		value, err := parseKlusterletAddons(addons)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_KLUSTERLET_ADDONS_KEY, err)
+		}
+		config.KlusterletAddons = value
+	}
+
+	config.ImagePullSecret = configMap.Data[HUB_CONFIG_IMAGE_PULL_SECRET_KEY]
+	if propagate := configMap.Data[HUB_CONFIG_PROPAGATE_IMAGE_PULL_SECRET_KEY]; propagate != "" {
+		value, err := strconv.ParseBool(propagate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", HUB_CONFIG_PROPAGATE_IMAGE_PULL_SECRET_KEY, err)
+		}
+		config.PropagateImagePullSecret = value
+	}
+
+	config.ExcludedClusters.Insert(strings.FieldsFunc(configMap.Data[HUB_CONFIG_EXCLUDED_CLUSTERS_KEY], func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})...)
+	return config, nil
+}
+
+// Excluded returns true if no hub should be installed on the managed cluster.
+func (c *HubConfig) Excluded(managedClusterName string) bool {
+	return c.ExcludedClusters.Has(managedClusterName)
+}
+
+// hubConfigLoader reads the hub configuration from the ConfigMap cache, the ConfigMap is only
+// parsed again when it is changed. An invalid configuration is reported and ignored, the last
+// valid one is used until it is fixed.
+type hubConfigLoader struct {
+	lister corev1listers.ConfigMapNamespaceLister
+
+	lock      sync.Mutex
+	configMap *corev1.ConfigMap
+	config    *HubConfig
+}
+
+func newHubConfigLoader(lister corev1listers.ConfigMapNamespaceLister) *hubConfigLoader {
+	return &hubConfigLoader{
+		lister: lister,
+		config: DefaultHubConfig(),
+	}
+}
+
+// get returns the current hub configuration.
+func (l *hubConfigLoader) get() *HubConfig {
+	configMap, err := l.lister.Get(HUB_CONFIG_NAME)
+	if errors.IsNotFound(err) {
+		configMap = nil
+	} else if err != nil {
+		klog.Errorf("Failed to get the hub configuration, using the last one: %v", err)
+		return l.lastConfig()
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	// the cache returns the same object until the ConfigMap is changed
+	if configMap == l.configMap {
+		return l.config
+	}
+	l.configMap = configMap
+
+	config, err := ParseHubConfig(configMap)
+	if err != nil {
+		klog.Errorf("Invalid hub configuration %s, using the last valid one: %v", HUB_CONFIG_NAME, err)
+		return l.config
+	}
+	klog.V(2).Infof("Loaded the hub configuration with channel %q and %d excluded clusters",
+		config.Channel, config.ExcludedClusters.Len())
+	l.config = config
+	return config
+}
+
+func (l *hubConfigLoader) lastConfig() *HubConfig {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.config
+}
@@ -0,0 +1,314 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func newHubConfigMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: HUB_CONFIG_NAME, Namespace: "test"},
+		Data:       data,
+	}
+}
+
+func TestParseHubConfig(t *testing.T) {
+	cases := []struct {
+		name          string
+		configMap     *corev1.ConfigMap
+		expected      *HubConfig
+		expectedError bool
+	}{
+		{
+			name:      "no configmap",
+			configMap: nil,
+			expected:  DefaultHubConfig(),
+		},
+		{
+			name:      "empty configmap",
+			configMap: newHubConfigMap(nil),
+			expected:  DefaultHubConfig(),
+		},
+		{
+			name: "channel without starting csv",
+			configMap: newHubConfigMap(map[string]string{
+				HUB_CONFIG_CHANNEL_KEY: "release-2.5",
+			}),
+			expected: &HubConfig{Channel: "release-2.5", ExcludedClusters: sets.NewString()},
+		},
+		{
+			name: "full configuration",
+			configMap: newHubConfigMap(map[string]string{
+				HUB_CONFIG_CHANNEL_KEY:           "release-2.5",
+				HUB_CONFIG_STARTING_CSV_KEY:      "advanced-cluster-management.v2.5.0",
+				HUB_CONFIG_MCH_KEY:               `{"spec":{"availabilityConfig":"Basic"}}`,
+				HUB_CONFIG_EXCLUDED_CLUSTERS_KEY: "cluster1, cluster2\ncluster3",
+			}),
+			expected: &HubConfig{
+				Channel:          "release-2.5",
+				StartingCSV:      "advanced-cluster-management.v2.5.0",
+				DefaultMCH:       `{"spec":{"availabilityConfig":"Basic"}}`,
+				ExcludedClusters: sets.NewString("cluster1", "cluster2", "cluster3"),
+			},
+		},
+		{
+			name:          "invalid mch",
+			configMap:     newHubConfigMap(map[string]string{HUB_CONFIG_MCH_KEY: "{"}),
+			expectedError: true,
+		},
+		{
+			name:          "mch without spec",
+			configMap:     newHubConfigMap(map[string]string{HUB_CONFIG_MCH_KEY: `{"metadata":{}}`}),
+			expectedError: true,
+		},
+		{
+			name:      "paused",
+			configMap: newHubConfigMap(map[string]string{HUB_CONFIG_PAUSED_KEY: "true"}),
+			expected: &HubConfig{
+				Channel:          defaultChannel,
+				StartingCSV:      defaultStartingCSV,
+				ExcludedClusters: sets.NewString(),
+				Paused:           true,
+			},
+		},
+		{
+			name:          "invalid paused",
+			configMap:     newHubConfigMap(map[string]string{HUB_CONFIG_PAUSED_KEY: "yes"}),
+			expectedError: true,
+		},
+		{
+			name:      "managed serviceaccount",
+			configMap: newHubConfigMap(map[string]string{HUB_CONFIG_MANAGED_SERVICE_ACCOUNT_KEY: "true"}),
+			expected: &HubConfig{
+				Channel:               defaultChannel,
+				StartingCSV:           defaultStartingCSV,
+				ExcludedClusters:      sets.NewString(),
+				ManagedServiceAccount: true,
+			},
+		},
+		{
+			name:      "max concurrent installs",
+			configMap: newHubConfigMap(map[string]string{HUB_CONFIG_MAX_CONCURRENT_INSTALLS_KEY: "20"}),
+			expected: &HubConfig{
+				Channel:               defaultChannel,
+				StartingCSV:           defaultStartingCSV,
+				ExcludedClusters:      sets.NewString(),
+				MaxConcurrentInstalls: 20,
+			},
+		},
+		{
+			name:      "basic availability max nodes",
+			configMap: newHubConfigMap(map[string]string{HUB_CONFIG_BASIC_AVAILABILITY_MAX_NODES_KEY: "3"}),
+			expected: &HubConfig{
+				Channel:                   defaultChannel,
+				StartingCSV:               defaultStartingCSV,
+				ExcludedClusters:          sets.NewString(),
+				BasicAvailabilityMaxNodes: 3,
+			},
+		},
+		{
+			name: "version drift",
+			configMap: newHubConfigMap(map[string]string{
+				HUB_CONFIG_MAX_MINOR_VERSIONS_KEY:  "2",
+				HUB_CONFIG_MAX_RELEASES_BEHIND_KEY: "1",
+			}),
+			expected: &HubConfig{
+				Channel:           defaultChannel,
+				StartingCSV:       defaultStartingCSV,
+				ExcludedClusters:  sets.NewString(),
+				MaxMinorVersions:  2,
+				MaxReleasesBehind: 1,
+			},
+		},
+		{
+			name:          "invalid namespace labels",
+			configMap:     newHubConfigMap(map[string]string{HUB_CONFIG_NAMESPACE_LABELS_KEY: `{"openshift.io/cluster monitoring":"true"}`}),
+			expectedError: true,
+		},
+		{
+			name:          "invalid namespace annotations",
+			configMap:     newHubConfigMap(map[string]string{HUB_CONFIG_NAMESPACE_ANNOTATIONS_KEY: `{"openshift.io/node selector":""}`}),
+			expectedError: true,
+		},
+		{
+			name:          "invalid flavor",
+			configMap:     newHubConfigMap(map[string]string{HUB_CONFIG_FLAVOR_KEY: "upstream"}),
+			expectedError: true,
+		},
+		{
+			name:          "invalid max releases behind",
+			configMap:     newHubConfigMap(map[string]string{HUB_CONFIG_MAX_RELEASES_BEHIND_KEY: "two"}),
+			expectedError: true,
+		},
+		{
+			name:          "negative max concurrent installs",
+			configMap:     newHubConfigMap(map[string]string{HUB_CONFIG_MAX_CONCURRENT_INSTALLS_KEY: "-1"}),
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config, err := ParseHubConfig(c.configMap)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error, got %v", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Channel != c.expected.Channel || config.StartingCSV != c.expected.StartingCSV ||
+				config.DefaultMCH != c.expected.DefaultMCH || !config.ExcludedClusters.Equal(c.expected.ExcludedClusters) ||
+				config.Paused != c.expected.Paused || config.MaxConcurrentInstalls != c.expected.MaxConcurrentInstalls ||
+				config.BasicAvailabilityMaxNodes != c.expected.BasicAvailabilityMaxNodes ||
+				config.MaxMinorVersions != c.expected.MaxMinorVersions || config.MaxReleasesBehind != c.expected.MaxReleasesBehind {
+				t.Errorf("expected %v, got %v", c.expected, config)
+			}
+		})
+	}
+}
+
+func TestParseHubConfigSelfManagement(t *testing.T) {
+	if config := DefaultHubConfig(); !config.DisableHubSelfManagement {
+		t.Errorf("expected the self management of the managed hubs to be disabled by default")
+	}
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{HUB_CONFIG_DISABLE_HUB_SELF_MANAGEMENT_KEY: "false"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.DisableHubSelfManagement {
+		t.Errorf("expected the self management of the managed hubs to be enabled")
+	}
+	if _, err := ParseHubConfig(newHubConfigMap(map[string]string{HUB_CONFIG_DISABLE_HUB_SELF_MANAGEMENT_KEY: "no"})); err == nil {
+		t.Errorf("expected an invalid %s to be rejected", HUB_CONFIG_DISABLE_HUB_SELF_MANAGEMENT_KEY)
+	}
+}
+
+func TestParseHubConfigGeneration(t *testing.T) {
+	configMap := newHubConfigMap(nil)
+	configMap.ResourceVersion = "42"
+	config, err := ParseHubConfig(configMap)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Generation != "42" {
+		t.Errorf("expected the generation 42, got %q", config.Generation)
+	}
+}
+
+func TestHubConfigLoaderKeepsLastValidConfig(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	loader := newHubConfigLoader(corev1listers.NewConfigMapLister(indexer).ConfigMaps("test"))
+	if loader.get().Channel != defaultChannel {
+		t.Errorf("expected the default channel, got %q", loader.get().Channel)
+	}
+
+	if err := indexer.Add(newHubConfigMap(map[string]string{HUB_CONFIG_CHANNEL_KEY: "release-2.5"})); err != nil {
+		t.Fatal(err)
+	}
+	if loader.get().Channel != "release-2.5" {
+		t.Errorf("expected the configured channel, got %q", loader.get().Channel)
+	}
+
+	if err := indexer.Update(newHubConfigMap(map[string]string{
+		HUB_CONFIG_CHANNEL_KEY: "release-2.6",
+		HUB_CONFIG_MCH_KEY:     "{",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if loader.get().Channel != "release-2.5" {
+		t.Errorf("expected the last valid channel, got %q", loader.get().Channel)
+	}
+}
+
+func TestCreateSubManifestworkChannel(t *testing.T) {
+	config := DefaultHubConfig()
+	config.Channel = "release-2.5"
+	config.StartingCSV = ""
+	work := CreateSubManifestwork("cluster1", config)
+
+	var subscription struct {
+		Kind string                 `json:"kind"`
+		Spec map[string]interface{} `json:"spec"`
+	}
+	for _, manifest := range work.Spec.Workload.Manifests {
+		if err := json.Unmarshal(manifest.Raw, &subscription); err != nil {
+			t.Fatal(err)
+		}
+		if subscription.Kind == "Subscription" {
+			break
+		}
+	}
+	if subscription.Spec["channel"] != "release-2.5" {
+		t.Errorf("expected channel release-2.5, got %v", subscription.Spec["channel"])
+	}
+	if _, ok := subscription.Spec["startingCSV"]; ok {
+		t.Errorf("expected no starting csv, got %v", subscription.Spec["startingCSV"])
+	}
+}
+
+func TestCreateSubManifestworkNamespace(t *testing.T) {
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_NAMESPACE_LABELS_KEY: `{"openshift.io/cluster-monitoring":"true",` +
+			`"pod-security.kubernetes.io/enforce":"privileged"}`,
+		HUB_CONFIG_NAMESPACE_ANNOTATIONS_KEY: `{"openshift.io/node-selector":""}`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := CreateSubManifestwork("cluster1", config)
+
+	var namespace metav1.PartialObjectMetadata
+	for _, manifest := range work.Spec.Workload.Manifests {
+		if err := json.Unmarshal(manifest.Raw, &namespace); err != nil {
+			t.Fatal(err)
+		}
+		if namespace.Kind == "Namespace" {
+			break
+		}
+	}
+	if namespace.Name != "open-cluster-management" || !reflect.DeepEqual(namespace.Labels, config.NamespaceLabels) ||
+		!reflect.DeepEqual(namespace.Annotations, config.NamespaceAnnotations) {
+		t.Errorf("expected the labeled install namespace, got %v", namespace)
+	}
+
+	// the namespace is rendered as is without labels nor annotations, so the fleet is not updated
+	if raw := string(installNamespaceManifest(DefaultHubConfig())); raw != `{
+	"apiVersion": "v1",
+	"kind": "Namespace",
+	"metadata": {
+		"name": "open-cluster-management"
+	}
+}` {
+		t.Errorf("unexpected default namespace %s", raw)
+	}
+}
+
+func TestSyncSkipsExcludedCluster(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{managedCluster})
+	ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t, newHubConfigMap(map[string]string{
+		HUB_CONFIG_EXCLUDED_CLUSTERS_KEY: "cluster1",
+	})))
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ctrl.workClient.Actions()) != 0 {
+		t.Errorf("expected no manifestwork actions, got %v", ctrl.workClient.Actions())
+	}
+}
@@ -2,170 +2,592 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
 
+	clusterclientv1 "open-cluster-management.io/api/client/cluster/clientset/versioned/typed/cluster/v1"
 	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	workclientv1 "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
 	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
 	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/metrics"
+	"github.com/stolostron/hub-cluster-controller/pkg/tracing"
+	"github.com/stolostron/hub-cluster-controller/pkg/version"
 )
 
-// clusterController reconciles instances of ManagedCluster on the hub.
+// ControllerOptions holds the settings of the hub cluster controllers.
+type ControllerOptions struct {
+	// InstallTimeout is the time a hub may take to install before it is reported as degraded
+	InstallTimeout time.Duration
+	// ResyncInterval is the interval to resync all managed hubs
+	ResyncInterval time.Duration
+	// MaxRetries is the number of failed syncs after which a managed hub is parked
+	MaxRetries int
+	// ParkedCooldown is the time after which a parked managed hub is retried once, it is only
+	// retried on a change if 0
+	ParkedCooldown time.Duration
+	// OperatorRecheckInterval is the interval to recheck the operator subscription while waiting
+	// for it to reach AtLatestKnown, in case a status event is missed
+	OperatorRecheckInterval time.Duration
+	// ConfigNamespace is the namespace of the hub configuration ConfigMap
+	ConfigNamespace string
+	// ShardCount is the number of shards the managed hubs are partitioned into across the replicas
+	ShardCount int
+	// ShardIndex is the shard of the managed hubs reconciled by this replica
+	ShardIndex int
+	// DryRun is true when the writes of the controller are not persisted
+	DryRun bool
+	// ClusterProxyURL is the URL of the cluster-proxy user server the managed hubs are queried
+	// through, the health of the managed hubs is not read if empty
+	ClusterProxyURL string
+	// ClusterProxyCAFile is the CA bundle verifying the certificate of the cluster-proxy user server
+	ClusterProxyCAFile string
+	// HealthCheckInterval is the interval the health of the managed hubs is read again
+	HealthCheckInterval time.Duration
+	// EventCoalescingWindow is the minimum interval between two syncs of a managed hub triggered by
+	// its manifestworks, the events received meanwhile are collapsed into one sync
+	EventCoalescingWindow time.Duration
+	// StuckWorkTimeout is the time the hub manifestworks of a deleted managed cluster may be stuck in
+	// deletion before their finalizers are removed, they are never removed if 0
+	StuckWorkTimeout time.Duration
+	// FeedbackMissingRechecks is the number of operator rechecks the subscription manifestwork may be
+	// applied without reporting the state of the operator subscription before the hub is reported as
+	// degraded, it is never reported if 0
+	FeedbackMissingRechecks int
+	// WorkNaming is the naming of the hub manifestworks created by the controller
+	WorkNaming WorkNaming
+}
+
+// feedbackTimeout returns the time the subscription manifestwork may be applied without reporting
+// the state of the operator subscription, the recheck falls back to the resync when disabled
+func (o ControllerOptions) feedbackTimeout() time.Duration {
+	interval := o.OperatorRecheckInterval
+	if interval <= 0 {
+		interval = o.ResyncInterval
+	}
+	return time.Duration(o.FeedbackMissingRechecks) * interval
+}
+
+// reconcileFunc reconciles a phase of the hub installation on a managed cluster.
+type reconcileFunc func(ctx context.Context, syncCtx factory.SyncContext, managedCluster *clusterv1.ManagedCluster) error
+
+// clusterController is the base of the controllers reconciling a phase of the hub installation
+// on each managed cluster. Each controller has its own queue, and retries failing clusters with
+// its own backoff after the first installs.
 type clusterController struct {
+	name          string
+	clusterclient clusterclientv1.ClusterV1Interface
 	workclient    workclientv1.WorkV1Interface
 	clusterLister clusterlisterv1.ManagedClusterLister
 	workLister    worklisterv1.ManifestWorkLister
+	workIndexer   cache.Indexer
 	cache         resourceapply.ResourceCache
 	eventRecorder events.Recorder
+	// clusterRecorder records the lifecycle events of the managed hubs into the managed cluster namespaces
+	clusterRecorder record.EventRecorder
+	options         ControllerOptions
+	backoff         *clusterBackoff
+	// firstInstalls holds the managed hubs queued for their first install
+	firstInstalls *pendingInstalls
+	// coalescer collapses the bursts of manifestwork events of each managed hub
+	coalescer *eventCoalescer
+	hubConfig *hubConfigLoader
+	// overrideLister lists the MultiClusterHubOverrides of the managed hubs, it is nil when their CRD is
+	// not installed
+	overrideLister cache.GenericLister
+	// secretLister gets the image pull secrets propagated to the managed hubs from the controller
+	// namespace
+	secretLister corev1listers.SecretNamespaceLister
+	// restoreInformer watches the Restores of the cluster backup operator, it is nil when the operator
+	// is not installed
+	restoreInformer informers.GenericInformer
+	restoreLister   cache.GenericLister
+	// knownWorks holds the manifestworks seen by the controller, so a manifestwork deleted by hand is
+	// told apart from a manifestwork not created yet
+	knownWorks sync.Map
+	// maintenanceHolds holds the managed clusters whose changes are held by their maintenance window
+	maintenanceHolds sync.Map
+	// waves caches the rollout state of the waves of the fleet
+	waves waveTracker
+	// invalidWaves holds the invalid wave annotation last logged for each managed cluster
+	invalidWaves sync.Map
+	// mchConflicts holds the fields of the user defined mch last reported as enforced for each managed
+	// cluster
+	mchConflicts sync.Map
+	// spokeClients caches the cluster-proxy client of each managed hub with the token it is built with
+	spokeClients sync.Map
+	// ownedWorks are the types of the hub manifestworks created by the controller
+	ownedWorks []string
+	// parkedCondition is the condition type reporting the phase is parked, it is empty for the
+	// controllers retrying forever
+	parkedCondition string
+	reconcile       reconcileFunc
 }
 
-// NewHubClusterController creates a new hub cluster controller
-func NewHubClusterController(
+// resyncJitterFactor is the part of the resync interval the resync of the managed hubs is
+// spread over, so that large fleets are not reconciled all at once.
+const resyncJitterFactor = 0.25
+
+func newClusterController(
+	name string,
+	clusterclient clusterclientv1.ClusterV1Interface,
 	workclient workclientv1.WorkV1Interface,
 	clusterInformer clusterinformerv1.ManagedClusterInformer,
 	workInformer workinformerv1.ManifestWorkInformer,
-	recorder events.Recorder) factory.Controller {
+	configMapInformer corev1informers.ConfigMapInformer,
+	secretInformer corev1informers.SecretInformer,
+	overrideInformer informers.GenericInformer,
+	restoreInformer informers.GenericInformer,
+	options ControllerOptions,
+	parkedCondition string,
+	recorder events.Recorder,
+	clusterRecorder record.EventRecorder) *clusterController {
 	c := &clusterController{
-		workclient:    workclient,
-		clusterLister: clusterInformer.Lister(),
-		workLister:    workInformer.Lister(),
-		cache:         resourceapply.NewResourceCache(),
-		eventRecorder: recorder.WithComponentSuffix("hub-cluster-controller"),
+		name:            name,
+		clusterclient:   clusterclient,
+		workclient:      workclient,
+		clusterLister:   clusterInformer.Lister(),
+		workLister:      workInformer.Lister(),
+		workIndexer:     workIndexer(workInformer.Informer()),
+		cache:           newSyncedResourceCache(),
+		eventRecorder:   recorder.WithComponentSuffix("hub-cluster-controller"),
+		clusterRecorder: clusterRecorder,
+		options:         options,
+		backoff:         newClusterBackoff(options.MaxRetries, options.ParkedCooldown),
+		firstInstalls:   newPendingInstalls(),
+		coalescer:       newEventCoalescer(),
+		hubConfig:       newHubConfigLoader(configMapInformer.Lister().ConfigMaps(options.ConfigNamespace)),
+		secretLister:    secretInformer.Lister().Secrets(options.ConfigNamespace),
+		parkedCondition: parkedCondition,
 	}
-	return factory.New().
-		WithFilteredEventsInformersQueueKeyFunc(
-			func(obj runtime.Object) string {
-				accessor, _ := meta.Accessor(obj)
-				return accessor.GetName()
-			},
-			func(obj interface{}) bool {
-				accessor, err := meta.Accessor(obj)
-				if err != nil {
-					return false
-				}
-				// enqueue all managed cluster except for local-cluster and hoh=disabled
-				if accessor.GetLabels()["hoh"] == "disabled" || accessor.GetName() == "local-cluster" {
-					return false
-				} else {
-					return true
-				}
-			}, clusterInformer.Informer()).
+	if overrideInformer != nil {
+		c.overrideLister = overrideInformer.Lister()
+	}
+	if restoreInformer != nil {
+		c.restoreInformer = restoreInformer
+		c.restoreLister = restoreInformer.Lister()
+	}
+	return c
+}
+
+// newFactory returns a controller factory enqueueing the managed hubs when they or the given
+// manifestworks in their namespace are changed, and resyncing all managed hubs when the hub
+// configuration or a Restore is changed.
+func (c *clusterController) newFactory(
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	workInformer workinformerv1.ManifestWorkInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	works ...string) *factory.Factory {
+	f := factory.New().
+		// the updates of the managed clusters and the given manifestworks are compared with their
+		// previous state, which the filters of the factory do not get, so their handlers are added once
+		// the controller is started
+		WithBareInformers(clusterInformer.Informer(), workInformer.Informer()).
+		WithPostStartHooks(func(ctx context.Context, syncCtx factory.SyncContext) error {
+			clusterInformer.Informer().AddEventHandler(c.clusterEventHandler(syncCtx.Queue()))
+			workInformer.Informer().AddEventHandler(c.workEventHandler(syncCtx.Queue(), works))
+			return nil
+		}).
 		WithFilteredEventsInformersQueueKeyFunc(
 			func(obj runtime.Object) string {
-				accessor, _ := meta.Accessor(obj)
-				return accessor.GetNamespace()
+				return factory.DefaultQueueKey
 			},
 			func(obj interface{}) bool {
-				accessor, err := meta.Accessor(obj)
-				if err != nil {
-					return false
-				}
-				// only enqueue when the hoh=enabled managed cluster is changed
-				if accessor.GetName() == accessor.GetNamespace()+"-"+HOH_HUB_CLUSTER_SUBSCRIPTION ||
-					accessor.GetName() == accessor.GetNamespace()+"-"+HOH_HUB_CLUSTER_MCH {
-					return true
-				}
-				return false
-			}, workInformer.Informer()).
+				accessor, err := objectMeta(obj)
+				return err == nil && accessor.GetNamespace() == c.options.ConfigNamespace &&
+					accessor.GetName() == HUB_CONFIG_NAME
+			}, configMapInformer.Informer()).
 		WithSync(c.sync).
-		ToController("HubClusterController", recorder)
+		ResyncEvery(c.options.ResyncInterval)
+	if c.restoreInformer != nil {
+		f = f.WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		}, c.restoreInformer.Informer())
+	}
+	return f
+}
+
+// objectMeta returns the metadata of an informer object, the deleted objects missed by the informer
+// are unwrapped from their tombstone so their deletion is still handled.
+func objectMeta(obj interface{}) (metav1.Object, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	return meta.Accessor(obj)
+}
+
+// HOH_LABEL enrolls the managed cluster as a managed hub, unless it is set to HOH_LABEL_DISABLED
+const (
+	HOH_LABEL          = "hoh"
+	HOH_LABEL_DISABLED = "disabled"
+)
+
+// IsManagedHub returns true if a hub should be installed on the managed cluster, that is on all
+// managed clusters except for local-cluster and hoh=disabled.
+func IsManagedHub(managedCluster metav1.Object) bool {
+	return managedCluster.GetLabels()[HOH_LABEL] != HOH_LABEL_DISABLED && managedCluster.GetName() != "local-cluster"
 }
 
 func (c *clusterController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
 	managedClusterName := syncCtx.QueueKey()
-	klog.V(2).Infof("Reconciling hub cluster for %s", managedClusterName)
+	if managedClusterName == factory.DefaultQueueKey {
+		return c.resyncAll(syncCtx)
+	}
+
+	if delay := c.coalescer.delay(managedClusterName, c.options.EventCoalescingWindow, time.Now()); delay > 0 {
+		syncCtx.Queue().AddAfter(managedClusterName, delay)
+		metrics.CoalescedSyncs.WithLabelValues(c.name).Inc()
+		return nil
+	}
+	defer c.firstInstalls.done(managedClusterName)
+	ctx, span := tracing.StartSync(ctx, c.name, managedClusterName)
+	ctx = withLogger(ctx, c.name, managedClusterName)
+	err := c.syncManagedCluster(ctx, syncCtx, managedClusterName)
+	tracing.EndSync(span, err)
+	return err
+}
+
+func (c *clusterController) syncManagedCluster(ctx context.Context, syncCtx factory.SyncContext, managedClusterName string) error {
+	logger := loggerFrom(ctx)
 	managedCluster, err := c.clusterLister.Get(managedClusterName)
 	if errors.IsNotFound(err) {
-		// Spoke cluster not found, could have been deleted, delete manifestwork.
-		// TODO: delete manifestwork
+		// Spoke cluster not found, could have been deleted, its manifestworks are deleted with its
+		// namespace.
+		c.backoff.succeeded(managedClusterName)
+		c.coalescer.forget(managedClusterName)
+		c.forgetWorks(managedClusterName)
+		c.maintenanceHolds.Delete(managedClusterName)
+		c.mchConflicts.Delete(managedClusterName)
+		c.spokeClients.Delete(managedClusterName)
+		return c.removeStuckFinalizers(ctx, syncCtx, managedClusterName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !c.ownsCluster(managedClusterName) {
+		// the hub is reconciled by the replica of another shard
+		return nil
+	}
+	// the hubs of the excluded and disabled managed clusters are left installed, so a bad configuration
+	// or label change never uninstalls the hubs of the fleet.
+	if !IsManagedHub(managedCluster) {
+		// the managed cluster is requeued by the events of its manifestworks, which are deleted when
+		// its hub is uninstalled
+		logger.V(4).Info("Skipping disabled hub cluster")
+		c.backoff.succeeded(managedClusterName)
+		return nil
+	}
+	if c.hubConfig.get().Excluded(managedClusterName) {
+		logger.V(4).Info("Skipping excluded hub cluster")
+		c.backoff.succeeded(managedClusterName)
 		return nil
 	}
+	if c.backoff.parked(managedCluster) {
+		logger.V(4).Info("Skipping parked hub cluster")
+		return nil
+	}
+	if meta.IsStatusConditionTrue(managedCluster.Status.Conditions, c.parkedCondition) {
+		// the desired state changed or the controller restarted, the status update triggers the retry
+		return c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    c.parkedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Retrying",
+			Message: "The hub installation is retried",
+		})
+	}
+
+	if delay, retrying := c.backoff.retrying(managedClusterName); retrying && c.firstInstalls.waiting(managedClusterName) {
+		// the first installs are processed before the retries of the failing hubs, which wait for
+		// another backoff delay rather than spinning in the queue
+		logger.V(4).Info("Deferring the retry of hub cluster after the first installs", "delay", delay)
+		syncCtx.Queue().AddAfter(managedClusterName, delay)
+		return nil
+	}
+
+	logger.V(2).Info("Reconciling hub cluster")
+	if err := c.reconcile(ctx, syncCtx, managedCluster); err != nil {
+		metrics.ReconcileErrors.WithLabelValues(c.name, managedClusterName).Inc()
+		tracing.RecordError(ctx, err)
+		delay, parked := c.backoff.failed(managedCluster)
+		if !parked {
+			metrics.SyncRetries.WithLabelValues(c.name).Inc()
+			logger.Error(err, "Failed to reconcile hub cluster, retrying", "delay", delay)
+			syncCtx.Queue().AddAfter(managedClusterName, delay)
+			if err := c.updateHubConditions(ctx, managedCluster, c.reconcileErrorCondition(err)); err != nil {
+				logger.Error(err, "Failed to report the reconcile error")
+			}
+			return nil
+		}
+		metrics.ParkedHubs.WithLabelValues(c.name).Inc()
+		logger.Error(err, "Failed to reconcile hub cluster, retries exhausted")
+		message := fmt.Sprintf("The hub installation is parked after %d failed retries, change the %s annotation to retry",
+			c.backoff.maxRetries, HOH_RETRY_ANNOTATION)
+		if c.options.ParkedCooldown > 0 {
+			// the circuit is half opened once the cooldown elapses
+			syncCtx.Queue().AddAfter(managedClusterName, c.options.ParkedCooldown)
+			message += fmt.Sprintf(", it is retried once in %s", c.options.ParkedCooldown)
+		}
+		return c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    c.parkedCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  "RetriesExhausted",
+			Message: fmt.Sprintf("%s: %v", message, err),
+		}, c.reconcileErrorCondition(err))
+	}
+	c.backoff.succeeded(managedClusterName)
+	if cond := meta.FindStatusCondition(managedCluster.Status.Conditions, HubConditionReconcileError); cond != nil &&
+		cond.Status == metav1.ConditionTrue && cond.Reason == c.name {
+		return c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    HubConditionReconcileError,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ReconcileSucceeded",
+			Message: fmt.Sprintf("The last reconcile of %s succeeded", c.name),
+		})
+	}
+	return nil
+}
+
+// resyncAll enqueues all managed hubs, spread over a part of the resync interval. It is also
+// called when the hub configuration is changed, to render the manifestworks with the new one.
+func (c *clusterController) resyncAll(syncCtx factory.SyncContext) error {
+	managedClusters, err := c.clusterLister.List(labels.Everything())
 	if err != nil {
 		return err
 	}
+	klog.V(2).InfoS("Resyncing managed clusters", "phase", c.name, "count", len(managedClusters))
+	maxDelay := int64(float64(c.options.ResyncInterval) * resyncJitterFactor)
+	hubConfig := c.hubConfig.get()
+	for _, managedCluster := range managedClusters {
+		if !IsManagedHub(managedCluster) || !c.ownsCluster(managedCluster.Name) || hubConfig.Excluded(managedCluster.Name) {
+			continue
+		}
+		c.enqueued(managedCluster.Name)
+		var delay time.Duration
+		if maxDelay > 0 {
+			delay = time.Duration(rand.Int63n(maxDelay))
+		}
+		syncCtx.Queue().AddAfter(managedCluster.Name, delay)
+	}
+	return nil
+}
+
+// applyManifestWork server-side applies the desired manifestwork if it does not exist yet, or patches
+// the fields differing from the existing one. It returns the existing manifestwork so the caller can
+// inspect its status, or nil if the manifestwork was just created.
+func (c *clusterController) applyManifestWork(ctx context.Context, managedCluster *clusterv1.ManagedCluster,
+	desired *workv1.ManifestWork) (*workv1.ManifestWork, error) {
+	SetManagedClusterUID(desired, managedCluster)
+	if err := SetSpecHash(desired); err != nil {
+		return nil, err
+	}
 
-	desiredSubscription := CreateSubManifestwork(managedClusterName)
-	subscription, err := c.workLister.ManifestWorks(managedClusterName).Get(managedClusterName + "-" + HOH_HUB_CLUSTER_SUBSCRIPTION)
+	// the manifestwork created with another naming scheme keeps its name rather than being orphaned
+	c.options.WorkNaming.setName(desired)
+	found, err := c.findManifestWork(managedCluster.Name, desired)
+	if err != nil {
+		return nil, err
+	}
+	if found != nil {
+		desired.Name = found.Name
+	}
+
+	workKey := knownWorkKey(managedCluster.Name, desired.Namespace, desired.Name, string(managedCluster.UID))
+	existing, err := c.workLister.ManifestWorks(desired.Namespace).Get(desired.Name)
+	if err == nil && IsStale(existing, managedCluster) {
+		// the managed cluster was deleted and imported again, the manifestwork is recreated once
+		// the stale one is deleted, its deletion requeues the managed cluster
+		return nil, c.deleteStaleManifestWork(ctx, managedCluster.Name, existing)
+	}
+	if err == nil && IsRestored(existing) {
+		// the manifestwork was restored before its managed cluster was imported again, it is adopted
+		// with the UID of the managed cluster rather than deleted as stale
+		loggerFrom(ctx).Info("Adopting the restored manifestwork", "manifestwork", desired.Name,
+			"restore", existing.Labels[RESTORE_NAME_LABEL])
+		desired.Annotations[RESTORE_ADOPTED_ANNOTATION] = existing.Labels[RESTORE_NAME_LABEL]
+	}
 	if errors.IsNotFound(err) {
-		klog.V(2).Infof("creating subscription manifestwork in %s namespace", managedClusterName)
-		_, err := c.workclient.ManifestWorks(managedClusterName).
-			Create(ctx, desiredSubscription, metav1.CreateOptions{})
+		loggerFrom(ctx).V(2).Info("Creating manifestwork", "manifestwork", desired.Name)
+		applied := desired.DeepCopy()
+		SetAuditAnnotations(applied, version.Get().GitVersion, c.hubConfig.get().Generation, time.Now())
+		actual, err := c.serverSideApply(ctx, applied)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		return nil
+		c.cache.UpdateCachedResourceMetadata(desired, actual)
+		// the manifestworks created in a dry run do not exist, they are created again on every sync
+		if _, deleted := c.knownWorks.LoadOrStore(workKey, struct{}{}); deleted && !c.options.DryRun {
+			loggerFrom(ctx).Info("Restored the manifestwork deleted by hand", "manifestwork", desired.Name)
+			c.clusterRecorder.Eventf(desired, corev1.EventTypeWarning, EventReasonManifestWorkRestored,
+				"The manifestwork %s was deleted, restored the managed state", desired.Name)
+		} else {
+			c.clusterRecorder.Eventf(desired, corev1.EventTypeNormal, EventReasonManifestWorkCreated,
+				"Created manifestwork %s", desired.Name)
+		}
+		return nil, nil
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
+	c.knownWorks.Store(workKey, struct{}{})
 
-	updated, err := EnsureManifestWork(subscription, desiredSubscription)
+	// the manifestwork is not changed since it was last applied by the controller
+	if c.cache.SafeToSkipApply(desired, existing) {
+		return existing, nil
+	}
+
+	metadataUnchanged := existing.Annotations[SPEC_HASH_ANNOTATION] == desired.Annotations[SPEC_HASH_ANNOTATION] &&
+		existing.Annotations[MANAGED_CLUSTER_UID_ANNOTATION] == desired.Annotations[MANAGED_CLUSTER_UID_ANNOTATION] &&
+		HasLabels(existing, desired)
+	// the rendered spec is unchanged and the existing spec still matches it
+	if metadataUnchanged && SpecUnchanged(existing, desired) {
+		c.cache.UpdateCachedResourceMetadata(desired, existing)
+		return existing, nil
+	}
+
+	updated, err := EnsureManifestWork(existing, desired)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if updated {
-		desiredSubscription.ObjectMeta.ResourceVersion = subscription.ObjectMeta.ResourceVersion
-		_, err := c.workclient.ManifestWorks(managedClusterName).
-			Update(ctx, desiredSubscription, metav1.UpdateOptions{})
-		if err != nil {
-			return err
+	if metadataUnchanged && !updated {
+		// the spec only differs by the fields defaulted by the kube-apiserver
+		c.cache.UpdateCachedResourceMetadata(desired, existing)
+		return existing, nil
+	}
+	patchTarget := desired.DeepCopy()
+	if !updated {
+		// the spec is unchanged, only stamp the spec hash
+		patchTarget.Spec = existing.Spec
+	}
+	SetAuditAnnotations(patchTarget, version.Get().GitVersion, c.hubConfig.get().Generation, time.Now())
+	patch, err := ManifestWorkMergePatch(existing, patchTarget)
+	if err != nil {
+		return nil, err
+	}
+	loggerFrom(ctx).V(2).Info("Patching manifestwork", "manifestwork", desired.Name)
+	actual, err := c.workclient.ManifestWorks(desired.Namespace).Patch(ctx, desired.Name, types.MergePatchType, patch,
+		metav1.PatchOptions{FieldManager: FIELD_MANAGER})
+	if err != nil {
+		return nil, err
+	}
+	c.cache.UpdateCachedResourceMetadata(desired, actual)
+	return existing, nil
+}
+
+// knownWorkKey returns the key of a manifestwork of the managed cluster in knownWorks, prefixed with
+// the managed cluster name so the entries of a deleted managed cluster are found whatever the
+// namespace of its manifestworks.
+func knownWorkKey(managedClusterName, namespace, name, uid string) string {
+	return managedClusterName + "/" + namespace + "/" + name + "/" + uid
+}
+
+// forgetWorks drops the manifestworks of a deleted managed cluster from knownWorks, so the entries do
+// not pile up as the managed clusters come and go.
+func (c *clusterController) forgetWorks(managedClusterName string) {
+	prefix := managedClusterName + "/"
+	c.knownWorks.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), prefix) {
+			c.knownWorks.Delete(key)
 		}
+		return true
+	})
+}
+
+// deleteStaleManifestWork deletes the manifestwork left by a previous managed cluster with the same
+// name, unless it is being deleted already.
+func (c *clusterController) deleteStaleManifestWork(ctx context.Context, managedClusterName string,
+	work *workv1.ManifestWork) error {
+	// the stale manifestwork is deleted on purpose, it is not restored as deleted by hand
+	c.knownWorks.Delete(knownWorkKey(managedClusterName, work.Namespace, work.Name,
+		work.Annotations[MANAGED_CLUSTER_UID_ANNOTATION]))
+	if work.DeletionTimestamp != nil {
+		loggerFrom(ctx).V(2).Info("Waiting for the stale manifestwork to be deleted", "manifestwork", work.Name)
+		return nil
+	}
+	loggerFrom(ctx).Info("Deleting the manifestwork of a previous managed cluster", "manifestwork", work.Name,
+		"uid", work.Annotations[MANAGED_CLUSTER_UID_ANNOTATION])
+	err := c.workclient.ManifestWorks(work.Namespace).Delete(ctx, work.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &work.UID},
+	})
+	if errors.IsNotFound(err) {
+		return nil
 	}
+	return err
+}
 
-	// if the csv PHASE is Succeeded, then create mch manifestwork to install Hub
-	for _, conditions := range subscription.Status.ResourceStatus.Manifests {
-		if conditions.ResourceMeta.Kind == "Subscription" {
-			for _, value := range conditions.StatusFeedbacks.Values {
-				if value.Name == "state" && *value.Value.String == "AtLatestKnown" {
-					//fetch user defined mch from annotation
-					userDefinedMCH := ""
-					if managedCluster.Annotations != nil {
-						userDefinedMCH = managedCluster.Annotations["mch"]
-					}
-
-					desiredMCH, err := CreateMCHManifestwork(managedClusterName, userDefinedMCH)
-					if err != nil {
-						return err
-					}
-					mch, err := c.workLister.ManifestWorks(managedClusterName).Get(managedClusterName + "-" + HOH_HUB_CLUSTER_MCH)
-					if errors.IsNotFound(err) {
-						klog.V(2).Infof("creating mch manifestwork in %s namespace", managedClusterName)
-						_, err := c.workclient.ManifestWorks(managedClusterName).
-							Create(ctx, desiredMCH, metav1.CreateOptions{})
-						if err != nil {
-							return err
-						}
-					}
-					if err != nil {
-						return err
-					}
-
-					updated, err := EnsureManifestWork(mch, desiredMCH)
-					if err != nil {
-						return err
-					}
-					if updated {
-						desiredMCH.ObjectMeta.ResourceVersion = mch.ObjectMeta.ResourceVersion
-						_, err := c.workclient.ManifestWorks(managedClusterName).
-							Update(ctx, desiredMCH, metav1.UpdateOptions{})
-						if err != nil {
-							return err
-						}
-					}
-					return nil
-				}
-			}
+// resourceRepairInterval is the minimum interval between two requests to reapply the manifests of a
+// manifestwork whose resource was deleted on the managed cluster
+const resourceRepairInterval = 5 * time.Minute
+
+// repairManifestWork requests the work agent to reapply the manifests of the manifestwork, because
+// the resource of the given kind was deleted on the managed cluster. The manifestwork is annotated
+// with the repair time, the update triggers the work agent to apply the manifests again. The
+// managed cluster is rechecked once the repair interval is elapsed.
+func (c *clusterController) repairManifestWork(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster, work *workv1.ManifestWork, kind string) error {
+	if repairedAt, err := time.Parse(time.RFC3339, work.Annotations[REPAIRED_AT_ANNOTATION]); err == nil {
+		if remaining := resourceRepairInterval - time.Since(repairedAt); remaining > 0 {
+			syncCtx.Queue().AddAfter(managedCluster.Name, remaining)
+			return nil
 		}
 	}
 
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				REPAIRED_AT_ANNOTATION: time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	loggerFrom(ctx).Info("Reapplying the manifestwork, its resource was deleted on the managed cluster",
+		"manifestwork", work.Name, "kind", kind)
+	if _, err := c.workclient.ManifestWorks(work.Namespace).Patch(ctx, work.Name, types.MergePatchType, patch,
+		metav1.PatchOptions{FieldManager: FIELD_MANAGER}); err != nil {
+		return err
+	}
+	metrics.ResourceRepairs.WithLabelValues(kind).Inc()
+	c.clusterRecorder.Eventf(managedClusterReference(managedCluster), corev1.EventTypeWarning, EventReasonResourceMissing,
+		"The %s of manifestwork %s was deleted on the managed cluster, it is reapplied", kind, work.Name)
+	syncCtx.Queue().AddAfter(managedCluster.Name, resourceRepairInterval)
 	return nil
 }
+
+// serverSideApply applies the manifestwork with the controller field manager, so the controller
+// only owns the fields it renders and other actors are able to annotate the manifestwork.
+func (c *clusterController) serverSideApply(ctx context.Context, work *workv1.ManifestWork) (*workv1.ManifestWork, error) {
+	data, err := json.Marshal(work)
+	if err != nil {
+		return nil, err
+	}
+	return c.workclient.ManifestWorks(work.Namespace).Patch(ctx, work.Name, types.ApplyPatchType, data,
+		metav1.PatchOptions{FieldManager: FIELD_MANAGER, Force: pointer.Bool(true)})
+}
@@ -0,0 +1,687 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	workv1listers "open-cluster-management.io/api/client/work/listers/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func TestResyncAll(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, managedCluster := range []*clusterv1.ManagedCluster{
+		{ObjectMeta: metav1.ObjectMeta{Name: "local-cluster"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "disabled", Labels: map[string]string{"hoh": "disabled"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cluster2"}},
+	} {
+		if err := indexer.Add(managedCluster); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctrl := &clusterController{
+		clusterLister: clusterv1listers.NewManagedClusterLister(indexer),
+		workIndexer:   cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{WORKS_BY_CLUSTER_INDEX: IndexWorkByCluster}),
+		options:       ControllerOptions{ResyncInterval: 40 * time.Millisecond},
+		hubConfig:     newHubConfigLoader(newConfigMapLister(t, nil)),
+		firstInstalls: newPendingInstalls(),
+		coalescer:     newEventCoalescer(),
+	}
+
+	syncCtx := testinghelpers.NewFakeSyncContext(t, factory.DefaultQueueKey)
+	if err := ctrl.resyncAll(syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	queued := map[string]bool{}
+	for len(queued) < 2 {
+		key, _ := syncCtx.Queue().Get()
+		queued[key.(string)] = true
+		syncCtx.Queue().Done(key)
+	}
+	if !queued["cluster1"] || !queued["cluster2"] {
+		t.Errorf("expected cluster1 and cluster2 to be queued, got %v", queued)
+	}
+	// wait longer than the max jitter to make sure no other cluster is queued
+	time.Sleep(20 * time.Millisecond)
+	if syncCtx.Queue().Len() != 0 {
+		t.Errorf("expected no other cluster to be queued, got %d", syncCtx.Queue().Len())
+	}
+}
+
+func TestHubConfigChangeResyncsAll(t *testing.T) {
+	ctrl := newTestController(t, nil, nil)
+	ctrl.options.ConfigNamespace = "test"
+	kubeClient := kubefake.NewSimpleClientset(newHubConfigMap(map[string]string{HUB_CONFIG_CHANNEL_KEY: "release-2.4"}))
+	kubeInformers := informers.NewSharedInformerFactory(kubeClient, 0)
+	clusterInformers := clusterinformers.NewSharedInformerFactory(ctrl.clusterClient, 0)
+	workInformers := workinformers.NewSharedInformerFactory(ctrl.workClient, 0)
+
+	queued := make(chan string, 10)
+	controller := ctrl.newFactory(clusterInformers.Cluster().V1().ManagedClusters(),
+		workInformers.Work().V1().ManifestWorks(), kubeInformers.Core().V1().ConfigMaps(), HOH_HUB_CLUSTER_SUBSCRIPTION).
+		WithSync(func(ctx context.Context, syncCtx factory.SyncContext) error {
+			queued <- syncCtx.QueueKey()
+			return nil
+		}).
+		ToController("TestController", events.NewInMemoryRecorder(t.Name()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	kubeInformers.Start(ctx.Done())
+	clusterInformers.Start(ctx.Done())
+	workInformers.Start(ctx.Done())
+	go controller.Run(ctx, 1)
+
+	waitForKey := func(expected string) {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case key := <-queued:
+				if key == expected {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("expected %q to be queued", expected)
+			}
+		}
+	}
+	// the hub configuration is loaded when the controller starts
+	waitForKey(factory.DefaultQueueKey)
+
+	if _, err := kubeClient.CoreV1().ConfigMaps("test").Update(context.TODO(),
+		newHubConfigMap(map[string]string{HUB_CONFIG_CHANNEL_KEY: "release-2.5"}), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForKey(factory.DefaultQueueKey)
+}
+
+type testController struct {
+	*clusterController
+	clusterClient *clusterfake.Clientset
+	workClient    *workfake.Clientset
+}
+
+// newConfigMapLister returns a lister of the test namespace backed by the given hub configuration.
+func newConfigMapLister(t *testing.T, configMap *corev1.ConfigMap) corev1listers.ConfigMapNamespaceLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if configMap != nil {
+		if err := indexer.Add(configMap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return corev1listers.NewConfigMapLister(indexer).ConfigMaps("test")
+}
+
+// newManagedCluster returns a managed cluster with a UID derived from its name
+func newManagedCluster(name string) *clusterv1.ManagedCluster {
+	return &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name + "-uid")}}
+}
+
+// newTestController returns a controller whose listers and clients are backed by the given objects.
+func newTestController(t *testing.T, managedClusters []*clusterv1.ManagedCluster, works []*workv1.ManifestWork) *testController {
+	clusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	clusterObjs := []runtime.Object{}
+	for _, managedCluster := range managedClusters {
+		if err := clusterIndexer.Add(managedCluster); err != nil {
+			t.Fatal(err)
+		}
+		clusterObjs = append(clusterObjs, managedCluster)
+	}
+	workIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{WORKS_BY_CLUSTER_INDEX: IndexWorkByCluster})
+	workObjs := []runtime.Object{}
+	for _, work := range works {
+		if err := workIndexer.Add(work); err != nil {
+			t.Fatal(err)
+		}
+		workObjs = append(workObjs, work)
+	}
+
+	clusterClient := clusterfake.NewSimpleClientset(clusterObjs...)
+	workClient := workfake.NewSimpleClientset(workObjs...)
+	// the fake clientset does not support server-side apply, so apply the object as is
+	workClient.PrependReactor("patch", "manifestworks", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clienttesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		work := &workv1.ManifestWork{}
+		if err := json.Unmarshal(patch.GetPatch(), work); err != nil {
+			return true, nil, err
+		}
+		gvr := workv1.GroupVersion.WithResource("manifestworks")
+		if _, err := workClient.Tracker().Get(gvr, work.Namespace, work.Name); errors.IsNotFound(err) {
+			return true, work, workClient.Tracker().Create(gvr, work, work.Namespace)
+		}
+		return true, work, workClient.Tracker().Update(gvr, work, work.Namespace)
+	})
+	return &testController{
+		clusterController: &clusterController{
+			name:            "TestController",
+			clusterclient:   clusterClient.ClusterV1(),
+			workclient:      workClient.WorkV1(),
+			clusterLister:   clusterv1listers.NewManagedClusterLister(clusterIndexer),
+			workLister:      workv1listers.NewManifestWorkLister(workIndexer),
+			workIndexer:     workIndexer,
+			cache:           resourceapply.NewResourceCache(),
+			eventRecorder:   events.NewInMemoryRecorder(t.Name()),
+			clusterRecorder: record.NewFakeRecorder(100),
+			backoff:         newClusterBackoff(0, 0),
+			firstInstalls:   newPendingInstalls(),
+			coalescer:       newEventCoalescer(),
+			hubConfig:       newHubConfigLoader(newConfigMapLister(t, nil)),
+
+			parkedCondition: "TestParked",
+		},
+		clusterClient: clusterClient,
+		workClient:    workClient,
+	}
+}
+
+// newTestMCHController returns a test controller reconciling the mch manifestwork
+func newTestMCHController(t *testing.T, managedClusters []*clusterv1.ManagedCluster, works []*workv1.ManifestWork) *testController {
+	ctrl := newTestController(t, managedClusters, works)
+	mch := &mchController{clusterController: ctrl.clusterController}
+	ctrl.reconcile = mch.reconcileMCH
+	return ctrl
+}
+
+func TestReconcileRechecksOperator(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: "UpgradePending"})
+	ctrl := newTestMCHController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{subscription})
+	ctrl.options.OperatorRecheckInterval = 10 * time.Millisecond
+
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range ctrl.workClient.Actions() {
+		if patch, ok := action.(clienttesting.PatchActionImpl); ok && patch.GetName() == "cluster1-"+HOH_HUB_CLUSTER_MCH {
+			t.Errorf("expected the mch manifestwork not to be applied, got %v", action)
+		}
+	}
+
+	key, _ := syncCtx.Queue().Get()
+	if key != "cluster1" {
+		t.Errorf("expected cluster1 to be requeued, got %v", key)
+	}
+}
+
+func TestReconcileCreatesMCH(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN})
+	ctrl := newTestMCHController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{subscription})
+
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_MCH, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the mch manifestwork to be created: %v", err)
+	}
+}
+
+func TestReconcileSkipsCSVGate(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:        "cluster1",
+		Annotations: map[string]string{HOH_SKIP_CSV_GATE_ANNOTATION: "true"},
+	}}
+	// the operator subscription reports no state
+	subscription := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	ctrl := newTestMCHController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{subscription})
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_MCH, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the mch manifestwork to be created: %v", err)
+	}
+}
+
+func TestReconcileSkipsInvalidMCH(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	managedCluster.Annotations = map[string]string{HOH_MCH_ANNOTATION: `{"spec":{"availabiltyConfig":"Basic"}}`}
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN})
+	SetManagedClusterUID(subscription, managedCluster)
+	ctrl := newTestMCHController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{subscription})
+
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_MCH, metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the mch manifestwork not to be created, got %v", err)
+	}
+	updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cond := meta.FindStatusCondition(updated.Status.Conditions, HubConditionMCHInvalid); cond == nil ||
+		cond.Status != metav1.ConditionTrue || !strings.Contains(cond.Message, "availabiltyConfig") {
+		t.Errorf("expected the invalid mch to be reported, got %v", updated.Status.Conditions)
+	}
+	if events := recordedEvents(ctrl.clusterRecorder.(*record.FakeRecorder)); len(events) != 1 ||
+		!strings.Contains(events[0], EventReasonInvalidMCH) {
+		t.Errorf("expected an %s event, got %v", EventReasonInvalidMCH, events)
+	}
+}
+
+func TestApplyManifestWorkWithFieldManager(t *testing.T) {
+	ctrl := newTestController(t, nil, nil)
+	if _, err := ctrl.applyManifestWork(context.TODO(), newManagedCluster("cluster1"), CreateSubManifestwork("cluster1", DefaultHubConfig())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actions := ctrl.workClient.Actions()
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 action, got %v", actions)
+	}
+	patch, ok := actions[0].(clienttesting.PatchActionImpl)
+	if !ok || patch.GetPatchType() != types.ApplyPatchType {
+		t.Fatalf("expected an apply patch, got %v", actions[0])
+	}
+	work := &workv1.ManifestWork{}
+	if err := json.Unmarshal(patch.GetPatch(), work); err != nil {
+		t.Fatal(err)
+	}
+	if work.Kind != "ManifestWork" || work.ResourceVersion != "" {
+		t.Errorf("unexpected applied manifestwork %v", work.ObjectMeta)
+	}
+	if events := recordedEvents(ctrl.clusterRecorder.(*record.FakeRecorder)); len(events) != 1 ||
+		!strings.Contains(events[0], EventReasonManifestWorkCreated) {
+		t.Errorf("expected a ManifestWorkCreated event, got %v", events)
+	}
+}
+
+func TestApplyManifestWorkSpecHash(t *testing.T) {
+	existing := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	SetManagedClusterUID(existing, newManagedCluster("cluster1"))
+	if err := SetSpecHash(existing); err != nil {
+		t.Fatal(err)
+	}
+	legacy := CreateSubManifestwork("cluster2", DefaultHubConfig())
+	changed := CreateSubManifestwork("cluster3", DefaultHubConfig())
+	changed.Annotations = map[string]string{SPEC_HASH_ANNOTATION: "outdated"}
+	changed.Spec.ManifestConfigs = nil
+	// the spec edited by hand keeps the spec hash of the rendered spec
+	edited := CreateSubManifestwork("cluster4", DefaultHubConfig())
+	SetManagedClusterUID(edited, newManagedCluster("cluster4"))
+	if err := SetSpecHash(edited); err != nil {
+		t.Fatal(err)
+	}
+	edited.Spec.ManifestConfigs = nil
+
+	ctrl := newTestController(t, nil, []*workv1.ManifestWork{existing, legacy, changed, edited})
+	for _, work := range []*workv1.ManifestWork{existing, legacy, changed, edited} {
+		if _, err := ctrl.applyManifestWork(context.TODO(), newManagedCluster(work.Namespace),
+			CreateSubManifestwork(work.Namespace, DefaultHubConfig())); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	patches := map[string]string{}
+	for _, action := range ctrl.workClient.Actions() {
+		patch := action.(clienttesting.PatchActionImpl)
+		patches[patch.GetNamespace()] = string(patch.GetPatch())
+	}
+	if _, ok := patches["cluster1"]; ok {
+		t.Errorf("expected the unchanged manifestwork not to be patched")
+	}
+	if patch := patches["cluster2"]; !strings.Contains(patch, SPEC_HASH_ANNOTATION) || strings.Contains(patch, `"spec"`) {
+		t.Errorf("expected only the spec hash to be stamped, got %s", patch)
+	}
+	if patch := patches["cluster3"]; !strings.Contains(patch, "manifestConfigs") {
+		t.Errorf("expected the spec to be patched, got %s", patch)
+	}
+	if patch := patches["cluster4"]; !strings.Contains(patch, "manifestConfigs") {
+		t.Errorf("expected the spec edited by hand to be repaired, got %s", patch)
+	}
+}
+
+func TestApplyManifestWorkResourceCache(t *testing.T) {
+	existing := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	existing.ResourceVersion = "1"
+	ctrl := newTestController(t, nil, []*workv1.ManifestWork{existing})
+
+	desired := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	if _, err := ctrl.applyManifestWork(context.TODO(), newManagedCluster("cluster1"), desired); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").Get(context.TODO(), existing.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ctrl.cache.SafeToSkipApply(desired, actual) {
+		t.Errorf("expected the applied manifestwork to be cached")
+	}
+
+	actual.ResourceVersion = "3"
+	if ctrl.cache.SafeToSkipApply(desired, actual) {
+		t.Errorf("expected the manifestwork changed by others not to be skipped")
+	}
+}
+
+func TestApplyManifestWorkAuditAnnotations(t *testing.T) {
+	ctrl := newTestController(t, nil, nil)
+	configMap := newHubConfigMap(nil)
+	configMap.ResourceVersion = "42"
+	ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t, configMap))
+
+	if _, err := ctrl.applyManifestWork(context.TODO(), newManagedCluster("cluster1"), CreateSubManifestwork("cluster1", DefaultHubConfig())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_SUBSCRIPTION, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.Annotations[CONFIG_GENERATION_ANNOTATION] != "42" {
+		t.Errorf("expected the config generation 42, got %v", actual.Annotations)
+	}
+	if _, err := time.Parse(time.RFC3339, actual.Annotations[RENDERED_AT_ANNOTATION]); err != nil {
+		t.Errorf("expected the render time to be stamped, got %v", actual.Annotations)
+	}
+	if actual.Annotations[SPEC_HASH_ANNOTATION] == "" {
+		t.Errorf("expected the spec hash to be stamped, got %v", actual.Annotations)
+	}
+}
+
+func TestApplyManifestWorkDeletesStaleWork(t *testing.T) {
+	stale := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	stale.UID = "work-uid"
+	SetManagedClusterUID(stale, &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1", UID: "previous-uid"}})
+	ctrl := newTestController(t, nil, []*workv1.ManifestWork{stale})
+
+	managedCluster := newManagedCluster("cluster1")
+	if _, err := ctrl.applyManifestWork(context.TODO(), managedCluster, CreateSubManifestwork("cluster1", DefaultHubConfig())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actions := ctrl.workClient.Actions()
+	if len(actions) != 1 {
+		t.Fatalf("expected the stale manifestwork to be deleted, got %v", actions)
+	}
+	deletion, ok := actions[0].(clienttesting.DeleteActionImpl)
+	if !ok || deletion.GetName() != stale.Name {
+		t.Errorf("expected the stale manifestwork to be deleted, got %v", actions[0])
+	}
+	if work, err := ctrl.getManifestWork(managedCluster, HOH_HUB_CLUSTER_SUBSCRIPTION); err != nil || work != nil {
+		t.Errorf("expected the stale manifestwork to be ignored, got %v, %v", work, err)
+	}
+
+	// the stale manifestwork is not deleted again while it is being deleted
+	now := metav1.Now()
+	stale.DeletionTimestamp = &now
+	ctrl = newTestController(t, nil, []*workv1.ManifestWork{stale})
+	if _, err := ctrl.applyManifestWork(context.TODO(), managedCluster, CreateSubManifestwork("cluster1", DefaultHubConfig())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := ctrl.workClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no action while the stale manifestwork is deleted, got %v", actions)
+	}
+}
+
+func TestApplyManifestWorkAdoptsRestoredWork(t *testing.T) {
+	restored := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	SetManagedClusterUID(restored, &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1", UID: "previous-uid"}})
+	restored.Labels[RESTORE_NAME_LABEL] = "restore-1"
+	ctrl := newTestController(t, nil, []*workv1.ManifestWork{restored})
+
+	managedCluster := newManagedCluster("cluster1")
+	if _, err := ctrl.applyManifestWork(context.TODO(), managedCluster, CreateSubManifestwork("cluster1", DefaultHubConfig())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actions := ctrl.workClient.Actions()
+	if len(actions) != 1 {
+		t.Fatalf("expected the restored manifestwork to be patched, got %v", actions)
+	}
+	patch, ok := actions[0].(clienttesting.PatchActionImpl)
+	if !ok || !strings.Contains(string(patch.GetPatch()), `"`+RESTORE_ADOPTED_ANNOTATION+`":"restore-1"`) ||
+		!strings.Contains(string(patch.GetPatch()), string(managedCluster.UID)) {
+		t.Errorf("expected the restored manifestwork to be adopted, got %v", actions[0])
+	}
+
+	// once adopted, the manifestwork is stale for the next managed cluster with the same name
+	restored.Annotations[RESTORE_ADOPTED_ANNOTATION] = "restore-1"
+	if !IsStale(restored, managedCluster) {
+		t.Errorf("expected the adopted manifestwork to be stale for another managed cluster")
+	}
+}
+
+func TestApplyManifestWorkRestoresDeletedWork(t *testing.T) {
+	existing := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	ctrl := newTestController(t, nil, []*workv1.ManifestWork{existing})
+	managedCluster := newManagedCluster("cluster1")
+	if _, err := ctrl.applyManifestWork(context.TODO(), managedCluster, CreateSubManifestwork("cluster1", DefaultHubConfig())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the manifestwork is deleted by hand
+	if err := ctrl.workIndexer.Delete(existing); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctrl.applyManifestWork(context.TODO(), managedCluster, CreateSubManifestwork("cluster1", DefaultHubConfig())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").Get(context.TODO(), existing.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the manifestwork to be restored: %v", err)
+	}
+	if events := recordedEvents(ctrl.clusterRecorder.(*record.FakeRecorder)); len(events) != 1 ||
+		!strings.HasPrefix(events[0], corev1.EventTypeWarning+" "+EventReasonManifestWorkRestored) {
+		t.Errorf("expected a ManifestWorkRestored event, got %v", events)
+	}
+}
+
+func TestForgetWorks(t *testing.T) {
+	ctrl := newTestController(t, nil, nil)
+	ctrl.knownWorks.Store(knownWorkKey("cluster1", "cluster1", "cluster1-subscription", "uid1"), struct{}{})
+	ctrl.knownWorks.Store(knownWorkKey("cluster1", "hosting", "cluster1-hosted-mch", "uid1"), struct{}{})
+	ctrl.knownWorks.Store(knownWorkKey("cluster10", "cluster10", "cluster10-subscription", "uid10"), struct{}{})
+
+	ctrl.forgetWorks("cluster1")
+	var keys []string
+	ctrl.knownWorks.Range(func(key, _ interface{}) bool {
+		keys = append(keys, key.(string))
+		return true
+	})
+	if len(keys) != 1 || keys[0] != knownWorkKey("cluster10", "cluster10", "cluster10-subscription", "uid10") {
+		t.Errorf("expected only the manifestworks of cluster10 to be known, got %v", keys)
+	}
+}
+
+func TestApplyManifestWorkKeepsRenamedWork(t *testing.T) {
+	existing := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	existing.Name = "old-cluster1-subscription"
+	ctrl := newTestController(t, nil, []*workv1.ManifestWork{existing})
+	managedCluster := newManagedCluster("cluster1")
+	config := DefaultHubConfig()
+	config.Channel = "release-2.6"
+	if _, err := ctrl.applyManifestWork(context.TODO(), managedCluster, CreateSubManifestwork("cluster1", config)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range ctrl.workClient.Actions() {
+		if name := action.(clienttesting.PatchActionImpl).GetName(); name != existing.Name {
+			t.Errorf("expected the manifestwork %s to be updated in place, got a patch of %s", existing.Name, name)
+		}
+	}
+	if len(ctrl.workClient.Actions()) == 0 {
+		t.Errorf("expected the manifestwork %s to be updated", existing.Name)
+	}
+	if work, err := ctrl.getManifestWork(managedCluster, HOH_HUB_CLUSTER_SUBSCRIPTION); err != nil || work == nil ||
+		work.Name != existing.Name {
+		t.Errorf("expected the renamed manifestwork to be found by its labels, got %v, %v", work, err)
+	}
+}
+
+func TestReconcileAdoptsWorksOfPreviousNaming(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	existing := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	SetManagedClusterUID(existing, managedCluster)
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{existing})
+	ctrl.reconcile = (&subscriptionController{clusterController: ctrl.clusterController}).reconcileSubscription
+
+	// the works are renamed, the existing one is found by its labels and kept
+	ctrl.options.WorkNaming = WorkNaming{Prefix: "team-a-", Suffix: "-v2"}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range ctrl.workClient.Actions() {
+		if action.GetVerb() == "create" {
+			t.Errorf("expected the existing manifestwork to be adopted, got %v", action)
+		}
+		if patch, ok := action.(clienttesting.PatchActionImpl); ok && patch.GetName() != existing.Name {
+			t.Errorf("expected the manifestwork %s to be kept, got a patch of %s", existing.Name, patch.GetName())
+		}
+	}
+	works, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(works.Items) != 1 || works.Items[0].Name != existing.Name {
+		t.Errorf("expected only the manifestwork %s, got %v", existing.Name, works.Items)
+	}
+}
+
+func TestObjectMetaUnwrapsTombstone(t *testing.T) {
+	work := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	accessor, err := objectMeta(cache.DeletedFinalStateUnknown{Key: "cluster1/" + work.Name, Obj: work})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if accessor.GetName() != work.Name {
+		t.Errorf("expected the deleted manifestwork %s, got %s", work.Name, accessor.GetName())
+	}
+}
+
+func TestReconcileRepairsDeletedMCH(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN})
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetManagedClusterUID(mch, managedCluster)
+	if err := SetSpecHash(mch); err != nil {
+		t.Fatal(err)
+	}
+	mch = withMissingResource(mch, "MultiClusterHub")
+	ctrl := newTestMCHController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{subscription, mch})
+
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actions := ctrl.workClient.Actions()
+	if len(actions) != 1 {
+		t.Fatalf("expected the mch manifestwork to be annotated, got %v", actions)
+	}
+	if patch := string(actions[0].(clienttesting.PatchActionImpl).GetPatch()); !strings.Contains(patch, REPAIRED_AT_ANNOTATION) {
+		t.Errorf("expected the repair time to be stamped, got %s", patch)
+	}
+	if events := recordedEvents(ctrl.clusterRecorder.(*record.FakeRecorder)); len(events) != 1 ||
+		!strings.HasPrefix(events[0], corev1.EventTypeWarning+" "+EventReasonResourceMissing) {
+		t.Errorf("expected a ResourceMissing event, got %v", events)
+	}
+	if syncCtx.Queue().Len() != 0 {
+		t.Errorf("expected the recheck to be delayed, got %d queued keys", syncCtx.Queue().Len())
+	}
+
+	// the manifestwork is not annotated again within the repair interval
+	mch.Annotations[REPAIRED_AT_ANNOTATION] = time.Now().UTC().Format(time.RFC3339)
+	ctrl = newTestMCHController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{subscription, mch})
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := ctrl.workClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no action within the repair interval, got %v", actions)
+	}
+}
+
+func TestReconcileSkipsPausedMCH(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	managedCluster.Annotations = map[string]string{HOH_PAUSE_ANNOTATION: "true"}
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN})
+	ctrl := newTestMCHController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{subscription})
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := ctrl.workClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected the mch manifestwork not to be created for the paused cluster, got %v", actions)
+	}
+}
+
+func TestReconcileReportsLastError(t *testing.T) {
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{newManagedCluster("cluster1")}, nil)
+	ctrl.reconcile = func(ctx context.Context, syncCtx factory.SyncContext, managedCluster *clusterv1.ManagedCluster) error {
+		return errors.NewForbidden(workv1.Resource("manifestworks"), "cluster1", nil)
+	}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cond := meta.FindStatusCondition(updated.Status.Conditions, HubConditionReconcileError)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != "TestController" ||
+		!strings.Contains(cond.Message, "forbidden") {
+		t.Fatalf("expected the reconcile error to be reported, got %v", updated.Status.Conditions)
+	}
+
+	// the error is cleared once the phase succeeds
+	ctrl.reconcile = func(ctx context.Context, syncCtx factory.SyncContext, managedCluster *clusterv1.ManagedCluster) error {
+		return nil
+	}
+	clusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := clusterIndexer.Add(updated); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.clusterLister = clusterv1listers.NewManagedClusterLister(clusterIndexer)
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, err = ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.IsStatusConditionFalse(updated.Status.Conditions, HubConditionReconcileError) {
+		t.Errorf("expected the reconcile error to be cleared, got %v", updated.Status.Conditions)
+	}
+}
@@ -0,0 +1,71 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// HubConditionCSVMismatch is true when the CSV installed by the operator subscription is not of the
+// minor version of the configured channel or starting CSV, as when the subscription was changed on
+// the managed cluster
+const HubConditionCSVMismatch = "HubOperatorCSVMismatch"
+
+// minorVersionOf returns the major and minor version of a CSV, such as 2.4 for
+// advanced-cluster-management.v2.4.1, or of a channel, such as 2.4 for release-2.4, or an empty
+// string if it has none
+func minorVersionOf(name string) string {
+	if i := strings.LastIndex(name, ".v"); i >= 0 {
+		name = name[i+2:]
+	} else {
+		name = name[strings.LastIndex(name, "-")+1:]
+	}
+	parts := strings.SplitN(name, ".", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// CSVMismatchCondition returns the condition reporting whether the CSV installed by the operator
+// subscription matches the minor version of the configured channel, and of the configured starting
+// CSV when it is pinned. The channels and CSVs without a version are not compared.
+func CSVMismatchCondition(subscription *workv1.ManifestWork, config *HubConfig) metav1.Condition {
+	installed := GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_INSTALLED_CSV_FEEDBACK)
+	if installed == "" {
+		return metav1.Condition{
+			Type:    HubConditionCSVMismatch,
+			Status:  metav1.ConditionFalse,
+			Reason:  "CSVNotReported",
+			Message: "The operator subscription reports no installed CSV yet",
+		}
+	}
+	version := minorVersionOf(installed)
+	if expected := minorVersionOf(config.Channel); version != "" && expected != "" && version != expected {
+		return metav1.Condition{
+			Type:   HubConditionCSVMismatch,
+			Status: metav1.ConditionTrue,
+			Reason: "ChannelMismatch",
+			Message: fmt.Sprintf("The installed CSV %s does not match the configured channel %s, current CSV is %q",
+				installed, config.Channel,
+				GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_CURRENT_CSV_FEEDBACK)),
+		}
+	}
+	if expected := minorVersionOf(config.StartingCSV); version != "" && expected != "" && version != expected {
+		return metav1.Condition{
+			Type:   HubConditionCSVMismatch,
+			Status: metav1.ConditionTrue,
+			Reason: "StartingCSVMismatch",
+			Message: fmt.Sprintf("The installed CSV %s does not match the configured starting CSV %s",
+				installed, config.StartingCSV),
+		}
+	}
+	return metav1.Condition{
+		Type:    HubConditionCSVMismatch,
+		Status:  metav1.ConditionFalse,
+		Reason:  "AsExpected",
+		Message: fmt.Sprintf("The installed CSV %s matches the configured channel", installed),
+	}
+}
@@ -0,0 +1,76 @@
+package cluster
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestCSVMismatchCondition(t *testing.T) {
+	newSubscription := func(installed string) *workv1.ManifestWork {
+		return withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription", map[string]string{
+			SUBSCRIPTION_INSTALLED_CSV_FEEDBACK: installed,
+			SUBSCRIPTION_CURRENT_CSV_FEEDBACK:   installed,
+		})
+	}
+
+	cases := []struct {
+		name           string
+		subscription   *workv1.ManifestWork
+		channel        string
+		startingCSV    string
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "not reported",
+			subscription:   CreateSubManifestwork("cluster1", DefaultHubConfig()),
+			channel:        "release-2.4",
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "CSVNotReported",
+		},
+		{
+			name:           "matching the channel",
+			subscription:   newSubscription("advanced-cluster-management.v2.4.2"),
+			channel:        "release-2.4",
+			startingCSV:    "advanced-cluster-management.v2.4.1",
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "AsExpected",
+		},
+		{
+			name:           "channel changed on the managed cluster",
+			subscription:   newSubscription("advanced-cluster-management.v2.5.0"),
+			channel:        "release-2.4",
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: "ChannelMismatch",
+		},
+		{
+			name:           "pinned to another version",
+			subscription:   newSubscription("advanced-cluster-management.v2.5.0"),
+			channel:        "stable",
+			startingCSV:    "advanced-cluster-management.v2.4.1",
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: "StartingCSVMismatch",
+		},
+		{
+			name:           "channel without version",
+			subscription:   newSubscription("advanced-cluster-management.v2.5.0"),
+			channel:        "stable",
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "AsExpected",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := DefaultHubConfig()
+			config.Channel = c.channel
+			config.StartingCSV = c.startingCSV
+			cond := CSVMismatchCondition(c.subscription, config)
+			if cond.Type != HubConditionCSVMismatch || cond.Status != c.expectedStatus || cond.Reason != c.expectedReason {
+				t.Errorf("expected %s with reason %s, got %v", c.expectedStatus, c.expectedReason, cond)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// eventQPS and eventBurst limit the events written into each managed cluster namespace
+	eventQPS   = 1.0 / 60
+	eventBurst = 10
+	// eventDedupWindow is the minimum interval between the count updates of a repeated event
+	eventDedupWindow = 10 * time.Minute
+	// eventCacheSize bounds the namespaces and events tracked, enough for the largest fleets
+	eventCacheSize = 8192
+)
+
+// rateLimitedEventSink wraps an event sink to prevent event storms on large fleets. The events
+// written into each managed cluster namespace are rate limited, and the count of an event repeated
+// on every resync is only written once per dedup window, so the event still reports the latest
+// count without an API call per occurrence. The new events of a transition are written as long as
+// the rate limit of the namespace allows it. Dropped events are only logged.
+type rateLimitedEventSink struct {
+	sink   record.EventSink
+	qps    float32
+	burst  int
+	window time.Duration
+
+	lock sync.Mutex
+	// limiters holds the rate limiter of each namespace
+	limiters *utilcache.LRUExpireCache
+	// recent holds the events written within the dedup window
+	recent *utilcache.LRUExpireCache
+}
+
+func newRateLimitedEventSink(sink record.EventSink, qps float32, burst int, window time.Duration) *rateLimitedEventSink {
+	return &rateLimitedEventSink{
+		sink:     sink,
+		qps:      qps,
+		burst:    burst,
+		window:   window,
+		limiters: utilcache.NewLRUExpireCache(eventCacheSize),
+		recent:   utilcache.NewLRUExpireCache(eventCacheSize),
+	}
+}
+
+func (s *rateLimitedEventSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	if !s.allow(event) {
+		return event, nil
+	}
+	return s.markWritten(s.sink.Create(event))
+}
+
+func (s *rateLimitedEventSink) Update(event *corev1.Event) (*corev1.Event, error) {
+	if s.recentlyWritten(event) || !s.allow(event) {
+		return event, nil
+	}
+	return s.markWritten(s.sink.Update(event))
+}
+
+func (s *rateLimitedEventSink) Patch(event *corev1.Event, data []byte) (*corev1.Event, error) {
+	if s.recentlyWritten(event) || !s.allow(event) {
+		return event, nil
+	}
+	return s.markWritten(s.sink.Patch(event, data))
+}
+
+// allow returns true if the rate limit of the event namespace allows writing the event
+func (s *rateLimitedEventSink) allow(event *corev1.Event) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var limiter flowcontrol.RateLimiter
+	if cached, ok := s.limiters.Get(event.Namespace); ok {
+		limiter = cached.(flowcontrol.RateLimiter)
+	} else {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(s.qps, s.burst)
+	}
+	// the limiter is forgotten once it would be full again
+	s.limiters.Add(event.Namespace, limiter, time.Duration(float32(s.burst)/s.qps)*time.Second)
+	if !limiter.TryAccept() {
+		klog.V(4).Infof("Dropping event %s/%s %s, the rate limit of the namespace is exceeded",
+			event.Namespace, event.Name, event.Reason)
+		return false
+	}
+	return true
+}
+
+// recentlyWritten returns true if the event was written within the dedup window
+func (s *rateLimitedEventSink) recentlyWritten(event *corev1.Event) bool {
+	if _, ok := s.recent.Get(eventKey(event)); ok {
+		klog.V(4).Infof("Skipping the count update of event %s/%s %s, it was written less than %s ago",
+			event.Namespace, event.Name, event.Reason, s.window)
+		return true
+	}
+	return false
+}
+
+func (s *rateLimitedEventSink) markWritten(event *corev1.Event, err error) (*corev1.Event, error) {
+	if err == nil {
+		s.recent.Add(eventKey(event), struct{}{}, s.window)
+	}
+	return event, err
+}
+
+func eventKey(event *corev1.Event) string {
+	return event.Namespace + "/" + event.Name
+}
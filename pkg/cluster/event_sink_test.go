@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeEventSink struct {
+	writes []string
+}
+
+func (s *fakeEventSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	s.writes = append(s.writes, "create "+eventKey(event))
+	return event, nil
+}
+
+func (s *fakeEventSink) Update(event *corev1.Event) (*corev1.Event, error) {
+	s.writes = append(s.writes, "update "+eventKey(event))
+	return event, nil
+}
+
+func (s *fakeEventSink) Patch(event *corev1.Event, data []byte) (*corev1.Event, error) {
+	s.writes = append(s.writes, "patch "+eventKey(event))
+	return event, nil
+}
+
+func newEvent(namespace, name string) *corev1.Event {
+	return &corev1.Event{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}, Reason: "Test"}
+}
+
+func TestRateLimitedEventSinkRateLimitsNamespaces(t *testing.T) {
+	fake := &fakeEventSink{}
+	sink := newRateLimitedEventSink(fake, 0.001, 2, time.Minute)
+
+	for _, name := range []string{"event1", "event2", "event3"} {
+		if _, err := sink.Create(newEvent("cluster1", name)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := sink.Create(newEvent("cluster2", "event1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"create cluster1/event1", "create cluster1/event2", "create cluster2/event1"}
+	if len(fake.writes) != len(expected) {
+		t.Fatalf("expected writes %v, got %v", expected, fake.writes)
+	}
+	for i := range expected {
+		if fake.writes[i] != expected[i] {
+			t.Errorf("expected writes %v, got %v", expected, fake.writes)
+		}
+	}
+}
+
+func TestRateLimitedEventSinkDeduplicatesCountUpdates(t *testing.T) {
+	fake := &fakeEventSink{}
+	sink := newRateLimitedEventSink(fake, 100, 100, 50*time.Millisecond)
+
+	event := newEvent("cluster1", "event1")
+	if _, err := sink.Create(event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the repeated event is not written again within the dedup window
+	if _, err := sink.Patch(event, []byte("{}")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := sink.Update(event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// a new event is written
+	if _, err := sink.Create(newEvent("cluster1", "event2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.writes) != 2 {
+		t.Fatalf("expected the count updates to be skipped, got %v", fake.writes)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := sink.Patch(event, []byte("{}")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.writes) != 3 || fake.writes[2] != "patch cluster1/event1" {
+		t.Errorf("expected the count to be updated after the dedup window, got %v", fake.writes)
+	}
+}
@@ -0,0 +1,55 @@
+package cluster
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// EVENT_COMPONENT is the source component of the events recorded into the managed cluster namespaces
+const EVENT_COMPONENT = "hub-cluster-controller"
+
+// reasons of the lifecycle events recorded into the managed cluster namespaces
+const (
+	EventReasonManifestWorkCreated      = "ManifestWorkCreated"
+	EventReasonManifestWorkRestored     = "ManifestWorkRestored"
+	EventReasonHubInstalled             = "HubInstalled"
+	EventReasonHubDegraded              = "HubDegraded"
+	EventReasonResourceMissing          = "ResourceMissing"
+	EventReasonInvalidMaintenanceWindow = "InvalidMaintenanceWindow"
+	EventReasonMaintenanceWindowOpened  = "MaintenanceWindowOpened"
+	EventReasonHubInstallPending        = "HubInstallPending"
+	EventReasonMCHOverrideConflict      = "MCHOverrideConflict"
+	EventReasonInvalidMCH               = "InvalidMCH"
+	EventReasonUnsupportedArchitecture  = "UnsupportedArchitecture"
+	EventReasonUnsupportedHyperShift    = "UnsupportedHyperShift"
+)
+
+// NewClusterEventRecorder returns a recorder of the lifecycle events of the managed hubs, which are
+// recorded into the managed cluster namespaces next to the rest of the managed cluster resources.
+// The events are rate limited and deduplicated per managed cluster namespace.
+// The returned function stops the recording.
+func NewClusterEventRecorder(kubeClient kubernetes.Interface) (record.EventRecorder, func()) {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(newRateLimitedEventSink(
+		&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")},
+		eventQPS, eventBurst, eventDedupWindow))
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: EVENT_COMPONENT}), broadcaster.Shutdown
+}
+
+// managedClusterReference returns the reference of the managed cluster as the involved object of its
+// events. The managed cluster is cluster scoped, the reference carries the managed cluster namespace
+// so the events are recorded there instead of the default namespace.
+func managedClusterReference(managedCluster *clusterv1.ManagedCluster) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion:      clusterv1.GroupVersion.String(),
+		Kind:            "ManagedCluster",
+		Name:            managedCluster.Name,
+		Namespace:       managedCluster.Name,
+		UID:             managedCluster.UID,
+		ResourceVersion: managedCluster.ResourceVersion,
+	}
+}
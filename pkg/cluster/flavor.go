@@ -0,0 +1,65 @@
+package cluster
+
+import (
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// HOH_FLAVOR_ANNOTATION is the product flavor of the hub of the managed cluster, acm or community,
+// such as the community operator on the development hubs of a fleet of downstream hubs. It is
+// preferred over the flavor configuration, an unknown flavor is ignored.
+const HOH_FLAVOR_ANNOTATION = "hoh-flavor"
+
+// product flavors of the hubs
+const (
+	// FlavorACM installs the downstream Advanced Cluster Management operator
+	FlavorACM = "acm"
+	// FlavorCommunity installs the community stolostron operator
+	FlavorCommunity = "community"
+)
+
+// operator packages of the product flavors
+const (
+	acmPackage       = "advanced-cluster-management"
+	communityPackage = "stolostron"
+)
+
+// Flavor returns the product flavor of the hub of the managed cluster, from its annotation or else
+// the hub configuration.
+func Flavor(managedCluster *clusterv1.ManagedCluster, config *HubConfig) string {
+	switch flavor := managedCluster.Annotations[HOH_FLAVOR_ANNOTATION]; flavor {
+	case FlavorACM, FlavorCommunity:
+		return flavor
+	}
+	return config.Flavor
+}
+
+// Channel returns the channel of the operator subscription of the hub of the managed cluster, the
+// configured channel of its product flavor.
+func Channel(managedCluster *clusterv1.ManagedCluster, config *HubConfig) string {
+	return config.forFlavor(Flavor(managedCluster, config)).Channel
+}
+
+// forFlavor returns the hub configuration installing the given product flavor. The community flavor
+// subscribes to the community channel from the community catalog source, without starting CSV as
+// the configured one is of the downstream operator.
+func (c *HubConfig) forFlavor(flavor string) *HubConfig {
+	if flavor == c.Flavor && flavor != FlavorCommunity {
+		return c
+	}
+	config := *c
+	config.Flavor = flavor
+	if flavor == FlavorCommunity {
+		config.Channel = c.CommunityChannel
+		config.StartingCSV = ""
+		config.CatalogSource = c.CommunityCatalogSource
+	}
+	return &config
+}
+
+// subscriptionPackage returns the operator package of the product flavor of the configuration
+func subscriptionPackage(config *HubConfig) string {
+	if config.Flavor == FlavorCommunity {
+		return communityPackage
+	}
+	return acmPackage
+}
@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// withFlavor returns the managed cluster with the given flavor annotation
+func withFlavor(managedCluster *clusterv1.ManagedCluster, flavor string) *clusterv1.ManagedCluster {
+	managedCluster.Annotations = map[string]string{HOH_FLAVOR_ANNOTATION: flavor}
+	return managedCluster
+}
+
+func TestSubManifestWorkFlavor(t *testing.T) {
+	communityConfig, err := ParseHubConfig(newHubConfigMap(map[string]string{HUB_CONFIG_FLAVOR_KEY: FlavorCommunity}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name                string
+		managedCluster      *clusterv1.ManagedCluster
+		config              *HubConfig
+		expectedPackage     string
+		expectedChannel     string
+		expectedSource      string
+		expectedStartingCSV string
+	}{
+		{
+			name:                "default flavor",
+			managedCluster:      newManagedCluster("cluster1"),
+			config:              DefaultHubConfig(),
+			expectedPackage:     acmPackage,
+			expectedChannel:     defaultChannel,
+			expectedSource:      defaultCatalogSource,
+			expectedStartingCSV: defaultStartingCSV,
+		},
+		{
+			name:            "community annotation",
+			managedCluster:  withFlavor(newManagedCluster("cluster1"), FlavorCommunity),
+			config:          DefaultHubConfig(),
+			expectedPackage: communityPackage,
+			expectedChannel: defaultCommunityChannel,
+			expectedSource:  defaultCommunityCatalogSource,
+		},
+		{
+			name:            "community configuration",
+			managedCluster:  newManagedCluster("cluster1"),
+			config:          communityConfig,
+			expectedPackage: communityPackage,
+			expectedChannel: defaultCommunityChannel,
+			expectedSource:  defaultCommunityCatalogSource,
+		},
+		{
+			name:                "acm annotation in a community fleet",
+			managedCluster:      withFlavor(newManagedCluster("cluster1"), FlavorACM),
+			config:              communityConfig,
+			expectedPackage:     acmPackage,
+			expectedChannel:     defaultChannel,
+			expectedSource:      defaultCatalogSource,
+			expectedStartingCSV: defaultStartingCSV,
+		},
+		{
+			name:                "unknown annotation",
+			managedCluster:      withFlavor(newManagedCluster("cluster1"), "upstream"),
+			config:              DefaultHubConfig(),
+			expectedPackage:     acmPackage,
+			expectedChannel:     defaultChannel,
+			expectedSource:      defaultCatalogSource,
+			expectedStartingCSV: defaultStartingCSV,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work, err := (&clusterController{}).desiredSubManifestWork(c.managedCluster, c.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var subscription struct {
+				Kind string `json:"kind"`
+				Spec struct {
+					Name        string `json:"name"`
+					Channel     string `json:"channel"`
+					Source      string `json:"source"`
+					StartingCSV string `json:"startingCSV"`
+				} `json:"spec"`
+			}
+			for _, manifest := range work.Spec.Workload.Manifests {
+				if err := json.Unmarshal(manifest.Raw, &subscription); err != nil {
+					t.Fatal(err)
+				}
+				if subscription.Kind == "Subscription" {
+					break
+				}
+			}
+			spec := subscription.Spec
+			if spec.Name != c.expectedPackage || spec.Channel != c.expectedChannel || spec.Source != c.expectedSource ||
+				spec.StartingCSV != c.expectedStartingCSV {
+				t.Errorf("unexpected operator subscription %+v", spec)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// the resources of the managed hubs read for their health
+var (
+	CSVsResource = schema.GroupVersionResource{
+		Group:    "operators.coreos.com",
+		Version:  "v1alpha1",
+		Resource: "clusterserviceversions",
+	}
+	MultiClusterHubsResource = schema.GroupVersionResource{
+		Group:    "operator.open-cluster-management.io",
+		Version:  "v1",
+		Resource: "multiclusterhubs",
+	}
+	deploymentsResource = schema.GroupVersionResource{
+		Group:    "apps",
+		Version:  "v1",
+		Resource: "deployments",
+	}
+)
+
+// HubHealth is the health of a managed hub, read from its API
+type HubHealth struct {
+	// CSVs are the ClusterServiceVersions of the hub namespace
+	CSVs []CSVHealth
+	// MultiClusterHubs are the MultiClusterHubs of the hub namespace
+	MultiClusterHubs []MultiClusterHubHealth
+	// UnavailableDeployments are the deployments of the hub namespace without all their replicas
+	// available
+	UnavailableDeployments []DeploymentHealth
+}
+
+// CSVHealth is the phase of a ClusterServiceVersion
+type CSVHealth struct {
+	Name    string
+	Phase   string
+	Reason  string
+	Message string
+}
+
+// MultiClusterHubHealth is the phase of a MultiClusterHub and its unhealthy components
+type MultiClusterHubHealth struct {
+	Name                string
+	Phase               string
+	Version             string
+	UnhealthyComponents []ComponentHealth
+}
+
+// ComponentHealth is the status of a component of a MultiClusterHub
+type ComponentHealth struct {
+	Name    string
+	Type    string
+	Status  string
+	Reason  string
+	Message string
+}
+
+// DeploymentHealth is the number of available replicas of a deployment
+type DeploymentHealth struct {
+	Name      string
+	Available int64
+	Desired   int64
+}
+
+// ReadHubHealth reads the health of the hub installed in the namespace of the managed cluster with the
+// given client, such as a client of the cluster-proxy. The MultiClusterHubs are not reported until
+// their CRD is installed by the operator.
+func ReadHubHealth(ctx context.Context, client dynamic.Interface, namespace string) (*HubHealth, error) {
+	health := &HubHealth{}
+	csvs, err := client.Resource(CSVsResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the CSVs: %v", err)
+	}
+	for _, csv := range csvs.Items {
+		health.CSVs = append(health.CSVs, CSVHealth{
+			Name:    csv.GetName(),
+			Phase:   nestedString(csv.Object, "status", "phase"),
+			Reason:  nestedString(csv.Object, "status", "reason"),
+			Message: nestedString(csv.Object, "status", "message"),
+		})
+	}
+
+	mchs, err := client.Resource(MultiClusterHubsResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to list the MultiClusterHubs: %v", err)
+	}
+	if err == nil {
+		for _, mch := range mchs.Items {
+			health.MultiClusterHubs = append(health.MultiClusterHubs, multiClusterHubHealth(&mch))
+		}
+	}
+
+	deployments, err := client.Resource(deploymentsResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the deployments: %v", err)
+	}
+	for _, deployment := range deployments.Items {
+		desired, ok, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+		if !ok {
+			desired = 1
+		}
+		available, _, _ := unstructured.NestedInt64(deployment.Object, "status", "availableReplicas")
+		if available < desired {
+			health.UnavailableDeployments = append(health.UnavailableDeployments, DeploymentHealth{
+				Name:      deployment.GetName(),
+				Available: available,
+				Desired:   desired,
+			})
+		}
+	}
+	return health, nil
+}
+
+func multiClusterHubHealth(mch *unstructured.Unstructured) MultiClusterHubHealth {
+	health := MultiClusterHubHealth{
+		Name:    mch.GetName(),
+		Phase:   nestedString(mch.Object, "status", "phase"),
+		Version: nestedString(mch.Object, "status", "currentVersion"),
+	}
+	components, _, _ := unstructured.NestedMap(mch.Object, "status", "components")
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		component, ok := components[name].(map[string]interface{})
+		if !ok || component["status"] == string(metav1.ConditionTrue) {
+			continue
+		}
+		health.UnhealthyComponents = append(health.UnhealthyComponents, ComponentHealth{
+			Name:    name,
+			Type:    nestedString(component, "type"),
+			Status:  nestedString(component, "status"),
+			Reason:  nestedString(component, "reason"),
+			Message: nestedString(component, "message"),
+		})
+	}
+	return health
+}
+
+func nestedString(obj map[string]interface{}, fields ...string) string {
+	value, _, _ := unstructured.NestedString(obj, fields...)
+	return value
+}
+
+// Problems returns the problems of the hub, empty if it is healthy
+func (h *HubHealth) Problems() []string {
+	problems := []string{}
+	for _, csv := range h.CSVs {
+		if csv.Phase != "Succeeded" {
+			problems = append(problems, fmt.Sprintf("CSV %s is %s: %s", csv.Name, csv.Phase, csv.Message))
+		}
+	}
+	for _, mch := range h.MultiClusterHubs {
+		for _, component := range mch.UnhealthyComponents {
+			problems = append(problems, fmt.Sprintf("component %s of MultiClusterHub %s is %s: %s",
+				component.Name, mch.Name, component.Reason, component.Message))
+		}
+	}
+	for _, deployment := range h.UnavailableDeployments {
+		problems = append(problems, fmt.Sprintf("deployment %s has %d/%d available replicas",
+			deployment.Name, deployment.Available, deployment.Desired))
+	}
+	return problems
+}
+
+// HOH_SERVICE_ACCOUNT is the name of the ManagedServiceAccount provisioned for each managed hub, and
+// of the secret its token is projected into in the managed cluster namespace
+const HOH_SERVICE_ACCOUNT = "hoh-hub-cluster-controller"
+
+// SERVICE_ACCOUNT_NAMESPACE is the namespace of the serviceaccounts of the ManagedServiceAccounts on
+// the managed clusters
+const SERVICE_ACCOUNT_NAMESPACE = "open-cluster-management-agent-addon"
+
+// ManagedServiceAccountsResource is the resource of the ManagedServiceAccounts
+var ManagedServiceAccountsResource = schema.GroupVersionResource{
+	Group:    "authentication.open-cluster-management.io",
+	Version:  "v1alpha1",
+	Resource: "managedserviceaccounts",
+}
+
+// newManagedServiceAccount returns the ManagedServiceAccount of the managed hub, its token is rotated
+// every week
+func newManagedServiceAccount(namespace string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": ManagedServiceAccountsResource.GroupVersion().String(),
+		"kind":       "ManagedServiceAccount",
+		"metadata": map[string]interface{}{
+			"name":      HOH_SERVICE_ACCOUNT,
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				MANAGED_BY_LABEL:      MANAGED_BY_VALUE,
+				MANAGED_CLUSTER_LABEL: namespace,
+				BACKUP_LABEL:          BACKUP_VALUE,
+			},
+		},
+		"spec": map[string]interface{}{
+			"rotation": map[string]interface{}{
+				"enabled":  true,
+				"validity": "168h",
+			},
+		},
+	}}
+}
+
+// healthReaderManifests returns the ClusterRole and ClusterRoleBinding allowing the serviceaccount of
+// the ManagedServiceAccount to read the health of the hub
+func healthReaderManifests() []workv1.Manifest {
+	name := "open-cluster-management:hub-cluster-controller:health-reader"
+	return []workv1.Manifest{
+		{RawExtension: runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{
+	"apiVersion": "rbac.authorization.k8s.io/v1",
+	"kind": "ClusterRole",
+	"metadata": {
+		"name": %q
+	},
+	"rules": [
+		{
+			"apiGroups": [%s],
+			"resources": ["clusterserviceversions", "subscriptions"],
+			"verbs": ["get", "list"]
+		},
+		{
+			"apiGroups": [%q],
+			"resources": ["multiclusterhubs"],
+			"verbs": ["get", "list"]
+		},
+		{
+			"apiGroups": ["apps"],
+			"resources": ["deployments"],
+			"verbs": ["get", "list"]
+		}
+	]
+}`, name, fmt.Sprintf("%q", CSVsResource.Group), MultiClusterHubsResource.Group))}},
+		{RawExtension: runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{
+	"apiVersion": "rbac.authorization.k8s.io/v1",
+	"kind": "ClusterRoleBinding",
+	"metadata": {
+		"name": %q
+	},
+	"roleRef": {
+		"apiGroup": "rbac.authorization.k8s.io",
+		"kind": "ClusterRole",
+		"name": %q
+	},
+	"subjects": [
+		{
+			"kind": "ServiceAccount",
+			"name": %q,
+			"namespace": %q
+		}
+	]
+}`, name, name, HOH_SERVICE_ACCOUNT, SERVICE_ACCOUNT_NAMESPACE))}},
+	}
+}
+
+// ClusterProxyConfig returns the configuration of the clients querying the managed cluster through
+// the cluster-proxy user server with the given bearer token.
+func ClusterProxyConfig(proxyURL, caFile, managedClusterName, token string) *rest.Config {
+	return &rest.Config{
+		Host:            strings.TrimSuffix(proxyURL, "/") + "/" + managedClusterName,
+		BearerToken:     token,
+		TLSClientConfig: rest.TLSClientConfig{CAFile: caFile},
+	}
+}
+
+// summarizeProblems joins the problems into a condition message
+func summarizeProblems(problems []string) string {
+	return strings.Join(problems, "; ")
+}
@@ -0,0 +1,175 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+
+	clusterclientv1 "open-cluster-management.io/api/client/cluster/clientset/versioned/typed/cluster/v1"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	workclientv1 "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// HubConditionHealthy reports the health of the managed hub read with its ManagedServiceAccount,
+// that is the phase of its CSVs, the components of its MultiClusterHub and its deployments
+const HubConditionHealthy = "HubHealthy"
+
+// HUB_NAMESPACE is the namespace the hub is installed in on the managed clusters
+const HUB_NAMESPACE = "open-cluster-management"
+
+// tokenRecheckInterval is the interval the token of the ManagedServiceAccount is read again until
+// it is projected into the managed cluster namespace
+const tokenRecheckInterval = 30 * time.Second
+
+// healthController provisions a ManagedServiceAccount per managed hub when enabled by the hub
+// configuration, and reads the health of the hub with its token through the cluster-proxy. The
+// health is richer than the status feedback of the manifestworks, it is reported by the HubHealthy
+// condition of the managed cluster.
+type healthController struct {
+	*clusterController
+	// secretClient gets the tokens of the ManagedServiceAccounts in the managed cluster namespaces
+	secretClient corev1client.SecretsGetter
+	// msaClient creates the ManagedServiceAccounts, it is nil when they are not served
+	msaClient dynamic.NamespaceableResourceInterface
+	// newSpokeClient returns the client querying a managed cluster with the given configuration
+	newSpokeClient func(config *rest.Config) (dynamic.Interface, error)
+}
+
+// NewHealthController creates a new hub health controller
+func NewHealthController(
+	clusterclient clusterclientv1.ClusterV1Interface,
+	workclient workclientv1.WorkV1Interface,
+	secretClient corev1client.SecretsGetter,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	workInformer workinformerv1.ManifestWorkInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	secretInformer corev1informers.SecretInformer,
+	overrideInformer informers.GenericInformer,
+	restoreInformer informers.GenericInformer,
+	msaClient dynamic.NamespaceableResourceInterface,
+	options ControllerOptions,
+	recorder events.Recorder,
+	clusterRecorder record.EventRecorder) factory.Controller {
+	// an unreachable hub is reported by its condition and read again on the next check
+	options.MaxRetries = 0
+	c := &healthController{
+		clusterController: newClusterController("HubHealthController", clusterclient, workclient,
+			clusterInformer, workInformer, configMapInformer, secretInformer, overrideInformer, restoreInformer, options, "",
+			recorder, clusterRecorder),
+		secretClient: secretClient,
+		msaClient:    msaClient,
+		newSpokeClient: func(config *rest.Config) (dynamic.Interface, error) {
+			return dynamic.NewForConfig(config)
+		},
+	}
+	c.reconcile = c.reconcileHealth
+	return c.newFactory(clusterInformer, workInformer, configMapInformer, HOH_HUB_CLUSTER_MCH).
+		ToController(c.name, recorder)
+}
+
+func (c *healthController) reconcileHealth(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster) error {
+	if !c.hubConfig.get().ManagedServiceAccount || c.msaClient == nil || c.options.ClusterProxyURL == "" {
+		return nil
+	}
+	mch, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_MCH)
+	if err != nil || mch == nil {
+		// the health is read once the hub is being installed
+		return err
+	}
+
+	msas := c.msaClient.Namespace(managedCluster.Name)
+	_, err = msas.Get(ctx, HOH_SERVICE_ACCOUNT, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		if c.paused(ctx, managedCluster) {
+			return nil
+		}
+		loggerFrom(ctx).Info("Creating the managed serviceaccount reading the health of the hub")
+		_, err = msas.Create(ctx, newManagedServiceAccount(managedCluster.Name), metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		syncCtx.Queue().AddAfter(managedCluster.Name, tokenRecheckInterval)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	secret, err := c.secretClient.Secrets(managedCluster.Name).Get(ctx, HOH_SERVICE_ACCOUNT, metav1.GetOptions{})
+	if errors.IsNotFound(err) || (err == nil && len(secret.Data["token"]) == 0) {
+		c.spokeClients.Delete(managedCluster.Name)
+		loggerFrom(ctx).V(2).Info("Waiting for the token of the managed serviceaccount")
+		syncCtx.Queue().AddAfter(managedCluster.Name, tokenRecheckInterval)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.options.HealthCheckInterval > 0 {
+		syncCtx.Queue().AddAfter(managedCluster.Name, c.options.HealthCheckInterval)
+	}
+	client, err := c.spokeClient(managedCluster.Name, string(secret.Data["token"]))
+	if err != nil {
+		return err
+	}
+	health, err := ReadHubHealth(ctx, client, HUB_NAMESPACE)
+	if err != nil {
+		loggerFrom(ctx).Error(err, "Failed to read the health of the hub")
+		return c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    HubConditionHealthy,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "HubUnreachable",
+			Message: fmt.Sprintf("The health of the hub could not be read through the cluster-proxy: %v", err),
+		})
+	}
+	if problems := health.Problems(); len(problems) > 0 {
+		return c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    HubConditionHealthy,
+			Status:  metav1.ConditionFalse,
+			Reason:  "HubUnhealthy",
+			Message: summarizeProblems(problems),
+		})
+	}
+	return c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+		Type:    HubConditionHealthy,
+		Status:  metav1.ConditionTrue,
+		Reason:  "HubHealthy",
+		Message: "The CSVs, the MultiClusterHub components and the deployments of the hub are healthy",
+	})
+}
+
+// cachedSpokeClient is the cluster-proxy client of a managed hub, built with the given token
+type cachedSpokeClient struct {
+	token  string
+	client dynamic.Interface
+}
+
+// spokeClient returns the cluster-proxy client of the managed hub. The client is cached between the
+// health checks and only built again when the token of the managed serviceaccount is rotated.
+func (c *healthController) spokeClient(managedClusterName, token string) (dynamic.Interface, error) {
+	if cached, ok := c.spokeClients.Load(managedClusterName); ok && cached.(cachedSpokeClient).token == token {
+		return cached.(cachedSpokeClient).client, nil
+	}
+	client, err := c.newSpokeClient(ClusterProxyConfig(c.options.ClusterProxyURL, c.options.ClusterProxyCAFile,
+		managedClusterName, token))
+	if err != nil {
+		return nil, err
+	}
+	c.spokeClients.Store(managedClusterName, cachedSpokeClient{token: token, client: client})
+	return client, nil
+}
@@ -0,0 +1,166 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func TestHealthControllerSync(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{mch})
+	ctrl.parkedCondition = ""
+	ctrl.options.ClusterProxyURL = "https://cluster-proxy.example.com"
+	ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t, newHubConfigMap(map[string]string{
+		HUB_CONFIG_MANAGED_SERVICE_ACCOUNT_KEY: "true",
+	})))
+	msaClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	kubeClient := kubefake.NewSimpleClientset()
+	var proxyConfig *rest.Config
+	health := &healthController{
+		clusterController: ctrl.clusterController,
+		secretClient:      kubeClient.CoreV1(),
+		msaClient:         msaClient.Resource(ManagedServiceAccountsResource),
+		newSpokeClient: func(config *rest.Config) (dynamic.Interface, error) {
+			proxyConfig = config
+			return newFakeHubClient(newHubObjects()...), nil
+		},
+	}
+	ctrl.reconcile = health.reconcileHealth
+
+	// the managed serviceaccount is created first
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := msaClient.Resource(ManagedServiceAccountsResource).Namespace("cluster1").Get(context.TODO(),
+		HOH_SERVICE_ACCOUNT, metav1.GetOptions{}); err != nil {
+		t.Fatalf("expected the managed serviceaccount to be created: %v", err)
+	}
+	if proxyConfig != nil {
+		t.Errorf("expected the hub not to be read without the token")
+	}
+
+	// the hub is read with the token once it is projected
+	if _, err := kubeClient.CoreV1().Secrets("cluster1").Create(context.TODO(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: HOH_SERVICE_ACCOUNT, Namespace: "cluster1"},
+		Data:       map[string][]byte{"token": []byte("token")},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if proxyConfig == nil || proxyConfig.Host != "https://cluster-proxy.example.com/cluster1" || proxyConfig.BearerToken != "token" {
+		t.Fatalf("unexpected proxy configuration %v", proxyConfig)
+	}
+	updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthy := meta.FindStatusCondition(updated.Status.Conditions, HubConditionHealthy)
+	if healthy == nil || healthy.Status != metav1.ConditionFalse || healthy.Reason != "HubUnhealthy" {
+		t.Errorf("expected the hub to be unhealthy, got %v", updated.Status.Conditions)
+	}
+}
+
+func TestHealthControllerCachesSpokeClient(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{mch})
+	ctrl.parkedCondition = ""
+	ctrl.options.ClusterProxyURL = "https://cluster-proxy.example.com"
+	ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t, newHubConfigMap(map[string]string{
+		HUB_CONFIG_MANAGED_SERVICE_ACCOUNT_KEY: "true",
+	})))
+	msaClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	kubeClient := kubefake.NewSimpleClientset()
+	tokens := []string{}
+	health := &healthController{
+		clusterController: ctrl.clusterController,
+		secretClient:      kubeClient.CoreV1(),
+		msaClient:         msaClient.Resource(ManagedServiceAccountsResource),
+		newSpokeClient: func(config *rest.Config) (dynamic.Interface, error) {
+			tokens = append(tokens, config.BearerToken)
+			return newFakeHubClient(newHubObjects()...), nil
+		},
+	}
+	ctrl.reconcile = health.reconcileHealth
+
+	// the managed serviceaccount is created first
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: HOH_SERVICE_ACCOUNT, Namespace: "cluster1"},
+		Data:       map[string][]byte{"token": []byte("token")},
+	}
+	if _, err := kubeClient.CoreV1().Secrets("cluster1").Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the client is reused by the following checks
+	for i := 0; i < 2; i++ {
+		if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(tokens) != 1 || tokens[0] != "token" {
+		t.Errorf("expected the client to be built once, got the tokens %v", tokens)
+	}
+
+	// the client is built again once the token is rotated
+	secret.Data["token"] = []byte("rotated")
+	if _, err := kubeClient.CoreV1().Secrets("cluster1").Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tokens) != 2 || tokens[1] != "rotated" {
+		t.Errorf("expected the client to be built again with the rotated token, got the tokens %v", tokens)
+	}
+}
+
+func TestHealthControllerDisabled(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{mch})
+	ctrl.options.ClusterProxyURL = "https://cluster-proxy.example.com"
+	msaClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	health := &healthController{
+		clusterController: ctrl.clusterController,
+		secretClient:      kubefake.NewSimpleClientset().CoreV1(),
+		msaClient:         msaClient.Resource(ManagedServiceAccountsResource),
+	}
+	ctrl.reconcile = health.reconcileHealth
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(msaClient.Actions()) != 0 {
+		t.Errorf("expected no managed serviceaccount actions, got %v", msaClient.Actions())
+	}
+}
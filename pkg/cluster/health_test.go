@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// newHubObjects returns a failed CSV, a MultiClusterHub with an unhealthy component and a
+// deployment without available replicas
+func newHubObjects() []runtime.Object {
+	return []runtime.Object{
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "operators.coreos.com/v1alpha1",
+			"kind":       "ClusterServiceVersion",
+			"metadata":   map[string]interface{}{"name": "advanced-cluster-management.v2.5.1", "namespace": HUB_NAMESPACE},
+			"status":     map[string]interface{}{"phase": "Failed", "message": "install strategy failed"},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "operator.open-cluster-management.io/v1",
+			"kind":       "MultiClusterHub",
+			"metadata":   map[string]interface{}{"name": "multiclusterhub", "namespace": HUB_NAMESPACE},
+			"status": map[string]interface{}{
+				"phase":          "Running",
+				"currentVersion": "2.5.1",
+				"components": map[string]interface{}{
+					"console-chart": map[string]interface{}{"type": "Deployed", "status": "True"},
+					"grc-chart": map[string]interface{}{"type": "Progressing", "status": "False", "reason": "InstallError",
+						"message": "deployment grc-policy-propagator is not ready"},
+				},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "grc-policy-propagator", "namespace": HUB_NAMESPACE},
+			"spec":       map[string]interface{}{"replicas": int64(2)},
+			"status":     map[string]interface{}{"availableReplicas": int64(1)},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "console", "namespace": HUB_NAMESPACE},
+			"spec":       map[string]interface{}{"replicas": int64(1)},
+			"status":     map[string]interface{}{"availableReplicas": int64(1)},
+		}},
+	}
+}
+
+func newFakeHubClient(objs ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		CSVsResource:             "ClusterServiceVersionList",
+		MultiClusterHubsResource: "MultiClusterHubList",
+		deploymentsResource:      "DeploymentList",
+	}, objs...)
+}
+
+func TestReadHubHealth(t *testing.T) {
+	health, err := ReadHubHealth(context.TODO(), newFakeHubClient(newHubObjects()...), HUB_NAMESPACE)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(health.MultiClusterHubs) != 1 || health.MultiClusterHubs[0].Version != "2.5.1" ||
+		len(health.MultiClusterHubs[0].UnhealthyComponents) != 1 {
+		t.Errorf("unexpected MultiClusterHubs %v", health.MultiClusterHubs)
+	}
+	problems := health.Problems()
+	if len(problems) != 3 {
+		t.Fatalf("expected 3 problems, got %v", problems)
+	}
+	for i, expected := range []string{
+		"CSV advanced-cluster-management.v2.5.1 is Failed",
+		"component grc-chart of MultiClusterHub multiclusterhub is InstallError",
+		"deployment grc-policy-propagator has 1/2 available replicas",
+	} {
+		if !strings.HasPrefix(problems[i], expected) {
+			t.Errorf("expected problem %q, got %q", expected, problems[i])
+		}
+	}
+
+	health, err = ReadHubHealth(context.TODO(), newFakeHubClient(), HUB_NAMESPACE)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(health.Problems()) != 0 {
+		t.Errorf("expected an empty hub to be healthy, got %v", health.Problems())
+	}
+}
+
+func TestSubManifestWorkGrantsHealthReader(t *testing.T) {
+	config := DefaultHubConfig()
+	work, err := (&clusterController{}).desiredSubManifestWork(newManagedCluster("cluster1"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := len(work.Spec.Workload.Manifests)
+
+	config.ManagedServiceAccount = true
+	work, err = (&clusterController{}).desiredSubManifestWork(newManagedCluster("cluster1"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(work.Spec.Workload.Manifests) != count+2 {
+		t.Fatalf("expected the health reader role and binding, got %d manifests", len(work.Spec.Workload.Manifests))
+	}
+	binding := string(work.Spec.Workload.Manifests[count+1].Raw)
+	if !strings.Contains(binding, HOH_SERVICE_ACCOUNT) || !strings.Contains(binding, SERVICE_ACCOUNT_NAMESPACE) {
+		t.Errorf("expected the binding to the managed serviceaccount, got %s", binding)
+	}
+}
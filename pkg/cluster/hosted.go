@@ -0,0 +1,50 @@
+package cluster
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// annotations of the managed clusters imported with a hosted klusterlet, whose agents run on a
+// hosting cluster instead of the managed cluster
+const (
+	// KLUSTERLET_DEPLOY_MODE_ANNOTATION is the deploy mode of the klusterlet of the managed cluster
+	KLUSTERLET_DEPLOY_MODE_ANNOTATION = "import.open-cluster-management.io/klusterlet-deploy-mode"
+	// KLUSTERLET_DEPLOY_MODE_HOSTED is the deploy mode of the hosted klusterlets
+	KLUSTERLET_DEPLOY_MODE_HOSTED = "Hosted"
+	// HOSTING_CLUSTER_NAME_ANNOTATION is the managed cluster hosting the klusterlet of a hosted
+	// managed cluster
+	HOSTING_CLUSTER_NAME_ANNOTATION = "import.open-cluster-management.io/hosting-cluster-name"
+)
+
+// HOSTING_CLUSTER_ANNOTATION is set on the manifestworks of a hosted managed cluster to the name of
+// its hosting cluster
+const HOSTING_CLUSTER_ANNOTATION = "hub-of-hubs.open-cluster-management.io/hosting-cluster"
+
+// IsHosted returns true if the klusterlet of the managed cluster is deployed in hosted mode.
+func IsHosted(managedCluster *clusterv1.ManagedCluster) bool {
+	return managedCluster.Annotations[KLUSTERLET_DEPLOY_MODE_ANNOTATION] == KLUSTERLET_DEPLOY_MODE_HOSTED
+}
+
+// placeManifestWork annotates the rendered hub manifestwork of a hosted managed cluster with its
+// hosting cluster. The manifestwork stays in the namespace of the hosted managed cluster: the work
+// agent of a hosted klusterlet runs on the hosting cluster but watches the namespace of the hosted
+// managed cluster and applies its manifestworks with the kubeconfig of the hosted managed cluster,
+// while a manifestwork in the namespace of the hosting cluster would be applied to the hosting
+// cluster itself.
+func placeManifestWork(managedCluster *clusterv1.ManagedCluster, work *workv1.ManifestWork) *workv1.ManifestWork {
+	if hosting := managedCluster.Annotations[HOSTING_CLUSTER_NAME_ANNOTATION]; IsHosted(managedCluster) && hosting != "" {
+		metav1.SetMetaDataAnnotation(&work.ObjectMeta, HOSTING_CLUSTER_ANNOTATION, hosting)
+	}
+	return work
+}
+
+// WorkManagedCluster returns the managed cluster of a manifestwork created by the controller, from its
+// managed cluster label or else its namespace.
+func WorkManagedCluster(work metav1.Object) string {
+	if name, ok := work.GetLabels()[MANAGED_CLUSTER_LABEL]; ok {
+		return name
+	}
+	return work.GetNamespace()
+}
@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func withHostingCluster(managedCluster *clusterv1.ManagedCluster, hosting string) *clusterv1.ManagedCluster {
+	managedCluster.Annotations = map[string]string{
+		KLUSTERLET_DEPLOY_MODE_ANNOTATION: KLUSTERLET_DEPLOY_MODE_HOSTED,
+		HOSTING_CLUSTER_NAME_ANNOTATION:   hosting,
+	}
+	return managedCluster
+}
+
+func TestDesiredSubManifestWorkHosted(t *testing.T) {
+	cases := []struct {
+		name            string
+		cluster         *clusterv1.ManagedCluster
+		expectedHosting string
+	}{
+		{
+			name:    "default mode",
+			cluster: newManagedCluster("cluster1"),
+		},
+		{
+			name:            "hosted mode",
+			cluster:         withHostingCluster(newManagedCluster("cluster1"), "hosting"),
+			expectedHosting: "hosting",
+		},
+		{
+			name:    "hosted mode without hosting cluster",
+			cluster: withHostingCluster(newManagedCluster("cluster1"), ""),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work, err := (&clusterController{}).desiredSubManifestWork(c.cluster, DefaultHubConfig())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// the work agent of a hosted klusterlet applies the manifestworks of the namespace of the
+			// hosted managed cluster
+			if work.Namespace != "cluster1" || work.Name != "cluster1-"+HOH_HUB_CLUSTER_SUBSCRIPTION {
+				t.Errorf("expected the manifestwork in the managed cluster namespace, got %s/%s", work.Namespace, work.Name)
+			}
+			if hosting := work.Annotations[HOSTING_CLUSTER_ANNOTATION]; hosting != c.expectedHosting {
+				t.Errorf("expected the hosting cluster %q, got %q", c.expectedHosting, hosting)
+			}
+		})
+	}
+}
+
+func TestSubscriptionControllerSyncHosted(t *testing.T) {
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{
+		withHostingCluster(newManagedCluster("cluster1"), "hosting"),
+	})
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_SUBSCRIPTION, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the subscription manifestwork in the managed cluster namespace: %v", err)
+	}
+	works, _ := ctrl.workClient.WorkV1().ManifestWorks("hosting").List(context.TODO(), metav1.ListOptions{})
+	if len(works.Items) != 0 {
+		t.Errorf("expected no manifestwork in the hosting cluster namespace, got %d", len(works.Items))
+	}
+}
@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"fmt"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// CREATED_VIA_ANNOTATION is set by the import controller to the way the managed cluster was created,
+// CREATED_VIA_HYPERSHIFT for the HyperShift hosted clusters
+const (
+	CREATED_VIA_ANNOTATION = "open-cluster-management/created-via"
+	CREATED_VIA_HYPERSHIFT = "hypershift"
+)
+
+// IsHyperShift returns true if the managed cluster is a HyperShift hosted cluster. Its control plane
+// runs on a management cluster and it has no OperatorHub catalog of its own by default.
+func IsHyperShift(managedCluster *clusterv1.ManagedCluster) bool {
+	return managedCluster.Annotations[CREATED_VIA_ANNOTATION] == CREATED_VIA_HYPERSHIFT
+}
+
+// UnsupportedHyperShiftError is returned for the HyperShift hosted clusters when no catalog source of
+// the hub is configured for them, the subscription manifestwork is not created until one is configured.
+type UnsupportedHyperShiftError struct{}
+
+func (e *UnsupportedHyperShiftError) Error() string {
+	return fmt.Sprintf("the HyperShift hosted cluster has no catalog of the hub, configure it in %s or the %s annotation",
+		HUB_CONFIG_HYPERSHIFT_CATALOG_SOURCE_KEY, HOH_CATALOG_SOURCE_ANNOTATION)
+}
+
+// hyperShiftCatalogSource returns the catalog source of the operator subscription of a HyperShift
+// hosted cluster, the standalone catalogs are not assumed to exist on the hosted clusters.
+func hyperShiftCatalogSource(config *HubConfig) (string, error) {
+	if config.HyperShiftCatalogSource == "" {
+		return "", &UnsupportedHyperShiftError{}
+	}
+	return config.HyperShiftCatalogSource, nil
+}
+
+// catalogSourceNamespace returns the namespace of the catalog source of the operator subscription of
+// the managed cluster, the configured one for the HyperShift hosted clusters. The catalog source
+// installed with the subscription is always in the marketplace namespace.
+func catalogSourceNamespace(managedCluster *clusterv1.ManagedCluster, config *HubConfig, source string) string {
+	if IsHyperShift(managedCluster) && source != HOH_CATALOG_SOURCE {
+		return config.HyperShiftCatalogSourceNamespace
+	}
+	return MARKETPLACE_NAMESPACE
+}
@@ -0,0 +1,103 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+// asHyperShift returns the managed cluster created as a HyperShift hosted cluster
+func asHyperShift(managedCluster *clusterv1.ManagedCluster) *clusterv1.ManagedCluster {
+	metav1.SetMetaDataAnnotation(&managedCluster.ObjectMeta, CREATED_VIA_ANNOTATION, CREATED_VIA_HYPERSHIFT)
+	return managedCluster
+}
+
+func TestHyperShiftInstall(t *testing.T) {
+	managedCluster := asHyperShift(newManagedCluster("cluster1"))
+	_, err := CatalogSource(managedCluster, DefaultHubConfig())
+	var unsupported *UnsupportedHyperShiftError
+	if !errors.As(err, &unsupported) {
+		t.Errorf("expected the hosted cluster without catalog to be unsupported, got %v", err)
+	}
+
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_HYPERSHIFT_CATALOG_SOURCE_KEY: "hub-catalog",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source, err := CatalogSource(managedCluster, config); err != nil || source != "hub-catalog" {
+		t.Errorf("expected the hypershift catalog source, got %q, %v", source, err)
+	}
+	if namespace := catalogSourceNamespace(managedCluster, config, "hub-catalog"); namespace != MARKETPLACE_NAMESPACE {
+		t.Errorf("expected the catalog source in %s by default, got %q", MARKETPLACE_NAMESPACE, namespace)
+	}
+	if availability := AvailabilityConfig(managedCluster, config); availability != AVAILABILITY_BASIC {
+		t.Errorf("expected a %s hub on the hosted cluster, got %q", AVAILABILITY_BASIC, availability)
+	}
+	managedCluster.Labels = map[string]string{HOH_SIZE_LABEL: HOH_SIZE_LARGE}
+	if availability := AvailabilityConfig(managedCluster, config); availability != AVAILABILITY_HIGH {
+		t.Errorf("expected the size label to be preferred, got %q", availability)
+	}
+}
+
+func TestReconcileSkipsUnsupportedHyperShift(t *testing.T) {
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{asHyperShift(newManagedCluster("cluster1"))})
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	works, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(works.Items) != 0 {
+		t.Errorf("expected the subscription manifestwork not to be created, got %d manifestworks", len(works.Items))
+	}
+	updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, HubConditionHyperShiftUnsupported) {
+		t.Errorf("expected the unsupported hosted cluster to be reported, got %v", updated.Status.Conditions)
+	}
+	if events := recordedEvents(ctrl.clusterRecorder.(*record.FakeRecorder)); len(events) != 1 ||
+		!strings.Contains(events[0], EventReasonUnsupportedHyperShift) {
+		t.Errorf("expected an %s event, got %v", EventReasonUnsupportedHyperShift, events)
+	}
+}
+
+func TestHyperShiftCatalogSourceNamespace(t *testing.T) {
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_HYPERSHIFT_CATALOG_SOURCE_KEY:           "hub-catalog",
+		HUB_CONFIG_HYPERSHIFT_CATALOG_SOURCE_NAMESPACE_KEY: "hosted-catalogs",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosted := asHyperShift(newManagedCluster("cluster1"))
+	subscription, err := subscriptionManifest(hosted, config, "hub-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(subscription), `"sourceNamespace": "hosted-catalogs"`) {
+		t.Errorf("expected the subscription of the hosted cluster from the configured namespace, got %s", subscription)
+	}
+
+	// the standalone clusters and the catalog source installed with the subscription stay in the
+	// marketplace namespace
+	if namespace := catalogSourceNamespace(newManagedCluster("cluster2"), config, "redhat-operators"); namespace != MARKETPLACE_NAMESPACE {
+		t.Errorf("expected the standalone cluster catalog source in %s, got %q", MARKETPLACE_NAMESPACE, namespace)
+	}
+	if namespace := catalogSourceNamespace(hosted, config, HOH_CATALOG_SOURCE); namespace != MARKETPLACE_NAMESPACE {
+		t.Errorf("expected the installed catalog source in %s, got %q", MARKETPLACE_NAMESPACE, namespace)
+	}
+}
@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// HOH_IMAGE_PULL_SECRET_ANNOTATION is the name of the image pull secret of the hub of the managed
+// cluster, it is preferred over the imagePullSecret configuration.
+const HOH_IMAGE_PULL_SECRET_ANNOTATION = "hoh-image-pull-secret"
+
+// ImagePullSecret returns the name of the image pull secret of the hub of the managed cluster, or an
+// empty string if the hub has no image pull secret.
+func ImagePullSecret(managedCluster *clusterv1.ManagedCluster, config *HubConfig) string {
+	if name := managedCluster.Annotations[HOH_IMAGE_PULL_SECRET_ANNOTATION]; name != "" {
+		return name
+	}
+	return config.ImagePullSecret
+}
+
+// imagePullSecretMCH returns the MultiClusterHub setting the image pull secret of the managed
+// cluster, to be merged onto the default one, or an empty string if the hub has no image pull secret.
+func imagePullSecretMCH(managedCluster *clusterv1.ManagedCluster, config *HubConfig) string {
+	name := ImagePullSecret(managedCluster, config)
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(`{"spec":{"imagePullSecret":%q}}`, name)
+}
+
+// imagePullSecretManifests returns the manifest copying the image pull secret referenced by the
+// rendered MultiClusterHub from the controller namespace to the managed hub, if the propagation of
+// the image pull secret is configured. A missing secret is returned as an error, so the mch is not
+// installed with an image pull secret which does not exist.
+func (c *clusterController) imagePullSecretManifests(mchJson []byte, config *HubConfig) ([]workv1.Manifest, error) {
+	if !config.PropagateImagePullSecret {
+		return nil, nil
+	}
+	mch := struct {
+		Spec struct {
+			ImagePullSecret string `json:"imagePullSecret"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(mchJson, &mch); err != nil {
+		return nil, err
+	}
+	name := mch.Spec.ImagePullSecret
+	if name == "" {
+		return nil, nil
+	}
+	secret, _, err := c.propagatedSecret(name, name, "open-cluster-management")
+	if err != nil {
+		return nil, err
+	}
+	return []workv1.Manifest{secret}, nil
+}
+
+// propagatesImagePullSecret returns true if the secret of the given name in the controller namespace
+// is propagated as the image pull secret of any managed hub, configured or set by the annotation of
+// its managed cluster.
+func (c *clusterController) propagatesImagePullSecret(name string) bool {
+	config := c.hubConfig.get()
+	if !config.PropagateImagePullSecret || name == "" {
+		return false
+	}
+	if name == config.ImagePullSecret {
+		return true
+	}
+	managedClusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		return false
+	}
+	for _, managedCluster := range managedClusters {
+		if managedCluster.Annotations[HOH_IMAGE_PULL_SECRET_ANNOTATION] == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestImagePullSecret(t *testing.T) {
+	config := DefaultHubConfig()
+	config.ImagePullSecret = "global-pull-secret"
+	managedCluster := newManagedCluster("cluster1")
+	if name := ImagePullSecret(managedCluster, config); name != "global-pull-secret" {
+		t.Errorf("expected the configured image pull secret, got %q", name)
+	}
+	managedCluster.Annotations = map[string]string{HOH_IMAGE_PULL_SECRET_ANNOTATION: "cluster1-pull-secret"}
+	if name := ImagePullSecret(managedCluster, config); name != "cluster1-pull-secret" {
+		t.Errorf("expected the image pull secret of the annotation, got %q", name)
+	}
+	if name := ImagePullSecret(newManagedCluster("cluster2"), DefaultHubConfig()); name != "" {
+		t.Errorf("expected no image pull secret, got %q", name)
+	}
+}
+
+func TestDesiredMCHManifestWorkPropagatesImagePullSecret(t *testing.T) {
+	config := DefaultHubConfig()
+	config.ImagePullSecret = "pull-secret"
+	config.PropagateImagePullSecret = true
+	managedCluster := newManagedCluster("cluster1")
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	c := &clusterController{
+		options:      ControllerOptions{ConfigNamespace: "test"},
+		secretLister: corev1listers.NewSecretLister(indexer).Secrets("test"),
+	}
+	if _, _, err := c.desiredMCHManifestWork(managedCluster, config); err == nil {
+		t.Errorf("expected an error for the missing image pull secret")
+	}
+
+	if err := indexer.Add(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: "test"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte("{}")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mch, _, err := c.desiredMCHManifestWork(managedCluster, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manifests := mch.Spec.Workload.Manifests
+	if len(manifests) != 2 {
+		t.Fatalf("expected the secret and the mch manifests, got %d manifests", len(manifests))
+	}
+	if secret := string(manifests[0].Raw); !strings.Contains(secret, `"namespace":"open-cluster-management"`) ||
+		!strings.Contains(secret, string(corev1.SecretTypeDockerConfigJson)) {
+		t.Errorf("expected the pull secret in the mch namespace, got %s", secret)
+	}
+	if !strings.Contains(string(manifests[1].Raw), `"imagePullSecret":"pull-secret"`) {
+		t.Errorf("expected the mch to reference the pull secret, got %s", manifests[1].Raw)
+	}
+}
+
+func TestSpecDiffRedactsImagePullSecret(t *testing.T) {
+	config := DefaultHubConfig()
+	config.ImagePullSecret = "pull-secret"
+	config.PropagateImagePullSecret = true
+	diff := rotatedSpecDiff(t, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: "test"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+	}, corev1.DockerConfigJsonKey, `{"auths":{"quay.io":{"auth":"old-token"}}}`, `{"auths":{"quay.io":{"auth":"new-token"}}}`,
+		func(c *clusterController) (*workv1.ManifestWork, error) {
+			mch, _, err := c.desiredMCHManifestWork(newManagedCluster("cluster1"), config)
+			return mch, err
+		})
+	expectRedacted(t, diff, "old-token", "new-token", `{"auths":{"quay.io":{"auth":"old-token"}}}`,
+		`{"auths":{"quay.io":{"auth":"new-token"}}}`)
+}
+
+func TestPropagatesImagePullSecret(t *testing.T) {
+	annotated := newManagedCluster("cluster1")
+	annotated.Annotations = map[string]string{HOH_IMAGE_PULL_SECRET_ANNOTATION: "cluster1-pull-secret"}
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{annotated, newManagedCluster("cluster2")}, nil)
+	ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t, newHubConfigMap(map[string]string{
+		HUB_CONFIG_IMAGE_PULL_SECRET_KEY: "pull-secret",
+	})))
+	if ctrl.propagatesImagePullSecret("pull-secret") {
+		t.Errorf("expected the image pull secret not to be watched unless it is propagated")
+	}
+
+	ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t, newHubConfigMap(map[string]string{
+		HUB_CONFIG_IMAGE_PULL_SECRET_KEY:           "pull-secret",
+		HUB_CONFIG_PROPAGATE_IMAGE_PULL_SECRET_KEY: "true",
+	})))
+	for name, expected := range map[string]bool{
+		"pull-secret":          true,
+		"cluster1-pull-secret": true,
+		"transport-secret":     false,
+	} {
+		if actual := ctrl.propagatesImagePullSecret(name); actual != expected {
+			t.Errorf("expected the secret %s to be propagated %v, got %v", name, expected, actual)
+		}
+	}
+}
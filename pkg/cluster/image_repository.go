@@ -0,0 +1,70 @@
+package cluster
+
+import (
+	"fmt"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// HOH_CATALOG_SOURCE_ANNOTATION is the catalog source of the operator subscription of the managed
+// cluster, such as a catalog of pre-release or mirrored builds. It is preferred over the
+// catalogSource configuration.
+const HOH_CATALOG_SOURCE_ANNOTATION = "hoh-catalog-source"
+
+// HOH_IMAGE_REPOSITORY_ANNOTATION is the image repository of the hub components of the managed
+// cluster, such as a mirror. It is preferred over the imageRepository configuration.
+const HOH_IMAGE_REPOSITORY_ANNOTATION = "hoh-image-repository"
+
+// MCH_IMAGE_REPOSITORY_ANNOTATION is the annotation of the MultiClusterHub overriding the image
+// repository of the hub components
+const MCH_IMAGE_REPOSITORY_ANNOTATION = "mch-imageRepository"
+
+// CatalogSource returns the catalog source of the operator subscription of the managed cluster, from
+// its annotation, the catalog source installed with the subscription when its default catalogs are
+// disabled, or else the builds of its architecture. An UnsupportedArchitectureError is returned
+// if the configured channel has no build for its architecture, and an UnsupportedHyperShiftError for
+// the HyperShift hosted clusters without configured catalog source. The community flavor is served
+// for all architectures by the community catalog source.
+func CatalogSource(managedCluster *clusterv1.ManagedCluster, config *HubConfig) (string, error) {
+	if source := managedCluster.Annotations[HOH_CATALOG_SOURCE_ANNOTATION]; source != "" {
+		return source, nil
+	}
+	if config.Flavor == FlavorCommunity {
+		return config.CatalogSource, nil
+	}
+	if config.CatalogSourceImage != "" && MarketplaceDisabled(managedCluster) {
+		// the default catalogs are disabled, the index image is served by the catalog source installed
+		// with the subscription
+		return HOH_CATALOG_SOURCE, nil
+	}
+	if IsHyperShift(managedCluster) {
+		return hyperShiftCatalogSource(config)
+	}
+	source, err := architectureCatalogSource(managedCluster, config)
+	if err != nil || source != "" {
+		return source, err
+	}
+	if config.CatalogSource != "" {
+		return config.CatalogSource, nil
+	}
+	return defaultCatalogSource, nil
+}
+
+// ImageRepository returns the image repository of the hub components of the managed cluster, or an
+// empty string if the image repository is not overridden.
+func ImageRepository(managedCluster *clusterv1.ManagedCluster, config *HubConfig) string {
+	if repository := managedCluster.Annotations[HOH_IMAGE_REPOSITORY_ANNOTATION]; repository != "" {
+		return repository
+	}
+	return config.ImageRepository
+}
+
+// imageRepositoryMCH returns the MultiClusterHub overriding the image repository of the managed
+// cluster, to be merged onto the default one, or an empty string if it is not overridden.
+func imageRepositoryMCH(managedCluster *clusterv1.ManagedCluster, config *HubConfig) string {
+	repository := ImageRepository(managedCluster, config)
+	if repository == "" {
+		return ""
+	}
+	return fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}},"spec":{}}`, MCH_IMAGE_REPOSITORY_ANNOTATION, repository)
+}
@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDesiredSubManifestWorkCatalogSource(t *testing.T) {
+	config := DefaultHubConfig()
+	managedCluster := newManagedCluster("cluster1")
+	subscription := func() string {
+		subscription, err := (&clusterController{}).desiredSubManifestWork(managedCluster, config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		manifests := subscription.Spec.Workload.Manifests
+		return string(manifests[len(manifests)-1].Raw)
+	}
+	if !strings.Contains(subscription(), `"source": "redhat-operators"`) {
+		t.Errorf("expected the released catalog source by default, got %s", subscription())
+	}
+	config.CatalogSource = "acm-mirror"
+	if !strings.Contains(subscription(), `"source": "acm-mirror"`) {
+		t.Errorf("expected the configured catalog source, got %s", subscription())
+	}
+	managedCluster.Annotations = map[string]string{HOH_CATALOG_SOURCE_ANNOTATION: "acm-dev-catalog"}
+	if !strings.Contains(subscription(), `"source": "acm-dev-catalog"`) {
+		t.Errorf("expected the catalog source of the annotation, got %s", subscription())
+	}
+}
+
+func TestDesiredMCHManifestWorkImageRepository(t *testing.T) {
+	config := DefaultHubConfig()
+	config.ImageRepository = "mirror.example.com/acm"
+	managedCluster := newManagedCluster("cluster1")
+	mch, _, err := (&clusterController{}).desiredMCHManifestWork(managedCluster, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw := string(mch.Spec.Workload.Manifests[0].Raw); !strings.Contains(raw, `"mch-imageRepository":"mirror.example.com/acm"`) {
+		t.Errorf("expected the image repository annotation on the mch, got %s", raw)
+	}
+
+	managedCluster.Annotations = map[string]string{HOH_IMAGE_REPOSITORY_ANNOTATION: "quay.io/stolostron"}
+	mch, _, err = (&clusterController{}).desiredMCHManifestWork(managedCluster, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw := string(mch.Spec.Workload.Manifests[0].Raw); !strings.Contains(raw, `"mch-imageRepository":"quay.io/stolostron"`) {
+		t.Errorf("expected the image repository of the annotation on the mch, got %s", raw)
+	}
+}
@@ -0,0 +1,104 @@
+package cluster
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// WORKS_BY_CLUSTER_INDEX indexes the manifestworks created by the controller by managed cluster
+const WORKS_BY_CLUSTER_INDEX = "worksByManagedCluster"
+
+// IndexWorkByCluster returns the managed cluster of a manifestwork created by the controller
+func IndexWorkByCluster(obj interface{}) ([]string, error) {
+	work, ok := obj.(*workv1.ManifestWork)
+	if !ok {
+		return nil, fmt.Errorf("expected a manifestwork, got %T", obj)
+	}
+	if cluster, ok := work.Labels[MANAGED_CLUSTER_LABEL]; ok {
+		return []string{cluster}, nil
+	}
+	return nil, nil
+}
+
+// workIndexer adds the managed cluster index to the manifestwork informer unless another controller
+// sharing the informer added it already, and returns the indexer of the informer.
+func workIndexer(informer cache.SharedIndexInformer) cache.Indexer {
+	if _, ok := informer.GetIndexer().GetIndexers()[WORKS_BY_CLUSTER_INDEX]; !ok {
+		if err := informer.AddIndexers(cache.Indexers{WORKS_BY_CLUSTER_INDEX: IndexWorkByCluster}); err != nil {
+			utilruntime.HandleError(err)
+		}
+	}
+	return informer.GetIndexer()
+}
+
+// listManifestWorks returns the manifestworks created by the controller for the managed cluster
+func (c *clusterController) listManifestWorks(managedClusterName string) ([]*workv1.ManifestWork, error) {
+	objs, err := c.workIndexer.ByIndex(WORKS_BY_CLUSTER_INDEX, managedClusterName)
+	if err != nil {
+		return nil, err
+	}
+	works := make([]*workv1.ManifestWork, 0, len(objs))
+	for _, obj := range objs {
+		works = append(works, obj.(*workv1.ManifestWork))
+	}
+	return works, nil
+}
+
+// hasManifestWork returns true if the hub manifestwork of the given type of the managed cluster is in
+// the cache
+func (c *clusterController) hasManifestWork(managedClusterName, work string) bool {
+	works, err := c.listManifestWorks(managedClusterName)
+	if err != nil {
+		return false
+	}
+	for _, manifestWork := range works {
+		if IsWorkOfType(manifestWork, work) {
+			return true
+		}
+	}
+	return false
+}
+
+// getManifestWork returns the hub manifestwork of the given type of the managed cluster from the
+// cache, or nil if it is not created yet. The manifestwork of a previous managed cluster with the
+// same name is ignored. The manifestwork is found by its labels, so the manifestworks created with
+// another naming scheme are still found.
+func (c *clusterController) getManifestWork(managedCluster *clusterv1.ManagedCluster, work string) (*workv1.ManifestWork, error) {
+	works, err := c.listManifestWorks(managedCluster.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, manifestWork := range works {
+		if IsWorkOfType(manifestWork, work) && !IsStale(manifestWork, managedCluster) {
+			return manifestWork, nil
+		}
+	}
+	return nil, nil
+}
+
+// findManifestWork returns the existing manifestwork of the type of the desired one from the cache,
+// including the stale manifestwork of a previous managed cluster with the same name, or nil if it
+// does not exist. It is found by its labels, so a manifestwork created with another naming scheme is
+// found too. The manifestworks created before the managed cluster label are found by their name.
+func (c *clusterController) findManifestWork(managedClusterName string, desired *workv1.ManifestWork) (*workv1.ManifestWork, error) {
+	works, err := c.listManifestWorks(managedClusterName)
+	if err != nil {
+		return nil, err
+	}
+	for _, work := range works {
+		if work.Namespace == desired.Namespace && IsWorkOfType(work, desired.Labels[WORK_TYPE_LABEL]) {
+			return work, nil
+		}
+	}
+	work, err := c.workLister.ManifestWorks(desired.Namespace).Get(
+		c.options.WorkNaming.Name(managedClusterName, desired.Labels[WORK_TYPE_LABEL]))
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return work, err
+}
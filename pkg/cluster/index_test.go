@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clienttesting "k8s.io/client-go/testing"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestListManifestWorks(t *testing.T) {
+	subscription := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := CreateSubManifestwork("cluster2", DefaultHubConfig())
+	unowned := &workv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{Name: "unowned", Namespace: "cluster1"}}
+	ctrl := newTestController(t, nil, []*workv1.ManifestWork{subscription, mch, other, unowned})
+
+	works, err := ctrl.listManifestWorks("cluster1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := map[string]bool{}
+	for _, work := range works {
+		names[work.Name] = true
+	}
+	if len(names) != 2 || !names[subscription.Name] || !names[mch.Name] {
+		t.Errorf("expected the hub manifestworks of cluster1, got %v", names)
+	}
+
+	work, err := ctrl.getManifestWork(newManagedCluster("cluster2"), HOH_HUB_CLUSTER_MCH)
+	if err != nil || work != nil {
+		t.Errorf("expected no mch manifestwork for cluster2, got %v, %v", work, err)
+	}
+}
+
+func TestApplyManifestWorkAddsClusterLabel(t *testing.T) {
+	existing := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	if err := SetSpecHash(existing); err != nil {
+		t.Fatal(err)
+	}
+	delete(existing.Labels, MANAGED_CLUSTER_LABEL)
+	ctrl := newTestController(t, nil, []*workv1.ManifestWork{existing})
+
+	if _, err := ctrl.applyManifestWork(context.TODO(), newManagedCluster("cluster1"), CreateSubManifestwork("cluster1", DefaultHubConfig())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actions := ctrl.workClient.Actions()
+	if len(actions) != 1 {
+		t.Fatalf("expected the manifestwork to be patched, got %v", actions)
+	}
+	if patch := string(actions[0].(clienttesting.PatchActionImpl).GetPatch()); !strings.Contains(patch, MANAGED_CLUSTER_LABEL) {
+		t.Errorf("expected the managed cluster label to be added, got %s", patch)
+	}
+}
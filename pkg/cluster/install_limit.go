@@ -0,0 +1,151 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// installSlotRecheckInterval is the interval to recheck a managed hub waiting for an install slot
+const installSlotRecheckInterval = 30 * time.Second
+
+// installStartTimeout bounds how long an install started by the controller is counted in flight
+// before its manifestwork shows up in the cache
+const installStartTimeout = time.Minute
+
+// installLimiter counts the installs started by the controller whose subscription manifestwork is
+// not in the cache yet, so the concurrent syncs do not start more installs than the cap. The started
+// installs are only known to the replica starting them, so each shard is capped to its own share of
+// the limit.
+type installLimiter struct {
+	lock    sync.Mutex
+	started map[string]time.Time
+}
+
+// installSlotAvailable returns true if the hub of the managed cluster can start installing, the
+// managed hubs installing already are never held. Otherwise the managed cluster waits for one of the
+// installs in flight to complete with the HubInstallPending condition, which is cleared once its
+// install starts, and is requeued to recheck.
+func (c *subscriptionController) installSlotAvailable(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster) (bool, error) {
+	limit := c.hubConfig.get().MaxConcurrentInstalls
+	if limit <= 0 {
+		return true, c.clearInstallPending(ctx, managedCluster)
+	}
+	limit = shardInstallLimit(limit, c.options.ShardCount, c.options.ShardIndex)
+	subscription, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_SUBSCRIPTION)
+	if err != nil || subscription != nil {
+		return true, c.clearInstallPending(ctx, managedCluster)
+	}
+	inFlight := c.installsInFlight()
+	if c.reserveInstallSlot(managedCluster.Name, inFlight.Len(), limit) {
+		return true, c.clearInstallPending(ctx, managedCluster)
+	}
+
+	loggerFrom(ctx).V(2).Info("Waiting for an install slot", "installsInFlight", inFlight.Len(), "limit", limit)
+	if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, HubConditionInstallPending) {
+		c.clusterRecorder.Eventf(managedClusterReference(managedCluster), corev1.EventTypeNormal,
+			EventReasonHubInstallPending, "Waiting for one of the %d hub installs in flight to complete", limit)
+	}
+	syncCtx.Queue().AddAfter(managedCluster.Name, installSlotRecheckInterval)
+	return false, c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+		Type:    HubConditionInstallPending,
+		Status:  metav1.ConditionTrue,
+		Reason:  "InstallCapReached",
+		Message: fmt.Sprintf("Waiting for one of the %d hub installs in flight to complete", limit),
+	})
+}
+
+// reserveInstallSlot records the install of the managed hub as started and returns true if the
+// installs in flight and started are below the limit.
+func (c *subscriptionController) reserveInstallSlot(managedClusterName string, inFlight, limit int) bool {
+	c.installs.lock.Lock()
+	defer c.installs.lock.Unlock()
+	if c.installs.started == nil {
+		c.installs.started = map[string]time.Time{}
+	}
+	now := time.Now()
+	for name, startedAt := range c.installs.started {
+		if c.hasManifestWork(name, HOH_HUB_CLUSTER_SUBSCRIPTION) || now.Sub(startedAt) > installStartTimeout {
+			delete(c.installs.started, name)
+		}
+	}
+	if inFlight+len(c.installs.started) >= limit {
+		return false
+	}
+	c.installs.started[managedClusterName] = now
+	return true
+}
+
+// shardInstallLimit returns the share of the fleet-wide install limit of the shard, the limit being
+// split evenly between the shards. Each shard installs at least one hub at once, so the fleet-wide
+// limit is raised to the number of shards when it is lower.
+func shardInstallLimit(limit, shardCount, shardIndex int) int {
+	if shardCount <= 1 {
+		return limit
+	}
+	share := limit / shardCount
+	if shardIndex < limit%shardCount {
+		share++
+	}
+	if share < 1 {
+		return 1
+	}
+	return share
+}
+
+// clearInstallPending clears the HubInstallPending condition of the managed hub once its install starts
+func (c *subscriptionController) clearInstallPending(ctx context.Context, managedCluster *clusterv1.ManagedCluster) error {
+	if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, HubConditionInstallPending) {
+		return nil
+	}
+	return c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+		Type:    HubConditionInstallPending,
+		Status:  metav1.ConditionFalse,
+		Reason:  "InstallStarted",
+		Message: "The hub install is started",
+	})
+}
+
+// installsInFlight returns the managed clusters of the shard whose hub is installing, as reported by
+// the feedback of their manifestworks. The hubs reported degraded, including the hubs exceeding the
+// install timeout, no longer hold an install slot.
+func (c *clusterController) installsInFlight() sets.String {
+	inFlight := sets.NewString()
+	works, err := c.workLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list the manifestworks: %v", err)
+		return inFlight
+	}
+	for _, subscription := range works {
+		if subscription.Labels[MANAGED_BY_LABEL] != MANAGED_BY_VALUE || !IsWorkOfType(subscription, HOH_HUB_CLUSTER_SUBSCRIPTION) ||
+			!c.ownsCluster(WorkManagedCluster(subscription)) {
+			continue
+		}
+		managedCluster, err := c.clusterLister.Get(WorkManagedCluster(subscription))
+		if err != nil || IsStale(subscription, managedCluster) ||
+			meta.IsStatusConditionTrue(managedCluster.Status.Conditions, HubConditionDegraded) {
+			continue
+		}
+		mch, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_MCH)
+		if err != nil {
+			continue
+		}
+		conditions := HubConditions(subscription, mch)
+		if meta.IsStatusConditionTrue(conditions, HubConditionInstalling) &&
+			!meta.IsStatusConditionTrue(conditions, HubConditionDegraded) {
+			inFlight.Insert(managedCluster.Name)
+		}
+	}
+	return inFlight
+}
@@ -0,0 +1,150 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func TestInstallSlotAvailable(t *testing.T) {
+	installing := newManagedCluster("cluster1")
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: "UpgradePending"})
+	SetManagedClusterUID(subscription, installing)
+	managedClusters := []*clusterv1.ManagedCluster{installing, newManagedCluster("cluster2"), newManagedCluster("cluster3")}
+
+	cases := []struct {
+		name     string
+		works    []*workv1.ManifestWork
+		expected map[string]bool
+	}{
+		{
+			name:     "cap reached",
+			works:    []*workv1.ManifestWork{subscription},
+			expected: map[string]bool{"cluster1": true, "cluster2": false},
+		},
+		{
+			name: "cap reached by a started install",
+			// cluster2 starts installing, its manifestwork is not in the cache yet
+			expected: map[string]bool{"cluster2": true, "cluster3": false},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := newTestController(t, managedClusters, c.works)
+			ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t,
+				newHubConfigMap(map[string]string{HUB_CONFIG_MAX_CONCURRENT_INSTALLS_KEY: "1"})))
+			subscriptionCtrl := &subscriptionController{clusterController: ctrl.clusterController}
+			for _, managedCluster := range managedClusters {
+				expected, ok := c.expected[managedCluster.Name]
+				if !ok {
+					continue
+				}
+				syncCtx := testinghelpers.NewFakeSyncContext(t, managedCluster.Name)
+				available, err := subscriptionCtrl.installSlotAvailable(context.TODO(), syncCtx, managedCluster)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if available != expected {
+					t.Errorf("expected an install slot available %v for %s, got %v", expected, managedCluster.Name, available)
+				}
+				updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), managedCluster.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if pending := meta.IsStatusConditionTrue(updated.Status.Conditions, HubConditionInstallPending); pending == expected {
+					t.Errorf("expected the HubInstallPending condition %v for %s, got %v", !expected, managedCluster.Name, pending)
+				}
+			}
+		})
+	}
+}
+
+func TestInstallSlotAvailableClearsPending(t *testing.T) {
+	pending := newManagedCluster("cluster1")
+	meta.SetStatusCondition(&pending.Status.Conditions, metav1.Condition{
+		Type: HubConditionInstallPending, Status: metav1.ConditionTrue, Reason: "InstallCapReached",
+	})
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{pending}, nil)
+	ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t,
+		newHubConfigMap(map[string]string{HUB_CONFIG_MAX_CONCURRENT_INSTALLS_KEY: "1"})))
+	subscriptionCtrl := &subscriptionController{clusterController: ctrl.clusterController}
+
+	available, err := subscriptionCtrl.installSlotAvailable(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1"), pending)
+	if err != nil || !available {
+		t.Fatalf("expected an install slot available, got %v, %v", available, err)
+	}
+	updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cond := meta.FindStatusCondition(updated.Status.Conditions, HubConditionInstallPending); cond == nil ||
+		cond.Status != metav1.ConditionFalse || cond.Reason != "InstallStarted" {
+		t.Errorf("expected the HubInstallPending condition to be cleared, got %v", cond)
+	}
+}
+
+func TestShardInstallLimit(t *testing.T) {
+	for _, c := range []struct {
+		limit, shardCount int
+		expected          []int
+	}{
+		{limit: 5, shardCount: 1, expected: []int{5}},
+		{limit: 5, shardCount: 2, expected: []int{3, 2}},
+		{limit: 6, shardCount: 3, expected: []int{2, 2, 2}},
+		{limit: 1, shardCount: 2, expected: []int{1, 1}},
+	} {
+		for shardIndex, expected := range c.expected {
+			if actual := shardInstallLimit(c.limit, c.shardCount, shardIndex); actual != expected {
+				t.Errorf("expected the limit %d of shard %d/%d to be %d, got %d", c.limit, shardIndex, c.shardCount,
+					expected, actual)
+			}
+		}
+	}
+}
+
+func TestInstallSlotAvailableAcrossShards(t *testing.T) {
+	managedClusters := []*clusterv1.ManagedCluster{}
+	for i := 1; i <= 8; i++ {
+		managedClusters = append(managedClusters, newManagedCluster(fmt.Sprintf("cluster%d", i)))
+	}
+	ctrl := newTestController(t, managedClusters, nil)
+	ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t,
+		newHubConfigMap(map[string]string{HUB_CONFIG_MAX_CONCURRENT_INSTALLS_KEY: "2"})))
+
+	// the limiters of the two shards share the listers, none of the started installs is in the cache
+	shards := make([]*subscriptionController, 2)
+	for i := range shards {
+		shards[i] = &subscriptionController{clusterController: &clusterController{
+			clusterclient:   ctrl.clusterController.clusterclient,
+			clusterLister:   ctrl.clusterController.clusterLister,
+			workLister:      ctrl.clusterController.workLister,
+			workIndexer:     ctrl.clusterController.workIndexer,
+			clusterRecorder: ctrl.clusterController.clusterRecorder,
+			hubConfig:       ctrl.hubConfig,
+			options:         ControllerOptions{ShardCount: 2, ShardIndex: i},
+		}}
+	}
+	started := map[int]int{}
+	for _, managedCluster := range managedClusters {
+		shard := ShardOf(managedCluster.Name, 2)
+		available, err := shards[shard].installSlotAvailable(context.TODO(),
+			testinghelpers.NewFakeSyncContext(t, managedCluster.Name), managedCluster)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if available {
+			started[shard]++
+		}
+	}
+	if started[0]+started[1] != 2 || started[0] != 1 || started[1] != 1 {
+		t.Errorf("expected each shard to start one of the 2 installs, got %v", started)
+	}
+}
@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// defaultKlusterletAddons are the addons of the local-cluster of the managed hubs, a managed hub only
+// needs the policy controller to be governed by the hub of hubs. The applications, search and the
+// other policy controllers are served by the hub of hubs.
+var defaultKlusterletAddons = map[string]bool{
+	"applicationManager":   false,
+	"certPolicyController": false,
+	"iamPolicyController":  false,
+	"policyController":     true,
+	"searchCollector":      false,
+}
+
+// parseKlusterletAddons parses the json map of the addons enabled on the local-cluster of the managed
+// hubs, merged onto the default ones
+func parseKlusterletAddons(value string) (map[string]bool, error) {
+	addons := map[string]bool{}
+	if err := json.Unmarshal([]byte(value), &addons); err != nil {
+		return nil, err
+	}
+	for addon := range addons {
+		if _, ok := defaultKlusterletAddons[addon]; !ok {
+			return nil, fmt.Errorf("unknown addon %s", addon)
+		}
+	}
+	return addons, nil
+}
+
+// klusterletAddonConfigManifest returns the KlusterletAddonConfig of the local-cluster of the managed
+// hub, if the managed hub manages itself. It is applied with the MultiClusterHub, the work agent
+// retries until the MultiClusterHub has installed its CRD and imported the local-cluster.
+func klusterletAddonConfigManifest(config *HubConfig) (workv1.Manifest, bool) {
+	if config.DisableHubSelfManagement {
+		return workv1.Manifest{}, false
+	}
+	spec := map[string]interface{}{}
+	for addon, enabled := range defaultKlusterletAddons {
+		if value, ok := config.KlusterletAddons[addon]; ok {
+			enabled = value
+		}
+		spec[addon] = map[string]interface{}{"enabled": enabled}
+	}
+	// marshaling generic JSON values does not fail
+	raw, _ := json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "agent.open-cluster-management.io/v1",
+		"kind":       "KlusterletAddonConfig",
+		"metadata": map[string]interface{}{
+			"name":      "local-cluster",
+			"namespace": "local-cluster",
+		},
+		"spec": spec,
+	}, "", "\t")
+	return workv1.Manifest{RawExtension: runtime.RawExtension{Raw: raw}}, true
+}
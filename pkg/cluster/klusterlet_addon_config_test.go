@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestKlusterletAddonConfig(t *testing.T) {
+	if _, ok := klusterletAddonConfigManifest(DefaultHubConfig()); ok {
+		t.Errorf("expected no KlusterletAddonConfig for the managed hubs not managing themselves")
+	}
+
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_DISABLE_HUB_SELF_MANAGEMENT_KEY: "false",
+		HUB_CONFIG_KLUSTERLET_ADDONS_KEY:           `{"searchCollector": true, "policyController": false}`,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	work, _, err := (&clusterController{}).desiredMCHManifestWork(newManagedCluster("cluster1"), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manifests := work.Spec.Workload.Manifests
+	addonConfig := struct {
+		Kind string                     `json:"kind"`
+		Spec map[string]map[string]bool `json:"spec"`
+	}{}
+	if err := json.Unmarshal(manifests[len(manifests)-1].Raw, &addonConfig); err != nil {
+		t.Fatal(err)
+	}
+	if addonConfig.Kind != "KlusterletAddonConfig" {
+		t.Fatalf("expected the KlusterletAddonConfig to be applied after the MultiClusterHub, got %s", addonConfig.Kind)
+	}
+	expected := map[string]bool{
+		"applicationManager":   false,
+		"certPolicyController": false,
+		"iamPolicyController":  false,
+		"policyController":     false,
+		"searchCollector":      true,
+	}
+	for addon, enabled := range expected {
+		if addonConfig.Spec[addon]["enabled"] != enabled {
+			t.Errorf("expected the addon %s enabled %v, got %v", addon, enabled, addonConfig.Spec[addon])
+		}
+	}
+
+	if _, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_KLUSTERLET_ADDONS_KEY: `{"observability": true}`,
+	})); err == nil {
+		t.Errorf("expected an unknown addon to be rejected")
+	}
+}
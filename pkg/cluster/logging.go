@@ -0,0 +1,28 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2/klogr"
+)
+
+// withLogger returns a context carrying a logger tagged with the phase and managed cluster of the
+// sync, so that every log line of the sync can be correlated.
+func withLogger(ctx context.Context, phase, managedClusterName string) context.Context {
+	return logr.NewContext(ctx, newKlogr().WithValues("phase", phase, "cluster", managedClusterName))
+}
+
+// loggerFrom returns the logger of the sync, or the klog logger outside of a sync.
+func loggerFrom(ctx context.Context) logr.Logger {
+	if logger, err := logr.FromContext(ctx); err == nil {
+		return logger
+	}
+	return newKlogr()
+}
+
+// newKlogr returns a logger passing the key/value pairs to klog, so they are kept as fields by the
+// JSON log format.
+func newKlogr() logr.Logger {
+	return klogr.NewWithOptions(klogr.WithFormat(klogr.FormatKlog))
+}
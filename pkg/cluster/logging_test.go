@@ -0,0 +1,32 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"k8s.io/klog/v2"
+)
+
+func TestWithLogger(t *testing.T) {
+	var lines []string
+	klog.SetLogger(funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{}))
+	defer klog.ClearLogger()
+
+	ctx := withLogger(context.TODO(), "MCHController", "cluster1")
+	loggerFrom(ctx).Info("Reconciling hub cluster")
+	loggerFrom(context.TODO()).Info("Resyncing managed clusters")
+
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %v", lines)
+	}
+	if !strings.Contains(lines[0], `"phase"="MCHController"`) || !strings.Contains(lines[0], `"cluster"="cluster1"`) {
+		t.Errorf("expected the sync log line to carry the phase and cluster, got %s", lines[0])
+	}
+	if strings.Contains(lines[1], `"cluster"`) {
+		t.Errorf("expected the log line outside of a sync to carry no cluster, got %s", lines[1])
+	}
+}
@@ -0,0 +1,122 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/robfig/cron"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// HOH_MAINTENANCE_WINDOW_ANNOTATION restricts the changes of the hub manifestworks of a managed
+// cluster to a maintenance window, the changes made outside of the window are applied when it opens.
+// The window is either an RFC 3339 interval, such as 2022-03-05T02:00:00Z/2022-03-05T06:00:00Z, or a
+// recurring window given by a standard cron schedule and a duration, such as "0 2 * * SAT;4h".
+const HOH_MAINTENANCE_WINDOW_ANNOTATION = "hoh-maintenance-window"
+
+// maintenanceWindow returns the time to wait from now until the window opens, zero if it is open,
+// or a negative duration if it never opens again.
+type maintenanceWindow func(now time.Time) time.Duration
+
+// ParseMaintenanceWindow parses the maintenance window of the HOH_MAINTENANCE_WINDOW_ANNOTATION.
+func ParseMaintenanceWindow(value string) (maintenanceWindow, error) {
+	if spec, duration, ok := cut(value, ";"); ok {
+		schedule, err := cron.ParseStandard(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window schedule %q: %v", spec, err)
+		}
+		length, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil || length <= 0 {
+			return nil, fmt.Errorf("invalid maintenance window duration %q", duration)
+		}
+		return func(now time.Time) time.Duration {
+			// the window is open if it was last opened less than its duration ago
+			next := schedule.Next(now.Add(-length))
+			if !next.After(now) {
+				return 0
+			}
+			return next.Sub(now)
+		}, nil
+	}
+
+	if start, end, ok := cut(value, "/"); ok {
+		startTime, err := time.Parse(time.RFC3339, strings.TrimSpace(start))
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window start %q: %v", start, err)
+		}
+		endTime, err := time.Parse(time.RFC3339, strings.TrimSpace(end))
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window end %q: %v", end, err)
+		}
+		if !endTime.After(startTime) {
+			return nil, fmt.Errorf("the maintenance window ends before it starts")
+		}
+		return func(now time.Time) time.Duration {
+			switch {
+			case now.Before(startTime):
+				return startTime.Sub(now)
+			case now.Before(endTime):
+				return 0
+			default:
+				return -1
+			}
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid maintenance window %q, expected an RFC 3339 interval or a cron schedule and a duration", value)
+}
+
+// inMaintenanceWindow returns true if the hub manifestworks of the managed cluster can be changed
+// now. Otherwise the managed cluster is requeued for when its window opens. An invalid window is
+// reported and holds the changes until the annotation is fixed. The events are only recorded when
+// the window state changes, not on every sync of the held managed cluster.
+func (c *clusterController) inMaintenanceWindow(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster) bool {
+	value, ok := managedCluster.Annotations[HOH_MAINTENANCE_WINDOW_ANNOTATION]
+	if !ok {
+		c.maintenanceHolds.Delete(managedCluster.Name)
+		return true
+	}
+	window, err := ParseMaintenanceWindow(value)
+	if err != nil {
+		loggerFrom(ctx).Error(err, "Holding the hub changes until the maintenance window is fixed")
+		if held, _ := c.maintenanceHolds.Load(managedCluster.Name); held != value {
+			c.clusterRecorder.Eventf(managedClusterReference(managedCluster), corev1.EventTypeWarning,
+				EventReasonInvalidMaintenanceWindow, "The hub changes are held: %v", err)
+		}
+		c.maintenanceHolds.Store(managedCluster.Name, value)
+		return false
+	}
+
+	wait := window(time.Now())
+	switch {
+	case wait == 0:
+		if held, ok := c.maintenanceHolds.LoadAndDelete(managedCluster.Name); ok && held == (maintenanceWindowClosed{}) {
+			c.clusterRecorder.Eventf(managedClusterReference(managedCluster), corev1.EventTypeNormal,
+				EventReasonMaintenanceWindowOpened, "The maintenance window is open, the held hub changes are applied by %s", c.name)
+		}
+		return true
+	case wait > 0:
+		loggerFrom(ctx).V(2).Info("Holding the hub changes until the maintenance window opens", "wait", wait)
+		syncCtx.Queue().AddAfter(managedCluster.Name, wait)
+	default:
+		loggerFrom(ctx).V(2).Info("Holding the hub changes, the maintenance window is over")
+	}
+	c.maintenanceHolds.Store(managedCluster.Name, maintenanceWindowClosed{})
+	return false
+}
+
+// maintenanceWindowClosed is held in clusterController.maintenanceHolds for the managed clusters
+// waiting for their maintenance window, the invalid windows are held with their annotation value
+type maintenanceWindowClosed struct{}
+
+// cut slices s around the first instance of sep, strings.Cut is not available with go 1.17
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,160 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	// a Saturday
+	saturday := time.Date(2022, 3, 5, 0, 0, 0, 0, time.Local)
+	cases := []struct {
+		name          string
+		window        string
+		now           time.Time
+		expectedWait  time.Duration
+		expectedError bool
+	}{
+		{
+			name:         "before the interval",
+			window:       "2022-03-05T02:00:00Z/2022-03-05T06:00:00Z",
+			now:          time.Date(2022, 3, 5, 1, 0, 0, 0, time.UTC),
+			expectedWait: time.Hour,
+		},
+		{
+			name:         "within the interval",
+			window:       "2022-03-05T02:00:00Z/2022-03-05T06:00:00Z",
+			now:          time.Date(2022, 3, 5, 3, 0, 0, 0, time.UTC),
+			expectedWait: 0,
+		},
+		{
+			name:         "after the interval",
+			window:       "2022-03-05T02:00:00Z/2022-03-05T06:00:00Z",
+			now:          time.Date(2022, 3, 5, 7, 0, 0, 0, time.UTC),
+			expectedWait: -1,
+		},
+		{
+			name:         "before the recurring window",
+			window:       "0 2 * * SAT;4h",
+			now:          saturday.Add(time.Hour),
+			expectedWait: time.Hour,
+		},
+		{
+			name:         "within the recurring window",
+			window:       "0 2 * * SAT;4h",
+			now:          saturday.Add(5 * time.Hour),
+			expectedWait: 0,
+		},
+		{
+			name:         "after the recurring window",
+			window:       "0 2 * * SAT;4h",
+			now:          saturday.Add(6 * time.Hour),
+			expectedWait: 7*24*time.Hour - 4*time.Hour,
+		},
+		{
+			name:          "interval ending before it starts",
+			window:        "2022-03-05T06:00:00Z/2022-03-05T02:00:00Z",
+			expectedError: true,
+		},
+		{
+			name:          "invalid schedule",
+			window:        "0 2 * *;4h",
+			expectedError: true,
+		},
+		{
+			name:          "invalid duration",
+			window:        "0 2 * * SAT;forever",
+			expectedError: true,
+		},
+		{
+			name:          "invalid window",
+			window:        "saturday",
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			window, err := ParseMaintenanceWindow(c.window)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if wait := window(c.now); wait != c.expectedWait {
+				t.Errorf("expected wait %s, got %s", c.expectedWait, wait)
+			}
+		})
+	}
+}
+
+func TestSubscriptionControllerHoldsChangesOutsideMaintenanceWindow(t *testing.T) {
+	start := time.Now().Add(time.Hour).UTC()
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name: "cluster1",
+		Annotations: map[string]string{
+			HOH_MAINTENANCE_WINDOW_ANNOTATION: start.Format(time.RFC3339) + "/" + start.Add(time.Hour).Format(time.RFC3339),
+		},
+	}}
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{managedCluster})
+
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := ctrl.workClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no manifestwork to be written outside of the maintenance window, got %v", actions)
+	}
+	if delay, ok := syncCtx.AddedAfter("cluster1"); !ok || delay <= 59*time.Minute || delay > time.Hour {
+		t.Errorf("expected the cluster to be requeued when the window opens in an hour, got %s", delay)
+	}
+}
+
+func TestInMaintenanceWindowRecordsTransitions(t *testing.T) {
+	ctrl := newTestController(t, nil, nil)
+	managedCluster := newManagedCluster("cluster1")
+	setWindow := func(start time.Time) {
+		managedCluster.Annotations = map[string]string{HOH_MAINTENANCE_WINDOW_ANNOTATION: start.UTC().Format(time.RFC3339) +
+			"/" + start.Add(time.Hour).UTC().Format(time.RFC3339)}
+	}
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+
+	managedCluster.Annotations = map[string]string{HOH_MAINTENANCE_WINDOW_ANNOTATION: "saturday"}
+	for i := 0; i < 2; i++ {
+		if ctrl.inMaintenanceWindow(context.TODO(), syncCtx, managedCluster) {
+			t.Errorf("expected the changes to be held by the invalid window")
+		}
+	}
+	setWindow(time.Now().Add(time.Hour))
+	for i := 0; i < 2; i++ {
+		if ctrl.inMaintenanceWindow(context.TODO(), syncCtx, managedCluster) {
+			t.Errorf("expected the changes to be held until the window opens")
+		}
+	}
+	setWindow(time.Now().Add(-time.Minute))
+	for i := 0; i < 2; i++ {
+		if !ctrl.inMaintenanceWindow(context.TODO(), syncCtx, managedCluster) {
+			t.Errorf("expected the changes to be applied in the window")
+		}
+	}
+
+	events := recordedEvents(ctrl.clusterRecorder.(*record.FakeRecorder))
+	if len(events) != 2 ||
+		!strings.HasPrefix(events[0], corev1.EventTypeWarning+" "+EventReasonInvalidMaintenanceWindow) ||
+		!strings.HasPrefix(events[1], corev1.EventTypeNormal+" "+EventReasonMaintenanceWindowOpened) {
+		t.Errorf("expected an event when the window is invalid and when it opens, got %v", events)
+	}
+}
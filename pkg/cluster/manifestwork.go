@@ -1,13 +1,26 @@
 package cluster
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/klog/v2"
 
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/dryrun"
 )
 
 const (
@@ -15,13 +28,175 @@ const (
 	HOH_HUB_CLUSTER_MCH          = "hoh-hub-cluster-mch"
 )
 
-func CreateSubManifestwork(namespace string) *workv1.ManifestWork {
+// WORK_TYPE_LABEL is set on the hub manifestworks to their type, such as HOH_HUB_CLUSTER_SUBSCRIPTION,
+// the manifestworks are found by their type and managed cluster labels rather than by name
+const WORK_TYPE_LABEL = "hub-of-hubs.open-cluster-management.io/work-type"
+
+// WorkNaming is the prefix and suffix of the names of the hub manifestworks, the names are
+// <prefix><managed cluster>-<type><suffix>. The existing manifestworks are found by their labels and
+// keep their name.
+type WorkNaming struct {
+	Prefix string
+	Suffix string
+}
+
+// Validate returns an error if the prefix or suffix make invalid manifestwork names
+func (n WorkNaming) Validate() error {
+	if errs := validation.IsDNS1123Subdomain(n.Name("cluster", HOH_HUB_CLUSTER_OBSERVABILITY)); len(errs) > 0 {
+		return fmt.Errorf("invalid manifestwork name prefix %q or suffix %q: %s", n.Prefix, n.Suffix, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Name returns the name of the hub manifestwork of the given type created for the managed cluster.
+func (n WorkNaming) Name(managedClusterName, workType string) string {
+	return n.Prefix + managedClusterName + "-" + workType + n.Suffix
+}
+
+// setName names the rendered hub manifestwork after its managed cluster and type label
+func (n WorkNaming) setName(work *workv1.ManifestWork) {
+	work.Name = n.Name(work.Namespace, work.Labels[WORK_TYPE_LABEL])
+}
+
+// IsWorkOfType returns true if the hub manifestwork is of the given type, from its type label or else
+// the suffix of its name for the manifestworks created before the label.
+func IsWorkOfType(work metav1.Object, workType string) bool {
+	if value, ok := work.GetLabels()[WORK_TYPE_LABEL]; ok {
+		return value == workType
+	}
+	return strings.HasSuffix(work.GetName(), "-"+workType)
+}
+
+// SPEC_HASH_ANNOTATION records the hash of the rendered spec on the manifestwork
+const SPEC_HASH_ANNOTATION = "hub-of-hubs.open-cluster-management.io/spec-hash"
+
+// MANAGED_BY_LABEL marks the manifestworks created by the controller, so only those are watched
+const (
+	MANAGED_BY_LABEL = "hub-of-hubs.open-cluster-management.io/managed-by"
+	MANAGED_BY_VALUE = "hoh"
+)
+
+// BACKUP_LABEL is set on the manifestworks and the other resources created by the controller, so they
+// are captured by the backup of the hub of hubs
+const (
+	BACKUP_LABEL = "cluster.open-cluster-management.io/backup"
+	BACKUP_VALUE = "hub-of-hubs"
+)
+
+// RESTORE_NAME_LABEL is set by velero on the resources of a restored backup to the name of the restore
+const RESTORE_NAME_LABEL = "velero.io/restore-name"
+
+// RESTORE_ADOPTED_ANNOTATION is set on a restored manifestwork to the name of its restore once it is
+// adopted by the managed cluster imported again on the restored hub of hubs
+const RESTORE_ADOPTED_ANNOTATION = "hub-of-hubs.open-cluster-management.io/restore-adopted"
+
+// MANAGED_CLUSTER_LABEL is set on the manifestworks created by the controller to the name of the
+// managed cluster they are created for
+const MANAGED_CLUSTER_LABEL = "hub-of-hubs.open-cluster-management.io/managed-cluster"
+
+// MANAGED_CLUSTER_UID_ANNOTATION is set on the manifestworks created by the controller to the UID of
+// the managed cluster they are created for, so the manifestworks left by a deleted managed cluster
+// are told apart when a managed cluster with the same name is imported again
+const MANAGED_CLUSTER_UID_ANNOTATION = "hub-of-hubs.open-cluster-management.io/managed-cluster-uid"
+
+// MANAGED_BY_SELECTOR is the label selector of the manifestworks created by the controller
+const MANAGED_BY_SELECTOR = MANAGED_BY_LABEL + "=" + MANAGED_BY_VALUE
+
+// audit annotations stamped on the manifestworks each time they are written by the controller
+const (
+	// CONTROLLER_VERSION_ANNOTATION is the version of the controller which last wrote the manifestwork
+	CONTROLLER_VERSION_ANNOTATION = "hub-of-hubs.open-cluster-management.io/controller-version"
+	// RENDERED_AT_ANNOTATION is the time the manifestwork was last written, in RFC 3339 format
+	RENDERED_AT_ANNOTATION = "hub-of-hubs.open-cluster-management.io/rendered-at"
+	// CONFIG_GENERATION_ANNOTATION is the resource version of the hub configuration ConfigMap the
+	// manifestwork was last rendered from, it is not set for the default configuration
+	CONFIG_GENERATION_ANNOTATION = "hub-of-hubs.open-cluster-management.io/config-generation"
+)
+
+// REPAIRED_AT_ANNOTATION is the time the controller last requested the work agent to reapply the
+// manifests of the manifestwork, because a resource was deleted on the managed cluster
+const REPAIRED_AT_ANNOTATION = "hub-of-hubs.open-cluster-management.io/repaired-at"
+
+// FIELD_MANAGER is the field manager of the manifestworks applied by the controller
+const FIELD_MANAGER = "hub-cluster-controller"
+
+// names of the status feedback values reported by the work agent
+const (
+	SUBSCRIPTION_STATE_FEEDBACK              = "state"
+	SUBSCRIPTION_RESOLUTION_FAILED_FEEDBACK  = "resolutionFailed"
+	SUBSCRIPTION_RESOLUTION_MESSAGE_FEEDBACK = "resolutionFailedMessage"
+	SUBSCRIPTION_INSTALLED_CSV_FEEDBACK      = "installedCSV"
+	SUBSCRIPTION_CURRENT_CSV_FEEDBACK        = "currentCSV"
+	MCH_PHASE_FEEDBACK                       = "phase"
+	MCH_VERSION_FEEDBACK                     = "currentVersion"
+)
+
+// states of the operator subscription
+const (
+	SUBSCRIPTION_STATE_AT_LATEST_KNOWN   = "AtLatestKnown"
+	SUBSCRIPTION_STATE_UPGRADE_FAILED    = "UpgradeFailed"
+	SUBSCRIPTION_STATE_UPGRADE_PENDING   = "UpgradePending"
+	SUBSCRIPTION_STATE_UPGRADE_AVAILABLE = "UpgradeAvailable"
+)
+
+// MCH_PHASE_RUNNING is the MultiClusterHub phase once the hub is installed and ready
+const MCH_PHASE_RUNNING = "Running"
+
+// desiredSubManifestWork renders the subscription manifestwork of the managed cluster, from the hub
+// configuration of its product flavor and the catalog source of the managed cluster, with the trust
+// bundle of the hub configuration.
+func (c *clusterController) desiredSubManifestWork(managedCluster *clusterv1.ManagedCluster,
+	config *HubConfig) (*workv1.ManifestWork, error) {
+	config = config.forFlavor(Flavor(managedCluster, config))
+	source, err := CatalogSource(managedCluster, config)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := subscriptionManifest(managedCluster, config, source)
+	if err != nil {
+		return nil, err
+	}
+	work := newSubManifestwork(managedCluster.Name, config, subscription)
+	if source == HOH_CATALOG_SOURCE {
+		// the catalog source of the subscription does not exist without the default catalogs
+		work.Spec.Workload.Manifests = append(work.Spec.Workload.Manifests,
+			catalogSourceManifests(config.CatalogSourceImage)...)
+	}
+	if config.ManagedServiceAccount {
+		// the managed serviceaccount reads the health of the hub from the start of its installation
+		work.Spec.Workload.Manifests = append(work.Spec.Workload.Manifests, healthReaderManifests()...)
+	}
+	work = placeManifestWork(managedCluster, work)
+	if err := c.withTrustedCABundle(work, config); err != nil {
+		return nil, err
+	}
+	return work, nil
+}
+
+// CreateSubManifestwork returns the subscription manifestwork installing the operator from the
+// channel and catalog source of the hub configuration, with the built-in operator subscription.
+func CreateSubManifestwork(namespace string, config *HubConfig) *workv1.ManifestWork {
+	config = config.forFlavor(config.Flavor)
+	return newSubManifestwork(namespace, config, builtinSubscriptionManifest(config, config.CatalogSource,
+		MARKETPLACE_NAMESPACE))
+}
+
+// newSubManifestwork returns the subscription manifestwork installing the given operator subscription
+// into the install namespace of the hub configuration
+func newSubManifestwork(namespace string, config *HubConfig, subscription []byte) *workv1.ManifestWork {
 	return &workv1.ManifestWork{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: workv1.GroupVersion.String(),
+			Kind:       "ManifestWork",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      namespace + "-" + HOH_HUB_CLUSTER_SUBSCRIPTION,
+			Name:      WorkNaming{}.Name(namespace, HOH_HUB_CLUSTER_SUBSCRIPTION),
 			Namespace: namespace,
 			Labels: map[string]string{
-				"hub-of-hubs.open-cluster-management.io/managed-by": "hoh",
+				MANAGED_BY_LABEL:      MANAGED_BY_VALUE,
+				MANAGED_CLUSTER_LABEL: namespace,
+				BACKUP_LABEL:          BACKUP_VALUE,
+				WORK_TYPE_LABEL:       HOH_HUB_CLUSTER_SUBSCRIPTION,
 			},
 		},
 		Spec: workv1.ManifestWorkSpec{
@@ -73,13 +248,7 @@ func CreateSubManifestwork(namespace string) *workv1.ManifestWork {
 }`),
 					}},
 					{RawExtension: runtime.RawExtension{
-						Raw: []byte(`{
-	"apiVersion": "v1",
-	"kind": "Namespace",
-	"metadata": {
-		"name": "open-cluster-management"
-	}
-}`),
+						Raw: installNamespaceManifest(config),
 					}},
 					{RawExtension: runtime.RawExtension{
 						Raw: []byte(`{
@@ -97,22 +266,7 @@ func CreateSubManifestwork(namespace string) *workv1.ManifestWork {
 }`),
 					}},
 					{RawExtension: runtime.RawExtension{
-						Raw: []byte(`{
-	"apiVersion": "operators.coreos.com/v1alpha1",
-	"kind": "Subscription",
-	"metadata": {
-		"name": "acm-operator-subscription",
-		"namespace": "open-cluster-management"
-	},
-	"spec": {
-		"channel": "release-2.4",
-		"installPlanApproval": "Automatic",
-		"name": "advanced-cluster-management",
-		"source": "redhat-operators",
-		"sourceNamespace": "openshift-marketplace",
-		"startingCSV": "advanced-cluster-management.v2.4.1"
-	}
-}`),
+						Raw: subscription,
 					}},
 				},
 			},
@@ -129,9 +283,25 @@ func CreateSubManifestwork(namespace string) *workv1.ManifestWork {
 							Type: workv1.JSONPathsType,
 							JsonPaths: []workv1.JsonPath{
 								{
-									Name: "state",
+									Name: SUBSCRIPTION_STATE_FEEDBACK,
 									Path: ".status.state",
 								},
+								{
+									Name: SUBSCRIPTION_RESOLUTION_FAILED_FEEDBACK,
+									Path: `.status.conditions[?(@.type=="ResolutionFailed")].status`,
+								},
+								{
+									Name: SUBSCRIPTION_RESOLUTION_MESSAGE_FEEDBACK,
+									Path: `.status.conditions[?(@.type=="ResolutionFailed")].message`,
+								},
+								{
+									Name: SUBSCRIPTION_INSTALLED_CSV_FEEDBACK,
+									Path: ".status.installedCSV",
+								},
+								{
+									Name: SUBSCRIPTION_CURRENT_CSV_FEEDBACK,
+									Path: ".status.currentCSV",
+								},
 							},
 						},
 					},
@@ -141,8 +311,118 @@ func CreateSubManifestwork(namespace string) *workv1.ManifestWork {
 	}
 }
 
-func CreateMCHManifestwork(namespace, userDefinedMCH string) (*workv1.ManifestWork, error) {
-	mchJson := `{
+// subscriptionManifest renders the operator subscription of the managed cluster from the channel and
+// starting CSV of the hub configuration, and the given catalog source, onto the subscription template
+// if one is loaded. The namespace of the catalog source of the HyperShift hosted clusters is set on
+// the template too, the others keep the namespace of the template.
+func subscriptionManifest(managedCluster *clusterv1.ManagedCluster, config *HubConfig, source string) ([]byte, error) {
+	sourceNamespace := catalogSourceNamespace(managedCluster, config, source)
+	if subscriptionTemplate == nil {
+		return builtinSubscriptionManifest(config, source, sourceNamespace), nil
+	}
+	fields := append([]manifestField{
+		{path: []string{"spec", "channel"}, value: config.Channel},
+		{path: []string{"spec", "source"}, value: source},
+	}, subscriptionEnforcedFields...)
+	if IsHyperShift(managedCluster) {
+		fields = append(fields, manifestField{path: []string{"spec", "sourceNamespace"}, value: sourceNamespace})
+	}
+	if config.StartingCSV != "" {
+		fields = append(fields, manifestField{path: []string{"spec", "startingCSV"}, value: config.StartingCSV})
+	}
+	if config.Flavor == FlavorCommunity {
+		fields = append(fields, manifestField{path: []string{"spec", "name"}, value: communityPackage})
+	}
+	return renderManifestTemplate(subscriptionTemplate, managedCluster, fields...)
+}
+
+// installNamespaceManifest renders the namespace the hub is installed into, with the labels and
+// annotations of the hub configuration. The namespace without labels nor annotations is rendered as
+// it always was, so the manifestworks of the fleet are not updated when they are not configured.
+func installNamespaceManifest(config *HubConfig) []byte {
+	if len(config.NamespaceLabels) == 0 && len(config.NamespaceAnnotations) == 0 {
+		return []byte(`{
+	"apiVersion": "v1",
+	"kind": "Namespace",
+	"metadata": {
+		"name": "open-cluster-management"
+	}
+}`)
+	}
+	metadata := map[string]interface{}{
+		"name": "open-cluster-management",
+	}
+	if len(config.NamespaceLabels) > 0 {
+		metadata["labels"] = config.NamespaceLabels
+	}
+	if len(config.NamespaceAnnotations) > 0 {
+		metadata["annotations"] = config.NamespaceAnnotations
+	}
+	// marshaling generic JSON values does not fail
+	raw, _ := json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   metadata,
+	}, "", "\t")
+	return raw
+}
+
+// builtinSubscriptionManifest renders the built-in operator subscription from the channel, starting
+// CSV and operator package of the product flavor of the hub configuration, and the given catalog
+// source and its namespace.
+func builtinSubscriptionManifest(config *HubConfig, source, sourceNamespace string) []byte {
+	spec := map[string]interface{}{
+		"channel":             config.Channel,
+		"installPlanApproval": "Automatic",
+		"name":                subscriptionPackage(config),
+		"source":              source,
+		"sourceNamespace":     sourceNamespace,
+	}
+	if config.StartingCSV != "" {
+		spec["startingCSV"] = config.StartingCSV
+	}
+	// marshaling generic JSON values does not fail
+	raw, _ := json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "operators.coreos.com/v1alpha1",
+		"kind":       "Subscription",
+		"metadata": map[string]interface{}{
+			"name":      "acm-operator-subscription",
+			"namespace": "open-cluster-management",
+		},
+		"spec": spec,
+	}, "", "\t")
+	return raw
+}
+
+type manifestField struct {
+	path  []string
+	value interface{}
+}
+
+// mchEnforcedFields returns the fields of the MultiClusterHub enforced by the controller, the user
+// defined values of these fields are reported as conflicts and replaced. The self management of the
+// managed hubs is disabled unless configured otherwise, a managed hub importing itself would nest
+// its own cluster under the hub of hubs.
+func mchEnforcedFields(disableHubSelfManagement bool) []manifestField {
+	return []manifestField{
+		{path: []string{"apiVersion"}, value: "operator.open-cluster-management.io/v1"},
+		{path: []string{"kind"}, value: "MultiClusterHub"},
+		{path: []string{"metadata", "name"}, value: "multiclusterhub"},
+		{path: []string{"metadata", "namespace"}, value: "open-cluster-management"},
+		{path: []string{"spec", "disableHubSelfManagement"}, value: disableHubSelfManagement},
+	}
+}
+
+// RenderMCH merges the given user defined MultiClusterHubs in order onto the default one, like merge
+// patches: the objects are merged field by field, the lists and other values are replaced and null
+// removes a field, except for the lists of mchKeyedLists whose items are merged by their key like
+// strategic merge patches. It returns the fields enforced by the controller which were overridden, as
+// conflicts. The default MultiClusterHub, or the MultiClusterHub template rendered for the managed
+// cluster if one is loaded, is returned as is if no MultiClusterHub is given.
+func RenderMCH(managedCluster *clusterv1.ManagedCluster, disableHubSelfManagement bool,
+	userDefinedMCHs ...string) ([]byte, []string, error) {
+	enforcedFields := mchEnforcedFields(disableHubSelfManagement)
+	mchJson := []byte(fmt.Sprintf(`{
 		"apiVersion": "operator.open-cluster-management.io/v1",
 		"kind": "MultiClusterHub",
 		"metadata": {
@@ -150,37 +430,206 @@ func CreateMCHManifestwork(namespace, userDefinedMCH string) (*workv1.ManifestWo
 			"namespace":"open-cluster-management"
 		},
 		"spec": {
-			"disableHubSelfManagement": true
+			"disableHubSelfManagement": %t
 		}
-	}`
-	if userDefinedMCH != "" {
-		var mch interface{}
-		err := json.Unmarshal([]byte(userDefinedMCH), &mch)
+	}`, disableHubSelfManagement))
+	if mchTemplate != nil {
+		var err error
+		if mchJson, err = renderManifestTemplate(mchTemplate, managedCluster, enforcedFields...); err != nil {
+			return nil, nil, err
+		}
+	}
+	merged := false
+	var conflicts []string
+	for _, userDefinedMCH := range userDefinedMCHs {
+		if userDefinedMCH == "" {
+			continue
+		}
+		var mch map[string]interface{}
+		if err := json.Unmarshal([]byte(userDefinedMCH), &mch); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := mch["spec"].(map[string]interface{}); !ok {
+			return nil, nil, fmt.Errorf("the multiclusterhub has no spec")
+		}
+		for _, field := range enforcedFields {
+			if value, ok := nestedField(mch, field.path); ok && value != field.value {
+				conflicts = append(conflicts, strings.Join(field.path, "."))
+			}
+		}
+		patched, err := mergeMCH(mchJson, mch)
+		if err != nil {
+			return nil, nil, err
+		}
+		mchJson, merged = patched, true
+	}
+	if !merged {
+		return mchJson, nil, nil
+	}
+
+	var mch map[string]interface{}
+	if err := json.Unmarshal(mchJson, &mch); err != nil {
+		return nil, nil, err
+	}
+	for _, field := range enforcedFields {
+		setNestedField(mch, field.path, field.value)
+	}
+	mchJson, err := json.Marshal(mch)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mchJson, conflicts, nil
+}
+
+// mchKeyedLists are the lists of the MultiClusterHub whose items are merged by the given key, so a
+// user defined MultiClusterHub toggling a single component keeps the other components of the
+// configuration.
+var mchKeyedLists = []struct {
+	path []string
+	key  string
+}{
+	{path: []string{"spec", "overrides", "components"}, key: "name"},
+}
+
+// mergeMCH merges the user defined MultiClusterHub onto the given one like a merge patch, the items of
+// the keyed lists being merged by their key: the items of the user defined list replace the fields of
+// the items with the same key, and the items with a new key are appended.
+func mergeMCH(mchJson []byte, userDefinedMCH map[string]interface{}) ([]byte, error) {
+	var mch map[string]interface{}
+	if err := json.Unmarshal(mchJson, &mch); err != nil {
+		return nil, err
+	}
+	patch, err := json.Marshal(userDefinedMCH)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := jsonpatch.MergePatch(mchJson, patch)
+	if err != nil {
+		return nil, err
+	}
+	var merged map[string]interface{}
+	if err := json.Unmarshal(patched, &merged); err != nil {
+		return nil, err
+	}
+	for _, list := range mchKeyedLists {
+		existing, _ := nestedField(mch, list.path)
+		existingItems, ok := existing.([]interface{})
+		if !ok {
+			continue
+		}
+		userDefined, _ := nestedField(userDefinedMCH, list.path)
+		userDefinedItems, ok := userDefined.([]interface{})
+		if !ok {
+			continue
+		}
+		items, err := mergeKeyedList(existingItems, userDefinedItems, list.key)
+		if err != nil {
+			return nil, err
+		}
+		setNestedField(merged, list.path, items)
+	}
+	return json.Marshal(merged)
+}
+
+// mergeKeyedList merges the user defined items onto the existing ones by their key, the existing
+// items keep their order.
+func mergeKeyedList(existing, userDefined []interface{}, key string) ([]interface{}, error) {
+	items := append([]interface{}{}, existing...)
+	index := map[interface{}]int{}
+	for i, item := range items {
+		if object, ok := item.(map[string]interface{}); ok && object[key] != nil {
+			index[object[key]] = i
+		}
+	}
+	for _, item := range userDefined {
+		object, ok := item.(map[string]interface{})
+		if !ok || object[key] == nil {
+			items = append(items, item)
+			continue
+		}
+		i, ok := index[object[key]]
+		if !ok {
+			index[object[key]] = len(items)
+			items = append(items, item)
+			continue
+		}
+		existingJson, err := json.Marshal(items[i])
 		if err != nil {
 			return nil, err
 		}
-		mch.(map[string]interface{})["spec"].(map[string]interface{})["disableHubSelfManagement"] = true
-		mchBytes, err := json.Marshal(mch)
+		itemJson, err := json.Marshal(item)
 		if err != nil {
 			return nil, err
 		}
-		mchJson = string(mchBytes)
+		mergedJson, err := jsonpatch.MergePatch(existingJson, itemJson)
+		if err != nil {
+			return nil, err
+		}
+		var merged interface{}
+		if err := json.Unmarshal(mergedJson, &merged); err != nil {
+			return nil, err
+		}
+		items[i] = merged
+	}
+	return items, nil
+}
+
+func nestedField(obj map[string]interface{}, path []string) (interface{}, bool) {
+	for _, name := range path[:len(path)-1] {
+		var ok bool
+		if obj, ok = obj[name].(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	value, ok := obj[path[len(path)-1]]
+	return value, ok
+}
+
+func setNestedField(obj map[string]interface{}, path []string, value interface{}) {
+	for _, name := range path[:len(path)-1] {
+		child, ok := obj[name].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			obj[name] = child
+		}
+		obj = child
+	}
+	obj[path[len(path)-1]] = value
+}
+
+// CreateMCHManifestwork returns the mch manifestwork installing the user defined MultiClusterHub
+// merged onto the default one, with the self management of the hub disabled.
+func CreateMCHManifestwork(namespace, userDefinedMCH string) (*workv1.ManifestWork, error) {
+	mch, _, err := RenderMCH(&clusterv1.ManagedCluster{}, true, userDefinedMCH)
+	if err != nil {
+		return nil, err
 	}
+	return newMCHManifestwork(namespace, mch), nil
+}
+
+// newMCHManifestwork returns the mch manifestwork installing the given MultiClusterHub and the
+// additional manifests it depends on
+func newMCHManifestwork(namespace string, mchJson []byte, manifests ...workv1.Manifest) *workv1.ManifestWork {
 	return &workv1.ManifestWork{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: workv1.GroupVersion.String(),
+			Kind:       "ManifestWork",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      namespace + "-" + HOH_HUB_CLUSTER_MCH,
+			Name:      WorkNaming{}.Name(namespace, HOH_HUB_CLUSTER_MCH),
 			Namespace: namespace,
 			Labels: map[string]string{
-				"hub-of-hubs.open-cluster-management.io/managed-by": "hoh",
+				MANAGED_BY_LABEL:      MANAGED_BY_VALUE,
+				MANAGED_CLUSTER_LABEL: namespace,
+				BACKUP_LABEL:          BACKUP_VALUE,
+				WORK_TYPE_LABEL:       HOH_HUB_CLUSTER_MCH,
 			},
 		},
 		Spec: workv1.ManifestWorkSpec{
 			Workload: workv1.ManifestsTemplate{
-				Manifests: []workv1.Manifest{
-					{RawExtension: runtime.RawExtension{
-						Raw: []byte(mchJson),
-					}},
-				},
+				Manifests: append(manifests, workv1.Manifest{
+					RawExtension: runtime.RawExtension{Raw: mchJson},
+				}),
 			},
 			ManifestConfigs: []workv1.ManifestConfigOption{
 				{
@@ -195,16 +644,11 @@ func CreateMCHManifestwork(namespace, userDefinedMCH string) (*workv1.ManifestWo
 							Type: workv1.JSONPathsType,
 							JsonPaths: []workv1.JsonPath{
 								{
-									Name: "state",
+									Name: MCH_PHASE_FEEDBACK,
 									Path: ".status.phase",
 								},
-							},
-						},
-						{
-							Type: workv1.JSONPathsType,
-							JsonPaths: []workv1.JsonPath{
 								{
-									Name: "currentVersion",
+									Name: MCH_VERSION_FEEDBACK,
 									Path: ".status.currentVersion",
 								},
 							},
@@ -213,23 +657,257 @@ func CreateMCHManifestwork(namespace, userDefinedMCH string) (*workv1.ManifestWo
 				},
 			},
 		},
-	}, nil
+	}
 }
 
+// EnsureManifestWork returns true if the spec of the existing manifestwork semantically differs
+// from the desired one. Formatting differences of the raw manifests, the order of the manifest
+// configs and fields not rendered by the controller, such as the ones defaulted by the server, are
+// ignored.
 func EnsureManifestWork(existing, desired *workv1.ManifestWork) (bool, error) {
-	// compare the manifests
-	existingBytes, err := json.Marshal(existing.Spec)
+	desiredSpec, err := normalizeSpec(desired.Spec)
 	if err != nil {
 		return false, err
 	}
-	desiredBytes, err := json.Marshal(desired.Spec)
+	existingSpec, err := normalizeSpec(existing.Spec)
 	if err != nil {
 		return false, err
 	}
-	if string(existingBytes) != string(desiredBytes) {
-		klog.V(2).Infof("the existing manifestwork is %s", string(existingBytes))
-		klog.V(2).Infof("the desired manifestwork is %s", string(desiredBytes))
-		return true, nil
+	for field := range existingSpec {
+		if _, ok := desiredSpec[field]; !ok {
+			delete(existingSpec, field)
+		}
+	}
+
+	if equality.Semantic.DeepEqual(existingSpec, desiredSpec) {
+		return false, nil
+	}
+	if klog.V(4).Enabled() {
+		klog.Infof("the manifestwork %s in %s namespace differs from the desired one (-existing +desired):\n%s",
+			desired.Name, desired.Namespace, specDiff(existingSpec, desiredSpec))
+	}
+	return true, nil
+}
+
+// specDiff returns a line based diff between the normalized existing and desired specs, the data of
+// the propagated secrets being redacted
+func specDiff(existingSpec, desiredSpec map[string]interface{}) string {
+	return cmp.Diff(dryrun.Redact(existingSpec), dryrun.Redact(desiredSpec))
+}
+
+// normalizeSpec converts the spec into generic JSON values with the manifest configs sorted by
+// their resource identifier.
+func normalizeSpec(spec workv1.ManifestWorkSpec) (map[string]interface{}, error) {
+	specBytes, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(specBytes, &fields); err != nil {
+		return nil, err
+	}
+
+	configs, ok := fields["manifestConfigs"].([]interface{})
+	if !ok {
+		return fields, nil
+	}
+	keys := map[int]string{}
+	for i, config := range configs {
+		key, err := json.Marshal(config.(map[string]interface{})["resourceIdentifier"])
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = string(key)
+	}
+	sorted := make([]int, len(configs))
+	for i := range sorted {
+		sorted[i] = i
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return keys[sorted[i]] < keys[sorted[j]] })
+	sortedConfigs := make([]interface{}, len(configs))
+	for i, index := range sorted {
+		sortedConfigs[i] = configs[index]
+	}
+	fields["manifestConfigs"] = sortedConfigs
+	return fields, nil
+}
+
+// GetFeedbackValue returns the string status feedback value with the given name reported for
+// the manifest of the given kind, or an empty string if the work agent has not reported it yet.
+func GetFeedbackValue(work *workv1.ManifestWork, kind, name string) string {
+	if work == nil {
+		return ""
+	}
+	for _, manifest := range work.Status.ResourceStatus.Manifests {
+		if manifest.ResourceMeta.Kind != kind {
+			continue
+		}
+		for _, value := range manifest.StatusFeedbacks.Values {
+			if value.Name == name && value.Value.String != nil {
+				return *value.Value.String
+			}
+		}
+	}
+	return ""
+}
+
+// GetFeedbackInteger returns the integer status feedback value with the given name reported for the
+// manifest of the given kind, or false if the work agent has not reported it yet.
+func GetFeedbackInteger(work *workv1.ManifestWork, kind, name string) (int64, bool) {
+	if work == nil {
+		return 0, false
+	}
+	for _, manifest := range work.Status.ResourceStatus.Manifests {
+		if manifest.ResourceMeta.Kind != kind {
+			continue
+		}
+		for _, value := range manifest.StatusFeedbacks.Values {
+			if value.Name == name && value.Value.Integer != nil {
+				return *value.Value.Integer, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// IsResourceMissing returns true if the work agent reports the resource of the given kind does not
+// exist on the managed cluster, although the manifestwork still exists.
+func IsResourceMissing(work *workv1.ManifestWork, kind string) bool {
+	if work == nil {
+		return false
+	}
+	for _, manifest := range work.Status.ResourceStatus.Manifests {
+		if manifest.ResourceMeta.Kind == kind {
+			return meta.IsStatusConditionFalse(manifest.Conditions, string(workv1.ManifestAvailable))
+		}
+	}
+	return false
+}
+
+// SetSpecHash stamps the hash of the rendered spec on the manifestwork, so that an unchanged
+// manifestwork is detected without comparing the whole spec.
+func SetSpecHash(work *workv1.ManifestWork) error {
+	hash, err := specHash(work.Spec)
+	if err != nil {
+		return err
+	}
+	if work.Annotations == nil {
+		work.Annotations = map[string]string{}
+	}
+	work.Annotations[SPEC_HASH_ANNOTATION] = hash
+	return nil
+}
+
+// SpecUnchanged returns true if the existing manifestwork was rendered with the spec of the desired
+// one, stamped by SetSpecHash, and its spec was not edited since. The spec of the existing
+// manifestwork is hashed again rather than trusting its annotation, so the spec edited by hand is
+// corrected on the next sync.
+func SpecUnchanged(existing, desired *workv1.ManifestWork) bool {
+	if existing.Annotations[SPEC_HASH_ANNOTATION] != desired.Annotations[SPEC_HASH_ANNOTATION] {
+		return false
+	}
+	hash, err := specHash(existing.Spec)
+	return err == nil && hash == desired.Annotations[SPEC_HASH_ANNOTATION]
+}
+
+func specHash(spec workv1.ManifestWorkSpec) (string, error) {
+	specBytes, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(specBytes)), nil
+}
+
+// SetAuditAnnotations stamps the controller version, the render time and the configuration
+// generation on the manifestwork, so it can be told which controller release last changed it and
+// when. It is only stamped on the manifestworks being written, so unchanged manifestworks are not
+// patched on every sync.
+func SetAuditAnnotations(work *workv1.ManifestWork, controllerVersion, configGeneration string, now time.Time) {
+	if work.Annotations == nil {
+		work.Annotations = map[string]string{}
+	}
+	if controllerVersion != "" {
+		work.Annotations[CONTROLLER_VERSION_ANNOTATION] = controllerVersion
+	}
+	work.Annotations[RENDERED_AT_ANNOTATION] = now.UTC().Format(time.RFC3339)
+	if configGeneration != "" {
+		work.Annotations[CONFIG_GENERATION_ANNOTATION] = configGeneration
+	}
+}
+
+// ManifestWorkMergePatch returns a JSON merge patch updating the labels, annotations and the spec
+// of the existing manifestwork to the desired ones, only the changed fields are included in the
+// patch. Labels and annotations not rendered by the controller are left untouched.
+func ManifestWorkMergePatch(existing, desired *workv1.ManifestWork) ([]byte, error) {
+	existingBytes, err := json.Marshal(workPatchFields(
+		renderedKeys(existing.Labels, desired.Labels),
+		renderedKeys(existing.Annotations, desired.Annotations),
+		existing.Spec))
+	if err != nil {
+		return nil, err
+	}
+	desiredBytes, err := json.Marshal(workPatchFields(desired.Labels, desired.Annotations, desired.Spec))
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.CreateMergePatch(existingBytes, desiredBytes)
+}
+
+// SetManagedClusterUID stamps the UID of the managed cluster the manifestwork is created for
+func SetManagedClusterUID(work *workv1.ManifestWork, managedCluster *clusterv1.ManagedCluster) {
+	if work.Annotations == nil {
+		work.Annotations = map[string]string{}
+	}
+	work.Annotations[MANAGED_CLUSTER_UID_ANNOTATION] = string(managedCluster.UID)
+}
+
+// IsStale returns true if the manifestwork was created for a previous managed cluster with the same
+// name. The manifestworks created before the UID was stamped are adopted by the current cluster, and
+// so are the restored manifestworks not adopted yet: the managed clusters get a new UID when they are
+// imported again on a restored hub of hubs, while their hubs are still installed.
+func IsStale(work *workv1.ManifestWork, managedCluster *clusterv1.ManagedCluster) bool {
+	uid := work.Annotations[MANAGED_CLUSTER_UID_ANNOTATION]
+	return uid != "" && uid != string(managedCluster.UID) && !IsRestored(work)
+}
+
+// IsRestored returns true if the manifestwork was restored from a backup and is not adopted yet by the
+// managed cluster imported again.
+func IsRestored(work *workv1.ManifestWork) bool {
+	restore := work.Labels[RESTORE_NAME_LABEL]
+	return restore != "" && work.Annotations[RESTORE_ADOPTED_ANNOTATION] != restore
+}
+
+// HasLabels returns true if the existing manifestwork has all the labels of the desired manifestwork
+func HasLabels(existing, desired *workv1.ManifestWork) bool {
+	for key, value := range desired.Labels {
+		if existing.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// renderedKeys returns the existing entries whose keys are rendered by the controller
+func renderedKeys(existing, desired map[string]string) map[string]string {
+	rendered := map[string]string{}
+	for key := range desired {
+		if value, ok := existing[key]; ok {
+			rendered[key] = value
+		}
+	}
+	return rendered
+}
+
+func workPatchFields(labels, annotations map[string]string, spec workv1.ManifestWorkSpec) map[string]interface{} {
+	metadata := map[string]interface{}{}
+	if len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	return map[string]interface{}{
+		"metadata": metadata,
+		"spec":     spec,
 	}
-	return false, nil
 }
@@ -1,9 +1,19 @@
 package cluster
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	workv1 "open-cluster-management.io/api/work/v1"
 )
 
 func TestCreateMCHManifestwork(t *testing.T) {
@@ -28,4 +38,392 @@ func TestCreateMCHManifestwork(t *testing.T) {
 	if !strings.Contains(string(mchByte), "disableHubSelfManagement") {
 		t.Fatalf("failed to find disableHubSelfManagement")
 	}
+	if mch.Labels[MANAGED_BY_LABEL] != MANAGED_BY_VALUE {
+		t.Errorf("expected the mch manifestwork to be labeled %s, got %v", MANAGED_BY_SELECTOR, mch.Labels)
+	}
+}
+
+func TestRenderMCH(t *testing.T) {
+	mch, conflicts, err := RenderMCH(newManagedCluster("cluster1"), true,
+		`{"spec":{"availabilityConfig":"Basic","nodeSelector":{"infra":"true"}}}`,
+		`{"metadata":{"name":"hub"},"spec":{"nodeSelector":{"zone":"a"},"disableHubSelfManagement":false}}`,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"apiVersion": "operator.open-cluster-management.io/v1",
+		"kind":       "MultiClusterHub",
+		"metadata":   map[string]interface{}{"name": "multiclusterhub", "namespace": "open-cluster-management"},
+		"spec": map[string]interface{}{
+			"availabilityConfig":       "Basic",
+			"nodeSelector":             map[string]interface{}{"infra": "true", "zone": "a"},
+			"disableHubSelfManagement": true,
+		},
+	}
+	actual := map[string]interface{}{}
+	if err := json.Unmarshal(mch, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected the merged mch %v, got %v", expected, actual)
+	}
+	if strings.Join(conflicts, ",") != "metadata.name,spec.disableHubSelfManagement" {
+		t.Errorf("expected the conflicts of the enforced fields, got %v", conflicts)
+	}
+
+	if _, conflicts, err := RenderMCH(newManagedCluster("cluster1"), true, "", ""); err != nil || conflicts != nil {
+		t.Errorf("expected the default mch without conflict, got %v, %v", conflicts, err)
+	}
+
+	// the self management is enforced as configured
+	mch, conflicts, err = RenderMCH(newManagedCluster("cluster1"), false, `{"spec":{"disableHubSelfManagement":true}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(mch), `"disableHubSelfManagement":false`) {
+		t.Errorf("expected the self management to be enabled, got %s", mch)
+	}
+	if strings.Join(conflicts, ",") != "spec.disableHubSelfManagement" {
+		t.Errorf("expected the conflict of the self management, got %v", conflicts)
+	}
+	if mch, _, _ := RenderMCH(newManagedCluster("cluster1"), false); !strings.Contains(string(mch), `"disableHubSelfManagement": false`) {
+		t.Errorf("expected the default mch to enable the self management, got %s", mch)
+	}
+}
+
+func TestRenderMCHMergesComponentsByName(t *testing.T) {
+	config := DefaultHubConfig()
+	config.MCHComponents = map[string]bool{"console": true, "search": false, "volsync": true}
+	mch, _, err := RenderMCH(newManagedCluster("cluster1"), true, componentsMCH(config),
+		`{"spec":{"overrides":{"components":[{"name":"search","enabled":true},{"name":"insights","enabled":false}]}}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual := map[string]interface{}{}
+	if err := json.Unmarshal(mch, &actual); err != nil {
+		t.Fatal(err)
+	}
+	// the single overridden component is toggled, the other configured ones are kept
+	expected := []interface{}{
+		map[string]interface{}{"name": "console", "enabled": true},
+		map[string]interface{}{"name": "search", "enabled": true},
+		map[string]interface{}{"name": "volsync", "enabled": true},
+		map[string]interface{}{"name": "insights", "enabled": false},
+	}
+	if components, _ := nestedField(actual, []string{"spec", "overrides", "components"}); !reflect.DeepEqual(expected, components) {
+		t.Errorf("expected the components %v, got %v", expected, components)
+	}
+}
+
+func TestCreateSubManifestworkLabel(t *testing.T) {
+	sub := CreateSubManifestwork("test", DefaultHubConfig())
+	if sub.Labels[MANAGED_BY_LABEL] != MANAGED_BY_VALUE {
+		t.Errorf("expected the subscription manifestwork to be labeled %s, got %v", MANAGED_BY_SELECTOR, sub.Labels)
+	}
+	if _, ok := sub.Labels[BACKUP_LABEL]; !ok {
+		t.Errorf("expected the subscription manifestwork to be labeled for the backup, got %v", sub.Labels)
+	}
+}
+
+func TestWorkNaming(t *testing.T) {
+	if err := (WorkNaming{Prefix: "Invalid_"}).Validate(); err == nil {
+		t.Errorf("expected the invalid prefix to be reported")
+	}
+	naming := WorkNaming{Prefix: "team-a-", Suffix: "-v2"}
+	if err := naming.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sub := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	if sub.Name != "cluster1-"+HOH_HUB_CLUSTER_SUBSCRIPTION {
+		t.Errorf("expected the manifestwork to be rendered with the default name, got %s", sub.Name)
+	}
+	naming.setName(sub)
+	if sub.Name != "team-a-cluster1-"+HOH_HUB_CLUSTER_SUBSCRIPTION+"-v2" {
+		t.Errorf("expected the manifestwork name to have the prefix and suffix, got %s", sub.Name)
+	}
+	if !IsWorkOfType(sub, HOH_HUB_CLUSTER_SUBSCRIPTION) || IsWorkOfType(sub, HOH_HUB_CLUSTER_MCH) {
+		t.Errorf("expected the manifestwork to be found by its type label %v", sub.Labels)
+	}
+
+	// the manifestworks created before the type label are found by the suffix of their name
+	delete(sub.Labels, WORK_TYPE_LABEL)
+	sub.Name = "cluster1-" + HOH_HUB_CLUSTER_SUBSCRIPTION
+	if !IsWorkOfType(sub, HOH_HUB_CLUSTER_SUBSCRIPTION) || IsWorkOfType(sub, HOH_HUB_CLUSTER_MCH) {
+		t.Errorf("expected the unlabeled manifestwork to be found by its name %s", sub.Name)
+	}
+}
+
+func TestMCHFeedbackRules(t *testing.T) {
+	mch, err := CreateMCHManifestwork("test", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	paths := map[string]string{}
+	for _, config := range mch.Spec.ManifestConfigs {
+		for _, rule := range config.FeedbackRules {
+			for _, path := range rule.JsonPaths {
+				paths[path.Name] = path.Path
+			}
+		}
+	}
+	if paths[MCH_PHASE_FEEDBACK] != ".status.phase" {
+		t.Errorf("expected phase feedback rule, got %v", paths)
+	}
+	if paths[MCH_VERSION_FEEDBACK] != ".status.currentVersion" {
+		t.Errorf("expected currentVersion feedback rule, got %v", paths)
+	}
+}
+
+func TestGetFeedbackValue(t *testing.T) {
+	running := MCH_PHASE_RUNNING
+	mch, _ := CreateMCHManifestwork("test", "")
+	if v := GetFeedbackValue(mch, "MultiClusterHub", MCH_PHASE_FEEDBACK); v != "" {
+		t.Errorf("expected no feedback value, got %q", v)
+	}
+	mch.Status.ResourceStatus.Manifests = []workv1.ManifestCondition{
+		{
+			ResourceMeta: workv1.ManifestResourceMeta{Kind: "MultiClusterHub"},
+			StatusFeedbacks: workv1.StatusFeedbackResult{
+				Values: []workv1.FeedbackValue{
+					{Name: MCH_PHASE_FEEDBACK, Value: workv1.FieldValue{Type: workv1.String, String: &running}},
+				},
+			},
+		},
+	}
+	if v := GetFeedbackValue(mch, "MultiClusterHub", MCH_PHASE_FEEDBACK); v != MCH_PHASE_RUNNING {
+		t.Errorf("expected %q, got %q", MCH_PHASE_RUNNING, v)
+	}
+}
+
+func TestManifestWorkMergePatch(t *testing.T) {
+	existing, _ := CreateMCHManifestwork("test", "")
+	existing.Labels["other"] = "value"
+	existing.ResourceVersion = "1"
+	desired, _ := CreateMCHManifestwork("test", `{
+		"apiVersion": "operator.open-cluster-management.io/v1",
+		"kind": "MultiClusterHub",
+		"metadata": {"name": "multiclusterhub", "namespace": "open-cluster-management"},
+		"spec": {"imagePullSecret": "pull-secret"}
+	}`)
+
+	patch, err := ManifestWorkMergePatch(existing, desired)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["metadata"]; ok {
+		t.Errorf("expected unchanged labels not to be patched, got %s", string(patch))
+	}
+	spec, ok := fields["spec"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected the spec to be patched, got %s", string(patch))
+	}
+	if _, ok := spec["workload"]; !ok {
+		t.Errorf("expected the workload to be patched, got %s", string(patch))
+	}
+	if _, ok := spec["manifestConfigs"]; ok {
+		t.Errorf("expected unchanged manifestConfigs not to be patched, got %s", string(patch))
+	}
+	if strings.Contains(string(patch), "resourceVersion") {
+		t.Errorf("expected no resourceVersion in the patch, got %s", string(patch))
+	}
+}
+
+func TestEnsureManifestWork(t *testing.T) {
+	compact := func(work *workv1.ManifestWork) *workv1.ManifestWork {
+		for i, manifest := range work.Spec.Workload.Manifests {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, manifest.Raw); err != nil {
+				t.Fatal(err)
+			}
+			work.Spec.Workload.Manifests[i].Raw = buf.Bytes()
+		}
+		return work
+	}
+
+	cases := []struct {
+		name            string
+		existing        func() *workv1.ManifestWork
+		expectedUpdated bool
+	}{
+		{
+			name:            "unchanged",
+			existing:        func() *workv1.ManifestWork { return CreateSubManifestwork("test", DefaultHubConfig()) },
+			expectedUpdated: false,
+		},
+		{
+			name:            "manifests formatted by the server",
+			existing:        func() *workv1.ManifestWork { return compact(CreateSubManifestwork("test", DefaultHubConfig())) },
+			expectedUpdated: false,
+		},
+		{
+			name: "manifest config added",
+			existing: func() *workv1.ManifestWork {
+				work := CreateSubManifestwork("test", DefaultHubConfig())
+				work.Spec.ManifestConfigs = append([]workv1.ManifestConfigOption{{
+					ResourceIdentifier: workv1.ResourceIdentifier{Resource: "namespaces", Name: "open-cluster-management"},
+				}}, work.Spec.ManifestConfigs...)
+				return work
+			},
+			expectedUpdated: true,
+		},
+		{
+			name: "delete option defaulted",
+			existing: func() *workv1.ManifestWork {
+				work := CreateSubManifestwork("test", DefaultHubConfig())
+				work.Spec.DeleteOption = &workv1.DeleteOption{PropagationPolicy: workv1.DeletePropagationPolicyTypeForeground}
+				return work
+			},
+			expectedUpdated: false,
+		},
+		{
+			name: "manifest changed",
+			existing: func() *workv1.ManifestWork {
+				work := CreateSubManifestwork("test", DefaultHubConfig())
+				work.Spec.Workload.Manifests = work.Spec.Workload.Manifests[1:]
+				return work
+			},
+			expectedUpdated: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			updated, err := EnsureManifestWork(c.existing(), CreateSubManifestwork("test", DefaultHubConfig()))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated != c.expectedUpdated {
+				t.Errorf("expected updated %v, got %v", c.expectedUpdated, updated)
+			}
+		})
+	}
+}
+
+func TestEnsureManifestWorkIgnoresConfigOrder(t *testing.T) {
+	desired := CreateSubManifestwork("test", DefaultHubConfig())
+	desired.Spec.ManifestConfigs = append(desired.Spec.ManifestConfigs, workv1.ManifestConfigOption{
+		ResourceIdentifier: workv1.ResourceIdentifier{Resource: "namespaces", Name: "open-cluster-management"},
+	})
+	existing := desired.DeepCopy()
+	existing.Spec.ManifestConfigs[0], existing.Spec.ManifestConfigs[1] =
+		existing.Spec.ManifestConfigs[1], existing.Spec.ManifestConfigs[0]
+
+	updated, err := EnsureManifestWork(existing, desired)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated {
+		t.Errorf("expected reordered manifest configs to be equal")
+	}
+}
+
+func TestSpecDiff(t *testing.T) {
+	existing := CreateSubManifestwork("test", DefaultHubConfig())
+	existing.Spec.ManifestConfigs[0].ResourceIdentifier.Name = "old-subscription"
+	existingSpec, err := normalizeSpec(existing.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desiredSpec, err := normalizeSpec(CreateSubManifestwork("test", DefaultHubConfig()).Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := specDiff(existingSpec, desiredSpec)
+	if !strings.Contains(diff, `-`) || !strings.Contains(diff, `"old-subscription"`) ||
+		!strings.Contains(diff, `"acm-operator-subscription"`) {
+		t.Errorf("expected the diff to show the changed name, got %s", diff)
+	}
+	if strings.Contains(diff, "channel") {
+		t.Errorf("expected unchanged manifests not to be in the diff, got %s", diff)
+	}
+}
+
+// rotatedSpecDiff returns the diff logged when the secret is rotated from the old to the new value,
+// between the works rendered by the given function before and after the rotation. The other secrets
+// are not rotated.
+func rotatedSpecDiff(t *testing.T, secret *corev1.Secret, key, old, new string,
+	render func(c *clusterController) (*workv1.ManifestWork, error), others ...*corev1.Secret) string {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, other := range others {
+		if err := indexer.Add(other); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &clusterController{
+		options:      ControllerOptions{ConfigNamespace: "test"},
+		secretLister: corev1listers.NewSecretLister(indexer).Secrets("test"),
+	}
+	secret = secret.DeepCopy()
+	secret.Data = map[string][]byte{key: []byte(old)}
+	if err := indexer.Add(secret); err != nil {
+		t.Fatal(err)
+	}
+	existing, err := render(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := secret.DeepCopy()
+	rotated.Data[key] = []byte(new)
+	if err := indexer.Update(rotated); err != nil {
+		t.Fatal(err)
+	}
+	desired, err := render(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existingSpec, err := normalizeSpec(existing.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desiredSpec, err := normalizeSpec(desired.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return specDiff(existingSpec, desiredSpec)
+}
+
+// expectRedacted fails if the values of the secret, or their base64 encoding, are in the diff
+func expectRedacted(t *testing.T, diff string, values ...string) {
+	t.Helper()
+	for _, value := range values {
+		if strings.Contains(diff, value) || strings.Contains(diff, base64.StdEncoding.EncodeToString([]byte(value))) {
+			t.Errorf("expected the secret value %q to be redacted from the diff, got %s", value, diff)
+		}
+	}
+}
+
+func TestSpecDiffRedactsSecrets(t *testing.T) {
+	config := DefaultHubConfig()
+	config.TransportSecret = "kafka-credentials"
+	diff := rotatedSpecDiff(t, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "kafka-credentials", Namespace: "test"}},
+		"sasl.password", "old-kafka-password", "new-kafka-password",
+		func(c *clusterController) (*workv1.ManifestWork, error) {
+			return c.desiredAgentManifestWork(newManagedCluster("cluster1"), config)
+		})
+	expectRedacted(t, diff, "old-kafka-password", "new-kafka-password")
+	if !strings.Contains(diff, SECRET_HASH_ANNOTATION) {
+		t.Errorf("expected the diff to still show the rollout of the agent, got %s", diff)
+	}
+}
+
+func TestSetAuditAnnotations(t *testing.T) {
+	work := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	SetAuditAnnotations(work, "v2.4.0", "", now)
+
+	if work.Annotations[CONTROLLER_VERSION_ANNOTATION] != "v2.4.0" {
+		t.Errorf("expected the controller version v2.4.0, got %v", work.Annotations)
+	}
+	if work.Annotations[RENDERED_AT_ANNOTATION] != "2022-03-01T12:00:00Z" {
+		t.Errorf("expected the render time 2022-03-01T12:00:00Z, got %v", work.Annotations)
+	}
+	if _, ok := work.Annotations[CONFIG_GENERATION_ANNOTATION]; ok {
+		t.Errorf("expected no config generation for the default configuration, got %v", work.Annotations)
+	}
 }
@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// MARKETPLACE_CLAIM is the ClusterClaim reporting whether the default catalogs of the OperatorHub of
+// the managed cluster are enabled, it is false on the managed clusters whose default catalogs are
+// disabled, such as the disconnected ones.
+const MARKETPLACE_CLAIM = "marketplace.hub-of-hubs.open-cluster-management.io"
+
+// MARKETPLACE_NAMESPACE is the namespace of the catalog sources the operator subscription is resolved
+// from
+const MARKETPLACE_NAMESPACE = "openshift-marketplace"
+
+// HOH_CATALOG_SOURCE is the name of the catalog source serving the catalogSourceImage configuration
+// on the managed clusters whose default catalogs are disabled. It does not take the name of a default
+// catalog, so it never conflicts with the OperatorHub when the default catalogs are enabled again.
+const HOH_CATALOG_SOURCE = "hoh-acm-catalog"
+
+// MarketplaceDisabled returns true if the default catalogs of the managed cluster are disabled, as
+// reported by its marketplace claim.
+func MarketplaceDisabled(managedCluster *clusterv1.ManagedCluster) bool {
+	for _, claim := range managedCluster.Status.ClusterClaims {
+		if claim.Name == MARKETPLACE_CLAIM {
+			return claim.Value == "false" || claim.Value == "disabled"
+		}
+	}
+	return false
+}
+
+// catalogUnavailableMessages are the resolution failures of the operator subscription when its
+// catalog source does not exist or does not serve on the managed cluster
+var catalogUnavailableMessages = []string{
+	"no operators found from catalog",
+	"failed to populate resolver cache from source",
+}
+
+// isCatalogUnavailable returns true if the resolution failure of the operator subscription is caused
+// by its catalog source, rather than by the operator it resolves
+func isCatalogUnavailable(message string) bool {
+	for _, unavailable := range catalogUnavailableMessages {
+		if strings.Contains(message, unavailable) {
+			return true
+		}
+	}
+	return false
+}
+
+// catalogSourceManifests returns the HOH_CATALOG_SOURCE catalog source serving the hub operator from
+// the configured index image, and the role allowing the work agent to create it. They are installed
+// on the managed clusters whose default catalogs are disabled.
+func catalogSourceManifests(image string) []workv1.Manifest {
+	name := "open-cluster-management:hub-cluster-controller:catalog-source"
+	return []workv1.Manifest{
+		{RawExtension: runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{
+	"apiVersion": "rbac.authorization.k8s.io/v1",
+	"kind": "ClusterRole",
+	"metadata": {
+		"name": %q
+	},
+	"rules": [
+		{
+			"apiGroups": ["operators.coreos.com"],
+			"resources": ["catalogsources"],
+			"verbs": ["create", "update", "get", "delete"]
+		}
+	]
+}`, name))}},
+		{RawExtension: runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{
+	"apiVersion": "rbac.authorization.k8s.io/v1",
+	"kind": "ClusterRoleBinding",
+	"metadata": {
+		"name": %q
+	},
+	"roleRef": {
+		"apiGroup": "rbac.authorization.k8s.io",
+		"kind": "ClusterRole",
+		"name": %q
+	},
+	"subjects": [
+		{
+			"kind": "ServiceAccount",
+			"name": "klusterlet-work-sa",
+			"namespace": "open-cluster-management-agent"
+		}
+	]
+}`, name, name))}},
+		{RawExtension: runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{
+	"apiVersion": "operators.coreos.com/v1alpha1",
+	"kind": "CatalogSource",
+	"metadata": {
+		"name": %q,
+		"namespace": %q
+	},
+	"spec": {
+		"displayName": "Advanced Cluster Management",
+		"image": %q,
+		"sourceType": "grpc"
+	}
+}`, HOH_CATALOG_SOURCE, MARKETPLACE_NAMESPACE, image))}},
+	}
+}
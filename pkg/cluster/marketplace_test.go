@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// withMarketplace returns the managed cluster with the given marketplace claim
+func withMarketplace(managedCluster *clusterv1.ManagedCluster, enabled string) *clusterv1.ManagedCluster {
+	managedCluster.Status.ClusterClaims = append(managedCluster.Status.ClusterClaims,
+		clusterv1.ManagedClusterClaim{Name: MARKETPLACE_CLAIM, Value: enabled})
+	return managedCluster
+}
+
+func TestSubManifestWorkInstallsCatalogSource(t *testing.T) {
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_CATALOG_SOURCE_IMAGE_KEY: "registry.example.com/acm/index:v2.4",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled, err := (&clusterController{}).desiredSubManifestWork(withMarketplace(newManagedCluster("cluster1"), "true"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disabled, err := (&clusterController{}).desiredSubManifestWork(withMarketplace(newManagedCluster("cluster1"), "false"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(disabled.Spec.Workload.Manifests) != len(enabled.Spec.Workload.Manifests)+3 {
+		t.Fatalf("expected the catalog source and its role, got %d manifests", len(disabled.Spec.Workload.Manifests))
+	}
+
+	var catalogSource struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Image string `json:"image"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(disabled.Spec.Workload.Manifests[len(disabled.Spec.Workload.Manifests)-1].Raw, &catalogSource); err != nil {
+		t.Fatal(err)
+	}
+	if catalogSource.Kind != "CatalogSource" || catalogSource.Metadata.Name != HOH_CATALOG_SOURCE ||
+		catalogSource.Metadata.Namespace != MARKETPLACE_NAMESPACE || catalogSource.Spec.Image != config.CatalogSourceImage {
+		t.Errorf("unexpected catalog source %+v", catalogSource)
+	}
+	subscriptionSource := func(work *workv1.ManifestWork) string {
+		for _, manifest := range work.Spec.Workload.Manifests {
+			subscription := struct {
+				Kind string `json:"kind"`
+				Spec struct {
+					Source string `json:"source"`
+				} `json:"spec"`
+			}{}
+			if err := json.Unmarshal(manifest.Raw, &subscription); err == nil && subscription.Kind == "Subscription" {
+				return subscription.Spec.Source
+			}
+		}
+		return ""
+	}
+	if source := subscriptionSource(disabled); source != HOH_CATALOG_SOURCE {
+		t.Errorf("expected the subscription to be resolved from the installed catalog source, got %q", source)
+	}
+	if source := subscriptionSource(enabled); source != defaultCatalogSource {
+		t.Errorf("expected the subscription to be resolved from the default catalog source, got %q", source)
+	}
+}
+
+func TestHubConditionsCatalogSourceUnavailable(t *testing.T) {
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription", map[string]string{
+		SUBSCRIPTION_RESOLUTION_FAILED_FEEDBACK: "True",
+		SUBSCRIPTION_RESOLUTION_MESSAGE_FEEDBACK: "constraints not satisfiable: no operators found from catalog " +
+			"redhat-operators in namespace openshift-marketplace referenced by subscription acm-operator-subscription",
+	})
+	degraded := meta.FindStatusCondition(HubConditions(subscription, nil), HubConditionDegraded)
+	if degraded.Reason != "CatalogSourceUnavailable" || !strings.Contains(degraded.Message, MARKETPLACE_CLAIM) {
+		t.Errorf("expected the catalog source to be reported unavailable, got %v", degraded)
+	}
+}
@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	clusterclientv1 "open-cluster-management.io/api/client/cluster/clientset/versioned/typed/cluster/v1"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	workclientv1 "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// mchController applies the manifestwork installing the MultiClusterHub on the managed hubs once
+// the operator subscription reports AtLatestKnown.
+type mchController struct {
+	*clusterController
+}
+
+// NewMCHController creates a new MultiClusterHub controller
+func NewMCHController(
+	clusterclient clusterclientv1.ClusterV1Interface,
+	workclient workclientv1.WorkV1Interface,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	workInformer workinformerv1.ManifestWorkInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	secretInformer corev1informers.SecretInformer,
+	overrideInformer informers.GenericInformer,
+	restoreInformer informers.GenericInformer,
+	options ControllerOptions,
+	recorder events.Recorder,
+	clusterRecorder record.EventRecorder) factory.Controller {
+	c := &mchController{
+		clusterController: newClusterController("MCHController", clusterclient, workclient,
+			clusterInformer, workInformer, configMapInformer, secretInformer, overrideInformer, restoreInformer, options,
+			HubConditionMCHParked, recorder, clusterRecorder),
+	}
+	c.reconcile = c.reconcileMCH
+	c.ownedWorks = []string{HOH_HUB_CLUSTER_MCH}
+	f := c.newFactory(clusterInformer, workInformer, configMapInformer, HOH_HUB_CLUSTER_SUBSCRIPTION, HOH_HUB_CLUSTER_MCH)
+	if overrideInformer != nil {
+		// rerender the mch of the managed hub when its override is changed
+		f = f.WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				accessor, _ := meta.Accessor(obj)
+				return accessor.GetNamespace()
+			},
+			func(obj interface{}) bool {
+				accessor, err := objectMeta(obj)
+				return err == nil && c.ownsCluster(accessor.GetNamespace())
+			}, overrideInformer.Informer())
+	}
+	// rerender the mch of all managed hubs when their propagated image pull secret is changed
+	return f.WithFilteredEventsInformersQueueKeyFunc(
+		func(obj runtime.Object) string {
+			return factory.DefaultQueueKey
+		},
+		func(obj interface{}) bool {
+			accessor, err := objectMeta(obj)
+			return err == nil && c.propagatesImagePullSecret(accessor.GetName())
+		}, secretInformer.Informer()).
+		ToController(c.name, recorder)
+}
+
+// HOH_SKIP_CSV_GATE_ANNOTATION can be set to true on a managed cluster to create its mch manifestwork
+// as soon as its subscription manifestwork exists, without waiting for the operator subscription to
+// reach AtLatestKnown, where the status feedback of OLM is unreliable but the operator is known to
+// install quickly. The work agent retries the MultiClusterHub until its CRD is installed.
+const HOH_SKIP_CSV_GATE_ANNOTATION = "hoh-skip-csv-gate"
+
+// SkipsCSVGate returns true if the mch manifestwork of the managed cluster is created without
+// waiting for the operator subscription.
+func SkipsCSVGate(managedCluster metav1.Object) bool {
+	return managedCluster.GetAnnotations()[HOH_SKIP_CSV_GATE_ANNOTATION] == "true"
+}
+
+func (c *mchController) reconcileMCH(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster) error {
+	if c.paused(ctx, managedCluster) || !c.inMaintenanceWindow(ctx, syncCtx, managedCluster) {
+		return nil
+	}
+	managedClusterName := managedCluster.Name
+	subscription, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_SUBSCRIPTION)
+	if err != nil {
+		return err
+	}
+	if c.upgrading(ctx, syncCtx, managedCluster, subscription) {
+		return nil
+	}
+
+	// the mch manifestwork is applied once the operator subscription reports AtLatestKnown in the
+	// status feedback of its manifestwork, or as soon as the subscription manifestwork exists when the
+	// gate is skipped; an operator not ready yet is checked again after the recheck interval
+	if SkipsCSVGate(managedCluster) {
+		if subscription == nil {
+			return nil
+		}
+		loggerFrom(ctx).V(4).Info("Skipping the AtLatestKnown gate of the operator subscription")
+	} else if GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_STATE_FEEDBACK) != SUBSCRIPTION_STATE_AT_LATEST_KNOWN {
+		if subscription != nil && c.options.OperatorRecheckInterval > 0 {
+			syncCtx.Queue().AddAfter(managedClusterName, c.options.OperatorRecheckInterval)
+		}
+		return nil
+	}
+
+	desiredMCH, conflicts, err := c.desiredMCHManifestWork(managedCluster, c.hubConfig.get())
+	var invalid *InvalidMCHError
+	if errors.As(err, &invalid) {
+		// retrying does not help, the managed cluster is synced again once its annotation is changed
+		loggerFrom(ctx).Error(err, "Skipping the update of the mch manifestwork")
+		c.clusterRecorder.Eventf(managedClusterReference(managedCluster), corev1.EventTypeWarning,
+			EventReasonInvalidMCH, "The mch manifestwork is not updated: %v", err)
+		return c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    HubConditionMCHInvalid,
+			Status:  metav1.ConditionTrue,
+			Reason:  "InvalidMultiClusterHub",
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if meta.IsStatusConditionTrue(managedCluster.Status.Conditions, HubConditionMCHInvalid) {
+		if err := c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    HubConditionMCHInvalid,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AsExpected",
+			Message: "The user defined mch is valid",
+		}); err != nil {
+			return err
+		}
+	}
+	c.reportMCHConflicts(managedCluster, conflicts)
+	if !c.waveOpen(ctx, syncCtx, managedCluster, desiredMCH) {
+		return nil
+	}
+	mch, err := c.applyManifestWork(ctx, managedCluster, desiredMCH)
+	if err != nil {
+		return err
+	}
+	if IsResourceMissing(mch, "MultiClusterHub") {
+		return c.repairManifestWork(ctx, syncCtx, managedCluster, mch, "MultiClusterHub")
+	}
+	loggerFrom(ctx).V(2).Info("Applied the mch manifestwork",
+		"mchPhase", GetFeedbackValue(mch, "MultiClusterHub", MCH_PHASE_FEEDBACK),
+		"mchVersion", GetFeedbackValue(mch, "MultiClusterHub", MCH_VERSION_FEEDBACK))
+	return nil
+}
+
+// reportMCHConflicts records an event for the fields of the user defined mch which are enforced by the
+// controller. The mch is rendered on every sync, so the event is only recorded when the conflicting
+// fields change.
+func (c *mchController) reportMCHConflicts(managedCluster *clusterv1.ManagedCluster, conflicts []string) {
+	if len(conflicts) == 0 {
+		c.mchConflicts.Delete(managedCluster.Name)
+		return
+	}
+	fields := strings.Join(conflicts, ", ")
+	if reported, ok := c.mchConflicts.Load(managedCluster.Name); ok && reported == fields {
+		return
+	}
+	c.mchConflicts.Store(managedCluster.Name, fields)
+	c.clusterRecorder.Eventf(managedClusterReference(managedCluster), corev1.EventTypeWarning,
+		EventReasonMCHOverrideConflict, "The fields %s of the user defined mch are enforced by the controller, they are ignored",
+		fields)
+}
@@ -0,0 +1,32 @@
+package cluster
+
+import (
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReportMCHConflicts(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	ctrl := newTestController(t, nil, nil)
+	mch := &mchController{clusterController: ctrl.clusterController}
+	recorder := ctrl.clusterRecorder.(*record.FakeRecorder)
+
+	mch.reportMCHConflicts(managedCluster, []string{"spec.disableHubSelfManagement"})
+	mch.reportMCHConflicts(managedCluster, []string{"spec.disableHubSelfManagement"})
+	if events := recordedEvents(recorder); len(events) != 1 {
+		t.Errorf("expected the conflict to be reported once, got %v", events)
+	}
+
+	mch.reportMCHConflicts(managedCluster, []string{"metadata.namespace", "spec.disableHubSelfManagement"})
+	if events := recordedEvents(recorder); len(events) != 1 {
+		t.Errorf("expected the changed conflict to be reported, got %v", events)
+	}
+
+	// a conflict resolved and introduced again is reported again
+	mch.reportMCHConflicts(managedCluster, nil)
+	mch.reportMCHConflicts(managedCluster, []string{"spec.disableHubSelfManagement"})
+	if events := recordedEvents(recorder); len(events) != 1 {
+		t.Errorf("expected the conflict introduced again to be reported, got %v", events)
+	}
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/apis/v1alpha1"
+)
+
+// HOH_MCH_OVERRIDE_ANNOTATION references the MultiClusterHubOverride customizing the MultiClusterHub of
+// the managed cluster, by its name in the managed cluster namespace. The override is preferred over
+// the mch annotation.
+const HOH_MCH_OVERRIDE_ANNOTATION = "hoh-mch-override"
+
+// RenderMCHOverride returns the MultiClusterHub of the override, in the format of the mch annotation.
+func RenderMCHOverride(override *v1alpha1.MultiClusterHubOverride) (string, error) {
+	mch, err := json.Marshal(map[string]interface{}{"spec": override.Spec})
+	if err != nil {
+		return "", err
+	}
+	return string(mch), nil
+}
+
+// desiredMCHManifestWork renders the mch manifestwork of the managed cluster, from its override or
+// the user defined mch of its annotation merged onto its image repository, image pull secret,
+// resource profile, availability preset, the components, the placement and the default one of the
+// hub configuration. The image pull secret is installed with the mch if its propagation is
+// configured. It also returns the fields enforced by the controller which were overridden.
+func (c *clusterController) desiredMCHManifestWork(managedCluster *clusterv1.ManagedCluster,
+	config *HubConfig) (*workv1.ManifestWork, []string, error) {
+	userDefinedMCH := managedCluster.Annotations[HOH_MCH_ANNOTATION]
+	if name := managedCluster.Annotations[HOH_MCH_OVERRIDE_ANNOTATION]; name == "" && userDefinedMCH != "" {
+		// the overrides are validated by the API server
+		if err := ValidateMCH(userDefinedMCH); err != nil {
+			return nil, nil, &InvalidMCHError{err: err}
+		}
+	}
+	if name := managedCluster.Annotations[HOH_MCH_OVERRIDE_ANNOTATION]; name != "" {
+		override, err := c.getMCHOverride(managedCluster.Name, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if userDefinedMCH, err = RenderMCHOverride(override); err != nil {
+			return nil, nil, err
+		}
+	}
+	profileMCH, err := resourceProfileMCH(managedCluster, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	mch, conflicts, err := RenderMCH(managedCluster, config.DisableHubSelfManagement, config.DefaultMCH, placementMCH(config),
+		componentsMCH(config), availabilityMCH(managedCluster, config), profileMCH, imagePullSecretMCH(managedCluster, config),
+		imageRepositoryMCH(managedCluster, config), userDefinedMCH)
+	if err != nil {
+		return nil, nil, err
+	}
+	secrets, err := c.imagePullSecretManifests(mch, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	work := newMCHManifestwork(managedCluster.Name, mch, secrets...)
+	if addonConfig, ok := klusterletAddonConfigManifest(config); ok {
+		work.Spec.Workload.Manifests = append(work.Spec.Workload.Manifests, addonConfig)
+	}
+	return placeManifestWork(managedCluster, work), conflicts, nil
+}
+
+// getMCHOverride returns the MultiClusterHubOverride of the managed cluster from the cache.
+func (c *clusterController) getMCHOverride(namespace, name string) (*v1alpha1.MultiClusterHubOverride, error) {
+	if c.overrideLister == nil {
+		return nil, fmt.Errorf("the MultiClusterHubOverride %s referenced by the %s annotation can not be read, "+
+			"the MultiClusterHubOverride CRD is not installed", name, HOH_MCH_OVERRIDE_ANNOTATION)
+	}
+	obj, err := c.overrideLister.ByNamespace(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return nil, fmt.Errorf("the MultiClusterHubOverride %s referenced by the %s annotation is not found",
+			name, HOH_MCH_OVERRIDE_ANNOTATION)
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("expected an unstructured MultiClusterHubOverride, got %T", obj)
+	}
+	override := &v1alpha1.MultiClusterHubOverride{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content.UnstructuredContent(), override); err != nil {
+		return nil, err
+	}
+	return override, nil
+}
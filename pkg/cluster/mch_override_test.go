@@ -0,0 +1,107 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/apis/v1alpha1"
+)
+
+func TestDesiredMCHManifestWork(t *testing.T) {
+	override := &v1alpha1.MultiClusterHubOverride{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "MultiClusterHubOverride"},
+		ObjectMeta: metav1.ObjectMeta{Name: "basic", Namespace: "cluster1"},
+		Spec:       v1alpha1.MultiClusterHubOverrideSpec{AvailabilityConfig: "Basic"},
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(&unstructured.Unstructured{Object: content}); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := &clusterController{
+		overrideLister: cache.NewGenericLister(indexer, v1alpha1.MultiClusterHubOverridesResource.GroupResource()),
+	}
+
+	cases := []struct {
+		name          string
+		annotations   map[string]string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:     "default",
+			expected: `"disableHubSelfManagement": true`,
+		},
+		{
+			name:        "mch annotation",
+			annotations: map[string]string{HOH_MCH_ANNOTATION: `{"spec":{"availabilityConfig":"High"}}`},
+			expected:    `"availabilityConfig":"High"`,
+		},
+		{
+			name: "override preferred over the mch annotation",
+			annotations: map[string]string{
+				HOH_MCH_ANNOTATION:          `{"spec":{"availabilityConfig":"High"}}`,
+				HOH_MCH_OVERRIDE_ANNOTATION: "basic",
+			},
+			expected: `"availabilityConfig":"Basic"`,
+		},
+		{
+			name:          "override not found",
+			annotations:   map[string]string{HOH_MCH_OVERRIDE_ANNOTATION: "missing"},
+			expectedError: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Annotations: c.annotations},
+			}
+			work, _, err := ctrl.desiredMCHManifestWork(managedCluster, DefaultHubConfig())
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mch := string(work.Spec.Workload.Manifests[0].Raw); !strings.Contains(mch, c.expected) {
+				t.Errorf("expected the mch to contain %s, got %s", c.expected, mch)
+			}
+		})
+	}
+}
+
+func TestDesiredMCHManifestWorkWithoutOverrideCRD(t *testing.T) {
+	ctrl := &clusterController{}
+
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cluster1",
+			Annotations: map[string]string{HOH_MCH_ANNOTATION: `{"spec":{"availabilityConfig":"High"}}`},
+		},
+	}
+	work, _, err := ctrl.desiredMCHManifestWork(managedCluster, DefaultHubConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mch := string(work.Spec.Workload.Manifests[0].Raw); !strings.Contains(mch, `"availabilityConfig":"High"`) {
+		t.Errorf("expected the mch annotation to be applied, got %s", mch)
+	}
+
+	managedCluster.Annotations[HOH_MCH_OVERRIDE_ANNOTATION] = "basic"
+	if _, _, err := ctrl.desiredMCHManifestWork(managedCluster, DefaultHubConfig()); err == nil ||
+		!strings.Contains(err.Error(), "CRD is not installed") {
+		t.Errorf("expected the override to be reported as not installed, got %v", err)
+	}
+}
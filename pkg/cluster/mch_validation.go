@@ -0,0 +1,122 @@
+package cluster
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	"sigs.k8s.io/yaml"
+)
+
+// HOH_MCH_ANNOTATION overrides the MultiClusterHub installed on the managed cluster, it is the JSON
+// of a MultiClusterHub with at least a spec
+const HOH_MCH_ANNOTATION = "mch"
+
+//go:embed mch_schema.yaml
+var mchSchemaYAML []byte
+
+// mchSchema is the MultiClusterHub schema loaded once from mchSchemaYAML, as a structural schema to
+// find the unknown fields and a validator for the types and values of the known ones
+var mchSchema struct {
+	once       sync.Once
+	structural *schema.Structural
+	validator  *validate.SchemaValidator
+	err        error
+}
+
+func loadMCHSchema() (*schema.Structural, *validate.SchemaValidator, error) {
+	mchSchema.once.Do(func() {
+		props := &apiextensionsv1.JSONSchemaProps{}
+		if err := yaml.Unmarshal(mchSchemaYAML, props); err != nil {
+			mchSchema.err = err
+			return
+		}
+		internal := &apiextensions.JSONSchemaProps{}
+		if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(props, internal, nil); err != nil {
+			mchSchema.err = err
+			return
+		}
+		if mchSchema.structural, mchSchema.err = schema.NewStructural(internal); mchSchema.err != nil {
+			return
+		}
+		mchSchema.validator, _, mchSchema.err = validation.NewSchemaValidator(
+			&apiextensions.CustomResourceValidation{OpenAPIV3Schema: internal})
+	})
+	return mchSchema.structural, mchSchema.validator, mchSchema.err
+}
+
+// ValidateMCH returns an error if the given MultiClusterHub does not match the OpenAPI schema of the
+// MultiClusterHub CRD, so a typo is reported when the mch annotation is edited rather than when the
+// manifestwork is rendered. The unknown fields, which the kube-apiserver would silently prune, are
+// reported as errors.
+func ValidateMCH(mch string) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(mch), &fields); err != nil {
+		return fmt.Errorf("the multiclusterhub is not a JSON object: %v", err)
+	}
+	if apiVersion, ok := fields["apiVersion"]; ok && apiVersion != "operator.open-cluster-management.io/v1" {
+		return fmt.Errorf("unexpected apiVersion %v, expected operator.open-cluster-management.io/v1", apiVersion)
+	}
+	if kind, ok := fields["kind"]; ok && kind != "MultiClusterHub" {
+		return fmt.Errorf("unexpected kind %v, expected MultiClusterHub", kind)
+	}
+	if _, ok := fields["spec"].(map[string]interface{}); !ok {
+		return fmt.Errorf("the multiclusterhub has no spec")
+	}
+
+	structural, validator, err := loadMCHSchema()
+	if err != nil {
+		return fmt.Errorf("failed to load the multiclusterhub schema: %v", err)
+	}
+	// null removes the field from the default multiclusterhub
+	removeNulls(fields)
+	var errs []string
+	for _, path := range pruning.PruneWithOptions(fields, structural, true, pruning.PruneOptions{ReturnPruned: true}) {
+		errs = append(errs, fmt.Sprintf("unknown field %s", path))
+	}
+	for _, err := range validation.ValidateCustomResource(nil, fields, validator) {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// removeNulls removes the null fields of the object and its nested objects
+func removeNulls(value interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for name, field := range typed {
+			if field == nil {
+				delete(typed, name)
+				continue
+			}
+			removeNulls(field)
+		}
+	case []interface{}:
+		for _, item := range typed {
+			removeNulls(item)
+		}
+	}
+}
+
+// InvalidMCHError is returned when the user defined mch of a managed cluster does not match the
+// MultiClusterHub schema, the mch manifestwork is not updated until it is fixed.
+type InvalidMCHError struct {
+	err error
+}
+
+func (e *InvalidMCHError) Error() string {
+	return fmt.Sprintf("invalid %s annotation: %v", HOH_MCH_ANNOTATION, e.err)
+}
@@ -0,0 +1,71 @@
+package cluster
+
+import "testing"
+
+func TestValidateMCH(t *testing.T) {
+	cases := []struct {
+		name          string
+		mch           string
+		expectedError bool
+	}{
+		{
+			name: "valid",
+			mch:  `{"apiVersion":"operator.open-cluster-management.io/v1","kind":"MultiClusterHub","spec":{"availabilityConfig":"Basic","nodeSelector":{"infra":"true"}}}`,
+		},
+		{
+			name:          "not json",
+			mch:           `{"spec":`,
+			expectedError: true,
+		},
+		{
+			name:          "no spec",
+			mch:           `{"metadata":{}}`,
+			expectedError: true,
+		},
+		{
+			name:          "unexpected kind",
+			mch:           `{"kind":"ClusterManager","spec":{}}`,
+			expectedError: true,
+		},
+		{
+			name:          "unknown field",
+			mch:           `{"spec":{"availabiltyConfig":"Basic"}}`,
+			expectedError: true,
+		},
+		{
+			name:          "wrong type",
+			mch:           `{"spec":{"disableUpdateClusterImageSets":"true"}}`,
+			expectedError: true,
+		},
+		{
+			name: "null removing a field",
+			mch:  `{"spec":{"nodeSelector":null,"overrides":{"components":[{"name":"search","enabled":false}]}}}`,
+		},
+		{
+			name:          "unknown nested field",
+			mch:           `{"spec":{"overrides":{"components":[{"name":"search","enabld":false}]}}}`,
+			expectedError: true,
+		},
+		{
+			name:          "wrong nested type",
+			mch:           `{"spec":{"tolerations":[{"key":"infra","tolerationSeconds":"60"}]}}`,
+			expectedError: true,
+		},
+		{
+			name:          "invalid availability",
+			mch:           `{"spec":{"availabilityConfig":"Medium"}}`,
+			expectedError: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateMCH(c.mch)
+			if c.expectedError && err == nil {
+				t.Errorf("expected an error")
+			}
+			if !c.expectedError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
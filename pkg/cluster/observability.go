@@ -0,0 +1,126 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// HOH_HUB_CLUSTER_OBSERVABILITY is the suffix of the manifestwork enabling the observability of the
+// managed hubs, it is created once the MultiClusterHub is running if configured.
+const HOH_HUB_CLUSTER_OBSERVABILITY = "hoh-hub-cluster-observability"
+
+const (
+	// OBSERVABILITY_NAMESPACE is the namespace of the observability of the managed hubs
+	OBSERVABILITY_NAMESPACE = "open-cluster-management-observability"
+	// OBSERVABILITY_STORAGE_SECRET is the name of the object storage configuration of the metrics of
+	// the managed hubs, under the thanos.yaml key
+	OBSERVABILITY_STORAGE_SECRET = "thanos-object-storage"
+	// OBSERVABILITY_WRITE_SECRET is the name of the remote write endpoint of the hub of hubs the
+	// managed hubs export their metrics to, under the ep.yaml key
+	OBSERVABILITY_WRITE_SECRET = "hoh-global-hub-metrics"
+)
+
+// applyObservability applies the observability manifestwork of the managed hub if the export of the
+// metrics to the hub of hubs is configured. The observability is not removed from the managed hubs
+// when it is no longer configured.
+func (c *clusterController) applyObservability(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster, config *HubConfig) error {
+	if config.ObservabilityWriteSecret == "" {
+		return nil
+	}
+	desired, err := c.desiredObservabilityManifestWork(managedCluster, config)
+	if err != nil {
+		return err
+	}
+	if !c.waveOpen(ctx, syncCtx, managedCluster, desired) {
+		return nil
+	}
+	_, err = c.applyManifestWork(ctx, managedCluster, desired)
+	return err
+}
+
+// desiredObservabilityManifestWork renders the observability manifestwork of the managed cluster, with
+// the object storage and remote write secrets of the hub configuration copied from the controller
+// namespace.
+func (c *clusterController) desiredObservabilityManifestWork(managedCluster *clusterv1.ManagedCluster,
+	config *HubConfig) (*workv1.ManifestWork, error) {
+	storage, _, err := c.propagatedSecret(config.ObservabilityStorageSecret, OBSERVABILITY_STORAGE_SECRET,
+		OBSERVABILITY_NAMESPACE)
+	if err != nil {
+		return nil, err
+	}
+	write, _, err := c.propagatedSecret(config.ObservabilityWriteSecret, OBSERVABILITY_WRITE_SECRET,
+		OBSERVABILITY_NAMESPACE)
+	if err != nil {
+		return nil, err
+	}
+	return placeManifestWork(managedCluster, newObservabilityManifestwork(managedCluster.Name, storage, write)), nil
+}
+
+// newObservabilityManifestwork returns the observability manifestwork installing the
+// MultiClusterObservability of the managed hub, exporting its metrics to the remote write endpoint of
+// the hub of hubs, with the given secrets.
+func newObservabilityManifestwork(namespace string, secrets ...workv1.Manifest) *workv1.ManifestWork {
+	// marshaling generic JSON values does not fail
+	mco, _ := json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "observability.open-cluster-management.io/v1beta2",
+		"kind":       "MultiClusterObservability",
+		"metadata": map[string]interface{}{
+			"name": "observability",
+		},
+		"spec": map[string]interface{}{
+			"observabilityAddonSpec": map[string]interface{}{},
+			"storageConfig": map[string]interface{}{
+				"metricObjectStorage": map[string]interface{}{
+					"name": OBSERVABILITY_STORAGE_SECRET,
+					"key":  "thanos.yaml",
+				},
+				"writeStorage": []interface{}{
+					map[string]interface{}{
+						"name": OBSERVABILITY_WRITE_SECRET,
+						"key":  "ep.yaml",
+					},
+				},
+			},
+		},
+	}, "", "\t")
+	return &workv1.ManifestWork{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: workv1.GroupVersion.String(),
+			Kind:       "ManifestWork",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      WorkNaming{}.Name(namespace, HOH_HUB_CLUSTER_OBSERVABILITY),
+			Namespace: namespace,
+			Labels: map[string]string{
+				MANAGED_BY_LABEL:      MANAGED_BY_VALUE,
+				MANAGED_CLUSTER_LABEL: namespace,
+				BACKUP_LABEL:          BACKUP_VALUE,
+				WORK_TYPE_LABEL:       HOH_HUB_CLUSTER_OBSERVABILITY,
+			},
+		},
+		Spec: workv1.ManifestWorkSpec{
+			Workload: workv1.ManifestsTemplate{
+				Manifests: append(append([]workv1.Manifest{
+					{RawExtension: runtime.RawExtension{
+						Raw: []byte(`{
+	"apiVersion": "v1",
+	"kind": "Namespace",
+	"metadata": {
+		"name": "open-cluster-management-observability"
+	}
+}`),
+					}},
+				}, secrets...), workv1.Manifest{
+					RawExtension: runtime.RawExtension{Raw: mco},
+				}),
+			},
+		},
+	}
+}
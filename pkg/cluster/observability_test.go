@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func TestParseHubConfigObservability(t *testing.T) {
+	if _, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_OBSERVABILITY_WRITE_SECRET_KEY: "global-hub-metrics",
+	})); err == nil {
+		t.Errorf("expected the observability without object storage to be rejected")
+	}
+}
+
+// newRunningHubAgentController returns a test agent controller of a managed hub whose MultiClusterHub
+// is running
+func newRunningHubAgentController(t *testing.T) *testController {
+	managedCluster := newManagedCluster("cluster1")
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mch = withFeedback(mch, "MultiClusterHub", map[string]string{MCH_PHASE_FEEDBACK: MCH_PHASE_RUNNING})
+	SetManagedClusterUID(mch, managedCluster)
+	return newTestAgentController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{mch})
+}
+
+func TestReconcileObservability(t *testing.T) {
+	ctrl := newRunningHubAgentController(t)
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_OBSERVABILITY, metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the observability not to be enabled unless configured, got %v", err)
+	}
+
+	ctrl = newRunningHubAgentController(t)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, name := range []string{"global-hub-metrics", "metrics-storage"} {
+		if err := indexer.Add(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Data:       map[string][]byte{"key": []byte(name)},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctrl.secretLister = corev1listers.NewSecretLister(indexer).Secrets("test")
+	ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t, newHubConfigMap(map[string]string{
+		HUB_CONFIG_OBSERVABILITY_WRITE_SECRET_KEY:   "global-hub-metrics",
+		HUB_CONFIG_OBSERVABILITY_STORAGE_SECRET_KEY: "metrics-storage",
+	})))
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	observability, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_OBSERVABILITY, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the observability manifestwork to be created: %v", err)
+	}
+	manifests := observability.Spec.Workload.Manifests
+	if len(manifests) != 4 {
+		t.Fatalf("expected the namespace, the 2 secrets and the MultiClusterObservability, got %d manifests", len(manifests))
+	}
+	if mco := string(manifests[3].Raw); !strings.Contains(mco, OBSERVABILITY_WRITE_SECRET) {
+		t.Errorf("expected the metrics to be written to the hub of hubs, got %s", mco)
+	}
+}
+
+func TestSpecDiffRedactsObservabilitySecrets(t *testing.T) {
+	config := DefaultHubConfig()
+	config.ObservabilityWriteSecret = "global-hub-metrics"
+	config.ObservabilityStorageSecret = "metrics-storage"
+	diff := rotatedSpecDiff(t, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "metrics-storage", Namespace: "test"}},
+		"thanos.yaml", "secret_key: old-storage-key", "secret_key: new-storage-key",
+		func(c *clusterController) (*workv1.ManifestWork, error) {
+			return c.desiredObservabilityManifestWork(newManagedCluster("cluster1"), config)
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "global-hub-metrics", Namespace: "test"},
+			Data:       map[string][]byte{"token": []byte("remote-write-token")},
+		})
+	expectRedacted(t, diff, "old-storage-key", "new-storage-key", "secret_key: old-storage-key",
+		"secret_key: new-storage-key", "remote-write-token")
+}
@@ -0,0 +1,37 @@
+package cluster
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HOH_PAUSE_ANNOTATION can be set to true on a managed cluster to freeze the creation and updates
+// of its hub manifestworks, for example during a maintenance of the managed cluster. The status of
+// the hub is still reported.
+const HOH_PAUSE_ANNOTATION = "hoh-pause"
+
+// IsPaused returns true if the hub manifestworks of the managed cluster must not be written.
+func IsPaused(managedCluster metav1.Object) bool {
+	return managedCluster.GetAnnotations()[HOH_PAUSE_ANNOTATION] == "true"
+}
+
+// paused returns true if the controller must not write the hub manifestworks of the managed cluster,
+// because the managed cluster or the whole fleet is paused, or the hub of hubs is being restored. The
+// managed cluster is synced again when it is resumed, since the annotation, the configuration and the
+// Restore changes are events.
+func (c *clusterController) paused(ctx context.Context, managedCluster metav1.Object) bool {
+	if restore := c.restoring(ctx); restore != "" {
+		loggerFrom(ctx).V(2).Info("Skipping hub cluster, the hub of hubs is being restored", "restore", restore)
+		return true
+	}
+	if c.hubConfig.get().Paused {
+		loggerFrom(ctx).V(2).Info("Skipping hub cluster, the fleet is paused")
+		return true
+	}
+	if IsPaused(managedCluster) {
+		loggerFrom(ctx).V(2).Info("Skipping paused hub cluster")
+		return true
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"encoding/json"
+
+	"k8s.io/klog/v2"
+)
+
+// placementMCH returns the MultiClusterHub placing the hub components on the nodes of the hub
+// configuration, such as the infrastructure nodes, to be merged onto the default one, or an empty
+// string if the placement is not configured. The node selector and tolerations of the user defined
+// MultiClusterHubs are merged onto it.
+func placementMCH(config *HubConfig) string {
+	if len(config.NodeSelector) == 0 && len(config.Tolerations) == 0 {
+		return ""
+	}
+	spec := map[string]interface{}{}
+	if len(config.NodeSelector) > 0 {
+		spec["nodeSelector"] = config.NodeSelector
+	}
+	if len(config.Tolerations) > 0 {
+		spec["tolerations"] = config.Tolerations
+	}
+	mch, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		// the configuration is parsed from json, it can always be marshalled back
+		klog.Errorf("Failed to render the placement of the hubs: %v", err)
+		return ""
+	}
+	return string(mch)
+}
@@ -0,0 +1,47 @@
+package cluster
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPlacementMCH(t *testing.T) {
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_NODE_SELECTOR_KEY: `{"node-role.kubernetes.io/infra":""}`,
+		HUB_CONFIG_TOLERATIONS_KEY:   `[{"key":"node-role.kubernetes.io/infra","effect":"NoSchedule","operator":"Exists"}]`,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mch := placementMCH(DefaultHubConfig()); mch != "" {
+		t.Errorf("expected no placement by default, got %s", mch)
+	}
+
+	// the node selector of the user defined mch is merged onto the configured one
+	rendered, _, err := RenderMCH(newManagedCluster("cluster1"), true, placementMCH(config), `{"spec":{"nodeSelector":{"zone":"a"}}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mch := struct {
+		Spec struct {
+			NodeSelector map[string]string   `json:"nodeSelector"`
+			Tolerations  []corev1.Toleration `json:"tolerations"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(rendered, &mch); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mch.Spec.NodeSelector, map[string]string{"node-role.kubernetes.io/infra": "", "zone": "a"}) {
+		t.Errorf("expected the merged node selector, got %v", mch.Spec.NodeSelector)
+	}
+	if len(mch.Spec.Tolerations) != 1 || mch.Spec.Tolerations[0].Effect != corev1.TaintEffectNoSchedule {
+		t.Errorf("expected the configured tolerations, got %v", mch.Spec.Tolerations)
+	}
+
+	if _, err := ParseHubConfig(newHubConfigMap(map[string]string{HUB_CONFIG_TOLERATIONS_KEY: `{}`})); err == nil {
+		t.Errorf("expected invalid tolerations to be rejected")
+	}
+}
@@ -0,0 +1,222 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// names of the governance resources enforcing the hubs in the policy deployment mode, the policies
+// are named after the manifestworks they replace
+const (
+	HOH_HUB_CLUSTER_PLACEMENT         = "hoh-hub-cluster-placement"
+	HOH_HUB_CLUSTER_PLACEMENT_BINDING = "hoh-hub-cluster-placement-binding"
+)
+
+// resources of the governance API and the placements the policies are bound to
+var (
+	PoliciesResource = schema.GroupVersionResource{
+		Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	PlacementBindingsResource = schema.GroupVersionResource{
+		Group: "policy.open-cluster-management.io", Version: "v1", Resource: "placementbindings"}
+	PlacementsResource = schema.GroupVersionResource{
+		Group: "cluster.open-cluster-management.io", Version: "v1alpha1", Resource: "placements"}
+)
+
+// RenderPolicies returns the Policies enforcing the operator subscription and the MultiClusterHub of
+// the hub configuration in the given namespace, with the Placement selecting the managed hubs and the
+// PlacementBinding binding the policies to it. The MultiClusterHub policy depends on the compliance of
+// the subscription policy, so the MultiClusterHub is only created once the operator is installed.
+//
+// The policies are shared by the whole fleet, the annotations and overrides of the managed clusters
+// do not apply to them. The image pull secret is propagated by the MultiClusterHub policy with a hub
+// template when its propagation is configured.
+func RenderPolicies(namespace string, config *HubConfig) ([]*unstructured.Unstructured, error) {
+	mch, err := renderFleetMCH(config)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := renderFleetSubscription(namespace, config)
+	if err != nil {
+		return nil, err
+	}
+	subscriptionPolicy, err := newPolicy(namespace, HOH_HUB_CLUSTER_SUBSCRIPTION, subscription.Spec.Workload.Manifests)
+	if err != nil {
+		return nil, err
+	}
+	mchManifests := newMCHManifestwork(namespace, mch).Spec.Workload.Manifests
+	if config.PropagateImagePullSecret && config.ImagePullSecret != "" {
+		// the image pull secret is enforced before the MultiClusterHub referencing it
+		mchManifests = append([]workv1.Manifest{imagePullSecretTemplate(namespace, config.ImagePullSecret)},
+			mchManifests...)
+	}
+	mchPolicy, err := newPolicy(namespace, HOH_HUB_CLUSTER_MCH, mchManifests)
+	if err != nil {
+		return nil, err
+	}
+	mchPolicy.Object["spec"].(map[string]interface{})["dependencies"] = []interface{}{
+		map[string]interface{}{
+			"apiVersion": "policy.open-cluster-management.io/v1",
+			"kind":       "Policy",
+			"name":       HOH_HUB_CLUSTER_SUBSCRIPTION,
+			"namespace":  namespace,
+			"compliance": "Compliant",
+		},
+	}
+	return []*unstructured.Unstructured{
+		subscriptionPolicy,
+		mchPolicy,
+		newPolicyPlacement(namespace, config),
+		newPolicyPlacementBinding(namespace),
+	}, nil
+}
+
+// renderFleetSubscription renders the subscription manifestwork of the hub configuration shared by the
+// whole fleet
+func renderFleetSubscription(namespace string, config *HubConfig) (*workv1.ManifestWork, error) {
+	config = config.forFlavor(config.Flavor)
+	subscription, err := subscriptionManifest(&clusterv1.ManagedCluster{}, config, config.CatalogSource)
+	if err != nil {
+		return nil, err
+	}
+	return newSubManifestwork(namespace, config, subscription), nil
+}
+
+// renderFleetMCH renders the MultiClusterHub of the hub configuration shared by the whole fleet
+func renderFleetMCH(config *HubConfig) ([]byte, error) {
+	fleet := &clusterv1.ManagedCluster{}
+	mch, _, err := RenderMCH(fleet, config.DisableHubSelfManagement, config.DefaultMCH, placementMCH(config),
+		componentsMCH(config), imagePullSecretMCH(fleet, config), imageRepositoryMCH(fleet, config))
+	return mch, err
+}
+
+// imagePullSecretTemplate returns the image pull secret of the MultiClusterHub, whose content is
+// copied from the secret of the policy namespace by a hub template when the policy is propagated, so
+// the credentials are not written in the policy.
+func imagePullSecretTemplate(namespace, name string) workv1.Manifest {
+	return workv1.Manifest{RawExtension: runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{
+	"apiVersion": "v1",
+	"kind": "Secret",
+	"metadata": {
+		"name": %q,
+		"namespace": "open-cluster-management"
+	},
+	"type": "kubernetes.io/dockerconfigjson",
+	"data": {
+		".dockerconfigjson": %q
+	}
+}`, name, fmt.Sprintf(`{{hub fromSecret %q %q ".dockerconfigjson" hub}}`, namespace, name)))}}
+}
+
+// newPolicy returns the Policy enforcing the given manifests with a ConfigurationPolicy of the same name
+func newPolicy(namespace, name string, manifests []workv1.Manifest) (*unstructured.Unstructured, error) {
+	objectTemplates := []interface{}{}
+	for _, manifest := range manifests {
+		object := map[string]interface{}{}
+		if err := json.Unmarshal(manifest.Raw, &object); err != nil {
+			return nil, err
+		}
+		objectTemplates = append(objectTemplates, map[string]interface{}{
+			"complianceType":   "musthave",
+			"objectDefinition": object,
+		})
+	}
+	return newGovernanceObject("policy.open-cluster-management.io/v1", "Policy", namespace, name,
+		map[string]interface{}{
+			"remediationAction": "enforce",
+			"disabled":          false,
+			"policy-templates": []interface{}{
+				map[string]interface{}{
+					"objectDefinition": map[string]interface{}{
+						"apiVersion": "policy.open-cluster-management.io/v1",
+						"kind":       "ConfigurationPolicy",
+						"metadata":   map[string]interface{}{"name": name},
+						"spec": map[string]interface{}{
+							"remediationAction": "enforce",
+							"severity":          "high",
+							"object-templates":  objectTemplates,
+						},
+					},
+				},
+			},
+		}), nil
+}
+
+// newPolicyPlacement returns the Placement selecting the managed hubs, the managed clusters disabled
+// by the hoh label, the local cluster and the excluded clusters are not selected.
+func newPolicyPlacement(namespace string, config *HubConfig) *unstructured.Unstructured {
+	excluded := []interface{}{"local-cluster"}
+	for _, name := range config.ExcludedClusters.List() {
+		excluded = append(excluded, name)
+	}
+	return newGovernanceObject("cluster.open-cluster-management.io/v1alpha1", "Placement", namespace,
+		HOH_HUB_CLUSTER_PLACEMENT, map[string]interface{}{
+			"predicates": []interface{}{
+				map[string]interface{}{
+					"requiredClusterSelector": map[string]interface{}{
+						"labelSelector": map[string]interface{}{
+							"matchExpressions": []interface{}{
+								map[string]interface{}{
+									"key":      HOH_LABEL,
+									"operator": string(metav1.LabelSelectorOpNotIn),
+									"values":   []interface{}{HOH_LABEL_DISABLED},
+								},
+								map[string]interface{}{
+									"key":      "name",
+									"operator": string(metav1.LabelSelectorOpNotIn),
+									"values":   excluded,
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+}
+
+// newPolicyPlacementBinding returns the PlacementBinding binding the hub policies to the Placement
+// of the managed hubs
+func newPolicyPlacementBinding(namespace string) *unstructured.Unstructured {
+	binding := newGovernanceObject("policy.open-cluster-management.io/v1", "PlacementBinding", namespace,
+		HOH_HUB_CLUSTER_PLACEMENT_BINDING, nil)
+	binding.Object["placementRef"] = map[string]interface{}{
+		"apiGroup": "cluster.open-cluster-management.io",
+		"kind":     "Placement",
+		"name":     HOH_HUB_CLUSTER_PLACEMENT,
+	}
+	binding.Object["subjects"] = []interface{}{
+		map[string]interface{}{
+			"apiGroup": "policy.open-cluster-management.io",
+			"kind":     "Policy",
+			"name":     HOH_HUB_CLUSTER_SUBSCRIPTION,
+		},
+		map[string]interface{}{
+			"apiGroup": "policy.open-cluster-management.io",
+			"kind":     "Policy",
+			"name":     HOH_HUB_CLUSTER_MCH,
+		},
+	}
+	return binding
+}
+
+func newGovernanceObject(apiVersion, kind, namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+			"labels":    map[string]interface{}{MANAGED_BY_LABEL: MANAGED_BY_VALUE, BACKUP_LABEL: BACKUP_VALUE},
+		},
+	}}
+	if spec != nil {
+		obj.Object["spec"] = spec
+	}
+	return obj
+}
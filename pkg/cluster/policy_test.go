@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRenderPolicies(t *testing.T) {
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_EXCLUDED_CLUSTERS_KEY: "cluster2",
+		HUB_CONFIG_IMAGE_PULL_SECRET_KEY: "pull-secret",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := RenderPolicies("test", config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 4 {
+		t.Fatalf("expected 2 policies, a placement and a placement binding, got %d objects", len(objects))
+	}
+
+	subscription, mch, placement := objects[0], objects[1], objects[2]
+	if subscription.GetName() != HOH_HUB_CLUSTER_SUBSCRIPTION || subscription.GetNamespace() != "test" {
+		t.Errorf("unexpected subscription policy %s/%s", subscription.GetNamespace(), subscription.GetName())
+	}
+	if kinds := templateKinds(t, subscription); !strings.Contains(kinds, "Subscription") {
+		t.Errorf("expected the subscription policy to enforce the Subscription, got %s", kinds)
+	}
+	if kinds := templateKinds(t, mch); kinds != "MultiClusterHub" {
+		t.Errorf("expected the mch policy to enforce the MultiClusterHub, got %s", kinds)
+	}
+	secret, _, _ := unstructured.NestedString(objectTemplates(t, mch)[0].(map[string]interface{}),
+		"objectDefinition", "spec", "imagePullSecret")
+	if secret != "pull-secret" {
+		t.Errorf("expected the configured image pull secret, got %q", secret)
+	}
+	dependencies, _, _ := unstructured.NestedSlice(mch.Object, "spec", "dependencies")
+	if len(dependencies) != 1 || dependencies[0].(map[string]interface{})["name"] != HOH_HUB_CLUSTER_SUBSCRIPTION {
+		t.Errorf("expected the mch policy to depend on the subscription policy, got %v", dependencies)
+	}
+
+	predicates, _, _ := unstructured.NestedSlice(placement.Object, "spec", "predicates")
+	expressions, _, _ := unstructured.NestedSlice(predicates[0].(map[string]interface{}),
+		"requiredClusterSelector", "labelSelector", "matchExpressions")
+	if values := expressions[1].(map[string]interface{})["values"].([]interface{}); len(values) != 2 ||
+		values[0] != "local-cluster" || values[1] != "cluster2" {
+		t.Errorf("expected the local and excluded clusters to not be selected, got %v", values)
+	}
+}
+
+func TestRenderPoliciesPropagatesImagePullSecret(t *testing.T) {
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_IMAGE_PULL_SECRET_KEY:           "pull-secret",
+		HUB_CONFIG_PROPAGATE_IMAGE_PULL_SECRET_KEY: "true",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := RenderPolicies("test", config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mch := objects[1]
+	if kinds := templateKinds(t, mch); kinds != "Secret,MultiClusterHub" {
+		t.Fatalf("expected the mch policy to enforce the image pull secret before the MultiClusterHub, got %s", kinds)
+	}
+	secret := objectTemplates(t, mch)[0].(map[string]interface{})
+	name, _, _ := unstructured.NestedString(secret, "objectDefinition", "metadata", "name")
+	namespace, _, _ := unstructured.NestedString(secret, "objectDefinition", "metadata", "namespace")
+	data, _, _ := unstructured.NestedString(secret, "objectDefinition", "data", ".dockerconfigjson")
+	if name != "pull-secret" || namespace != "open-cluster-management" ||
+		data != `{{hub fromSecret "test" "pull-secret" ".dockerconfigjson" hub}}` {
+		t.Errorf("expected the image pull secret to be copied from the policy namespace, got %v", secret)
+	}
+}
+
+func objectTemplates(t *testing.T, policy *unstructured.Unstructured) []interface{} {
+	templates, _, _ := unstructured.NestedSlice(policy.Object, "spec", "policy-templates")
+	if len(templates) != 1 {
+		t.Fatalf("expected a policy template, got %v", templates)
+	}
+	objectTemplates, _, _ := unstructured.NestedSlice(templates[0].(map[string]interface{}),
+		"objectDefinition", "spec", "object-templates")
+	return objectTemplates
+}
+
+// templateKinds returns the comma separated kinds of the objects enforced by the policy
+func templateKinds(t *testing.T, policy *unstructured.Unstructured) string {
+	kinds := []string{}
+	for _, template := range objectTemplates(t, policy) {
+		kind, _, _ := unstructured.NestedString(template.(map[string]interface{}), "objectDefinition", "kind")
+		kinds = append(kinds, kind)
+	}
+	return strings.Join(kinds, ",")
+}
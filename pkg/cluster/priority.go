@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// pendingInstalls tracks the managed hubs queued for their first install, that is without any hub
+// manifestwork yet. The retries of the failing managed hubs yield to them, so a backlog of broken
+// clusters does not delay the onboarding of the new ones.
+type pendingInstalls struct {
+	lock     sync.Mutex
+	clusters sets.String
+}
+
+func newPendingInstalls() *pendingInstalls {
+	return &pendingInstalls{clusters: sets.NewString()}
+}
+
+// add records the managed hub is queued for its first install
+func (p *pendingInstalls) add(name string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.clusters.Insert(name)
+}
+
+// done records the managed hub is synced
+func (p *pendingInstalls) done(name string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.clusters.Delete(name)
+}
+
+// waiting returns true if a managed hub other than the given one is queued for its first install
+func (p *pendingInstalls) waiting(name string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.clusters.Len() > 1 || (p.clusters.Len() == 1 && !p.clusters.Has(name))
+}
+
+// enqueued records the managed hub as a first install when it has no hub manifestwork yet
+func (c *clusterController) enqueued(managedClusterName string) {
+	works, err := c.listManifestWorks(managedClusterName)
+	if err == nil && len(works) == 0 {
+		c.firstInstalls.add(managedClusterName)
+	}
+}
@@ -0,0 +1,50 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func TestRetryYieldsToFirstInstalls(t *testing.T) {
+	failing := newManagedCluster("cluster1")
+	subscription := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{failing, newManagedCluster("cluster2")},
+		[]*workv1.ManifestWork{subscription})
+	reconciled := []string{}
+	ctrl.reconcile = func(ctx context.Context, syncCtx factory.SyncContext, managedCluster *clusterv1.ManagedCluster) error {
+		reconciled = append(reconciled, managedCluster.Name)
+		return nil
+	}
+	ctrl.backoff.failed(failing)
+	// cluster1 is not a first install, it has a manifestwork already
+	ctrl.enqueued("cluster1")
+	ctrl.enqueued("cluster2")
+
+	// the retry of cluster1 is deferred while cluster2 waits for its first install
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reconciled) != 0 {
+		t.Fatalf("expected the retry to be deferred, got %v", reconciled)
+	}
+	if delay, ok := syncCtx.AddedAfter("cluster1"); !ok || delay != retryBaseDelay {
+		t.Errorf("expected the retry to be requeued after its backoff delay, got %v", delay)
+	}
+
+	for _, name := range []string{"cluster2", "cluster1"} {
+		if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, name)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(reconciled) != 2 || reconciled[0] != "cluster2" || reconciled[1] != "cluster1" {
+		t.Errorf("expected the first install before the retry, got %v", reconciled)
+	}
+}
@@ -0,0 +1,32 @@
+package cluster
+
+import (
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// RenderManifestWorks renders the subscription and mch manifestworks the controller creates for the
+// managed cluster with the hub configuration, for review outside the controller. The resources the
+// controller reads from the hub, such as the MultiClusterHubOverrides and the propagated secrets, are
+// not available, the managed clusters or configurations referencing them are returned as an error.
+// The manifestworks are named with the given naming.
+func RenderManifestWorks(managedCluster *clusterv1.ManagedCluster, config *HubConfig,
+	naming WorkNaming) ([]*workv1.ManifestWork, error) {
+	c := &clusterController{}
+	subscription, err := c.desiredSubManifestWork(managedCluster, config)
+	if err != nil {
+		return nil, err
+	}
+	mch, _, err := c.desiredMCHManifestWork(managedCluster, config)
+	if err != nil {
+		return nil, err
+	}
+	works := []*workv1.ManifestWork{subscription, mch}
+	for _, work := range works {
+		naming.setName(work)
+		if err := SetSpecHash(work); err != nil {
+			return nil, err
+		}
+	}
+	return works, nil
+}
@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// ManifestWorkReplicaSetsResource is the resource of the ManifestWorkReplicaSets fanning the
+// manifestworks out to the clusters selected by a Placement. It is not part of the work API the
+// controller is built with, so it is handled as unstructured.
+var ManifestWorkReplicaSetsResource = schema.GroupVersionResource{
+	Group: "work.open-cluster-management.io", Version: "v1alpha1", Resource: "manifestworkreplicasets"}
+
+// RenderManifestWorkReplicaSets returns the ManifestWorkReplicaSets of the subscription and mch
+// manifestworks of the hub configuration in the given namespace, with the Placement selecting the
+// managed hubs they are bound to. The work API creates the manifestworks in the namespaces of the
+// selected clusters and aggregates their status.
+//
+// The MultiClusterHub is not gated on the subscription, it is applied by the work agent once the
+// operator has installed its CRD. As with the policies, the annotations and overrides of the
+// managed clusters do not apply.
+func RenderManifestWorkReplicaSets(namespace string, config *HubConfig) ([]*unstructured.Unstructured, error) {
+	mch, err := renderFleetMCH(config)
+	if err != nil {
+		return nil, err
+	}
+	subscriptionWork, err := renderFleetSubscription(namespace, config)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := newManifestWorkReplicaSet(namespace, HOH_HUB_CLUSTER_SUBSCRIPTION, subscriptionWork)
+	if err != nil {
+		return nil, err
+	}
+	mchReplicaSet, err := newManifestWorkReplicaSet(namespace, HOH_HUB_CLUSTER_MCH,
+		newMCHManifestwork(namespace, mch))
+	if err != nil {
+		return nil, err
+	}
+	return []*unstructured.Unstructured{
+		subscription,
+		mchReplicaSet,
+		newPolicyPlacement(namespace, config),
+	}, nil
+}
+
+// newManifestWorkReplicaSet returns the ManifestWorkReplicaSet of the given manifestwork bound to the
+// Placement of the managed hubs
+func newManifestWorkReplicaSet(namespace, name string, work *workv1.ManifestWork) (*unstructured.Unstructured, error) {
+	raw, err := json.Marshal(work.Spec)
+	if err != nil {
+		return nil, err
+	}
+	template := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &template); err != nil {
+		return nil, err
+	}
+	return newGovernanceObject("work.open-cluster-management.io/v1alpha1", "ManifestWorkReplicaSet",
+		namespace, name, map[string]interface{}{
+			"manifestWorkTemplate": template,
+			"placementRefs": []interface{}{
+				map[string]interface{}{"name": HOH_HUB_CLUSTER_PLACEMENT},
+			},
+		}), nil
+}
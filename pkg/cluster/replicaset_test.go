@@ -0,0 +1,35 @@
+package cluster
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRenderManifestWorkReplicaSets(t *testing.T) {
+	objects, err := RenderManifestWorkReplicaSets("test", DefaultHubConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 3 {
+		t.Fatalf("expected 2 replica sets and a placement, got %d objects", len(objects))
+	}
+	for i, name := range []string{HOH_HUB_CLUSTER_SUBSCRIPTION, HOH_HUB_CLUSTER_MCH} {
+		replicaSet := objects[i]
+		if replicaSet.GetKind() != "ManifestWorkReplicaSet" || replicaSet.GetName() != name {
+			t.Errorf("unexpected %s %s", replicaSet.GetKind(), replicaSet.GetName())
+		}
+		refs, _, _ := unstructured.NestedSlice(replicaSet.Object, "spec", "placementRefs")
+		if len(refs) != 1 || refs[0].(map[string]interface{})["name"] != HOH_HUB_CLUSTER_PLACEMENT {
+			t.Errorf("expected %s to be bound to the placement, got %v", name, refs)
+		}
+	}
+	manifests, _, _ := unstructured.NestedSlice(objects[1].Object, "spec", "manifestWorkTemplate", "workload", "manifests")
+	if len(manifests) != 1 || manifests[0].(map[string]interface{})["kind"] != "MultiClusterHub" {
+		t.Errorf("expected the mch replica set to apply the MultiClusterHub, got %v", manifests)
+	}
+	configs, _, _ := unstructured.NestedSlice(objects[0].Object, "spec", "manifestWorkTemplate", "manifestConfigs")
+	if len(configs) == 0 {
+		t.Errorf("expected the status feedback of the subscription to be kept")
+	}
+}
@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"sync"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// syncedResourceCache guards the library-go resource cache, which is a plain map, so the cache can
+// be shared by the concurrent workers of a controller.
+type syncedResourceCache struct {
+	lock  sync.Mutex
+	cache resourceapply.ResourceCache
+}
+
+func newSyncedResourceCache() *syncedResourceCache {
+	return &syncedResourceCache{cache: resourceapply.NewResourceCache()}
+}
+
+func (c *syncedResourceCache) UpdateCachedResourceMetadata(required runtime.Object, actual runtime.Object) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cache.UpdateCachedResourceMetadata(required, actual)
+}
+
+func (c *syncedResourceCache) SafeToSkipApply(required runtime.Object, existing runtime.Object) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cache.SafeToSkipApply(required, existing)
+}
@@ -0,0 +1,26 @@
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSyncedResourceCacheConcurrentWorkers(t *testing.T) {
+	cache := newSyncedResourceCache()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			work := CreateSubManifestwork(fmt.Sprintf("cluster%d", i), DefaultHubConfig())
+			work.ResourceVersion = "1"
+			cache.UpdateCachedResourceMetadata(work, work)
+			if !cache.SafeToSkipApply(work, work) {
+				t.Errorf("expected the cached manifestwork %s to be skipped", work.Name)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// HOH_RESOURCE_PROFILE_ANNOTATION is the resource profile of the hub of the managed cluster, one of
+// the resourceProfiles configuration or the built-in minimal, medium and large profiles. It sets the
+// availability, the components and the annotations of the MultiClusterHub together.
+const HOH_RESOURCE_PROFILE_ANNOTATION = "hoh-resource-profile"
+
+// ResourceProfile is a preset of the MultiClusterHub of the managed hubs.
+type ResourceProfile struct {
+	// AvailabilityConfig is the availability of the MultiClusterHub, it is not preset if empty
+	AvailabilityConfig string `json:"availabilityConfig,omitempty"`
+	// Components are the components of the MultiClusterHub to enable or disable, on top of the
+	// components of the hub configuration
+	Components map[string]bool `json:"components,omitempty"`
+	// Annotations are set on the MultiClusterHub
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// builtinResourceProfiles returns the built-in resource profiles, from a single node hub running the
+// only components a managed hub needs to a highly available hub with all the components
+func builtinResourceProfiles() map[string]ResourceProfile {
+	return map[string]ResourceProfile{
+		"minimal": {
+			AvailabilityConfig: AVAILABILITY_BASIC,
+			Components:         profileComponents(MCHProfileLeaf),
+		},
+		"medium": {
+			AvailabilityConfig: AVAILABILITY_BASIC,
+			Components:         map[string]bool{"cluster-backup": false, "insights": false},
+		},
+		"large": {
+			AvailabilityConfig: AVAILABILITY_HIGH,
+		},
+	}
+}
+
+// resourceProfileMCH returns the MultiClusterHub of the resource profile of the managed cluster, to be
+// merged onto the default one, or an empty string if it has no profile. Its components are merged with
+// the components of the hub configuration by their name. An error is returned for an unknown profile.
+func resourceProfileMCH(managedCluster *clusterv1.ManagedCluster, config *HubConfig) (string, error) {
+	name := managedCluster.Annotations[HOH_RESOURCE_PROFILE_ANNOTATION]
+	if name == "" {
+		return "", nil
+	}
+	profile, ok := config.ResourceProfiles[name]
+	if !ok {
+		return "", fmt.Errorf("the resource profile %s of the %s annotation is not found", name, HOH_RESOURCE_PROFILE_ANNOTATION)
+	}
+
+	spec := map[string]interface{}{}
+	if profile.AvailabilityConfig != "" {
+		spec["availabilityConfig"] = profile.AvailabilityConfig
+	}
+	if len(profile.Components) > 0 {
+		spec["overrides"] = map[string]interface{}{"components": renderComponents(profile.Components)}
+	}
+	mch := map[string]interface{}{"spec": spec}
+	if len(profile.Annotations) > 0 {
+		mch["metadata"] = map[string]interface{}{"annotations": profile.Annotations}
+	}
+	raw, err := json.Marshal(mch)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
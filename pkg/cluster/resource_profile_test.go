@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// withResourceProfile returns the managed cluster with the given resource profile annotation
+func withResourceProfile(managedCluster *clusterv1.ManagedCluster, profile string) *clusterv1.ManagedCluster {
+	managedCluster.Annotations = map[string]string{HOH_RESOURCE_PROFILE_ANNOTATION: profile}
+	return managedCluster
+}
+
+func TestResourceProfileMCH(t *testing.T) {
+	config, err := ParseHubConfig(newHubConfigMap(map[string]string{
+		HUB_CONFIG_MCH_COMPONENTS_KEY:    `{"volsync":false}`,
+		HUB_CONFIG_RESOURCE_PROFILES_KEY: `{"edge":{"availabilityConfig":"Basic","annotations":{"mch-pause":"false"}}}`,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mch, err := resourceProfileMCH(newManagedCluster("cluster1"), config); err != nil || mch != "" {
+		t.Errorf("expected no profile without annotation, got %q, %v", mch, err)
+	}
+	if _, err := resourceProfileMCH(withResourceProfile(newManagedCluster("cluster1"), "huge"), config); err == nil {
+		t.Errorf("expected an error for an unknown profile")
+	}
+
+	type component struct {
+		Name    string `json:"name"`
+		Enabled bool   `json:"enabled"`
+	}
+	type renderedMCH struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Spec struct {
+			AvailabilityConfig string `json:"availabilityConfig"`
+			Overrides          struct {
+				Components []component `json:"components"`
+			} `json:"overrides"`
+		} `json:"spec"`
+	}
+	render := func(profile string) renderedMCH {
+		managedCluster := withResourceProfile(newManagedCluster("cluster1"), profile)
+		profileMCH, err := resourceProfileMCH(managedCluster, config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rendered, _, err := RenderMCH(managedCluster, true, componentsMCH(config), profileMCH)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		mch := renderedMCH{}
+		if err := json.Unmarshal(rendered, &mch); err != nil {
+			t.Fatal(err)
+		}
+		return mch
+	}
+
+	// the components of the profile are merged with the configured ones
+	minimal := render("minimal")
+	expected := []component{
+		{Name: "volsync"},
+		{Name: "app-lifecycle"},
+		{Name: "cluster-backup"},
+		{Name: "console"},
+		{Name: "insights"},
+		{Name: "search"},
+	}
+	if minimal.Spec.AvailabilityConfig != AVAILABILITY_BASIC || !reflect.DeepEqual(minimal.Spec.Overrides.Components, expected) {
+		t.Errorf("unexpected minimal profile %+v", minimal.Spec)
+	}
+	large := render("large")
+	if large.Spec.AvailabilityConfig != AVAILABILITY_HIGH ||
+		!reflect.DeepEqual(large.Spec.Overrides.Components, []component{{Name: "volsync"}}) {
+		t.Errorf("unexpected large profile %+v", large.Spec)
+	}
+	edge := render("edge")
+	if edge.Spec.AvailabilityConfig != AVAILABILITY_BASIC || edge.Metadata.Annotations["mch-pause"] != "false" {
+		t.Errorf("unexpected configured profile %+v", edge)
+	}
+}
@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// RestoresResource is the resource of the Restores of the cluster backup operator
+var RestoresResource = schema.GroupVersionResource{
+	Group:    "cluster.open-cluster-management.io",
+	Version:  "v1beta1",
+	Resource: "restores",
+}
+
+// RESTORE_NAMESPACE is the namespace of the Restores of the cluster backup operator
+const RESTORE_NAMESPACE = "open-cluster-management-backup"
+
+// the phases of a Restore in progress. A Restore syncing the backups to a passive hub of hubs is
+// Enabled until it is activated.
+var restoreActivePhases = map[string]bool{
+	"":        true,
+	"Started": true,
+	"Running": true,
+	"Enabled": true,
+}
+
+// IsRestoreInProgress returns true if the Restore is still restoring the backups.
+func IsRestoreInProgress(restore *unstructured.Unstructured) bool {
+	phase, _, _ := unstructured.NestedString(restore.Object, "status", "phase")
+	return restoreActivePhases[phase]
+}
+
+// restoring returns the name of the Restore in progress on the hub of hubs, or an empty string. The
+// manifestworks are not written while the backups are restored, so the controller does not fight the
+// restore nor uninstall the hubs of the managed clusters not restored yet. All managed hubs are
+// synced again when a Restore is changed.
+func (c *clusterController) restoring(ctx context.Context) string {
+	if c.restoreLister == nil {
+		return ""
+	}
+	restores, err := c.restoreLister.ByNamespace(RESTORE_NAMESPACE).List(labels.Everything())
+	if err != nil {
+		loggerFrom(ctx).Error(err, "Failed to list the restores")
+		return ""
+	}
+	for _, obj := range restores {
+		restore, ok := obj.(*unstructured.Unstructured)
+		if ok && IsRestoreInProgress(restore) {
+			return restore.GetName()
+		}
+	}
+	return ""
+}
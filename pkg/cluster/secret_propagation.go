@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// propagatedSecret returns the copy of the secret of the controller namespace to be installed on the
+// managed hubs with the given name and namespace, and the hash of its content so the workloads using
+// it can be rolled out when it is rotated. A missing secret is returned as an error, so nothing is
+// installed with a secret which does not exist.
+func (c *clusterController) propagatedSecret(source, name, namespace string) (workv1.Manifest, string, error) {
+	if c.secretLister == nil {
+		return workv1.Manifest{}, "", fmt.Errorf("the secrets are not watched")
+	}
+	secret, err := c.secretLister.Get(source)
+	if errors.IsNotFound(err) {
+		return workv1.Manifest{}, "", fmt.Errorf("the secret %s is not found in the namespace %s",
+			source, c.options.ConfigNamespace)
+	}
+	if err != nil {
+		return workv1.Manifest{}, "", err
+	}
+	raw, err := json.Marshal(&corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: secret.Type,
+		Data: secret.Data,
+	})
+	if err != nil {
+		return workv1.Manifest{}, "", err
+	}
+	return workv1.Manifest{RawExtension: runtime.RawExtension{Raw: raw}}, fmt.Sprintf("%x", sha256.Sum256(raw)), nil
+}
+
+// propagatedConfigMap returns the copy of the ConfigMap of the controller namespace to be installed
+// on the managed hubs with the given name and namespace, and the hash of its content.
+func (c *clusterController) propagatedConfigMap(source, name, namespace string) (workv1.Manifest, string, error) {
+	if c.hubConfig == nil {
+		return workv1.Manifest{}, "", fmt.Errorf("the configmaps are not watched")
+	}
+	configMap, err := c.hubConfig.lister.Get(source)
+	if errors.IsNotFound(err) {
+		return workv1.Manifest{}, "", fmt.Errorf("the configmap %s is not found in the namespace %s",
+			source, c.options.ConfigNamespace)
+	}
+	if err != nil {
+		return workv1.Manifest{}, "", err
+	}
+	raw, err := json.Marshal(&corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Data:       configMap.Data,
+		BinaryData: configMap.BinaryData,
+	})
+	if err != nil {
+		return workv1.Manifest{}, "", err
+	}
+	return workv1.Manifest{RawExtension: runtime.RawExtension{Raw: raw}}, fmt.Sprintf("%x", sha256.Sum256(raw)), nil
+}
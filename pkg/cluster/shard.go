@@ -0,0 +1,21 @@
+package cluster
+
+import (
+	"hash/fnv"
+)
+
+// ShardOf returns the shard of the managed cluster among the given number of shards, from a hash of
+// its name, so the shard of a cluster is stable as long as the number of shards is unchanged.
+func ShardOf(managedClusterName string, shardCount int) int {
+	if shardCount <= 1 {
+		return 0
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(managedClusterName))
+	return int(hash.Sum32() % uint32(shardCount))
+}
+
+// ownsCluster returns true if the managed cluster belongs to the shard of the controller.
+func (c *clusterController) ownsCluster(managedClusterName string) bool {
+	return ShardOf(managedClusterName, c.options.ShardCount) == c.options.ShardIndex
+}
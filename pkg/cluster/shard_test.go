@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func TestShardOf(t *testing.T) {
+	if shard := ShardOf("cluster1", 1); shard != 0 {
+		t.Errorf("expected shard 0 without sharding, got %d", shard)
+	}
+	if shard := ShardOf("cluster1", 0); shard != 0 {
+		t.Errorf("expected shard 0 for an unset shard count, got %d", shard)
+	}
+
+	counts := make([]int, 4)
+	for i := 0; i < 1000; i++ {
+		name := "cluster" + string(rune('a'+i%26)) + string(rune('a'+i/26))
+		shard := ShardOf(name, 4)
+		if shard < 0 || shard >= 4 {
+			t.Fatalf("shard %d of %s is out of range", shard, name)
+		}
+		if ShardOf(name, 4) != shard {
+			t.Fatalf("shard of %s is not stable", name)
+		}
+		counts[shard]++
+	}
+	for shard, count := range counts {
+		if count < 150 {
+			t.Errorf("expected the clusters to be spread over the shards, shard %d has %d of 1000", shard, count)
+		}
+	}
+}
+
+func TestSyncSkipsClustersOfOtherShards(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{managedCluster})
+	ctrl.options.ShardCount = 2
+	ctrl.options.ShardIndex = 1 - ShardOf("cluster1", 2)
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := ctrl.workClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected the hub of another shard not to be reconciled, got %v", actions)
+	}
+
+	ctrl.options.ShardIndex = ShardOf("cluster1", 2)
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_SUBSCRIPTION, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the hub of the shard to be reconciled: %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// HOH_HUB_VERSION_LABEL is set on the managed cluster to the version of the installed hub,
+// so that placements and other controllers are able to select clusters by hub version.
+const HOH_HUB_VERSION_LABEL = "hoh-hub-version"
+
+// condition types set on the managed cluster status to report the hub installation
+const (
+	HubConditionInstalling = "HubInstalling"
+	HubConditionInstalled  = "HubInstalled"
+	HubConditionDegraded   = "HubDegraded"
+	// HubConditionOperatorParked and HubConditionMCHParked are true when the installation of the
+	// operator or the MultiClusterHub is no longer retried after repeated failures
+	HubConditionOperatorParked = "HubOperatorParked"
+	HubConditionMCHParked      = "HubMultiClusterHubParked"
+	// HubConditionAgentParked is true when the installation of the multicluster-global-hub agent is
+	// no longer retried after repeated failures
+	HubConditionAgentParked = "HubAgentParked"
+	// HubConditionMCHInvalid is true when the user defined mch does not match the MultiClusterHub
+	// schema, the mch manifestwork is not updated until it is fixed
+	HubConditionMCHInvalid = "HubMultiClusterHubInvalid"
+	// HubConditionArchitectureUnsupported is true when the configured hub has no build for the CPU
+	// architecture of the managed cluster, the subscription manifestwork is not updated until one is
+	// configured
+	HubConditionArchitectureUnsupported = "HubArchitectureUnsupported"
+	// HubConditionHyperShiftUnsupported is true when the managed cluster is a HyperShift hosted
+	// cluster and no catalog of the hub is configured for it, the subscription manifestwork is not
+	// created until one is configured
+	HubConditionHyperShiftUnsupported = "HubHyperShiftUnsupported"
+	// HubConditionInstallPending is true while the install of the hub waits for one of the installs
+	// in flight to complete, as capped by the maxConcurrentInstalls configuration
+	HubConditionInstallPending = "HubInstallPending"
+	// HubConditionReconcileError is true while the reconcile of a phase of the hub fails, its reason
+	// is the controller of the phase, its message the last error and its transition time the time the
+	// phase started failing
+	HubConditionReconcileError = "HubReconcileError"
+)
+
+// HubConditions computes the hub installation conditions of a managed cluster from the status
+// of the subscription and mch manifestworks. Either manifestwork is nil if not created yet.
+func HubConditions(subscription, mch *workv1.ManifestWork) []metav1.Condition {
+	installing := metav1.Condition{Type: HubConditionInstalling, Status: metav1.ConditionTrue}
+	installed := metav1.Condition{Type: HubConditionInstalled, Status: metav1.ConditionFalse}
+
+	state := GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_STATE_FEEDBACK)
+	phase := GetFeedbackValue(mch, "MultiClusterHub", MCH_PHASE_FEEDBACK)
+	switch {
+	case subscription == nil:
+		installing.Reason = "OperatorSubscriptionPending"
+		installing.Message = "The operator subscription manifestwork is being created"
+	case state != SUBSCRIPTION_STATE_AT_LATEST_KNOWN:
+		installing.Reason = "OperatorInstalling"
+		installing.Message = fmt.Sprintf("Waiting for the operator subscription to reach AtLatestKnown, current state is %q", state)
+	case mch == nil:
+		installing.Reason = "MultiClusterHubPending"
+		installing.Message = "The multiclusterhub manifestwork is being created"
+	case phase != MCH_PHASE_RUNNING:
+		installing.Reason = "MultiClusterHubInstalling"
+		installing.Message = fmt.Sprintf("The multiclusterhub is in phase %q", phase)
+	default:
+		installing.Status = metav1.ConditionFalse
+		installing.Reason = "HubInstalled"
+		installing.Message = "The hub is installed"
+		installed.Status = metav1.ConditionTrue
+		installed.Reason = "MultiClusterHubRunning"
+		installed.Message = fmt.Sprintf("The hub of version %s is running",
+			GetFeedbackValue(mch, "MultiClusterHub", MCH_VERSION_FEEDBACK))
+	}
+	if installed.Status == metav1.ConditionFalse {
+		installed.Reason = installing.Reason
+		installed.Message = installing.Message
+	}
+
+	degraded := metav1.Condition{
+		Type:    HubConditionDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  "AsExpected",
+		Message: "The hub manifestworks are applied",
+	}
+	switch {
+	case state == SUBSCRIPTION_STATE_UPGRADE_FAILED:
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = "OperatorUpgradeFailed"
+		degraded.Message = "The operator subscription failed to upgrade"
+		return []metav1.Condition{installing, installed, degraded}
+	case GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_RESOLUTION_FAILED_FEEDBACK) == string(metav1.ConditionTrue) &&
+		isCatalogUnavailable(GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_RESOLUTION_MESSAGE_FEEDBACK)):
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = "CatalogSourceUnavailable"
+		degraded.Message = fmt.Sprintf("The catalog source of the operator subscription is not available on the managed "+
+			"cluster, if its default catalogs are disabled set the %s claim to false and configure %s: %s",
+			MARKETPLACE_CLAIM, HUB_CONFIG_CATALOG_SOURCE_IMAGE_KEY,
+			GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_RESOLUTION_MESSAGE_FEEDBACK))
+		return []metav1.Condition{installing, installed, degraded}
+	case GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_RESOLUTION_FAILED_FEEDBACK) == string(metav1.ConditionTrue):
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = "OperatorResolutionFailed"
+		degraded.Message = fmt.Sprintf("The operator subscription failed to resolve: %s",
+			GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_RESOLUTION_MESSAGE_FEEDBACK))
+		return []metav1.Condition{installing, installed, degraded}
+	case IsResourceMissing(mch, "MultiClusterHub"):
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = "MultiClusterHubMissing"
+		degraded.Message = "The multiclusterhub was deleted on the managed cluster, it is being reapplied"
+		return []metav1.Condition{installing, installed, degraded}
+	}
+	for _, work := range []*workv1.ManifestWork{subscription, mch} {
+		if work == nil {
+			continue
+		}
+		if cond := meta.FindStatusCondition(work.Status.Conditions, workv1.WorkDegraded); cond != nil &&
+			cond.Status == metav1.ConditionTrue {
+			degraded.Status = metav1.ConditionTrue
+			degraded.Reason = "ManifestWorkDegraded"
+			degraded.Message = fmt.Sprintf("The manifestwork %s is degraded: %s", work.Name, cond.Message)
+			break
+		}
+		if cond := meta.FindStatusCondition(work.Status.Conditions, workv1.WorkApplied); cond != nil &&
+			cond.Status == metav1.ConditionFalse {
+			degraded.Status = metav1.ConditionTrue
+			degraded.Reason = "ManifestWorkNotApplied"
+			degraded.Message = fmt.Sprintf("The manifestwork %s is not applied: %s", work.Name, cond.Message)
+			break
+		}
+	}
+
+	return []metav1.Condition{installing, installed, degraded}
+}
+
+// CheckInstallTimeout marks the hub degraded in the given conditions if it is still installing
+// after the timeout since the subscription manifestwork was created. It returns the time left
+// until the timeout is reached, or zero if there is nothing left to wait for. A hub that timed
+// out is only rechecked when the status of its manifestworks changes.
+func CheckInstallTimeout(conditions []metav1.Condition, subscription, mch *workv1.ManifestWork,
+	timeout time.Duration, now time.Time) time.Duration {
+	if timeout <= 0 || subscription == nil || !meta.IsStatusConditionTrue(conditions, HubConditionInstalling) {
+		return 0
+	}
+	if remaining := subscription.CreationTimestamp.Add(timeout).Sub(now); remaining > 0 {
+		return remaining
+	}
+	// keep the more specific reason if the hub is degraded already
+	if meta.IsStatusConditionTrue(conditions, HubConditionDegraded) {
+		return 0
+	}
+
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:   HubConditionDegraded,
+		Status: metav1.ConditionTrue,
+		Reason: "InstallTimeout",
+		Message: fmt.Sprintf("The hub is not installed after %s, the operator subscription is in state %q and the multiclusterhub is in phase %q",
+			timeout,
+			GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_STATE_FEEDBACK),
+			GetFeedbackValue(mch, "MultiClusterHub", MCH_PHASE_FEEDBACK)),
+	})
+	return 0
+}
+
+// CheckFeedbackMissing marks the hub degraded in the given conditions if the subscription
+// manifestwork is applied for longer than the timeout without reporting the state of the operator
+// subscription, as when the work agent of the managed cluster does not support or enable the status
+// feedback. It returns the time left until the timeout is reached, or zero if there is nothing left
+// to wait for.
+func CheckFeedbackMissing(conditions []metav1.Condition, subscription *workv1.ManifestWork,
+	timeout time.Duration, now time.Time) time.Duration {
+	if timeout <= 0 || subscription == nil ||
+		GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_STATE_FEEDBACK) != "" {
+		return 0
+	}
+	applied := meta.FindStatusCondition(subscription.Status.Conditions, workv1.WorkApplied)
+	if applied == nil || applied.Status != metav1.ConditionTrue {
+		return 0
+	}
+	if remaining := applied.LastTransitionTime.Add(timeout).Sub(now); remaining > 0 {
+		return remaining
+	}
+	if meta.IsStatusConditionTrue(conditions, HubConditionDegraded) {
+		return 0
+	}
+
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:   HubConditionDegraded,
+		Status: metav1.ConditionTrue,
+		Reason: "OperatorFeedbackMissing",
+		Message: fmt.Sprintf("The manifestwork %s is applied for %s but reports no state of the operator subscription, "+
+			"check that the work agent of the managed cluster supports and enables the status feedback, or set the %s "+
+			"annotation to create the multiclusterhub without it",
+			subscription.Name, timeout, HOH_SKIP_CSV_GATE_ANNOTATION),
+	})
+	return 0
+}
+
+// reconcileErrorCondition returns the condition reporting the failed reconcile of the phase of the
+// controller
+func (c *clusterController) reconcileErrorCondition(err error) metav1.Condition {
+	return metav1.Condition{
+		Type:    HubConditionReconcileError,
+		Status:  metav1.ConditionTrue,
+		Reason:  c.name,
+		Message: err.Error(),
+	}
+}
+
+// updateHubConditions sets the given conditions on the managed cluster status, the status is only
+// updated if any condition is changed.
+func (c *clusterController) updateHubConditions(ctx context.Context,
+	managedCluster *clusterv1.ManagedCluster, conditions ...metav1.Condition) error {
+	updated := managedCluster.DeepCopy()
+	for _, cond := range conditions {
+		meta.SetStatusCondition(&updated.Status.Conditions, cond)
+	}
+	if equality.Semantic.DeepEqual(managedCluster.Status.Conditions, updated.Status.Conditions) {
+		return nil
+	}
+
+	loggerFrom(ctx).V(2).Info("Updating hub conditions")
+	_, err := c.clusterclient.ManagedClusters().UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	// only record the events when the hub turns installed or degraded, to not flood the events on
+	// every resync
+	if meta.IsStatusConditionTrue(updated.Status.Conditions, HubConditionInstalled) &&
+		!meta.IsStatusConditionTrue(managedCluster.Status.Conditions, HubConditionInstalled) {
+		c.clusterRecorder.Event(managedClusterReference(managedCluster), corev1.EventTypeNormal,
+			EventReasonHubInstalled, "The hub is installed")
+	}
+	if degraded := meta.FindStatusCondition(updated.Status.Conditions, HubConditionDegraded); degraded != nil &&
+		degraded.Status == metav1.ConditionTrue &&
+		!meta.IsStatusConditionTrue(managedCluster.Status.Conditions, HubConditionDegraded) {
+		c.clusterRecorder.Eventf(managedClusterReference(managedCluster), corev1.EventTypeWarning,
+			EventReasonHubDegraded, "The hub is degraded: %s", degraded.Message)
+	}
+	return nil
+}
+
+// ensureHubVersionLabel patches the hub version label onto the managed cluster when the reported
+// version differs from the current label. Nothing is done until the version is reported.
+func (c *clusterController) ensureHubVersionLabel(ctx context.Context,
+	managedCluster *clusterv1.ManagedCluster, version string) error {
+	if version == "" || managedCluster.Labels[HOH_HUB_VERSION_LABEL] == version {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]string{
+				HOH_HUB_VERSION_LABEL: version,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	loggerFrom(ctx).V(2).Info("Labeling managed cluster with hub version", "version", version)
+	_, err = c.clusterclient.ManagedClusters().Patch(ctx, managedCluster.Name, types.MergePatchType,
+		patch, metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,117 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	clusterclientv1 "open-cluster-management.io/api/client/cluster/clientset/versioned/typed/cluster/v1"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	workclientv1 "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/metrics"
+)
+
+// statusController propagates the status of the hub manifestworks to the managed hubs, that is the
+// hub conditions and version label. It never applies manifestworks, so the status feedback of the
+// works is reported without going through the apply path, and the cluster status is only updated
+// when the conditions or version change.
+type statusController struct {
+	*clusterController
+	// viewClient creates the ManagedClusterViews verifying the MultiClusterHubs, it is nil when the
+	// views are not served
+	viewClient dynamic.NamespaceableResourceInterface
+	// views holds the managed clusters a ManagedClusterView was created for
+	views sync.Map
+}
+
+// NewStatusController creates a new hub status controller
+func NewStatusController(
+	clusterclient clusterclientv1.ClusterV1Interface,
+	workclient workclientv1.WorkV1Interface,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	workInformer workinformerv1.ManifestWorkInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	secretInformer corev1informers.SecretInformer,
+	overrideInformer informers.GenericInformer,
+	restoreInformer informers.GenericInformer,
+	viewClient dynamic.NamespaceableResourceInterface,
+	options ControllerOptions,
+	recorder events.Recorder,
+	clusterRecorder record.EventRecorder) factory.Controller {
+	// a status update is retried until it succeeds, there is nothing to park
+	options.MaxRetries = 0
+	c := &statusController{
+		clusterController: newClusterController("HubStatusController", clusterclient, workclient,
+			clusterInformer, workInformer, configMapInformer, secretInformer, overrideInformer, restoreInformer, options, "",
+			recorder, clusterRecorder),
+		viewClient: viewClient,
+	}
+	c.reconcile = c.reconcileStatus
+	return c.newFactory(clusterInformer, workInformer, configMapInformer, HOH_HUB_CLUSTER_SUBSCRIPTION, HOH_HUB_CLUSTER_MCH).
+		ToController(c.name, recorder)
+}
+
+func (c *statusController) reconcileStatus(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster) error {
+	subscription, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_SUBSCRIPTION)
+	if err != nil {
+		return err
+	}
+	mch, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_MCH)
+	if err != nil {
+		return err
+	}
+	if mch, err = c.verifyMCH(ctx, syncCtx, managedCluster, mch); err != nil {
+		return err
+	}
+
+	conditions := HubConditions(subscription, mch)
+	// the missing feedback is reported before the install timeout, as the more specific reason
+	if remaining := CheckFeedbackMissing(conditions, subscription, c.options.feedbackTimeout(), time.Now()); remaining > 0 {
+		syncCtx.Queue().AddAfter(managedCluster.Name, remaining)
+	}
+	// recheck the hub when the install timeout is reached, in case no status change is received
+	if remaining := CheckInstallTimeout(conditions, subscription, mch, c.options.InstallTimeout, time.Now()); remaining > 0 {
+		syncCtx.Queue().AddAfter(managedCluster.Name, remaining)
+	}
+	config := c.hubConfig.get()
+	config = config.forFlavor(Flavor(managedCluster, config))
+	if err := c.updateHubConditions(ctx, managedCluster,
+		append(conditions, CSVMismatchCondition(subscription, config), UpgradingCondition(subscription))...); err != nil {
+		return err
+	}
+	recordTransitionMetrics(managedCluster.Status.Conditions, conditions, subscription, time.Now())
+
+	return c.ensureHubVersionLabel(ctx, managedCluster,
+		GetFeedbackValue(mch, "MultiClusterHub", MCH_VERSION_FEEDBACK))
+}
+
+// recordTransitionMetrics records the hubs turning installed or degraded since the conditions last
+// reported on the managed cluster. The install duration is measured from the creation of the
+// subscription manifestwork.
+func recordTransitionMetrics(existing, conditions []metav1.Condition, subscription *workv1.ManifestWork, now time.Time) {
+	if meta.IsStatusConditionTrue(conditions, HubConditionInstalled) && !meta.IsStatusConditionTrue(existing, HubConditionInstalled) {
+		var installStart time.Time
+		if subscription != nil {
+			installStart = subscription.CreationTimestamp.Time
+		}
+		metrics.ObserveHubInstalled(installStart, now)
+	}
+	if degraded := meta.FindStatusCondition(conditions, HubConditionDegraded); degraded != nil &&
+		degraded.Status == metav1.ConditionTrue && !meta.IsStatusConditionTrue(existing, HubConditionDegraded) {
+		metrics.HubsFailed.WithLabelValues(degraded.Reason).Inc()
+	}
+}
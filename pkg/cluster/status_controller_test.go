@@ -0,0 +1,208 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+	"github.com/stolostron/hub-cluster-controller/pkg/metrics"
+)
+
+// newTestStatusController returns a test controller reporting the hub status
+func newTestStatusController(t *testing.T, managedClusters []*clusterv1.ManagedCluster, works []*workv1.ManifestWork) *testController {
+	ctrl := newTestController(t, managedClusters, works)
+	status := &statusController{clusterController: ctrl.clusterController}
+	ctrl.reconcile = status.reconcileStatus
+	ctrl.parkedCondition = ""
+	return ctrl
+}
+
+func TestStatusControllerSync(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN})
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mch = withFeedback(mch, "MultiClusterHub",
+		map[string]string{MCH_PHASE_FEEDBACK: MCH_PHASE_RUNNING, MCH_VERSION_FEEDBACK: "2.5.0"})
+	ctrl := newTestStatusController(t, []*clusterv1.ManagedCluster{managedCluster},
+		[]*workv1.ManifestWork{subscription, mch})
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ctrl.workClient.Actions()) != 0 {
+		t.Errorf("expected no manifestwork actions, got %v", ctrl.workClient.Actions())
+	}
+	updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, HubConditionInstalled) {
+		t.Errorf("expected the hub to be installed, got %v", updated.Status.Conditions)
+	}
+	if updated.Labels[HOH_HUB_VERSION_LABEL] != "2.5.0" {
+		t.Errorf("expected the hub version label 2.5.0, got %q", updated.Labels[HOH_HUB_VERSION_LABEL])
+	}
+}
+
+func TestStatusControllerPendingHub(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	ctrl := newTestStatusController(t, []*clusterv1.ManagedCluster{managedCluster}, nil)
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	installing := meta.FindStatusCondition(updated.Status.Conditions, HubConditionInstalling)
+	if installing == nil || installing.Reason != "OperatorSubscriptionPending" {
+		t.Errorf("expected the operator subscription to be pending, got %v", updated.Status.Conditions)
+	}
+}
+
+func TestStatusControllerVerifiesMCHWithView(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN})
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the mch is applied but its phase is not reported
+	mch.Status.Conditions = []metav1.Condition{{Type: workv1.WorkApplied, Status: metav1.ConditionTrue}}
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{subscription, mch})
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{ManagedClusterViewsResource: "ManagedClusterViewList"})
+	status := &statusController{clusterController: ctrl.clusterController,
+		viewClient: dynamicClient.Resource(ManagedClusterViewsResource)}
+	ctrl.reconcile = status.reconcileStatus
+	ctrl.parkedCondition = ""
+	views := dynamicClient.Resource(ManagedClusterViewsResource).Namespace("cluster1")
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	view, err := views.Get(context.TODO(), HOH_HUB_CLUSTER_MCH_VIEW, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the view of the mch to be created: %v", err)
+	}
+
+	// the view reads the running mch
+	view.Object["status"] = map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"type": "Processing", "status": "True"}},
+		"result": map[string]interface{}{
+			"status": map[string]interface{}{"phase": MCH_PHASE_RUNNING, "currentVersion": "2.5.0"},
+		},
+	}
+	if _, err := views.Update(context.TODO(), view, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, HubConditionInstalled) {
+		t.Errorf("expected the hub to be installed from the view, got %v", updated.Status.Conditions)
+	}
+
+	// the view is deleted once the feedback is reported again
+	if err := ctrl.workIndexer.Update(withFeedback(mch.DeepCopy(), "MultiClusterHub",
+		map[string]string{MCH_PHASE_FEEDBACK: MCH_PHASE_RUNNING, MCH_VERSION_FEEDBACK: "2.5.0"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := views.Get(context.TODO(), HOH_HUB_CLUSTER_MCH_VIEW, metav1.GetOptions{}); err == nil {
+		t.Errorf("expected the view of the mch to be deleted")
+	}
+}
+
+func TestIsMCHFeedbackAmbiguous(t *testing.T) {
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsMCHFeedbackAmbiguous(mch) {
+		t.Errorf("expected the feedback of the mch not applied yet not to be ambiguous")
+	}
+	mch = withFeedback(mch, "MultiClusterHub", map[string]string{MCH_PHASE_FEEDBACK: MCH_PHASE_RUNNING})
+	mch.Status.Conditions = []metav1.Condition{{Type: workv1.WorkApplied, Status: metav1.ConditionTrue}}
+	if IsMCHFeedbackAmbiguous(mch) {
+		t.Errorf("expected the reported phase not to be ambiguous")
+	}
+	mch.Status.ResourceStatus.Manifests[0].Conditions = []metav1.Condition{
+		{Type: MANIFEST_FEEDBACK_SYNCED, Status: metav1.ConditionFalse},
+	}
+	if !IsMCHFeedbackAmbiguous(mch) {
+		t.Errorf("expected the phase not synced to be ambiguous")
+	}
+}
+
+func TestRecordTransitionMetrics(t *testing.T) {
+	installed := []metav1.Condition{
+		{Type: HubConditionInstalled, Status: metav1.ConditionTrue},
+		{Type: HubConditionDegraded, Status: metav1.ConditionFalse},
+	}
+	degraded := []metav1.Condition{
+		{Type: HubConditionInstalled, Status: metav1.ConditionFalse},
+		{Type: HubConditionDegraded, Status: metav1.ConditionTrue, Reason: "InstallTimeout"},
+	}
+	now := time.Now()
+	subscription := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	subscription.CreationTimestamp = metav1.NewTime(now.Add(-10 * time.Minute))
+
+	installedBefore, _ := testutil.GetCounterMetricValue(metrics.HubsInstalled)
+	durationsBefore := installDurationCount(t)
+	failedBefore, _ := testutil.GetCounterMetricValue(metrics.HubsFailed.WithLabelValues("InstallTimeout"))
+
+	recordTransitionMetrics(nil, installed, subscription, now)
+	// no transition, nothing is recorded
+	recordTransitionMetrics(installed, installed, subscription, now)
+	recordTransitionMetrics(installed, degraded, subscription, now)
+	recordTransitionMetrics(degraded, degraded, subscription, now)
+
+	if value, _ := testutil.GetCounterMetricValue(metrics.HubsInstalled); value-installedBefore != 1 {
+		t.Errorf("expected 1 installed hub, got %v", value-installedBefore)
+	}
+	if count := installDurationCount(t); count-durationsBefore != 1 {
+		t.Errorf("expected 1 install duration, got %v", count-durationsBefore)
+	}
+	if value, _ := testutil.GetCounterMetricValue(metrics.HubsFailed.WithLabelValues("InstallTimeout")); value-failedBefore != 1 {
+		t.Errorf("expected 1 failed hub, got %v", value-failedBefore)
+	}
+}
+
+// installDurationCount returns the number of observed hub install durations.
+func installDurationCount(t *testing.T) uint64 {
+	families, err := legacyregistry.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() == "open_cluster_management_hub_controller_hub_install_duration_seconds" {
+			return family.GetMetric()[0].GetHistogram().GetSampleCount()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,443 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestEnsureHubVersionLabel(t *testing.T) {
+	cases := []struct {
+		name            string
+		labels          map[string]string
+		version         string
+		expectedActions int
+	}{
+		{
+			name:            "version not reported",
+			version:         "",
+			expectedActions: 0,
+		},
+		{
+			name:            "label is up to date",
+			labels:          map[string]string{HOH_HUB_VERSION_LABEL: "2.4.1"},
+			version:         "2.4.1",
+			expectedActions: 0,
+		},
+		{
+			name:            "label is missing",
+			version:         "2.4.1",
+			expectedActions: 1,
+		},
+		{
+			name:            "label is outdated",
+			labels:          map[string]string{HOH_HUB_VERSION_LABEL: "2.4.0"},
+			version:         "2.4.1",
+			expectedActions: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Labels: c.labels},
+			}
+			clusterClient := clusterfake.NewSimpleClientset(managedCluster)
+			ctrl := &clusterController{clusterclient: clusterClient.ClusterV1()}
+
+			if err := ctrl.ensureHubVersionLabel(context.TODO(), managedCluster, c.version); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(clusterClient.Actions()) != c.expectedActions {
+				t.Fatalf("expected %d actions, got %v", c.expectedActions, clusterClient.Actions())
+			}
+			if c.expectedActions == 0 {
+				return
+			}
+			patched, err := clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if patched.Labels[HOH_HUB_VERSION_LABEL] != c.version {
+				t.Errorf("expected label %q, got %q", c.version, patched.Labels[HOH_HUB_VERSION_LABEL])
+			}
+		})
+	}
+}
+
+// withFeedback sets the given string feedback values for the manifest of kind on the work.
+func withFeedback(work *workv1.ManifestWork, kind string, values map[string]string) *workv1.ManifestWork {
+	condition := workv1.ManifestCondition{ResourceMeta: workv1.ManifestResourceMeta{Kind: kind}}
+	for name, value := range values {
+		value := value
+		condition.StatusFeedbacks.Values = append(condition.StatusFeedbacks.Values, workv1.FeedbackValue{
+			Name:  name,
+			Value: workv1.FieldValue{Type: workv1.String, String: &value},
+		})
+	}
+	work.Status.ResourceStatus.Manifests = append(work.Status.ResourceStatus.Manifests, condition)
+	return work
+}
+
+// withMissingResource reports the resource of the given kind as not existing on the managed cluster
+func withMissingResource(work *workv1.ManifestWork, kind string) *workv1.ManifestWork {
+	work.Status.ResourceStatus.Manifests = append(work.Status.ResourceStatus.Manifests, workv1.ManifestCondition{
+		ResourceMeta: workv1.ManifestResourceMeta{Kind: kind},
+		Conditions: []metav1.Condition{
+			{Type: string(workv1.ManifestAvailable), Status: metav1.ConditionFalse, Reason: "ResourceNotAvailable"},
+		},
+	})
+	return work
+}
+
+func TestHubConditions(t *testing.T) {
+	newMCH := func() *workv1.ManifestWork {
+		mch, _ := CreateMCHManifestwork("cluster1", "")
+		return mch
+	}
+	atLatestKnown := func() *workv1.ManifestWork {
+		return withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+			map[string]string{SUBSCRIPTION_STATE_FEEDBACK: "AtLatestKnown"})
+	}
+
+	cases := []struct {
+		name               string
+		subscription       *workv1.ManifestWork
+		mch                *workv1.ManifestWork
+		expectedInstalling metav1.ConditionStatus
+		expectedInstalled  metav1.ConditionStatus
+		expectedDegraded   metav1.ConditionStatus
+		expectedReason     string
+	}{
+		{
+			name:               "subscription not created",
+			expectedInstalling: metav1.ConditionTrue,
+			expectedInstalled:  metav1.ConditionFalse,
+			expectedDegraded:   metav1.ConditionFalse,
+			expectedReason:     "OperatorSubscriptionPending",
+		},
+		{
+			name:               "operator installing",
+			subscription:       CreateSubManifestwork("cluster1", DefaultHubConfig()),
+			expectedInstalling: metav1.ConditionTrue,
+			expectedInstalled:  metav1.ConditionFalse,
+			expectedDegraded:   metav1.ConditionFalse,
+			expectedReason:     "OperatorInstalling",
+		},
+		{
+			name:               "mch installing",
+			subscription:       atLatestKnown(),
+			mch:                withFeedback(newMCH(), "MultiClusterHub", map[string]string{MCH_PHASE_FEEDBACK: "Installing"}),
+			expectedInstalling: metav1.ConditionTrue,
+			expectedInstalled:  metav1.ConditionFalse,
+			expectedDegraded:   metav1.ConditionFalse,
+			expectedReason:     "MultiClusterHubInstalling",
+		},
+		{
+			name:         "mch running",
+			subscription: atLatestKnown(),
+			mch: withFeedback(newMCH(), "MultiClusterHub", map[string]string{
+				MCH_PHASE_FEEDBACK:   MCH_PHASE_RUNNING,
+				MCH_VERSION_FEEDBACK: "2.4.1",
+			}),
+			expectedInstalling: metav1.ConditionFalse,
+			expectedInstalled:  metav1.ConditionTrue,
+			expectedDegraded:   metav1.ConditionFalse,
+			expectedReason:     "MultiClusterHubRunning",
+		},
+		{
+			name: "operator upgrade failed",
+			subscription: withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+				map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_UPGRADE_FAILED}),
+			expectedInstalling: metav1.ConditionTrue,
+			expectedInstalled:  metav1.ConditionFalse,
+			expectedDegraded:   metav1.ConditionTrue,
+			expectedReason:     "OperatorInstalling",
+		},
+		{
+			name: "operator resolution failed",
+			subscription: withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription", map[string]string{
+				SUBSCRIPTION_RESOLUTION_FAILED_FEEDBACK:  "True",
+				SUBSCRIPTION_RESOLUTION_MESSAGE_FEEDBACK: "constraints not satisfiable",
+			}),
+			expectedInstalling: metav1.ConditionTrue,
+			expectedInstalled:  metav1.ConditionFalse,
+			expectedDegraded:   metav1.ConditionTrue,
+			expectedReason:     "OperatorInstalling",
+		},
+		{
+			name: "subscription work not applied",
+			subscription: func() *workv1.ManifestWork {
+				work := CreateSubManifestwork("cluster1", DefaultHubConfig())
+				work.Status.Conditions = []metav1.Condition{
+					{Type: workv1.WorkApplied, Status: metav1.ConditionFalse, Message: "forbidden"},
+				}
+				return work
+			}(),
+			expectedInstalling: metav1.ConditionTrue,
+			expectedInstalled:  metav1.ConditionFalse,
+			expectedDegraded:   metav1.ConditionTrue,
+			expectedReason:     "OperatorInstalling",
+		},
+		{
+			name:               "mch deleted on the managed cluster",
+			subscription:       atLatestKnown(),
+			mch:                withMissingResource(newMCH(), "MultiClusterHub"),
+			expectedInstalling: metav1.ConditionTrue,
+			expectedInstalled:  metav1.ConditionFalse,
+			expectedDegraded:   metav1.ConditionTrue,
+			expectedReason:     "MultiClusterHubInstalling",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conditions := HubConditions(c.subscription, c.mch)
+			installing := meta.FindStatusCondition(conditions, HubConditionInstalling)
+			installed := meta.FindStatusCondition(conditions, HubConditionInstalled)
+			degraded := meta.FindStatusCondition(conditions, HubConditionDegraded)
+			if installing.Status != c.expectedInstalling {
+				t.Errorf("expected installing %s, got %s", c.expectedInstalling, installing.Status)
+			}
+			if installed.Status != c.expectedInstalled {
+				t.Errorf("expected installed %s, got %s", c.expectedInstalled, installed.Status)
+			}
+			if degraded.Status != c.expectedDegraded {
+				t.Errorf("expected degraded %s, got %s", c.expectedDegraded, degraded.Status)
+			}
+			if installed.Reason != c.expectedReason {
+				t.Errorf("expected reason %s, got %s", c.expectedReason, installed.Reason)
+			}
+		})
+	}
+}
+
+func TestUpdateHubConditions(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	clusterClient := clusterfake.NewSimpleClientset(managedCluster)
+	ctrl := &clusterController{
+		clusterclient:   clusterClient.ClusterV1(),
+		clusterRecorder: record.NewFakeRecorder(10),
+	}
+
+	conditions := HubConditions(nil, nil)
+	if err := ctrl.updateHubConditions(context.TODO(), managedCluster, conditions...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(clusterClient.Actions()) != 1 {
+		t.Fatalf("expected 1 action, got %v", clusterClient.Actions())
+	}
+
+	updated, err := clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clusterClient.ClearActions()
+	if err := ctrl.updateHubConditions(context.TODO(), updated, conditions...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(clusterClient.Actions()) != 0 {
+		t.Fatalf("expected no action, got %v", clusterClient.Actions())
+	}
+}
+
+func TestUpdateHubConditionsRecordsDegradedEvent(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	clusterClient := clusterfake.NewSimpleClientset(managedCluster)
+	recorder := record.NewFakeRecorder(10)
+	ctrl := &clusterController{clusterclient: clusterClient.ClusterV1(), clusterRecorder: recorder}
+
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_UPGRADE_FAILED})
+	if err := ctrl.updateHubConditions(context.TODO(), managedCluster, HubConditions(subscription, nil)...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if events := recordedEvents(recorder); len(events) != 1 ||
+		!strings.HasPrefix(events[0], corev1.EventTypeWarning+" "+EventReasonHubDegraded) {
+		t.Fatalf("expected a HubDegraded event, got %v", events)
+	}
+
+	// the event is not recorded again while the hub stays degraded
+	updated, err := clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conditions := HubConditions(subscription, nil)
+	conditions[2].Message = "changed"
+	if err := ctrl.updateHubConditions(context.TODO(), updated, conditions...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if events := recordedEvents(recorder); len(events) != 0 {
+		t.Fatalf("expected no other event, got %v", events)
+	}
+}
+
+func TestUpdateHubConditionsRecordsInstalledEvent(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	clusterClient := clusterfake.NewSimpleClientset(managedCluster)
+	recorder := record.NewFakeRecorder(10)
+	ctrl := &clusterController{clusterclient: clusterClient.ClusterV1(), clusterRecorder: recorder}
+
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN})
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mch = withFeedback(mch, "MultiClusterHub", map[string]string{MCH_PHASE_FEEDBACK: MCH_PHASE_RUNNING})
+	if err := ctrl.updateHubConditions(context.TODO(), managedCluster, HubConditions(subscription, mch)...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if events := recordedEvents(recorder); len(events) != 1 ||
+		!strings.HasPrefix(events[0], corev1.EventTypeNormal+" "+EventReasonHubInstalled) {
+		t.Fatalf("expected a HubInstalled event, got %v", events)
+	}
+}
+
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	events := []string{}
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestCheckInstallTimeout(t *testing.T) {
+	now := time.Now()
+	newSubscription := func(age time.Duration, state string) *workv1.ManifestWork {
+		work := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+			map[string]string{SUBSCRIPTION_STATE_FEEDBACK: state})
+		work.CreationTimestamp = metav1.NewTime(now.Add(-age))
+		return work
+	}
+
+	cases := []struct {
+		name              string
+		subscription      *workv1.ManifestWork
+		timeout           time.Duration
+		expectedRemaining time.Duration
+		expectedDegraded  string
+	}{
+		{
+			name:             "timeout disabled",
+			subscription:     newSubscription(2*time.Hour, "UpgradePending"),
+			timeout:          0,
+			expectedDegraded: "AsExpected",
+		},
+		{
+			name:              "still within the timeout",
+			subscription:      newSubscription(20*time.Minute, "UpgradePending"),
+			timeout:           time.Hour,
+			expectedRemaining: 40 * time.Minute,
+			expectedDegraded:  "AsExpected",
+		},
+		{
+			name:             "timed out",
+			subscription:     newSubscription(2*time.Hour, "UpgradePending"),
+			timeout:          time.Hour,
+			expectedDegraded: "InstallTimeout",
+		},
+		{
+			name:             "timed out and degraded already",
+			subscription:     newSubscription(2*time.Hour, SUBSCRIPTION_STATE_UPGRADE_FAILED),
+			timeout:          time.Hour,
+			expectedDegraded: "OperatorUpgradeFailed",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conditions := HubConditions(c.subscription, nil)
+			remaining := CheckInstallTimeout(conditions, c.subscription, nil, c.timeout, now)
+			if remaining != c.expectedRemaining {
+				t.Errorf("expected remaining %s, got %s", c.expectedRemaining, remaining)
+			}
+			if reason := meta.FindStatusCondition(conditions, HubConditionDegraded).Reason; reason != c.expectedDegraded {
+				t.Errorf("expected degraded reason %s, got %s", c.expectedDegraded, reason)
+			}
+		})
+	}
+}
+
+func TestCheckFeedbackMissing(t *testing.T) {
+	now := time.Now()
+	newSubscription := func(applied time.Duration, feedback map[string]string) *workv1.ManifestWork {
+		work := CreateSubManifestwork("cluster1", DefaultHubConfig())
+		if feedback != nil {
+			work = withFeedback(work, "Subscription", feedback)
+		}
+		work.Status.Conditions = []metav1.Condition{{
+			Type:               workv1.WorkApplied,
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(now.Add(-applied)),
+		}}
+		return work
+	}
+
+	cases := []struct {
+		name              string
+		subscription      *workv1.ManifestWork
+		timeout           time.Duration
+		expectedRemaining time.Duration
+		expectedDegraded  string
+	}{
+		{
+			name:             "check disabled",
+			subscription:     newSubscription(time.Hour, nil),
+			timeout:          0,
+			expectedDegraded: "AsExpected",
+		},
+		{
+			name:              "still within the timeout",
+			subscription:      newSubscription(4*time.Minute, nil),
+			timeout:           10 * time.Minute,
+			expectedRemaining: 6 * time.Minute,
+			expectedDegraded:  "AsExpected",
+		},
+		{
+			name:             "feedback missing",
+			subscription:     newSubscription(time.Hour, nil),
+			timeout:          10 * time.Minute,
+			expectedDegraded: "OperatorFeedbackMissing",
+		},
+		{
+			name:             "state reported",
+			subscription:     newSubscription(time.Hour, map[string]string{SUBSCRIPTION_STATE_FEEDBACK: "UpgradePending"}),
+			timeout:          10 * time.Minute,
+			expectedDegraded: "AsExpected",
+		},
+		{
+			name:             "not applied yet",
+			subscription:     CreateSubManifestwork("cluster1", DefaultHubConfig()),
+			timeout:          10 * time.Minute,
+			expectedDegraded: "AsExpected",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conditions := HubConditions(c.subscription, nil)
+			remaining := CheckFeedbackMissing(conditions, c.subscription, c.timeout, now)
+			if remaining != c.expectedRemaining {
+				t.Errorf("expected remaining %s, got %s", c.expectedRemaining, remaining)
+			}
+			if reason := meta.FindStatusCondition(conditions, HubConditionDegraded).Reason; reason != c.expectedDegraded {
+				t.Errorf("expected degraded reason %s, got %s", c.expectedDegraded, reason)
+			}
+		})
+	}
+}
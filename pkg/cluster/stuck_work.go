@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// EventReasonWorkFinalizersRemoved is the reason of the event recorded when the finalizers of a
+// manifestwork stuck in deletion are removed
+const EventReasonWorkFinalizersRemoved = "ManifestWorkFinalizersRemoved"
+
+// removeStuckFinalizers removes the finalizers of the hub manifestworks of a deleted managed cluster
+// which are stuck in deletion for longer than the stuck work timeout. The work agent of a deleted
+// managed cluster is gone, so nothing removes the finalizers of its manifestworks, and the managed
+// cluster namespace can not be cleaned up. Only the manifestworks created by the controller in the
+// managed cluster namespace are remediated, the work agent of the hosting cluster of a hosted
+// managed cluster still removes its finalizers. The managed cluster is confirmed deleted with the
+// apiserver first, since the cache may lag behind a managed cluster recreated with the same name.
+func (c *clusterController) removeStuckFinalizers(ctx context.Context, syncCtx factory.SyncContext,
+	managedClusterName string) error {
+	timeout := c.options.StuckWorkTimeout
+	if timeout <= 0 {
+		return nil
+	}
+	works, err := c.listManifestWorks(managedClusterName)
+	if err != nil {
+		return err
+	}
+	var stuck []*workv1.ManifestWork
+	var recheck time.Duration
+	for _, work := range works {
+		if work.DeletionTimestamp == nil || len(work.Finalizers) == 0 || work.Namespace != managedClusterName ||
+			!c.ownsWork(work) {
+			continue
+		}
+		if remaining := timeout - time.Since(work.DeletionTimestamp.Time); remaining > 0 {
+			if recheck == 0 || remaining < recheck {
+				recheck = remaining
+			}
+			continue
+		}
+		stuck = append(stuck, work)
+	}
+	if recheck > 0 {
+		syncCtx.Queue().AddAfter(managedClusterName, recheck)
+	}
+	if len(stuck) == 0 {
+		return nil
+	}
+
+	_, err = c.clusterclient.ManagedClusters().Get(ctx, managedClusterName, metav1.GetOptions{})
+	if err == nil {
+		// the managed cluster is recreated, its work agent handles the deletion again
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+	logger := loggerFrom(ctx)
+	for _, work := range stuck {
+		logger.Info("Removing the finalizers of the manifestwork stuck in deletion", "manifestwork", work.Name,
+			"deletionTimestamp", work.DeletionTimestamp, "finalizers", work.Finalizers)
+		// the resource version precondition fails if the work agent came back meanwhile
+		patch := fmt.Sprintf(`{"metadata":{"finalizers":null,"resourceVersion":%q}}`, work.ResourceVersion)
+		_, err := c.workclient.ManifestWorks(work.Namespace).Patch(ctx, work.Name, types.MergePatchType,
+			[]byte(patch), metav1.PatchOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		c.eventRecorder.Warningf(EventReasonWorkFinalizersRemoved,
+			"Removed the finalizers of the manifestwork %s/%s, stuck in deletion since %s after its managed cluster was deleted",
+			work.Namespace, work.Name, work.DeletionTimestamp.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ownsWork returns true if the manifestwork is of a type created by the controller
+func (c *clusterController) ownsWork(work *workv1.ManifestWork) bool {
+	for _, workType := range c.ownedWorks {
+		if IsWorkOfType(work, workType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func newDeletingMCHWork(t *testing.T, deletedSince time.Duration) *workv1.ManifestWork {
+	work, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletionTimestamp := metav1.NewTime(time.Now().Add(-deletedSince))
+	work.DeletionTimestamp = &deletionTimestamp
+	work.Finalizers = []string{"cluster.open-cluster-management.io/manifest-work-cleanup"}
+	return work
+}
+
+func TestRemoveStuckFinalizers(t *testing.T) {
+	cases := []struct {
+		name           string
+		timeout        time.Duration
+		deletedSince   time.Duration
+		managedCluster *clusterv1.ManagedCluster
+		expectedPatch  bool
+	}{
+		{name: "disabled", deletedSince: 2 * time.Hour},
+		{name: "within the timeout", timeout: time.Hour, deletedSince: time.Minute},
+		{name: "stuck", timeout: time.Hour, deletedSince: 2 * time.Hour, expectedPatch: true},
+		{name: "managed cluster recreated", timeout: time.Hour, deletedSince: 2 * time.Hour,
+			managedCluster: newManagedCluster("cluster1")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := newTestController(t, nil, []*workv1.ManifestWork{newDeletingMCHWork(t, c.deletedSince)})
+			ctrl.options.StuckWorkTimeout = c.timeout
+			ctrl.ownedWorks = []string{HOH_HUB_CLUSTER_MCH}
+			if c.managedCluster != nil {
+				// the managed cluster is recreated but not in the cache yet
+				if _, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Create(context.TODO(), c.managedCluster,
+					metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+			if err := ctrl.removeStuckFinalizers(context.TODO(), syncCtx, "cluster1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			patched := false
+			for _, action := range ctrl.workClient.Actions() {
+				if action.GetVerb() == "patch" {
+					patched = true
+					if patch := string(action.(clienttesting.PatchAction).GetPatch()); patch !=
+						`{"metadata":{"finalizers":null,"resourceVersion":""}}` {
+						t.Errorf("unexpected patch %s", patch)
+					}
+				}
+			}
+			if patched != c.expectedPatch {
+				t.Errorf("expected patched %v, got %v", c.expectedPatch, ctrl.workClient.Actions())
+			}
+		})
+	}
+}
+
+func TestRemoveStuckFinalizersOwnedWorks(t *testing.T) {
+	ctrl := newTestController(t, nil, []*workv1.ManifestWork{newDeletingMCHWork(t, 2*time.Hour)})
+	ctrl.options.StuckWorkTimeout = time.Hour
+	ctrl.ownedWorks = []string{HOH_HUB_CLUSTER_SUBSCRIPTION}
+
+	if err := ctrl.removeStuckFinalizers(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1"), "cluster1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ctrl.workClient.Actions()) != 0 {
+		t.Errorf("expected the manifestworks of the other controllers to be left, got %v", ctrl.workClient.Actions())
+	}
+}
@@ -0,0 +1,127 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	clusterclientv1 "open-cluster-management.io/api/client/cluster/clientset/versioned/typed/cluster/v1"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	workclientv1 "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// subscriptionController applies the manifestwork installing the operator subscription on the
+// managed hubs.
+type subscriptionController struct {
+	*clusterController
+	installs installLimiter
+}
+
+// NewSubscriptionController creates a new operator subscription controller
+func NewSubscriptionController(
+	clusterclient clusterclientv1.ClusterV1Interface,
+	workclient workclientv1.WorkV1Interface,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	workInformer workinformerv1.ManifestWorkInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	secretInformer corev1informers.SecretInformer,
+	overrideInformer informers.GenericInformer,
+	restoreInformer informers.GenericInformer,
+	options ControllerOptions,
+	recorder events.Recorder,
+	clusterRecorder record.EventRecorder) factory.Controller {
+	c := &subscriptionController{
+		clusterController: newClusterController("SubscriptionController", clusterclient, workclient,
+			clusterInformer, workInformer, configMapInformer, secretInformer, overrideInformer, restoreInformer, options,
+			HubConditionOperatorParked, recorder, clusterRecorder),
+	}
+	c.reconcile = c.reconcileSubscription
+	c.ownedWorks = []string{HOH_HUB_CLUSTER_SUBSCRIPTION}
+	return c.newFactory(clusterInformer, workInformer, configMapInformer, HOH_HUB_CLUSTER_SUBSCRIPTION).
+		// copy the trust bundle of the hub operator to all managed hubs when it is rotated
+		WithFilteredEventsInformersQueueKeyFunc(
+			func(obj runtime.Object) string {
+				return factory.DefaultQueueKey
+			},
+			func(obj interface{}) bool {
+				accessor, err := objectMeta(obj)
+				return err == nil && accessor.GetName() == c.hubConfig.get().TrustedCABundleConfigMap
+			}, configMapInformer.Informer()).
+		ToController(c.name, recorder)
+}
+
+func (c *subscriptionController) reconcileSubscription(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster) error {
+	if c.paused(ctx, managedCluster) || !c.inMaintenanceWindow(ctx, syncCtx, managedCluster) {
+		return nil
+	}
+	config := c.hubConfig.get()
+	desired, err := c.desiredSubManifestWork(managedCluster, config)
+	var unsupported *UnsupportedArchitectureError
+	if errors.As(err, &unsupported) {
+		// retrying does not help, the managed cluster is synced again once the configuration is changed
+		loggerFrom(ctx).Error(err, "Skipping the update of the subscription manifestwork")
+		c.clusterRecorder.Eventf(managedClusterReference(managedCluster), corev1.EventTypeWarning,
+			EventReasonUnsupportedArchitecture, "The subscription manifestwork is not updated: %v", err)
+		return c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    HubConditionArchitectureUnsupported,
+			Status:  metav1.ConditionTrue,
+			Reason:  "NoBuildForArchitecture",
+			Message: err.Error(),
+		})
+	}
+	var hyperShift *UnsupportedHyperShiftError
+	if errors.As(err, &hyperShift) {
+		loggerFrom(ctx).Error(err, "Skipping the update of the subscription manifestwork")
+		c.clusterRecorder.Eventf(managedClusterReference(managedCluster), corev1.EventTypeWarning,
+			EventReasonUnsupportedHyperShift, "The subscription manifestwork is not updated: %v", err)
+		return c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    HubConditionHyperShiftUnsupported,
+			Status:  metav1.ConditionTrue,
+			Reason:  "NoCatalogForHostedCluster",
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if meta.IsStatusConditionTrue(managedCluster.Status.Conditions, HubConditionHyperShiftUnsupported) {
+		if err := c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    HubConditionHyperShiftUnsupported,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AsExpected",
+			Message: "The catalog of the hub of the HyperShift hosted cluster is configured",
+		}); err != nil {
+			return err
+		}
+	}
+	if meta.IsStatusConditionTrue(managedCluster.Status.Conditions, HubConditionArchitectureUnsupported) {
+		if err := c.updateHubConditions(ctx, managedCluster, metav1.Condition{
+			Type:    HubConditionArchitectureUnsupported,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AsExpected",
+			Message: "The configured hub has a build for the architecture of the managed cluster",
+		}); err != nil {
+			return err
+		}
+	}
+	if !c.waveOpen(ctx, syncCtx, managedCluster, desired) {
+		return nil
+	}
+	if available, err := c.installSlotAvailable(ctx, syncCtx, managedCluster); err != nil || !available {
+		return err
+	}
+	_, err = c.applyManifestWork(ctx, managedCluster, desired)
+	return err
+}
@@ -0,0 +1,130 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func newTestSubscriptionController(t *testing.T, managedClusters []*clusterv1.ManagedCluster) *testController {
+	ctrl := newTestController(t, managedClusters, nil)
+	subscription := &subscriptionController{clusterController: ctrl.clusterController}
+	ctrl.reconcile = subscription.reconcileSubscription
+	ctrl.parkedCondition = HubConditionOperatorParked
+	return ctrl
+}
+
+func TestSubscriptionControllerSync(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{managedCluster})
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_SUBSCRIPTION, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the subscription manifestwork to be created: %v", err)
+	}
+	if _, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_MCH, metav1.GetOptions{}); err == nil {
+		t.Errorf("expected the mch manifestwork not to be created")
+	}
+}
+
+func TestSubscriptionControllerParks(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{managedCluster})
+	ctrl.backoff = newClusterBackoff(1, 0)
+	ctrl.workClient.PrependReactor("patch", "manifestworks", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("failed to apply")
+	})
+
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster1")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, err := ctrl.clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, HubConditionOperatorParked) {
+		t.Errorf("expected the operator to be parked, got %v", updated.Status.Conditions)
+	}
+	if meta.FindStatusCondition(updated.Status.Conditions, HubConditionMCHParked) != nil {
+		t.Errorf("expected the mch not to be parked, got %v", updated.Status.Conditions)
+	}
+}
+
+func TestSubscriptionControllerSkipsPausedCluster(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:        "cluster1",
+		Annotations: map[string]string{HOH_PAUSE_ANNOTATION: "true"},
+	}}
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{managedCluster})
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := ctrl.workClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no manifestwork to be written for the paused cluster, got %v", actions)
+	}
+}
+
+func TestSubscriptionControllerSkipsPausedFleet(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{managedCluster})
+	ctrl.hubConfig = newHubConfigLoader(newConfigMapLister(t,
+		newHubConfigMap(map[string]string{HUB_CONFIG_PAUSED_KEY: "true"})))
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := ctrl.workClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no manifestwork to be written while the fleet is paused, got %v", actions)
+	}
+}
+
+func TestSubscriptionControllerSkipsRestore(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{managedCluster})
+	restore := &unstructured.Unstructured{}
+	restore.SetNamespace(RESTORE_NAMESPACE)
+	restore.SetName("restore-acm")
+	if err := unstructured.SetNestedField(restore.Object, "Running", "status", "phase"); err != nil {
+		t.Fatal(err)
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(restore); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.restoreLister = cache.NewGenericLister(indexer, RestoresResource.GroupResource())
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := ctrl.workClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no manifestwork to be written while the hub of hubs is restored, got %v", actions)
+	}
+
+	// the manifestworks are written once the restore is finished
+	if err := unstructured.SetNestedField(restore.Object, "Finished", "status", "phase"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := ctrl.workClient.Actions(); len(actions) == 0 {
+		t.Errorf("expected the subscription manifestwork to be written once the restore is finished")
+	}
+}
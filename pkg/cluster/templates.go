@@ -0,0 +1,151 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// names of the manifest templates in the template directory, each of them is optional
+const (
+	SUBSCRIPTION_TEMPLATE_FILE = "subscription.yaml"
+	MCH_TEMPLATE_FILE          = "mch.yaml"
+)
+
+// the manifest templates replacing the built-in operator subscription and MultiClusterHub, loaded
+// once before the controllers are started
+var (
+	subscriptionTemplate *manifestTemplate
+	mchTemplate          *manifestTemplate
+)
+
+// subscriptionEnforcedFields are the fields of the operator subscription identifying it for the status
+// feedback, they are set on the subscription template
+var subscriptionEnforcedFields = []manifestField{
+	{path: []string{"apiVersion"}, value: "operators.coreos.com/v1alpha1"},
+	{path: []string{"kind"}, value: "Subscription"},
+	{path: []string{"metadata", "name"}, value: "acm-operator-subscription"},
+	{path: []string{"metadata", "namespace"}, value: "open-cluster-management"},
+}
+
+// TemplateData is the data the manifest templates are executed with, the values of the managed
+// cluster the manifest is rendered for. The manifests shared by the fleet, in the Policy and
+// ManifestWorkReplicaSet deployment modes, are rendered without values.
+type TemplateData struct {
+	// Name is the name of the managed cluster
+	Name string
+	// Labels are the labels of the managed cluster
+	Labels map[string]string
+	// Annotations are the annotations of the managed cluster
+	Annotations map[string]string
+	// Claims are the values of the ClusterClaims of the managed cluster by name, such as
+	// region.open-cluster-management.io
+	Claims map[string]string
+}
+
+// NewTemplateData returns the template data of the managed cluster.
+func NewTemplateData(managedCluster *clusterv1.ManagedCluster) *TemplateData {
+	claims := map[string]string{}
+	for _, claim := range managedCluster.Status.ClusterClaims {
+		claims[claim.Name] = claim.Value
+	}
+	return &TemplateData{
+		Name:        managedCluster.Name,
+		Labels:      managedCluster.Labels,
+		Annotations: managedCluster.Annotations,
+		Claims:      claims,
+	}
+}
+
+// manifestTemplate is a Go template of a YAML manifest of the given kind
+type manifestTemplate struct {
+	file     string
+	kind     string
+	template *template.Template
+}
+
+// LoadManifestTemplates loads the operator subscription and MultiClusterHub templates of the directory,
+// such as a mounted ConfigMap, replacing the built-in ones. The templates are Go templates executed
+// with the TemplateData of each managed cluster. The controller keeps rendering the channel, catalog
+// source and starting CSV of the subscription, and the enforced fields of the MultiClusterHub, the
+// other fields are taken from the templates. The built-in manifest is kept if a template is missing.
+func LoadManifestTemplates(dir string) error {
+	subscription, err := loadManifestTemplate(filepath.Join(dir, SUBSCRIPTION_TEMPLATE_FILE), "Subscription")
+	if err != nil {
+		return err
+	}
+	mch, err := loadManifestTemplate(filepath.Join(dir, MCH_TEMPLATE_FILE), "MultiClusterHub")
+	if err != nil {
+		return err
+	}
+	subscriptionTemplate, mchTemplate = subscription, mch
+	return nil
+}
+
+// loadManifestTemplate parses the template of a YAML manifest of the given kind from the file, it
+// returns nil if the file does not exist. The template is checked by executing it without values.
+func loadManifestTemplate(file, kind string) (*manifestTemplate, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// referencing a missing label, annotation or claim fails the rendering, the optional ones are
+	// referenced with index
+	tmpl, err := template.New(filepath.Base(file)).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", file, err)
+	}
+	check, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := (&manifestTemplate{file: file, kind: kind, template: check.Option("missingkey=zero")}).
+		execute(&TemplateData{}); err != nil {
+		return nil, err
+	}
+	klog.Infof("Loaded the %s template %s", kind, file)
+	return &manifestTemplate{file: file, kind: kind, template: tmpl}, nil
+}
+
+// execute renders the manifest of the template with the given data
+func (t *manifestTemplate) execute(data *TemplateData) (map[string]interface{}, error) {
+	out := &bytes.Buffer{}
+	if err := t.template.Execute(out, data); err != nil {
+		return nil, fmt.Errorf("failed to execute the template %s: %v", t.file, err)
+	}
+	manifest := map[string]interface{}{}
+	if err := yaml.Unmarshal(out.Bytes(), &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", t.file, err)
+	}
+	if manifest["kind"] != t.kind {
+		return nil, fmt.Errorf("invalid %s: the template is not a %s", t.file, t.kind)
+	}
+	if _, ok := manifest["spec"].(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("invalid %s: the template has no spec", t.file)
+	}
+	return manifest, nil
+}
+
+// renderManifestTemplate renders the template with the data of the managed cluster and sets the
+// given fields, it returns the manifest as indented JSON.
+func renderManifestTemplate(t *manifestTemplate, managedCluster *clusterv1.ManagedCluster,
+	fields ...manifestField) ([]byte, error) {
+	manifest, err := t.execute(NewTemplateData(managedCluster))
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		setNestedField(manifest, field.path, field.value)
+	}
+	return json.MarshalIndent(manifest, "", "\t")
+}
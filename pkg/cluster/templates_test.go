@@ -0,0 +1,158 @@
+package cluster
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func writeManifestTemplates(t *testing.T, templates map[string]string) string {
+	dir := t.TempDir()
+	for file, content := range templates {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		subscriptionTemplate, mchTemplate = nil, nil
+	})
+	return dir
+}
+
+func TestLoadManifestTemplates(t *testing.T) {
+	dir := writeManifestTemplates(t, map[string]string{
+		SUBSCRIPTION_TEMPLATE_FILE: `apiVersion: operators.coreos.com/v1alpha1
+kind: Subscription
+metadata:
+  name: renamed
+spec:
+  channel: release-2.3
+  installPlanApproval: Manual
+  name: advanced-cluster-management
+  source: redhat-operators
+  sourceNamespace: custom-marketplace
+  config:
+    env:
+    - name: CLUSTER
+      value: {{ .Name }}
+`,
+		MCH_TEMPLATE_FILE: `apiVersion: operator.open-cluster-management.io/v1
+kind: MultiClusterHub
+metadata:
+  name: multiclusterhub
+spec:
+  availabilityConfig: Basic
+  overrides:
+    storageClass: "{{ .Labels.region }}-{{ index .Claims "platform.open-cluster-management.io" }}"
+{{- with index .Annotations "hub-owner" }}
+  owner: {{ . }}
+{{- end }}
+`,
+	})
+	if err := LoadManifestTemplates(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	managedCluster := newManagedCluster("cluster1")
+	managedCluster.Labels = map[string]string{"region": "eu"}
+	managedCluster.Status.ClusterClaims = []clusterv1.ManagedClusterClaim{
+		{Name: "platform.open-cluster-management.io", Value: "AWS"},
+	}
+	config := DefaultHubConfig()
+	config.StartingCSV = "advanced-cluster-management.v2.5.1"
+	rendered, err := subscriptionManifest(managedCluster, config, "pre-release")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	subscription := map[string]interface{}{}
+	if err := json.Unmarshal(rendered, &subscription); err != nil {
+		t.Fatal(err)
+	}
+	spec := subscription["spec"].(map[string]interface{})
+	for field, expected := range map[string]string{
+		"channel":             config.Channel,
+		"source":              "pre-release",
+		"startingCSV":         "advanced-cluster-management.v2.5.1",
+		"installPlanApproval": "Manual",
+		"sourceNamespace":     "custom-marketplace",
+	} {
+		if spec[field] != expected {
+			t.Errorf("expected spec.%s %q, got %v", field, expected, spec[field])
+		}
+	}
+	if name, _ := nestedField(subscription, []string{"metadata", "name"}); name != "acm-operator-subscription" {
+		t.Errorf("expected the subscription name to be enforced, got %v", name)
+	}
+	if env, _ := nestedField(subscription, []string{"spec", "config", "env"}); !reflect.DeepEqual(env,
+		[]interface{}{map[string]interface{}{"name": "CLUSTER", "value": "cluster1"}}) {
+		t.Errorf("expected the cluster name to be rendered in the env, got %v", env)
+	}
+
+	rendered, conflicts, err := RenderMCH(managedCluster, true)
+	if err != nil || conflicts != nil {
+		t.Fatalf("unexpected error %v or conflicts %v", err, conflicts)
+	}
+	mch := map[string]interface{}{}
+	if err := json.Unmarshal(rendered, &mch); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := nestedField(mch, []string{"spec", "availabilityConfig"}); value != "Basic" {
+		t.Errorf("expected the availabilityConfig of the template, got %v", value)
+	}
+	if value, _ := nestedField(mch, []string{"spec", "disableHubSelfManagement"}); value != true {
+		t.Errorf("expected disableHubSelfManagement to be enforced, got %v", value)
+	}
+	if value, _ := nestedField(mch, []string{"metadata", "namespace"}); value != "open-cluster-management" {
+		t.Errorf("expected the namespace to be enforced, got %v", value)
+	}
+	if value, _ := nestedField(mch, []string{"spec", "overrides", "storageClass"}); value != "eu-AWS" {
+		t.Errorf("expected the storage class of the region and platform, got %v", value)
+	}
+	if _, ok := nestedField(mch, []string{"spec", "owner"}); ok {
+		t.Errorf("expected the optional owner annotation to be skipped")
+	}
+
+	managedCluster.Annotations = map[string]string{"hub-owner": "team-a"}
+	if rendered, _, err = RenderMCH(managedCluster, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(rendered), `"owner": "team-a"`) {
+		t.Errorf("expected the owner annotation to be rendered, got %s", rendered)
+	}
+
+	// a referenced label missing on the managed cluster fails the rendering
+	if _, _, err := RenderMCH(newManagedCluster("cluster2"), true); err == nil {
+		t.Errorf("expected the missing region label to be reported")
+	}
+}
+
+func TestLoadManifestTemplatesMissing(t *testing.T) {
+	if err := LoadManifestTemplates(writeManifestTemplates(t, nil)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if subscriptionTemplate != nil || mchTemplate != nil {
+		t.Errorf("expected the built-in manifests to be kept")
+	}
+}
+
+func TestLoadManifestTemplatesInvalid(t *testing.T) {
+	cases := map[string]string{
+		"wrong kind":     "apiVersion: v1\nkind: ConfigMap\nspec: {}\n",
+		"no spec":        "kind: MultiClusterHub\n",
+		"not yaml":       "kind: [",
+		"not a template": "kind: MultiClusterHub\nspec:\n  a: {{ .Labels\n",
+	}
+	for name, template := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := writeManifestTemplates(t, map[string]string{MCH_TEMPLATE_FILE: template})
+			if err := LoadManifestTemplates(dir); err == nil {
+				t.Errorf("expected an invalid template error")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+const (
+	// TRUSTED_CA_BUNDLE is the name of the ConfigMap of the additional trust bundle on the managed
+	// hubs, mounted in the hub operator
+	TRUSTED_CA_BUNDLE = "hub-trusted-ca-bundle"
+	// TRUSTED_CA_BUNDLE_KEY is the key of the trust bundle in the ConfigMap
+	TRUSTED_CA_BUNDLE_KEY = "ca-bundle.crt"
+	// TRUSTED_CA_BUNDLE_HASH_ENV is set on the hub operator to the hash of the trust bundle, so the
+	// operator is restarted when the bundle is rotated
+	TRUSTED_CA_BUNDLE_HASH_ENV = "TRUSTED_CA_BUNDLE_HASH"
+
+	// trustedCABundleMountPath replaces the system trust store of the operator image with the bundle
+	trustedCABundleMountPath = "/etc/pki/ca-trust/extracted/pem"
+)
+
+// withTrustedCABundle copies the trust bundle of the hub configuration from the controller namespace
+// to the install namespace of the subscription manifestwork, and mounts it in the hub operator with
+// the config of the operator subscription, so the operator trusts the internally signed registries.
+// The bundle replaces the trust store of the operator, it should include the public CAs the operator
+// still needs.
+func (c *clusterController) withTrustedCABundle(work *workv1.ManifestWork, config *HubConfig) error {
+	if config.TrustedCABundleConfigMap == "" {
+		return nil
+	}
+	bundle, hash, err := c.propagatedConfigMap(config.TrustedCABundleConfigMap, TRUSTED_CA_BUNDLE, "open-cluster-management")
+	if err != nil {
+		return err
+	}
+	manifests := work.Spec.Workload.Manifests
+	for i := range manifests {
+		subscription := &unstructured.Unstructured{}
+		if err := subscription.UnmarshalJSON(manifests[i].Raw); err != nil {
+			return err
+		}
+		if subscription.GetKind() != "Subscription" {
+			continue
+		}
+		if err := mountTrustedCABundle(subscription, hash); err != nil {
+			return err
+		}
+		raw, err := json.MarshalIndent(subscription.Object, "", "\t")
+		if err != nil {
+			return err
+		}
+		manifests[i] = workv1.Manifest{RawExtension: runtime.RawExtension{Raw: raw}}
+		// the bundle is applied before the subscription mounting it
+		work.Spec.Workload.Manifests = append(manifests[:i], append([]workv1.Manifest{bundle}, manifests[i:]...)...)
+		return nil
+	}
+	return fmt.Errorf("the subscription manifestwork %s has no operator subscription", work.Name)
+}
+
+// mountTrustedCABundle adds the volume and mount of the trust bundle to the config of the operator
+// subscription, with the hash of the bundle as environment variable
+func mountTrustedCABundle(subscription *unstructured.Unstructured, hash string) error {
+	config, _, err := unstructured.NestedMap(subscription.Object, "spec", "config")
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	for field, value := range map[string]interface{}{
+		"volumes": map[string]interface{}{
+			"name": TRUSTED_CA_BUNDLE,
+			"configMap": map[string]interface{}{
+				"name": TRUSTED_CA_BUNDLE,
+				"items": []interface{}{
+					map[string]interface{}{"key": TRUSTED_CA_BUNDLE_KEY, "path": "tls-ca-bundle.pem"},
+				},
+			},
+		},
+		"volumeMounts": map[string]interface{}{
+			"name":      TRUSTED_CA_BUNDLE,
+			"mountPath": trustedCABundleMountPath,
+			"readOnly":  true,
+		},
+		"env": map[string]interface{}{
+			"name":  TRUSTED_CA_BUNDLE_HASH_ENV,
+			"value": hash,
+		},
+	} {
+		values, _ := config[field].([]interface{})
+		config[field] = append(values, value)
+	}
+	return unstructured.SetNestedMap(subscription.Object, config, "spec", "config")
+}
@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWithTrustedCABundle(t *testing.T) {
+	config := DefaultHubConfig()
+	config.TrustedCABundleConfigMap = "registry-ca"
+	c := &clusterController{
+		hubConfig: newHubConfigLoader(newConfigMapLister(t, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-ca", Namespace: "test"},
+			Data:       map[string]string{TRUSTED_CA_BUNDLE_KEY: "ca"},
+		})),
+	}
+	work := CreateSubManifestwork("cluster1", config)
+	count := len(work.Spec.Workload.Manifests)
+	if err := c.withTrustedCABundle(work, config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manifests := work.Spec.Workload.Manifests
+	if len(manifests) != count+1 {
+		t.Fatalf("expected the trust bundle to be added, got %d manifests", len(manifests))
+	}
+
+	// the bundle is copied to the install namespace right before the subscription mounting it
+	bundle := &corev1.ConfigMap{}
+	if err := json.Unmarshal(manifests[len(manifests)-2].Raw, bundle); err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Kind != "ConfigMap" || bundle.Name != TRUSTED_CA_BUNDLE || bundle.Namespace != "open-cluster-management" ||
+		bundle.Data[TRUSTED_CA_BUNDLE_KEY] != "ca" {
+		t.Errorf("unexpected trust bundle %v", bundle)
+	}
+	var subscription struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Config struct {
+				Volumes      []corev1.Volume      `json:"volumes"`
+				VolumeMounts []corev1.VolumeMount `json:"volumeMounts"`
+				Env          []corev1.EnvVar      `json:"env"`
+			} `json:"config"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(manifests[len(manifests)-1].Raw, &subscription); err != nil {
+		t.Fatal(err)
+	}
+	subscriptionConfig := subscription.Spec.Config
+	if subscription.Kind != "Subscription" || len(subscriptionConfig.Volumes) != 1 ||
+		subscriptionConfig.Volumes[0].ConfigMap == nil || subscriptionConfig.Volumes[0].ConfigMap.Name != TRUSTED_CA_BUNDLE ||
+		len(subscriptionConfig.VolumeMounts) != 1 || subscriptionConfig.VolumeMounts[0].MountPath != trustedCABundleMountPath ||
+		len(subscriptionConfig.Env) != 1 || subscriptionConfig.Env[0].Name != TRUSTED_CA_BUNDLE_HASH_ENV {
+		t.Errorf("expected the trust bundle to be mounted in the operator, got %+v", subscriptionConfig)
+	}
+
+	config.TrustedCABundleConfigMap = "missing-ca"
+	if err := c.withTrustedCABundle(CreateSubManifestwork("cluster1", config), config); err == nil {
+		t.Errorf("expected an error for the missing trust bundle")
+	}
+}
@@ -0,0 +1,128 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	addonclientv1alpha1 "open-cluster-management.io/api/client/addon/clientset/versioned/typed/addon/v1alpha1"
+	clusterclientv1 "open-cluster-management.io/api/client/cluster/clientset/versioned/typed/cluster/v1"
+	workclientv1 "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+)
+
+// teardownOrder is the order the hub manifestworks are deleted in, the reverse of the installation:
+// the observability and the agent depend on the MultiClusterHub, which is uninstalled by its operator
+// before the operator subscription is removed.
+var teardownOrder = []string{
+	HOH_HUB_CLUSTER_OBSERVABILITY,
+	HOH_HUB_CLUSTER_AGENT,
+	HOH_HUB_CLUSTER_MCH,
+	HOH_HUB_CLUSTER_SUBSCRIPTION,
+}
+
+// Uninstaller uninstalls the hubs of named managed clusters.
+type Uninstaller struct {
+	clusterClient clusterclientv1.ClusterV1Interface
+	workClient    workclientv1.WorkV1Interface
+	addOnClient   addonclientv1alpha1.AddonV1alpha1Interface
+	interval      time.Duration
+}
+
+// NewUninstaller creates an uninstaller polling the deletion of the hub manifestworks at the given
+// interval
+func NewUninstaller(
+	clusterClient clusterclientv1.ClusterV1Interface,
+	workClient workclientv1.WorkV1Interface,
+	addOnClient addonclientv1alpha1.AddonV1alpha1Interface,
+	interval time.Duration) *Uninstaller {
+	return &Uninstaller{
+		clusterClient: clusterClient,
+		workClient:    workClient,
+		addOnClient:   addOnClient,
+		interval:      interval,
+	}
+}
+
+// Uninstall uninstalls the hub of the managed cluster and waits until it is removed. The managed
+// cluster is labeled hoh=disabled first so the controller does not install the hub again, then the
+// agent addon and the hub manifestworks are deleted one at a time in the teardown order, each
+// manifestwork being gone once the work agent has deleted its resources on the managed cluster. The
+// hub version label is removed at last. An interrupted uninstallation is resumed by running it again.
+func (u *Uninstaller) Uninstall(ctx context.Context, managedClusterName string) error {
+	managedCluster, err := u.clusterClient.ManagedClusters().Get(ctx, managedClusterName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if IsManagedHub(managedCluster) {
+		klog.Infof("Disabling the hub of managed cluster %s", managedClusterName)
+		if err := u.patchLabels(ctx, managedClusterName, map[string]interface{}{HOH_LABEL: HOH_LABEL_DISABLED}); err != nil {
+			return err
+		}
+	}
+
+	err = u.addOnClient.ManagedClusterAddOns(managedClusterName).Delete(ctx, AGENT_ADDON_NAME, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	// the manifestworks are found by their labels, whatever their naming scheme
+	works, err := u.workClient.ManifestWorks(managedClusterName).List(ctx, metav1.ListOptions{
+		LabelSelector: MANAGED_CLUSTER_LABEL + "=" + managedClusterName,
+	})
+	if err != nil {
+		return err
+	}
+	for _, workType := range teardownOrder {
+		for i := range works.Items {
+			if works.Items[i].Labels[MANAGED_BY_LABEL] != MANAGED_BY_VALUE || !IsWorkOfType(&works.Items[i], workType) {
+				continue
+			}
+			if err := u.deleteManifestWork(ctx, managedClusterName, works.Items[i].Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, ok := managedCluster.Labels[HOH_HUB_VERSION_LABEL]; ok {
+		return u.patchLabels(ctx, managedClusterName, map[string]interface{}{HOH_HUB_VERSION_LABEL: nil})
+	}
+	return nil
+}
+
+// deleteManifestWork deletes the manifestwork and waits until it is gone.
+func (u *Uninstaller) deleteManifestWork(ctx context.Context, namespace, name string) error {
+	err := u.workClient.ManifestWorks(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	klog.Infof("Waiting for the manifestwork %s/%s to be deleted", namespace, name)
+	return wait.PollImmediateUntil(u.interval, func() (bool, error) {
+		_, err := u.workClient.ManifestWorks(namespace).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}, ctx.Done())
+}
+
+func (u *Uninstaller) patchLabels(ctx context.Context, managedClusterName string, labels map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": labels,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = u.clusterClient.ManagedClusters().Patch(ctx, managedClusterName, types.MergePatchType, patch,
+		metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func TestUninstall(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	managedCluster.Labels = map[string]string{HOH_HUB_VERSION_LABEL: "2.5.0"}
+	clusterClient := clusterfake.NewSimpleClientset(managedCluster)
+	subscription := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the agent manifestwork was created with another naming scheme
+	agent := CreateAgentManifestwork("cluster1", DefaultHubConfig())
+	agent.Name = "renamed-agent"
+	workClient := workfake.NewSimpleClientset(subscription, mch, agent)
+	addOnClient := addonfake.NewSimpleClientset(&addonv1alpha1.ManagedClusterAddOn{
+		ObjectMeta: metav1.ObjectMeta{Name: AGENT_ADDON_NAME, Namespace: "cluster1"},
+	})
+
+	uninstaller := NewUninstaller(clusterClient.ClusterV1(), workClient.WorkV1(), addOnClient.AddonV1alpha1(), time.Millisecond)
+	if err := uninstaller.Uninstall(context.TODO(), "cluster1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deleted := []string{}
+	for _, action := range workClient.Actions() {
+		if deletion, ok := action.(clienttesting.DeleteActionImpl); ok {
+			deleted = append(deleted, deletion.GetName())
+		}
+	}
+	expected := []string{
+		"renamed-agent",
+		"cluster1-" + HOH_HUB_CLUSTER_MCH,
+		"cluster1-" + HOH_HUB_CLUSTER_SUBSCRIPTION,
+	}
+	if len(deleted) != len(expected) {
+		t.Fatalf("expected the manifestworks to be deleted in order %v, got %v", expected, deleted)
+	}
+	for i := range expected {
+		if deleted[i] != expected[i] {
+			t.Errorf("expected the manifestworks to be deleted in order %v, got %v", expected, deleted)
+			break
+		}
+	}
+	if works, _ := workClient.WorkV1().ManifestWorks("cluster1").List(context.TODO(), metav1.ListOptions{}); len(works.Items) != 0 {
+		t.Errorf("expected the hub manifestworks to be deleted, got %d", len(works.Items))
+	}
+	if addOns, _ := addOnClient.AddonV1alpha1().ManagedClusterAddOns("cluster1").List(context.TODO(), metav1.ListOptions{}); len(addOns.Items) != 0 {
+		t.Errorf("expected the agent addon to be deleted")
+	}
+
+	updated, err := clusterClient.ClusterV1().ManagedClusters().Get(context.TODO(), "cluster1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Labels[HOH_LABEL] != HOH_LABEL_DISABLED {
+		t.Errorf("expected the managed cluster to be disabled, got labels %v", updated.Labels)
+	}
+	if _, ok := updated.Labels[HOH_HUB_VERSION_LABEL]; ok {
+		t.Errorf("expected the hub version label to be removed, got labels %v", updated.Labels)
+	}
+
+	// the uninstallation is resumed without error once the hub is removed
+	if err := uninstaller.Uninstall(context.TODO(), "cluster1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUninstallTimeout(t *testing.T) {
+	mch, err := CreateMCHManifestwork("cluster1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workClient := workfake.NewSimpleClientset(mch)
+	// the work agent has not deleted the resources of the manifestwork yet
+	workClient.PrependReactor("delete", "manifestworks", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+	uninstaller := NewUninstaller(clusterfake.NewSimpleClientset(newManagedCluster("cluster1")).ClusterV1(),
+		workClient.WorkV1(), addonfake.NewSimpleClientset().AddonV1alpha1(), time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	if err := uninstaller.Uninstall(ctx, "cluster1"); err == nil {
+		t.Errorf("expected the uninstallation to time out while the mch manifestwork exists")
+	}
+}
+
+func TestSyncSkipsDisabledCluster(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	managedCluster.Labels = map[string]string{HOH_LABEL: HOH_LABEL_DISABLED}
+	ctrl := newTestSubscriptionController(t, []*clusterv1.ManagedCluster{managedCluster})
+
+	// the deletion of the manifestworks of an uninstalled hub requeues its managed cluster
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ctrl.workClient.Actions()) != 0 {
+		t.Errorf("expected no manifestwork actions, got %v", ctrl.workClient.Actions())
+	}
+}
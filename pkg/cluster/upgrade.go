@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// HubConditionUpgrading is true while the operator subscription replaces the installed CSV, the
+// changes of the other hub manifestworks are held until the upgrade settles
+const HubConditionUpgrading = "HubUpgrading"
+
+// IsUpgrading returns true if the operator subscription reports an installed CSV being replaced,
+// that is an upgrade pending or available, or a current CSV of the channel other than the installed
+// one. The first installation of the operator is not an upgrade.
+func IsUpgrading(subscription *workv1.ManifestWork) bool {
+	installed := GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_INSTALLED_CSV_FEEDBACK)
+	if installed == "" {
+		return false
+	}
+	switch GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_STATE_FEEDBACK) {
+	case SUBSCRIPTION_STATE_UPGRADE_PENDING, SUBSCRIPTION_STATE_UPGRADE_AVAILABLE:
+		return true
+	}
+	current := GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_CURRENT_CSV_FEEDBACK)
+	return current != "" && current != installed
+}
+
+// UpgradingCondition returns the condition reporting whether the operator subscription is upgrading
+// the installed CSV
+func UpgradingCondition(subscription *workv1.ManifestWork) metav1.Condition {
+	if !IsUpgrading(subscription) {
+		return metav1.Condition{
+			Type:    HubConditionUpgrading,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AsExpected",
+			Message: "The operator subscription is not upgrading",
+		}
+	}
+	return metav1.Condition{
+		Type:   HubConditionUpgrading,
+		Status: metav1.ConditionTrue,
+		Reason: "CSVReplacing",
+		Message: fmt.Sprintf("The operator subscription in state %q is replacing the installed CSV %s with %s",
+			GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_STATE_FEEDBACK),
+			GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_INSTALLED_CSV_FEEDBACK),
+			GetFeedbackValue(subscription, "Subscription", SUBSCRIPTION_CURRENT_CSV_FEEDBACK)),
+	}
+}
+
+// upgrading returns true if the changes of the hub manifestworks of the managed cluster are held
+// because its operator subscription is upgrading. The managed cluster is rechecked after the
+// operator recheck interval, in case the status event of the settled upgrade is missed.
+func (c *clusterController) upgrading(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster, subscription *workv1.ManifestWork) bool {
+	if !IsUpgrading(subscription) {
+		return false
+	}
+	loggerFrom(ctx).V(2).Info("Holding the hub changes until the operator upgrade settles")
+	if c.options.OperatorRecheckInterval > 0 {
+		syncCtx.Queue().AddAfter(managedCluster.Name, c.options.OperatorRecheckInterval)
+	}
+	return true
+}
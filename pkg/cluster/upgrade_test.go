@@ -0,0 +1,91 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func TestIsUpgrading(t *testing.T) {
+	cases := []struct {
+		name     string
+		feedback map[string]string
+		expected bool
+	}{
+		{
+			name:     "first installation",
+			feedback: map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_UPGRADE_PENDING},
+		},
+		{
+			name: "upgrade pending",
+			feedback: map[string]string{
+				SUBSCRIPTION_STATE_FEEDBACK:         SUBSCRIPTION_STATE_UPGRADE_PENDING,
+				SUBSCRIPTION_INSTALLED_CSV_FEEDBACK: "advanced-cluster-management.v2.4.1",
+			},
+			expected: true,
+		},
+		{
+			name: "upgrade available",
+			feedback: map[string]string{
+				SUBSCRIPTION_STATE_FEEDBACK:         SUBSCRIPTION_STATE_UPGRADE_AVAILABLE,
+				SUBSCRIPTION_INSTALLED_CSV_FEEDBACK: "advanced-cluster-management.v2.4.1",
+			},
+			expected: true,
+		},
+		{
+			name: "csv replacing",
+			feedback: map[string]string{
+				SUBSCRIPTION_INSTALLED_CSV_FEEDBACK: "advanced-cluster-management.v2.4.1",
+				SUBSCRIPTION_CURRENT_CSV_FEEDBACK:   "advanced-cluster-management.v2.4.2",
+			},
+			expected: true,
+		},
+		{
+			name: "settled",
+			feedback: map[string]string{
+				SUBSCRIPTION_STATE_FEEDBACK:         SUBSCRIPTION_STATE_AT_LATEST_KNOWN,
+				SUBSCRIPTION_INSTALLED_CSV_FEEDBACK: "advanced-cluster-management.v2.4.2",
+				SUBSCRIPTION_CURRENT_CSV_FEEDBACK:   "advanced-cluster-management.v2.4.2",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription", c.feedback)
+			if upgrading := IsUpgrading(subscription); upgrading != c.expected {
+				t.Errorf("expected upgrading %t, got %t", c.expected, upgrading)
+			}
+			if cond := UpgradingCondition(subscription); (cond.Status == metav1.ConditionTrue) != c.expected {
+				t.Errorf("expected upgrading %t, got condition %v", c.expected, cond)
+			}
+		})
+	}
+}
+
+func TestReconcileHoldsChangesWhileUpgrading(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:        "cluster1",
+		Annotations: map[string]string{HOH_SKIP_CSV_GATE_ANNOTATION: "true"},
+	}}
+	subscription := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription", map[string]string{
+		SUBSCRIPTION_STATE_FEEDBACK:         SUBSCRIPTION_STATE_UPGRADE_PENDING,
+		SUBSCRIPTION_INSTALLED_CSV_FEEDBACK: "advanced-cluster-management.v2.4.1",
+		SUBSCRIPTION_CURRENT_CSV_FEEDBACK:   "advanced-cluster-management.v2.4.2",
+	})
+	ctrl := newTestMCHController(t, []*clusterv1.ManagedCluster{managedCluster}, []*workv1.ManifestWork{subscription})
+
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "cluster1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ctrl.workClient.WorkV1().ManifestWorks("cluster1").
+		Get(context.TODO(), "cluster1-"+HOH_HUB_CLUSTER_MCH, metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the mch manifestwork to be held during the upgrade, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// ManagedClusterViewsResource is the resource of the ManagedClusterViews reading the resources of the
+// managed clusters
+var ManagedClusterViewsResource = schema.GroupVersionResource{
+	Group:    "view.open-cluster-management.io",
+	Version:  "v1beta1",
+	Resource: "managedclusterviews",
+}
+
+// HOH_HUB_CLUSTER_MCH_VIEW is the name of the ManagedClusterView of the MultiClusterHub of a managed
+// hub, created in the managed cluster namespace
+const HOH_HUB_CLUSTER_MCH_VIEW = "hoh-hub-cluster-mch"
+
+// MANIFEST_FEEDBACK_SYNCED is the condition type the work agent reports on a manifest once its status
+// feedback is synced
+const MANIFEST_FEEDBACK_SYNCED = "StatusFeedbackSynced"
+
+// viewRecheckInterval is the interval the MultiClusterHub is read again by its ManagedClusterView
+// while its status feedback is ambiguous
+const viewRecheckInterval = 30 * time.Second
+
+// IsMCHFeedbackAmbiguous returns true if the status feedback of the MultiClusterHub can not be
+// trusted: the manifestwork is applied but no phase is reported, or the work agent failed to sync
+// the feedback so the reported phase may be stale.
+func IsMCHFeedbackAmbiguous(mch *workv1.ManifestWork) bool {
+	if mch == nil {
+		return false
+	}
+	for _, manifest := range mch.Status.ResourceStatus.Manifests {
+		if manifest.ResourceMeta.Kind != "MultiClusterHub" {
+			continue
+		}
+		if cond := meta.FindStatusCondition(manifest.Conditions, MANIFEST_FEEDBACK_SYNCED); cond != nil &&
+			cond.Status == metav1.ConditionFalse {
+			return true
+		}
+	}
+	return meta.IsStatusConditionTrue(mch.Status.Conditions, workv1.WorkApplied) &&
+		GetFeedbackValue(mch, "MultiClusterHub", MCH_PHASE_FEEDBACK) == ""
+}
+
+// newMCHView returns the ManagedClusterView reading the MultiClusterHub of the managed hub
+func newMCHView(namespace string) *unstructured.Unstructured {
+	view := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": ManagedClusterViewsResource.GroupVersion().String(),
+		"kind":       "ManagedClusterView",
+		"metadata": map[string]interface{}{
+			"name":      HOH_HUB_CLUSTER_MCH_VIEW,
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				MANAGED_BY_LABEL:      MANAGED_BY_VALUE,
+				MANAGED_CLUSTER_LABEL: namespace,
+			},
+		},
+		"spec": map[string]interface{}{
+			"scope": map[string]interface{}{
+				"apiGroup":              "operator.open-cluster-management.io",
+				"version":               "v1",
+				"kind":                  "MultiClusterHub",
+				"resource":              "multiclusterhubs",
+				"name":                  "multiclusterhub",
+				"namespace":             "open-cluster-management",
+				"updateIntervalSeconds": int64(viewRecheckInterval / time.Second),
+			},
+		},
+	}}
+	return view
+}
+
+// viewedMCH returns the phase and the version of the MultiClusterHub read by the view, or an empty
+// phase if the view has not read it yet.
+func viewedMCH(view *unstructured.Unstructured) (phase, version string) {
+	conditions, _, _ := unstructured.NestedSlice(view.Object, "status", "conditions")
+	processing := false
+	for _, obj := range conditions {
+		condition, ok := obj.(map[string]interface{})
+		if ok && condition["type"] == "Processing" && condition["status"] == string(metav1.ConditionTrue) {
+			processing = true
+		}
+	}
+	if !processing {
+		return "", ""
+	}
+	phase, _, _ = unstructured.NestedString(view.Object, "status", "result", "status", "phase")
+	version, _, _ = unstructured.NestedString(view.Object, "status", "result", "status", "currentVersion")
+	return phase, version
+}
+
+// withMCHFeedback returns a copy of the manifestwork reporting the given phase and version as the
+// status feedback of the MultiClusterHub
+func withMCHFeedback(mch *workv1.ManifestWork, phase, version string) *workv1.ManifestWork {
+	mch = mch.DeepCopy()
+	values := []workv1.FeedbackValue{
+		{Name: MCH_PHASE_FEEDBACK, Value: workv1.FieldValue{Type: workv1.String, String: &phase}},
+		{Name: MCH_VERSION_FEEDBACK, Value: workv1.FieldValue{Type: workv1.String, String: &version}},
+	}
+	for i := range mch.Status.ResourceStatus.Manifests {
+		if mch.Status.ResourceStatus.Manifests[i].ResourceMeta.Kind == "MultiClusterHub" {
+			mch.Status.ResourceStatus.Manifests[i].StatusFeedbacks.Values = values
+			return mch
+		}
+	}
+	mch.Status.ResourceStatus.Manifests = append(mch.Status.ResourceStatus.Manifests, workv1.ManifestCondition{
+		ResourceMeta:    workv1.ManifestResourceMeta{Kind: "MultiClusterHub"},
+		StatusFeedbacks: workv1.StatusFeedbackResult{Values: values},
+	})
+	return mch
+}
+
+// verifyMCH reads the MultiClusterHub of the managed hub with a ManagedClusterView when its status
+// feedback is ambiguous, and returns the mch manifestwork with the phase and version of the view.
+// The manifestwork is returned as is until the view has read the MultiClusterHub, and the view is
+// deleted once the feedback can be trusted again.
+func (c *statusController) verifyMCH(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster, mch *workv1.ManifestWork) (*workv1.ManifestWork, error) {
+	if c.viewClient == nil {
+		return mch, nil
+	}
+	views := c.viewClient.Namespace(managedCluster.Name)
+	if !IsMCHFeedbackAmbiguous(mch) {
+		if _, ok := c.views.Load(managedCluster.Name); !ok {
+			return mch, nil
+		}
+		err := views.Delete(ctx, HOH_HUB_CLUSTER_MCH_VIEW, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		c.views.Delete(managedCluster.Name)
+		return mch, nil
+	}
+
+	// read the MultiClusterHub again until its feedback can be trusted
+	syncCtx.Queue().AddAfter(managedCluster.Name, viewRecheckInterval)
+	view, err := views.Get(ctx, HOH_HUB_CLUSTER_MCH_VIEW, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		loggerFrom(ctx).Info("Verifying the multiclusterhub with a view, its status feedback is ambiguous")
+		_, err = views.Create(ctx, newMCHView(managedCluster.Name), metav1.CreateOptions{})
+		if err == nil || errors.IsAlreadyExists(err) {
+			c.views.Store(managedCluster.Name, struct{}{})
+			return mch, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.views.Store(managedCluster.Name, struct{}{})
+	phase, version := viewedMCH(view)
+	if phase == "" {
+		return mch, nil
+	}
+	loggerFrom(ctx).V(2).Info("Using the multiclusterhub read by the view", "phase", phase, "version", version)
+	return withMCHFeedback(mch, phase, version), nil
+}
@@ -0,0 +1,196 @@
+package cluster
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// HOH_WAVE_ANNOTATION orders the rollout of the configuration and version changes across the fleet.
+// The changes of the hub manifestworks of a managed cluster in wave n are held until the hubs of all
+// lower waves run with the changes and are healthy, as reported by the feedback of their
+// manifestworks. The managed clusters without the annotation are in wave 0.
+const HOH_WAVE_ANNOTATION = "hoh-wave"
+
+// waveRecheckInterval is the interval to recheck a managed hub waiting for the lower waves, it also
+// bounds how long the rollout state of the fleet is cached.
+const waveRecheckInterval = 30 * time.Second
+
+// Wave returns the rollout wave of the managed cluster, an invalid wave is handled as wave 0.
+func Wave(managedCluster metav1.Object) int {
+	wave, _ := parseWave(managedCluster)
+	return wave
+}
+
+// parseWave returns the rollout wave of the managed cluster, and false if its wave annotation is
+// invalid.
+func parseWave(managedCluster metav1.Object) (int, bool) {
+	value, ok := managedCluster.GetAnnotations()[HOH_WAVE_ANNOTATION]
+	if !ok {
+		return 0, true
+	}
+	wave, err := strconv.Atoi(value)
+	if err != nil || wave < 0 {
+		return 0, false
+	}
+	return wave, true
+}
+
+// wave returns the rollout wave of the managed cluster. The wave is read on every sync and fleet
+// scan, so an invalid wave annotation is only logged when it changes.
+func (c *clusterController) wave(managedCluster metav1.Object) int {
+	wave, valid := parseWave(managedCluster)
+	if valid {
+		c.invalidWaves.Delete(managedCluster.GetName())
+		return wave
+	}
+	value := managedCluster.GetAnnotations()[HOH_WAVE_ANNOTATION]
+	if reported, ok := c.invalidWaves.Load(managedCluster.GetName()); !ok || reported != value {
+		c.invalidWaves.Store(managedCluster.GetName(), value)
+		klog.Warningf("Invalid %s annotation %q of managed cluster %s, using wave 0",
+			HOH_WAVE_ANNOTATION, value, managedCluster.GetName())
+	}
+	return wave
+}
+
+// waveTracker caches the lowest wave of the fleet which is not rolled out yet, so the fleet is not
+// scanned on each sync of the managed hubs waiting for their wave.
+type waveTracker struct {
+	lock       sync.Mutex
+	config     *HubConfig
+	computedAt time.Time
+	pending    int
+	rolledOut  bool
+}
+
+// waveOpen returns true if the desired hub manifestwork of the managed cluster can be applied now.
+// The first installation is never held, only the changes of an existing manifestwork are held until
+// the lower waves are rolled out, and the managed cluster is requeued to recheck them.
+func (c *clusterController) waveOpen(ctx context.Context, syncCtx factory.SyncContext,
+	managedCluster *clusterv1.ManagedCluster, desired *workv1.ManifestWork) bool {
+	wave := c.wave(managedCluster)
+	if wave == 0 {
+		return true
+	}
+	existing, err := c.findManifestWork(managedCluster.Name, desired)
+	if err != nil || existing == nil || IsStale(existing, managedCluster) {
+		return true
+	}
+	rendered := desired.DeepCopy()
+	if err := SetSpecHash(rendered); err != nil {
+		return true
+	}
+	// the rendered spec is unchanged, the repair of a spec edited by hand is not a rollout to hold
+	if existing.Annotations[SPEC_HASH_ANNOTATION] == rendered.Annotations[SPEC_HASH_ANNOTATION] {
+		return true
+	}
+
+	pending, ok := c.pendingWave()
+	if !ok || wave <= pending {
+		return true
+	}
+	loggerFrom(ctx).V(2).Info("Holding the hub changes until the lower waves are rolled out",
+		"wave", wave, "pendingWave", pending)
+	syncCtx.Queue().AddAfter(managedCluster.Name, waveRecheckInterval)
+	return false
+}
+
+// pendingWave returns the lowest wave with a managed hub not running with the current configuration
+// or not healthy, or false if the whole fleet is rolled out.
+func (c *clusterController) pendingWave() (int, bool) {
+	config := c.hubConfig.get()
+
+	c.waves.lock.Lock()
+	defer c.waves.lock.Unlock()
+	// the cached state is dropped as soon as the configuration changes
+	if c.waves.config == config && time.Since(c.waves.computedAt) < waveRecheckInterval {
+		return c.waves.pending, !c.waves.rolledOut
+	}
+
+	managedClusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		// hold the higher waves until the fleet can be checked
+		klog.Errorf("Failed to list the managed clusters: %v", err)
+		return 0, true
+	}
+	// the invalid waves of the deleted managed clusters are forgotten
+	names := make(map[string]bool, len(managedClusters))
+	for _, managedCluster := range managedClusters {
+		names[managedCluster.Name] = true
+	}
+	c.invalidWaves.Range(func(name, _ interface{}) bool {
+		if !names[name.(string)] {
+			c.invalidWaves.Delete(name)
+		}
+		return true
+	})
+
+	pending, found := 0, false
+	for _, managedCluster := range managedClusters {
+		if !IsManagedHub(managedCluster) || config.Excluded(managedCluster.Name) {
+			continue
+		}
+		wave := c.wave(managedCluster)
+		if found && wave >= pending {
+			continue
+		}
+		if !c.rolledOut(managedCluster, config) {
+			pending, found = wave, true
+		}
+	}
+	c.waves.config = config
+	c.waves.computedAt = time.Now()
+	c.waves.pending = pending
+	c.waves.rolledOut = !found
+	return pending, found
+}
+
+// rolledOut returns true if the hub manifestworks of the managed cluster are rendered from the given
+// configuration, applied by the work agent, and the hub is installed and not degraded.
+func (c *clusterController) rolledOut(managedCluster *clusterv1.ManagedCluster, config *HubConfig) bool {
+	subscription, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_SUBSCRIPTION)
+	if err != nil {
+		return false
+	}
+	mch, err := c.getManifestWork(managedCluster, HOH_HUB_CLUSTER_MCH)
+	if err != nil {
+		return false
+	}
+	desiredMCH, _, err := c.desiredMCHManifestWork(managedCluster, config)
+	if err != nil {
+		// the mch of the managed cluster is invalid, it can not be rolled out
+		return false
+	}
+	desiredSubscription, err := c.desiredSubManifestWork(managedCluster, config)
+	if err != nil {
+		// the configured hub has no build for the managed cluster, it can not be rolled out
+		return false
+	}
+	if !appliedWith(subscription, desiredSubscription) ||
+		!appliedWith(mch, desiredMCH) {
+		return false
+	}
+	conditions := HubConditions(subscription, mch)
+	return meta.IsStatusConditionTrue(conditions, HubConditionInstalled) &&
+		!meta.IsStatusConditionTrue(conditions, HubConditionDegraded)
+}
+
+// appliedWith returns true if the manifestwork has the rendered spec of the desired one and its
+// current generation is applied by the work agent, so its feedback is not left from a previous spec.
+func appliedWith(work, desired *workv1.ManifestWork) bool {
+	if work == nil || SetSpecHash(desired) != nil ||
+		work.Annotations[SPEC_HASH_ANNOTATION] != desired.Annotations[SPEC_HASH_ANNOTATION] {
+		return false
+	}
+	applied := meta.FindStatusCondition(work.Status.Conditions, workv1.WorkApplied)
+	return applied != nil && applied.Status == metav1.ConditionTrue && applied.ObservedGeneration == work.Generation
+}
@@ -0,0 +1,174 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func TestWave(t *testing.T) {
+	cases := map[string]int{
+		"":   0,
+		"2":  2,
+		"-1": 0,
+		"x":  0,
+	}
+	for value, expected := range cases {
+		managedCluster := newManagedCluster("cluster1")
+		if value != "" {
+			managedCluster.Annotations = map[string]string{HOH_WAVE_ANNOTATION: value}
+		}
+		if wave := Wave(managedCluster); wave != expected {
+			t.Errorf("expected wave %d for %q, got %d", expected, value, wave)
+		}
+	}
+}
+
+func TestWaveReportsInvalidWaveOnce(t *testing.T) {
+	managedCluster := newManagedCluster("cluster1")
+	managedCluster.Annotations = map[string]string{HOH_WAVE_ANNOTATION: "x"}
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{managedCluster}, nil)
+	if wave := ctrl.wave(managedCluster); wave != 0 {
+		t.Errorf("expected the invalid wave to be wave 0, got %d", wave)
+	}
+	if reported, ok := ctrl.invalidWaves.Load(managedCluster.Name); !ok || reported != "x" {
+		t.Errorf("expected the invalid wave to be reported, got %v", reported)
+	}
+	if _, ok := newTestController(t, nil, nil).invalidWaves.Load(managedCluster.Name); ok {
+		t.Errorf("expected the invalid waves not to be shared between the controllers")
+	}
+
+	fixed := managedCluster.DeepCopy()
+	fixed.Annotations[HOH_WAVE_ANNOTATION] = "1"
+	if wave := ctrl.wave(fixed); wave != 1 {
+		t.Errorf("expected wave 1, got %d", wave)
+	}
+	if _, ok := ctrl.invalidWaves.Load(managedCluster.Name); ok {
+		t.Errorf("expected the fixed wave to be reported again once invalid")
+	}
+
+	// the invalid wave of a deleted managed cluster is forgotten by the scan of the fleet
+	ctrl.invalidWaves.Store("deleted", "x")
+	ctrl.pendingWave()
+	if _, ok := ctrl.invalidWaves.Load("deleted"); ok {
+		t.Errorf("expected the invalid wave of the deleted managed cluster to be forgotten")
+	}
+}
+
+// newAppliedWork returns the given manifestwork as applied by the work agent for its managed cluster
+func newAppliedWork(t *testing.T, work *workv1.ManifestWork, managedCluster *clusterv1.ManagedCluster) *workv1.ManifestWork {
+	SetManagedClusterUID(work, managedCluster)
+	if err := SetSpecHash(work); err != nil {
+		t.Fatal(err)
+	}
+	work.Status.Conditions = []metav1.Condition{{Type: workv1.WorkApplied, Status: metav1.ConditionTrue}}
+	return work
+}
+
+func TestWaveOpen(t *testing.T) {
+	config := DefaultHubConfig()
+	wave1 := newManagedCluster("cluster1")
+	wave1.Annotations = map[string]string{HOH_WAVE_ANNOTATION: "1"}
+	wave2 := newManagedCluster("cluster2")
+	wave2.Annotations = map[string]string{HOH_WAVE_ANNOTATION: "2"}
+
+	// the subscription of the second wave is rendered from a previous configuration
+	outdated := CreateSubManifestwork("cluster2", &HubConfig{Channel: "release-2.3"})
+	outdatedSubscription := newAppliedWork(t, outdated, wave2)
+	mch, _, err := (&clusterController{}).desiredMCHManifestWork(wave1, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rolledOutMCH := withFeedback(newAppliedWork(t, mch, wave1), "MultiClusterHub",
+		map[string]string{MCH_PHASE_FEEDBACK: MCH_PHASE_RUNNING})
+	newSubscription := func() *workv1.ManifestWork {
+		return withFeedback(newAppliedWork(t, CreateSubManifestwork("cluster1", config), wave1), "Subscription",
+			map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN})
+	}
+
+	cases := []struct {
+		name     string
+		works    []*workv1.ManifestWork
+		expected bool
+	}{
+		{
+			name:     "lower wave installing",
+			works:    []*workv1.ManifestWork{newSubscription(), outdatedSubscription},
+			expected: false,
+		},
+		{
+			name: "lower wave not applied yet",
+			works: []*workv1.ManifestWork{func() *workv1.ManifestWork {
+				subscription := newSubscription()
+				subscription.Generation = 2
+				return subscription
+			}(), rolledOutMCH, outdatedSubscription},
+			expected: false,
+		},
+		{
+			name:     "lower wave rolled out",
+			works:    []*workv1.ManifestWork{newSubscription(), rolledOutMCH, outdatedSubscription},
+			expected: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := newTestController(t, []*clusterv1.ManagedCluster{wave1, wave2}, c.works)
+			syncCtx := testinghelpers.NewFakeSyncContext(t, "cluster2")
+			desired := CreateSubManifestwork("cluster2", config)
+			if open := ctrl.waveOpen(context.TODO(), syncCtx, wave2, desired); open != c.expected {
+				t.Errorf("expected the wave to be open %v, got %v", c.expected, open)
+			}
+		})
+	}
+}
+
+func TestRolledOutWithTrustedCABundle(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, configMap := range []*corev1.ConfigMap{
+		newHubConfigMap(map[string]string{HUB_CONFIG_TRUSTED_CA_BUNDLE_CONFIGMAP_KEY: "registry-ca"}),
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-ca", Namespace: "test"},
+			Data:       map[string]string{TRUSTED_CA_BUNDLE_KEY: "ca"},
+		},
+	} {
+		if err := indexer.Add(configMap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	managedCluster := newManagedCluster("cluster1")
+	ctrl := newTestController(t, []*clusterv1.ManagedCluster{managedCluster}, nil)
+	ctrl.hubConfig = newHubConfigLoader(corev1listers.NewConfigMapLister(indexer).ConfigMaps("test"))
+	config := ctrl.hubConfig.get()
+
+	// the subscription is applied by the subscription controller with the trust bundle
+	subscription, err := ctrl.desiredSubManifestWork(managedCluster, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mch, _, err := ctrl.desiredMCHManifestWork(managedCluster, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, work := range []*workv1.ManifestWork{
+		withFeedback(newAppliedWork(t, subscription, managedCluster), "Subscription",
+			map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN}),
+		withFeedback(newAppliedWork(t, mch, managedCluster), "MultiClusterHub",
+			map[string]string{MCH_PHASE_FEEDBACK: MCH_PHASE_RUNNING}),
+	} {
+		if err := ctrl.workIndexer.Add(work); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !ctrl.rolledOut(managedCluster, config) {
+		t.Errorf("expected the hub applied with the trust bundle to be rolled out")
+	}
+}
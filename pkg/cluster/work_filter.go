@@ -0,0 +1,111 @@
+package cluster
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// the condition types of the manifestworks and their manifests the controllers react to
+var relevantWorkConditions = sets.NewString(
+	workv1.WorkApplied,
+	workv1.WorkAvailable,
+	workv1.WorkDegraded,
+	MANIFEST_FEEDBACK_SYNCED,
+)
+
+// workState is the part of a manifestwork the controllers react to: its spec, metadata and deletion,
+// the status feedback of its manifests and the Applied, Available and Degraded conditions. The work
+// agents also update the other conditions and the transition times, which are ignored.
+type workState struct {
+	Generation  int64
+	Labels      map[string]string
+	Annotations map[string]string
+	Deleting    bool
+	Conditions  []metav1.Condition
+	Manifests   []manifestState
+}
+
+type manifestState struct {
+	ResourceMeta workv1.ManifestResourceMeta
+	Conditions   []metav1.Condition
+	Feedback     []workv1.FeedbackValue
+}
+
+func newWorkState(work *workv1.ManifestWork) workState {
+	state := workState{
+		Generation:  work.Generation,
+		Labels:      work.Labels,
+		Annotations: work.Annotations,
+		Deleting:    work.DeletionTimestamp != nil,
+		Conditions:  relevantConditions(work.Status.Conditions),
+	}
+	for _, manifest := range work.Status.ResourceStatus.Manifests {
+		state.Manifests = append(state.Manifests, manifestState{
+			ResourceMeta: manifest.ResourceMeta,
+			Conditions:   relevantConditions(manifest.Conditions),
+			Feedback:     manifest.StatusFeedbacks.Values,
+		})
+	}
+	return state
+}
+
+func relevantConditions(conditions []metav1.Condition) []metav1.Condition {
+	relevant := []metav1.Condition{}
+	for _, cond := range conditions {
+		if relevantWorkConditions.Has(cond.Type) {
+			cond.LastTransitionTime = metav1.Time{}
+			relevant = append(relevant, cond)
+		}
+	}
+	return relevant
+}
+
+// workChanged returns true if the relevant state of the manifestwork changed between the old and new
+// objects of an update event, so the status updates of the work agents not changing the feedback or
+// the conditions do not trigger a sync.
+func workChanged(old, new *workv1.ManifestWork) bool {
+	return !equality.Semantic.DeepEqual(newWorkState(old), newWorkState(new))
+}
+
+// workEventHandler enqueues the managed cluster of the given types of manifestworks owned by the
+// controller into the queue. The creations and deletions of the manifestworks always trigger a sync,
+// the updates only when their relevant state changed.
+func (c *clusterController) workEventHandler(queue workqueue.Interface, works []string) cache.ResourceEventHandler {
+	enqueue := func(obj interface{}) {
+		accessor, err := objectMeta(obj)
+		if err != nil {
+			return
+		}
+		queue.Add(WorkManagedCluster(accessor))
+	}
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			accessor, err := objectMeta(obj)
+			if err != nil || !c.ownsCluster(WorkManagedCluster(accessor)) {
+				return false
+			}
+			for _, work := range works {
+				if IsWorkOfType(accessor, work) {
+					return true
+				}
+			}
+			return false
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: enqueue,
+			UpdateFunc: func(old, new interface{}) {
+				oldWork, ok := old.(*workv1.ManifestWork)
+				newWork, ok2 := new.(*workv1.ManifestWork)
+				if ok && ok2 && !workChanged(oldWork, newWork) {
+					return
+				}
+				enqueue(new)
+			},
+			DeleteFunc: enqueue,
+		},
+	}
+}
@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestWorkChanged(t *testing.T) {
+	work := withFeedback(CreateSubManifestwork("cluster1", DefaultHubConfig()), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: "UpgradePending"})
+
+	// a status update only changing an irrelevant condition and the transition times is ignored
+	updated := work.DeepCopy()
+	updated.ResourceVersion = "2"
+	updated.Status.Conditions = append(updated.Status.Conditions, metav1.Condition{
+		Type: "Progressing", Status: metav1.ConditionTrue, LastTransitionTime: metav1.Now(),
+	})
+	if workChanged(work, updated) {
+		t.Errorf("expected the irrelevant status update to be ignored")
+	}
+
+	changed := withFeedback(updated.DeepCopy(), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN})
+	if !workChanged(updated, changed) {
+		t.Errorf("expected the feedback change to trigger a sync")
+	}
+	available := changed.DeepCopy()
+	available.Status.Conditions = append(available.Status.Conditions, metav1.Condition{
+		Type: workv1.WorkAvailable, Status: metav1.ConditionTrue, LastTransitionTime: metav1.Now(),
+	})
+	if !workChanged(changed, available) {
+		t.Errorf("expected the Available condition change to trigger a sync")
+	}
+}
+
+func TestWorkEventHandler(t *testing.T) {
+	ctrl := newTestController(t, nil, nil)
+	work := CreateSubManifestwork("cluster1", DefaultHubConfig())
+	other := CreateAgentManifestwork("cluster2", DefaultHubConfig())
+
+	queue := workqueue.New()
+	defer queue.ShutDown()
+	handler := ctrl.workEventHandler(queue, []string{HOH_HUB_CLUSTER_SUBSCRIPTION})
+	expectQueued := func(expected ...string) {
+		t.Helper()
+		if queue.Len() != len(expected) {
+			t.Fatalf("expected %v to be queued, got %d keys", expected, queue.Len())
+		}
+		for _, name := range expected {
+			key, _ := queue.Get()
+			if key != name {
+				t.Errorf("expected %s to be queued, got %v", name, key)
+			}
+			queue.Done(key)
+		}
+	}
+
+	// the creations and deletions always trigger a sync, but only for the given manifestworks
+	handler.OnAdd(work)
+	handler.OnAdd(other)
+	expectQueued("cluster1")
+
+	// the updates only when their relevant state changed
+	updated := work.DeepCopy()
+	updated.ResourceVersion = "2"
+	handler.OnUpdate(work, updated)
+	expectQueued()
+	changed := withFeedback(updated.DeepCopy(), "Subscription",
+		map[string]string{SUBSCRIPTION_STATE_FEEDBACK: SUBSCRIPTION_STATE_AT_LATEST_KNOWN})
+	handler.OnUpdate(updated, changed)
+	expectQueued("cluster1")
+
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "cluster1/" + work.Name, Obj: changed})
+	expectQueued("cluster1")
+}
@@ -0,0 +1,187 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+// DiagnoseOptions holds configuration for the diagnosis of a managed hub through the cluster-proxy
+type DiagnoseOptions struct {
+	Kubeconfig            string
+	ClusterName           string
+	ProxyURL              string
+	ProxyCAFile           string
+	TokenFile             string
+	ManagedServiceAccount string
+	Namespace             string
+}
+
+// NewDiagnoseOptions returns a DiagnoseOptions with default values
+func NewDiagnoseOptions() *DiagnoseOptions {
+	return &DiagnoseOptions{
+		Namespace: "open-cluster-management",
+	}
+}
+
+// AddFlags registers flags for the diagnosis of a managed hub
+func (o *DiagnoseOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig,
+		"The kubeconfig of the hub of hubs, the in-cluster configuration is used if empty.")
+	flags.StringVar(&o.ClusterName, "cluster", o.ClusterName, "The name of the managed cluster to diagnose the hub of.")
+	flags.StringVar(&o.ProxyURL, "proxy-url", o.ProxyURL,
+		"The URL of the cluster-proxy user server, the managed cluster is queried at <proxy-url>/<cluster>.")
+	flags.StringVar(&o.ProxyCAFile, "proxy-ca-file", o.ProxyCAFile,
+		"The CA bundle verifying the certificate of the cluster-proxy user server.")
+	flags.StringVar(&o.TokenFile, "token-file", o.TokenFile,
+		"The file of the bearer token authenticating to the managed cluster.")
+	flags.StringVar(&o.ManagedServiceAccount, "managed-serviceaccount", o.ManagedServiceAccount,
+		"The ManagedServiceAccount whose token, projected into the managed cluster namespace of the hub of hubs, authenticates to the managed cluster, instead of --token-file.")
+	flags.StringVar(&o.Namespace, "namespace", o.Namespace,
+		"The namespace of the operator subscription and the MultiClusterHub on the managed cluster.")
+}
+
+func NewDiagnose() *cobra.Command {
+	opts := NewDiagnoseOptions()
+	cmd := &cobra.Command{
+		Use:   "diagnose",
+		Short: "Print the CSV conditions and the MultiClusterHub components of a managed hub through the cluster-proxy",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if opts.ClusterName == "" || opts.ProxyURL == "" {
+				return fmt.Errorf("--cluster and --proxy-url are required")
+			}
+			if (opts.TokenFile == "") == (opts.ManagedServiceAccount == "") {
+				return fmt.Errorf("one of --token-file or --managed-serviceaccount is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token, err := opts.token(cmd.Context())
+			if err != nil {
+				return err
+			}
+			client, err := dynamic.NewForConfig(opts.ProxyConfig(token))
+			if err != nil {
+				return err
+			}
+			return opts.Diagnose(cmd.Context(), client, cmd.OutOrStdout())
+		},
+	}
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// ProxyConfig returns the configuration of the clients querying the managed cluster through the
+// cluster-proxy with the given bearer token.
+func (o *DiagnoseOptions) ProxyConfig(token string) *rest.Config {
+	return cluster.ClusterProxyConfig(o.ProxyURL, o.ProxyCAFile, o.ClusterName, token)
+}
+
+// token returns the bearer token authenticating to the managed cluster, read from the token file or
+// the secret of the ManagedServiceAccount in the managed cluster namespace of the hub of hubs
+func (o *DiagnoseOptions) token(ctx context.Context) (string, error) {
+	if o.TokenFile != "" {
+		data, err := os.ReadFile(o.TokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", o.Kubeconfig)
+	if err != nil {
+		return "", err
+	}
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return "", err
+	}
+	secret, err := kubeClient.CoreV1().Secrets(o.ClusterName).Get(ctx, o.ManagedServiceAccount, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	token := string(secret.Data["token"])
+	if token == "" {
+		return "", fmt.Errorf("the secret %s/%s of the managed serviceaccount has no token yet", o.ClusterName, o.ManagedServiceAccount)
+	}
+	return token, nil
+}
+
+// Diagnose queries the managed hub and writes the phase and conditions of the CSVs of the namespace,
+// and the phase, version and unhealthy components of the MultiClusterHub.
+func (o *DiagnoseOptions) Diagnose(ctx context.Context, client dynamic.Interface, out io.Writer) error {
+	csvs, err := client.Resource(cluster.CSVsResource).Namespace(o.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list the CSVs of managed cluster %s: %v", o.ClusterName, err)
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CSV\tPHASE\tREASON\tMESSAGE")
+	for _, csv := range csvs.Items {
+		phase, _, _ := unstructured.NestedString(csv.Object, "status", "phase")
+		reason, _, _ := unstructured.NestedString(csv.Object, "status", "reason")
+		message, _, _ := unstructured.NestedString(csv.Object, "status", "message")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", csv.GetName(), valueOrNone(phase), valueOrNone(reason), valueOrNone(message))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	mchs, err := client.Resource(cluster.MultiClusterHubsResource).Namespace(o.Namespace).List(ctx, metav1.ListOptions{})
+	if errors.IsNotFound(err) {
+		// the MultiClusterHub CRD is not installed by the operator yet
+		fmt.Fprintln(out, "\nThe MultiClusterHub CRD is not installed")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list the MultiClusterHubs of managed cluster %s: %v", o.ClusterName, err)
+	}
+	for _, mch := range mchs.Items {
+		phase, _, _ := unstructured.NestedString(mch.Object, "status", "phase")
+		version, _, _ := unstructured.NestedString(mch.Object, "status", "currentVersion")
+		fmt.Fprintf(out, "\nMultiClusterHub %s: phase %s, version %s\n", mch.GetName(), valueOrNone(phase), valueOrNone(version))
+
+		components, _, _ := unstructured.NestedMap(mch.Object, "status", "components")
+		names := make([]string, 0, len(components))
+		for name := range components {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "COMPONENT\tTYPE\tSTATUS\tREASON\tMESSAGE")
+		for _, name := range names {
+			component, ok := components[name].(map[string]interface{})
+			if !ok || component["status"] == "True" {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, componentField(component, "type"),
+				componentField(component, "status"), componentField(component, "reason"), componentField(component, "message"))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(mchs.Items) == 0 {
+		fmt.Fprintln(out, "\nNo MultiClusterHub")
+	}
+	return nil
+}
+
+func componentField(component map[string]interface{}, field string) string {
+	value, _ := component[field].(string)
+	return valueOrNone(value)
+}
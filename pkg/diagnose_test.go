@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+func TestDiagnose(t *testing.T) {
+	csv := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "operators.coreos.com/v1alpha1",
+		"kind":       "ClusterServiceVersion",
+		"metadata":   map[string]interface{}{"name": "advanced-cluster-management.v2.5.1", "namespace": "open-cluster-management"},
+		"status": map[string]interface{}{
+			"phase":   "Failed",
+			"reason":  "InstallComponentFailed",
+			"message": "install strategy failed",
+		},
+	}}
+	mch := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "operator.open-cluster-management.io/v1",
+		"kind":       "MultiClusterHub",
+		"metadata":   map[string]interface{}{"name": "multiclusterhub", "namespace": "open-cluster-management"},
+		"status": map[string]interface{}{
+			"phase":          "Installing",
+			"currentVersion": "2.5.0",
+			"components": map[string]interface{}{
+				"console-chart": map[string]interface{}{"type": "Deployed", "status": "True"},
+				"grc-chart": map[string]interface{}{"type": "Progressing", "status": "False", "reason": "InstallError",
+					"message": "deployment grc-policy-propagator is not ready"},
+			},
+		},
+	}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		cluster.CSVsResource:             "ClusterServiceVersionList",
+		cluster.MultiClusterHubsResource: "MultiClusterHubList",
+	}, csv, mch)
+
+	opts := NewDiagnoseOptions()
+	opts.ClusterName = "cluster1"
+	out := &bytes.Buffer{}
+	if err := opts.Diagnose(context.TODO(), client, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"advanced-cluster-management.v2.5.1  Failed  InstallComponentFailed  install strategy failed",
+		"MultiClusterHub multiclusterhub: phase Installing, version 2.5.0",
+		"grc-chart  Progressing  False   InstallError  deployment grc-policy-propagator is not ready",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in the diagnosis:\n%s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "console-chart") {
+		t.Errorf("expected the healthy components to be omitted:\n%s", out.String())
+	}
+}
+
+func TestProxyConfig(t *testing.T) {
+	opts := &DiagnoseOptions{ClusterName: "cluster1", ProxyURL: "https://cluster-proxy.example.com/"}
+	config := opts.ProxyConfig("token")
+	if config.Host != "https://cluster-proxy.example.com/cluster1" || config.BearerToken != "token" {
+		t.Errorf("unexpected proxy configuration %s %s", config.Host, config.BearerToken)
+	}
+}
@@ -0,0 +1,3 @@
+// package dryrun runs the controller against a fleet without changing it, the writes of its clients
+// are logged and sent to the kube-apiserver as server-side dry runs.
+package dryrun
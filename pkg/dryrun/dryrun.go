@@ -0,0 +1,205 @@
+package dryrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// WrapConfig turns the writes of the clients created from the config into server-side dry runs, so
+// they are validated and admitted by the kube-apiserver without being persisted. Each write is logged
+// with its method, resource and name, and from verbosity 2 with the diff between the live object and
+// the object returned by the dry run, the data of the secrets being redacted. The events are still
+// created, so the decisions of the controller are reported on the managed clusters.
+func WrapConfig(config *rest.Config) {
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &transport{delegate: rt}
+	})
+}
+
+type transport struct {
+	delegate http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isWrite(req) || isEvent(req) {
+		return t.delegate.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	resource, namespace, name := target(req.URL.Path)
+	if name == "" && req.Method == http.MethodPost {
+		// the name of the created object is in its body
+		created := &metav1.PartialObjectMetadata{}
+		if err := json.Unmarshal(body, created); err == nil {
+			name = created.Name
+		}
+	}
+	klog.InfoS("Dry run", "method", req.Method, "resource", resource, "namespace", namespace, "name", name)
+
+	var live map[string]interface{}
+	if klog.V(2).Enabled() {
+		objectPath := req.URL.Path
+		if req.Method == http.MethodPost && name != "" {
+			objectPath = strings.TrimSuffix(objectPath, "/") + "/" + name
+		}
+		live = t.get(req, objectPath)
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	query := req.URL.Query()
+	query.Set("dryRun", metav1.DryRunAll)
+	req.URL.RawQuery = query.Encode()
+	resp, err := t.delegate.RoundTrip(req)
+	if err != nil || !klog.V(2).Enabled() {
+		return resp, err
+	}
+
+	var result map[string]interface{}
+	if req.Method != http.MethodDelete && resp.StatusCode < http.StatusMultipleChoices && isJSON(resp.Header) {
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+		result = decode(respBody)
+	}
+	klog.V(2).InfoS("Dry run diff", "method", req.Method, "resource", resource, "namespace", namespace, "name", name,
+		"diff", cmp.Diff(Redact(live), Redact(result)))
+	return resp, nil
+}
+
+// get returns the live object at the given path, or nil if it does not exist or can not be read
+func (t *transport) get(req *http.Request, path string) map[string]interface{} {
+	get := req.Clone(req.Context())
+	get.Method = http.MethodGet
+	get.Body = nil
+	get.GetBody = nil
+	get.ContentLength = 0
+	get.URL.Path = path
+	get.URL.RawQuery = ""
+	get.Header.Set("Accept", "application/json")
+	get.Header.Del("Content-Type")
+	resp, err := t.delegate.RoundTrip(get)
+	if err != nil {
+		klog.V(2).Infof("Dry run: failed to get the live object %s: %v", path, err)
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil
+	}
+	return decode(body)
+}
+
+func isWrite(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isJSON(header http.Header) bool {
+	return strings.HasPrefix(header.Get("Content-Type"), "application/json")
+}
+
+// decode returns the JSON object of the body, or nil if it is not a JSON object
+func decode(body []byte) map[string]interface{} {
+	object := map[string]interface{}{}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil
+	}
+	// the server managed fields are noise in the diff
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "managedFields")
+		delete(metadata, "resourceVersion")
+	}
+	return object
+}
+
+// Redact replaces the values of the data of the secrets in the generic JSON value, including the
+// secrets embedded in the manifests of a manifestwork, so the propagated credentials are not logged.
+func Redact(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(typed))
+		for key, field := range typed {
+			redacted[key] = Redact(field)
+		}
+		if typed["kind"] == "Secret" {
+			for _, key := range []string{"data", "stringData"} {
+				if data, ok := typed[key].(map[string]interface{}); ok {
+					values := make(map[string]interface{}, len(data))
+					for name := range data {
+						values[name] = "<redacted>"
+					}
+					redacted[key] = values
+				}
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(typed))
+		for i, item := range typed {
+			redacted[i] = Redact(item)
+		}
+		return redacted
+	}
+	return value
+}
+
+// isEvent returns true for the requests on the core and events.k8s.io events
+func isEvent(req *http.Request) bool {
+	resource, _, _ := target(req.URL.Path)
+	return resource == "events"
+}
+
+// target returns the resource, namespace and name of a request path, /api/{version}/... for the core
+// group or /apis/{group}/{version}/... for the others, followed by namespaces/{namespace}/ for the
+// namespaced resources, then the resource, name and subresource.
+func target(path string) (resource, namespace, name string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(segments) > 1 && segments[0] == "api":
+		segments = segments[2:]
+	case len(segments) > 0 && segments[0] == "apis" && len(segments) > 2:
+		segments = segments[3:]
+	default:
+		return "", "", ""
+	}
+	if len(segments) > 2 && segments[0] == "namespaces" {
+		namespace = segments[1]
+		segments = segments[2:]
+	}
+	if len(segments) == 0 {
+		return "", namespace, ""
+	}
+	if len(segments) > 1 {
+		name = segments[1]
+	}
+	return segments[0], namespace, name
+}
@@ -0,0 +1,114 @@
+package dryrun
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestWrapConfig(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		dryRun string
+		body   string
+	}
+	requests := []request{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.Path, r.URL.Query().Get("dryRun"), string(body)})
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	WrapConfig(config)
+	client, err := rest.HTTPClientFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		dryRun string
+	}{
+		{http.MethodGet, "/apis/work.open-cluster-management.io/v1/namespaces/cluster1/manifestworks", ""},
+		{http.MethodPatch, "/apis/work.open-cluster-management.io/v1/namespaces/cluster1/manifestworks/work", "All"},
+		{http.MethodPut, "/apis/cluster.open-cluster-management.io/v1/managedclusters/events/status", "All"},
+		{http.MethodDelete, "/api/v1/namespaces/events/configmaps/config", "All"},
+		{http.MethodPost, "/api/v1/namespaces/cluster1/events", ""},
+		{http.MethodPatch, "/apis/events.k8s.io/v1/namespaces/cluster1/events/event", ""},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, server.URL+c.path, strings.NewReader(`{"spec":{}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(requests) != len(cases) {
+		t.Fatalf("expected %d requests, got %d", len(cases), len(requests))
+	}
+	for i, c := range cases {
+		if requests[i].dryRun != c.dryRun {
+			t.Errorf("expected %s %s to have dryRun %q, got %q", c.method, c.path, c.dryRun, requests[i].dryRun)
+		}
+		if requests[i].body != `{"spec":{}}` {
+			t.Errorf("expected the body of %s %s to be sent, got %q", c.method, c.path, requests[i].body)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	cases := []struct {
+		path      string
+		resource  string
+		namespace string
+		name      string
+	}{
+		{"/api/v1/namespaces/cluster1/secrets/pull-secret", "secrets", "cluster1", "pull-secret"},
+		{"/apis/work.open-cluster-management.io/v1/namespaces/cluster1/manifestworks", "manifestworks", "cluster1", ""},
+		{"/apis/cluster.open-cluster-management.io/v1/managedclusters/cluster1/status", "managedclusters", "", "cluster1"},
+		{"/api/v1/namespaces/cluster1", "namespaces", "", "cluster1"},
+		{"/healthz", "", "", ""},
+	}
+	for _, c := range cases {
+		resource, namespace, name := target(c.path)
+		if resource != c.resource || namespace != c.namespace || name != c.name {
+			t.Errorf("expected %s to target %s %s/%s, got %s %s/%s", c.path, c.resource, c.namespace, c.name,
+				resource, namespace, name)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	work := decode([]byte(`{
+		"kind": "ManifestWork",
+		"metadata": {"name": "work", "resourceVersion": "1", "managedFields": []},
+		"spec": {"workload": {"manifests": [
+			{"kind": "Secret", "metadata": {"name": "pull-secret"}, "data": {".dockerconfigjson": "c2VjcmV0"}},
+			{"kind": "ConfigMap", "metadata": {"name": "config"}, "data": {"key": "value"}}
+		]}}
+	}`))
+	redacted := fmt.Sprint(Redact(work))
+	if strings.Contains(redacted, "c2VjcmV0") || !strings.Contains(redacted, ".dockerconfigjson:<redacted>") {
+		t.Errorf("expected the secret data to be redacted, got %s", redacted)
+	}
+	if !strings.Contains(redacted, "key:value") {
+		t.Errorf("expected the configmap data to be kept, got %s", redacted)
+	}
+	if strings.Contains(redacted, "resourceVersion") || strings.Contains(redacted, "managedFields") {
+		t.Errorf("expected the server managed fields to be dropped, got %s", redacted)
+	}
+}
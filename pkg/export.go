@@ -0,0 +1,160 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+// ExportOptions holds configuration for the export of the hub manifestworks of the fleet
+type ExportOptions struct {
+	Kubeconfig          string
+	Namespace           string
+	ConfigFile          string
+	OutputDir           string
+	ManifestTemplateDir string
+	WorkNamePrefix      string
+	WorkNameSuffix      string
+}
+
+// NewExportOptions returns an ExportOptions with default values
+func NewExportOptions() *ExportOptions {
+	return &ExportOptions{
+		Namespace: "open-cluster-management",
+	}
+}
+
+// AddFlags registers flags for the export of the hub manifestworks of the fleet
+func (o *ExportOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig,
+		"The kubeconfig of the hub of hubs, the in-cluster configuration is used if empty.")
+	flags.StringVar(&o.Namespace, "namespace", o.Namespace,
+		"The namespace of the controller the hub-cluster-controller-config ConfigMap is read from.")
+	flags.StringVar(&o.ConfigFile, "config", o.ConfigFile,
+		"The YAML file of the hub-cluster-controller-config ConfigMap to render the manifestworks with, instead of the one of the hub.")
+	flags.StringVar(&o.OutputDir, "output-dir", o.OutputDir,
+		"The directory the manifestworks are written to, one file per manifestwork under a directory per namespace.")
+	flags.StringVar(&o.ManifestTemplateDir, "manifest-template-dir", o.ManifestTemplateDir,
+		"The directory of the subscription.yaml and mch.yaml templates of the controller, the built-in manifests are used if empty.")
+	flags.StringVar(&o.WorkNamePrefix, "work-name-prefix", o.WorkNamePrefix,
+		"The prefix of the names of the manifestworks, as set on the controller.")
+	flags.StringVar(&o.WorkNameSuffix, "work-name-suffix", o.WorkNameSuffix,
+		"The suffix of the names of the manifestworks, as set on the controller.")
+}
+
+func NewExport() *cobra.Command {
+	opts := NewExportOptions()
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the subscription and mch manifestworks of all managed hubs to a directory",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if opts.OutputDir == "" {
+				return fmt.Errorf("--output-dir is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeConfig, err := clientcmd.BuildConfigFromFlags("", opts.Kubeconfig)
+			if err != nil {
+				return err
+			}
+			clusterClient, err := clusterv1client.NewForConfig(kubeConfig)
+			if err != nil {
+				return err
+			}
+			kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+			if err != nil {
+				return err
+			}
+			return opts.Export(cmd.Context(), clusterClient, kubeClient, cmd.ErrOrStderr())
+		},
+	}
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// Export renders the manifestworks of the managed hubs, that is the managed clusters neither disabled
+// nor excluded by the configuration, and writes them to <output-dir>/<namespace>/<name>.yaml. The
+// managed clusters that can not be rendered outside the controller are reported to errOut and the
+// export fails once the others are written.
+func (o *ExportOptions) Export(ctx context.Context, clusterClient clusterv1client.Interface,
+	kubeClient kubernetes.Interface, errOut io.Writer) error {
+	naming := cluster.WorkNaming{Prefix: o.WorkNamePrefix, Suffix: o.WorkNameSuffix}
+	if err := naming.Validate(); err != nil {
+		return err
+	}
+	if o.ManifestTemplateDir != "" {
+		if err := cluster.LoadManifestTemplates(o.ManifestTemplateDir); err != nil {
+			return err
+		}
+	}
+	configMap, err := readConfigFile(o.ConfigFile)
+	if err != nil {
+		return err
+	}
+	if configMap == nil {
+		configMap, err = kubeClient.CoreV1().ConfigMaps(o.Namespace).Get(ctx, cluster.HUB_CONFIG_NAME, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			configMap, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	config, err := cluster.ParseHubConfig(configMap)
+	if err != nil {
+		return err
+	}
+
+	managedClusters, err := clusterClient.ClusterV1().ManagedClusters().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	sort.Slice(managedClusters.Items, func(i, j int) bool {
+		return managedClusters.Items[i].Name < managedClusters.Items[j].Name
+	})
+	failed := 0
+	for i := range managedClusters.Items {
+		managedCluster := &managedClusters.Items[i]
+		if !cluster.IsManagedHub(managedCluster) || config.Excluded(managedCluster.Name) {
+			continue
+		}
+		works, err := cluster.RenderManifestWorks(managedCluster, config, naming)
+		if err != nil {
+			fmt.Fprintf(errOut, "Failed to render the manifestworks of managed cluster %s: %v\n", managedCluster.Name, err)
+			failed++
+			continue
+		}
+		for _, work := range works {
+			data, err := yaml.Marshal(work)
+			if err != nil {
+				return err
+			}
+			dir := filepath.Join(o.OutputDir, work.Namespace)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(dir, work.Name+".yaml"), data, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to render the manifestworks of %d managed clusters", failed)
+	}
+	return nil
+}
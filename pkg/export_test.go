@@ -0,0 +1,67 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+func TestExport(t *testing.T) {
+	clusterClient := clusterfake.NewSimpleClientset(
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}},
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster2", Annotations: map[string]string{"mch": "{"}}},
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster3", Labels: map[string]string{cluster.HOH_LABEL: cluster.HOH_LABEL_DISABLED}}},
+		&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster4"}},
+	)
+	kubeClient := kubefake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: cluster.HUB_CONFIG_NAME, Namespace: "test"},
+		Data: map[string]string{
+			cluster.HUB_CONFIG_CHANNEL_KEY:           "release-2.5",
+			cluster.HUB_CONFIG_EXCLUDED_CLUSTERS_KEY: "cluster4",
+		},
+	})
+	opts := &ExportOptions{Namespace: "test", OutputDir: t.TempDir()}
+	errOut := &bytes.Buffer{}
+	if err := opts.Export(context.TODO(), clusterClient, kubeClient, errOut); err == nil {
+		t.Errorf("expected the invalid mch annotation of cluster2 to fail the export")
+	}
+	if !strings.Contains(errOut.String(), "managed cluster cluster2") {
+		t.Errorf("expected cluster2 to be reported, got %q", errOut.String())
+	}
+
+	exported := []string{}
+	if err := filepath.Walk(opts.OutputDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			relative, _ := filepath.Rel(opts.OutputDir, path)
+			exported = append(exported, relative)
+		}
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		filepath.Join("cluster1", "cluster1-"+cluster.HOH_HUB_CLUSTER_MCH+".yaml"),
+		filepath.Join("cluster1", "cluster1-"+cluster.HOH_HUB_CLUSTER_SUBSCRIPTION+".yaml"),
+	}
+	if strings.Join(exported, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected the manifestworks %v to be exported, got %v", expected, exported)
+	}
+	data, err := os.ReadFile(filepath.Join(opts.OutputDir, expected[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "channel: release-2.5") {
+		t.Errorf("expected the subscription to be rendered with the hub configuration:\n%s", data)
+	}
+}
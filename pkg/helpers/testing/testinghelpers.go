@@ -0,0 +1,58 @@
+// Package testing contains helpers shared by the controller tests.
+package testing
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// FakeSyncContext is a factory.SyncContext backed by a real queue and an in-memory recorder.
+type FakeSyncContext struct {
+	queueKey string
+	queue    *delayRecordingQueue
+	recorder events.Recorder
+}
+
+// NewFakeSyncContext returns a FakeSyncContext for the given queue key
+func NewFakeSyncContext(t *testing.T, queueKey string) *FakeSyncContext {
+	return &FakeSyncContext{
+		queueKey: queueKey,
+		queue: &delayRecordingQueue{
+			RateLimitingInterface: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+			delays:                map[interface{}]time.Duration{},
+		},
+		recorder: events.NewInMemoryRecorder(t.Name()),
+	}
+}
+
+func (f FakeSyncContext) Queue() workqueue.RateLimitingInterface { return f.queue }
+func (f FakeSyncContext) QueueKey() string                       { return f.queueKey }
+func (f FakeSyncContext) Recorder() events.Recorder              { return f.recorder }
+
+// AddedAfter returns the delay of the last AddAfter of the key to the queue, and false if the key
+// was never added with a delay.
+func (f FakeSyncContext) AddedAfter(key interface{}) (time.Duration, bool) {
+	f.queue.lock.Lock()
+	defer f.queue.lock.Unlock()
+	delay, ok := f.queue.delays[key]
+	return delay, ok
+}
+
+// delayRecordingQueue records the delays of the keys added after a delay, which are not observable
+// on the queue until they elapse.
+type delayRecordingQueue struct {
+	workqueue.RateLimitingInterface
+	lock   sync.Mutex
+	delays map[interface{}]time.Duration
+}
+
+func (q *delayRecordingQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.lock.Lock()
+	q.delays[item] = duration
+	q.lock.Unlock()
+	q.RateLimitingInterface.AddAfter(item, duration)
+}
@@ -0,0 +1,105 @@
+package pkg
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+// lastAppliedAnnotation is set by kubectl apply with a full copy of the applied object
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// newManagedClusterInformer returns a ManagedCluster informer which drops the fields never read by
+// the controllers before caching the managed clusters.
+func newManagedClusterInformer(client clusterv1client.Interface, resync time.Duration) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		strippingListWatch(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.ClusterV1().ManagedClusters().List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.ClusterV1().ManagedClusters().Watch(context.TODO(), options)
+			},
+		}),
+		&clusterv1.ManagedCluster{},
+		resync,
+		cache.Indexers{},
+	)
+}
+
+// newManifestWorkInformer returns a ManifestWork informer of the manifestworks created by the
+// controller, which drops the fields never read by the controllers before caching the manifestworks.
+func newManifestWorkInformer(client workv1client.Interface, resync time.Duration) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		strippingListWatch(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = cluster.MANAGED_BY_SELECTOR
+				return client.WorkV1().ManifestWorks(metav1.NamespaceAll).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = cluster.MANAGED_BY_SELECTOR
+				return client.WorkV1().ManifestWorks(metav1.NamespaceAll).Watch(context.TODO(), options)
+			},
+		}),
+		&workv1.ManifestWork{},
+		resync,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+}
+
+// strippingListWatch strips the listed and watched objects with stripObject. client-go does not
+// support transforming the objects of a shared informer yet, so they are stripped as they are
+// received instead.
+func strippingListWatch(lw *cache.ListWatch) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			list, err := lw.List(options)
+			if err != nil {
+				return nil, err
+			}
+			if err := meta.EachListItem(list, func(obj runtime.Object) error {
+				stripObject(obj)
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			w, err := lw.Watch(options)
+			if err != nil {
+				return nil, err
+			}
+			return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+				stripObject(event.Object)
+				return event, true
+			}), nil
+		},
+	}
+}
+
+// stripObject drops the managed fields and the last applied configuration of the object, which can
+// be larger than the rest of the object and are never read by the controllers. The status updates
+// and patches sent by the controllers leave them untouched on the server.
+func stripObject(obj runtime.Object) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	accessor.SetManagedFields(nil)
+	if annotations := accessor.GetAnnotations(); annotations[lastAppliedAnnotation] != "" {
+		delete(annotations, lastAppliedAnnotation)
+		accessor.SetAnnotations(annotations)
+	}
+}
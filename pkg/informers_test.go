@@ -0,0 +1,98 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+func newObjectMeta(name, namespace string, labels map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Labels:      labels,
+		Annotations: map[string]string{lastAppliedAnnotation: "{}", "kept": "true"},
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply},
+		},
+	}
+}
+
+func assertStripped(t *testing.T, obj metav1.Object) {
+	if len(obj.GetManagedFields()) != 0 {
+		t.Errorf("expected the managed fields of %s to be stripped, got %v", obj.GetName(), obj.GetManagedFields())
+	}
+	if _, ok := obj.GetAnnotations()[lastAppliedAnnotation]; ok {
+		t.Errorf("expected the last applied configuration of %s to be stripped", obj.GetName())
+	}
+	if obj.GetAnnotations()["kept"] != "true" {
+		t.Errorf("expected the other annotations of %s to be kept, got %v", obj.GetName(), obj.GetAnnotations())
+	}
+}
+
+func TestManagedClusterInformerStripsObjects(t *testing.T) {
+	client := clusterfake.NewSimpleClientset(&clusterv1.ManagedCluster{ObjectMeta: newObjectMeta("cluster1", "", nil)})
+	informer := newManagedClusterInformer(client, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		t.Fatalf("failed to sync the informer")
+	}
+
+	if _, err := client.ClusterV1().ManagedClusters().Create(context.TODO(),
+		&clusterv1.ManagedCluster{ObjectMeta: newObjectMeta("cluster2", "", nil)}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"cluster1", "cluster2"} {
+		var obj interface{}
+		if err := waitFor(func() bool {
+			var exists bool
+			obj, exists, _ = informer.GetStore().GetByKey(name)
+			return exists
+		}); err != nil {
+			t.Fatalf("failed to find %s in the informer cache", name)
+		}
+		assertStripped(t, obj.(*clusterv1.ManagedCluster))
+	}
+}
+
+func TestManifestWorkInformerOnlyCachesManagedWorks(t *testing.T) {
+	client := workfake.NewSimpleClientset(
+		&workv1.ManifestWork{ObjectMeta: newObjectMeta("managed", "cluster1",
+			map[string]string{cluster.MANAGED_BY_LABEL: cluster.MANAGED_BY_VALUE})},
+		&workv1.ManifestWork{ObjectMeta: newObjectMeta("other", "cluster1", nil)},
+	)
+	informer := newManifestWorkInformer(client, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		t.Fatalf("failed to sync the informer")
+	}
+
+	obj, exists, _ := informer.GetStore().GetByKey("cluster1/managed")
+	if !exists {
+		t.Fatalf("expected the managed manifestwork to be cached")
+	}
+	assertStripped(t, obj.(*workv1.ManifestWork))
+	if _, exists, _ := informer.GetStore().GetByKey("cluster1/other"); exists {
+		t.Errorf("expected the manifestwork not created by the controller not to be cached")
+	}
+}
+
+func waitFor(condition func() bool) error {
+	return wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return condition(), nil
+	})
+}
@@ -0,0 +1,305 @@
+package inventory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
+	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/apis/v1alpha1"
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+	"github.com/stolostron/hub-cluster-controller/pkg/metrics"
+)
+
+// inventoryController aggregates the hub conditions of all managed clusters into the
+// ManagedHubInventory.
+type inventoryController struct {
+	dynamicClient dynamic.Interface
+	clusterLister clusterlisterv1.ManagedClusterLister
+	// workLister lists the subscription manifestworks reporting the installed CSVs, it is nil if
+	// the CSVs are not reported
+	workLister worklisterv1.ManifestWorkLister
+	// configMapLister gets the hub configuration excluding managed clusters and tolerating the
+	// version drift, it is nil if the default configuration is used
+	configMapLister corev1listers.ConfigMapNamespaceLister
+}
+
+// NewInventoryController creates a new managed hub inventory controller
+func NewInventoryController(
+	dynamicClient dynamic.Interface,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	workInformer workinformerv1.ManifestWorkInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	configNamespace string,
+	recorder events.Recorder) factory.Controller {
+	c := &inventoryController{
+		dynamicClient:   dynamicClient,
+		clusterLister:   clusterInformer.Lister(),
+		workLister:      workInformer.Lister(),
+		configMapLister: configMapInformer.Lister().ConfigMaps(configNamespace),
+	}
+	// the installed CSVs are refreshed with the version label of the managed clusters, so the frequent
+	// status updates of the manifestworks do not rebuild the inventory
+	return factory.New().
+		WithInformers(clusterInformer.Informer(), configMapInformer.Informer()).
+		WithBareInformers(workInformer.Informer()).
+		WithSync(c.sync).
+		ToController("ManagedHubInventoryController", recorder)
+}
+
+func (c *inventoryController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	managedClusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	desired := BuildInventoryStatus(managedClusters)
+	if err := c.setCSVs(desired.Hubs); err != nil {
+		return err
+	}
+	recordPhaseMetrics(desired)
+	recordVersionMetrics(desired)
+	config := c.hubConfig()
+	recordDriftMetrics(desired, managedClusters, config)
+	recordSkippedMetrics(managedClusters, config)
+
+	client := c.dynamicClient.Resource(v1alpha1.ManagedHubInventoriesResource)
+	obj, err := client.Get(ctx, v1alpha1.ManagedHubInventoryName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		klog.V(2).Infof("creating managed hub inventory %s", v1alpha1.ManagedHubInventoryName)
+		obj, err = toUnstructured(&v1alpha1.ManagedHubInventory{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       "ManagedHubInventory",
+			},
+			ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.ManagedHubInventoryName},
+		})
+		if err != nil {
+			return err
+		}
+		obj, err = client.Create(ctx, obj, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	inventory := &v1alpha1.ManagedHubInventory{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), inventory); err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(inventory.Status, desired) {
+		return nil
+	}
+
+	inventory.Status = desired
+	obj, err = toUnstructured(inventory)
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("updating managed hub inventory with %d hubs", desired.Total)
+	_, err = client.UpdateStatus(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
+
+// BuildInventoryStatus aggregates the hub conditions and version labels of the managed clusters
+// into the inventory status. Managed clusters without a managed hub are skipped.
+func BuildInventoryStatus(managedClusters []*clusterv1.ManagedCluster) v1alpha1.ManagedHubInventoryStatus {
+	status := v1alpha1.ManagedHubInventoryStatus{}
+	for _, managedCluster := range managedClusters {
+		if !cluster.IsManagedHub(managedCluster) {
+			continue
+		}
+		hub := v1alpha1.ManagedHub{
+			Name:    managedCluster.Name,
+			Version: managedCluster.Labels[cluster.HOH_HUB_VERSION_LABEL],
+			Phase:   v1alpha1.ManagedHubPending,
+		}
+		conditions := managedCluster.Status.Conditions
+		switch {
+		case meta.IsStatusConditionTrue(conditions, cluster.HubConditionDegraded):
+			hub.Phase = v1alpha1.ManagedHubDegraded
+			degraded := meta.FindStatusCondition(conditions, cluster.HubConditionDegraded)
+			hub.LastError = degraded.Message
+			hub.LastErrorTime = transitionTime(degraded)
+			status.Degraded++
+		case meta.IsStatusConditionTrue(conditions, cluster.HubConditionInstalled):
+			hub.Phase = v1alpha1.ManagedHubInstalled
+			status.Installed++
+		case meta.IsStatusConditionTrue(conditions, cluster.HubConditionInstalling):
+			hub.Phase = v1alpha1.ManagedHubInstalling
+		}
+		// the hubs failing to reconcile report their last error whatever their phase
+		if reconcileError := meta.FindStatusCondition(conditions, cluster.HubConditionReconcileError); hub.LastError == "" &&
+			reconcileError != nil && reconcileError.Status == metav1.ConditionTrue {
+			hub.LastError = reconcileError.Message
+			hub.LastErrorTime = transitionTime(reconcileError)
+		}
+		status.Hubs = append(status.Hubs, hub)
+	}
+	status.Total = int32(len(status.Hubs))
+	sort.Slice(status.Hubs, func(i, j int) bool {
+		return status.Hubs[i].Name < status.Hubs[j].Name
+	})
+	return status
+}
+
+// setCSVs sets the installed and current CSVs reported by the status feedback of the subscription
+// manifestworks on the managed hubs
+func (c *inventoryController) setCSVs(hubs []v1alpha1.ManagedHub) error {
+	if c.workLister == nil {
+		return nil
+	}
+	works, err := c.workLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	subscriptions := map[string]*workv1.ManifestWork{}
+	for _, work := range works {
+		if cluster.IsWorkOfType(work, cluster.HOH_HUB_CLUSTER_SUBSCRIPTION) {
+			subscriptions[cluster.WorkManagedCluster(work)] = work
+		}
+	}
+	for i := range hubs {
+		subscription := subscriptions[hubs[i].Name]
+		hubs[i].InstalledCSV = cluster.GetFeedbackValue(subscription, "Subscription", cluster.SUBSCRIPTION_INSTALLED_CSV_FEEDBACK)
+		hubs[i].CurrentCSV = cluster.GetFeedbackValue(subscription, "Subscription", cluster.SUBSCRIPTION_CURRENT_CSV_FEEDBACK)
+	}
+	return nil
+}
+
+// recordVersionMetrics sets the number of managed hubs running each version and CSV, the hubs not
+// reporting them yet are counted with empty labels.
+func recordVersionMetrics(status v1alpha1.ManagedHubInventoryStatus) {
+	type versionKey struct{ version, csv string }
+	counts := map[versionKey]int{}
+	for _, hub := range status.Hubs {
+		counts[versionKey{hub.Version, hub.InstalledCSV}]++
+	}
+	// drop the versions no longer running
+	metrics.HubVersions.Reset()
+	for key, count := range counts {
+		metrics.HubVersions.WithLabelValues(key.version, key.csv).Set(float64(count))
+	}
+}
+
+// hubConfig returns the current hub configuration, or the default one if it can not be read
+func (c *inventoryController) hubConfig() *cluster.HubConfig {
+	if c.configMapLister == nil {
+		return cluster.DefaultHubConfig()
+	}
+	configMap, err := c.configMapLister.Get(cluster.HUB_CONFIG_NAME)
+	if errors.IsNotFound(err) {
+		return cluster.DefaultHubConfig()
+	}
+	if err == nil {
+		var config *cluster.HubConfig
+		if config, err = cluster.ParseHubConfig(configMap); err == nil {
+			return config
+		}
+	}
+	klog.Errorf("Failed to read the hub configuration, the excluded clusters and version drift are not reported: %v", err)
+	return cluster.DefaultHubConfig()
+}
+
+// the reasons the hub of a managed cluster is not installed or not reconciled
+const (
+	SkipReasonLocalCluster            = "LocalCluster"
+	SkipReasonDisabled                = "Disabled"
+	SkipReasonExcluded                = "Excluded"
+	SkipReasonMCHInvalid              = "MultiClusterHubInvalid"
+	SkipReasonArchitectureUnsupported = "ArchitectureUnsupported"
+	SkipReasonHyperShiftUnsupported   = "HyperShiftUnsupported"
+)
+
+// SkipReason returns why the hub of the managed cluster is not installed or not reconciled, that is
+// the event filter of the controllers, the hub configuration or a failed preflight check, or an
+// empty string if it is reconciled.
+func SkipReason(managedCluster *clusterv1.ManagedCluster, config *cluster.HubConfig) string {
+	conditions := managedCluster.Status.Conditions
+	switch {
+	case managedCluster.Name == "local-cluster":
+		return SkipReasonLocalCluster
+	case managedCluster.Labels[cluster.HOH_LABEL] == cluster.HOH_LABEL_DISABLED:
+		return SkipReasonDisabled
+	case config.Excluded(managedCluster.Name):
+		return SkipReasonExcluded
+	case meta.IsStatusConditionTrue(conditions, cluster.HubConditionMCHInvalid):
+		return SkipReasonMCHInvalid
+	case meta.IsStatusConditionTrue(conditions, cluster.HubConditionArchitectureUnsupported):
+		return SkipReasonArchitectureUnsupported
+	case meta.IsStatusConditionTrue(conditions, cluster.HubConditionHyperShiftUnsupported):
+		return SkipReasonHyperShiftUnsupported
+	}
+	return ""
+}
+
+// recordSkippedMetrics sets the number of managed clusters whose hub is not installed or not
+// reconciled, by reason.
+func recordSkippedMetrics(managedClusters []*clusterv1.ManagedCluster, config *cluster.HubConfig) {
+	counts := map[string]int{
+		SkipReasonLocalCluster:            0,
+		SkipReasonDisabled:                0,
+		SkipReasonExcluded:                0,
+		SkipReasonMCHInvalid:              0,
+		SkipReasonArchitectureUnsupported: 0,
+		SkipReasonHyperShiftUnsupported:   0,
+	}
+	for _, managedCluster := range managedClusters {
+		if reason := SkipReason(managedCluster, config); reason != "" {
+			counts[reason]++
+		}
+	}
+	for reason, count := range counts {
+		metrics.SkippedClusters.WithLabelValues(reason).Set(float64(count))
+	}
+}
+
+// transitionTime returns the transition time of the condition, or nil if it is not set
+func transitionTime(condition *metav1.Condition) *metav1.Time {
+	if condition.LastTransitionTime.IsZero() {
+		return nil
+	}
+	return condition.LastTransitionTime.DeepCopy()
+}
+
+// recordPhaseMetrics sets the number of managed hubs in each phase.
+func recordPhaseMetrics(status v1alpha1.ManagedHubInventoryStatus) {
+	counts := map[v1alpha1.ManagedHubPhase]int{
+		v1alpha1.ManagedHubPending:    0,
+		v1alpha1.ManagedHubInstalling: 0,
+		v1alpha1.ManagedHubInstalled:  0,
+		v1alpha1.ManagedHubDegraded:   0,
+	}
+	for _, hub := range status.Hubs {
+		counts[hub.Phase]++
+	}
+	for phase, count := range counts {
+		metrics.ManagedHubs.WithLabelValues(string(phase)).Set(float64(count))
+	}
+}
+
+func toUnstructured(inventory *v1alpha1.ManagedHubInventory) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(inventory)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: content}, nil
+}
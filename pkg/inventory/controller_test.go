@@ -0,0 +1,241 @@
+package inventory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/component-base/metrics/testutil"
+
+	clusterv1listers "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	workv1listers "open-cluster-management.io/api/client/work/listers/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/apis/v1alpha1"
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+	"github.com/stolostron/hub-cluster-controller/pkg/metrics"
+)
+
+func newManagedCluster(name string, labels map[string]string, conditions ...metav1.Condition) *clusterv1.ManagedCluster {
+	return &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status:     clusterv1.ManagedClusterStatus{Conditions: conditions},
+	}
+}
+
+func TestBuildInventoryStatus(t *testing.T) {
+	failingSince := metav1.NewTime(time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC))
+	status := BuildInventoryStatus([]*clusterv1.ManagedCluster{
+		newManagedCluster("local-cluster", nil),
+		newManagedCluster("disabled", map[string]string{"hoh": "disabled"}),
+		newManagedCluster("pending", nil),
+		newManagedCluster("installed", map[string]string{cluster.HOH_HUB_VERSION_LABEL: "2.4.1"},
+			metav1.Condition{Type: cluster.HubConditionInstalled, Status: metav1.ConditionTrue}),
+		newManagedCluster("degraded", nil,
+			metav1.Condition{Type: cluster.HubConditionInstalling, Status: metav1.ConditionTrue},
+			metav1.Condition{Type: cluster.HubConditionDegraded, Status: metav1.ConditionTrue, Message: "forbidden"}),
+		newManagedCluster("installing", nil,
+			metav1.Condition{Type: cluster.HubConditionInstalling, Status: metav1.ConditionTrue},
+			metav1.Condition{Type: cluster.HubConditionReconcileError, Status: metav1.ConditionTrue,
+				Message: "conflict", LastTransitionTime: failingSince}),
+	})
+
+	expected := v1alpha1.ManagedHubInventoryStatus{
+		Total:     4,
+		Installed: 1,
+		Degraded:  1,
+		Hubs: []v1alpha1.ManagedHub{
+			{Name: "degraded", Phase: v1alpha1.ManagedHubDegraded, LastError: "forbidden"},
+			{Name: "installed", Phase: v1alpha1.ManagedHubInstalled, Version: "2.4.1"},
+			{Name: "installing", Phase: v1alpha1.ManagedHubInstalling, LastError: "conflict", LastErrorTime: &failingSince},
+			{Name: "pending", Phase: v1alpha1.ManagedHubPending},
+		},
+	}
+	if len(status.Hubs) != len(expected.Hubs) {
+		t.Fatalf("expected %v, got %v", expected, status)
+	}
+	for i := range expected.Hubs {
+		if !equality.Semantic.DeepEqual(status.Hubs[i], expected.Hubs[i]) {
+			t.Errorf("expected %v, got %v", expected.Hubs[i], status.Hubs[i])
+		}
+	}
+	if status.Total != expected.Total || status.Installed != expected.Installed || status.Degraded != expected.Degraded {
+		t.Errorf("expected %v, got %v", expected, status)
+	}
+}
+
+func TestSync(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(newManagedCluster("cluster1", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.Install(scheme); err != nil {
+		t.Fatal(err)
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme)
+	ctrl := &inventoryController{
+		dynamicClient: dynamicClient,
+		clusterLister: clusterv1listers.NewManagedClusterLister(indexer),
+	}
+
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "key")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// get, create, update status, then a single get on the second sync
+	if len(dynamicClient.Actions()) != 4 {
+		t.Fatalf("expected 4 actions, got %v", dynamicClient.Actions())
+	}
+	obj, err := dynamicClient.Resource(v1alpha1.ManagedHubInventoriesResource).
+		Get(context.TODO(), v1alpha1.ManagedHubInventoryName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	inventory := &v1alpha1.ManagedHubInventory{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), inventory); err != nil {
+		t.Fatal(err)
+	}
+	if inventory.Status.Total != 1 || inventory.Status.Hubs[0].Name != "cluster1" {
+		t.Errorf("unexpected inventory status %v", inventory.Status)
+	}
+}
+
+func TestRecordPhaseMetrics(t *testing.T) {
+	recordPhaseMetrics(v1alpha1.ManagedHubInventoryStatus{
+		Hubs: []v1alpha1.ManagedHub{
+			{Name: "cluster1", Phase: v1alpha1.ManagedHubInstalled},
+			{Name: "cluster2", Phase: v1alpha1.ManagedHubInstalled},
+			{Name: "cluster3", Phase: v1alpha1.ManagedHubDegraded},
+		},
+	})
+
+	for phase, expected := range map[v1alpha1.ManagedHubPhase]float64{
+		v1alpha1.ManagedHubPending:    0,
+		v1alpha1.ManagedHubInstalling: 0,
+		v1alpha1.ManagedHubInstalled:  2,
+		v1alpha1.ManagedHubDegraded:   1,
+	} {
+		value, err := testutil.GetGaugeMetricValue(metrics.ManagedHubs.WithLabelValues(string(phase)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Errorf("expected %v hubs in phase %s, got %v", expected, phase, value)
+		}
+	}
+}
+
+func TestRecordSkippedMetrics(t *testing.T) {
+	config, err := cluster.ParseHubConfig(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: cluster.HUB_CONFIG_NAME},
+		Data:       map[string]string{cluster.HUB_CONFIG_EXCLUDED_CLUSTERS_KEY: "excluded"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordSkippedMetrics([]*clusterv1.ManagedCluster{
+		newManagedCluster("local-cluster", nil),
+		newManagedCluster("disabled1", map[string]string{cluster.HOH_LABEL: cluster.HOH_LABEL_DISABLED}),
+		newManagedCluster("disabled2", map[string]string{cluster.HOH_LABEL: cluster.HOH_LABEL_DISABLED}),
+		newManagedCluster("excluded", nil),
+		newManagedCluster("arm64", nil,
+			metav1.Condition{Type: cluster.HubConditionArchitectureUnsupported, Status: metav1.ConditionTrue}),
+		newManagedCluster("installed", nil,
+			metav1.Condition{Type: cluster.HubConditionInstalled, Status: metav1.ConditionTrue}),
+	}, config)
+
+	for reason, expected := range map[string]float64{
+		SkipReasonLocalCluster:            1,
+		SkipReasonDisabled:                2,
+		SkipReasonExcluded:                1,
+		SkipReasonMCHInvalid:              0,
+		SkipReasonArchitectureUnsupported: 1,
+		SkipReasonHyperShiftUnsupported:   0,
+	} {
+		value, err := testutil.GetGaugeMetricValue(metrics.SkippedClusters.WithLabelValues(reason))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Errorf("expected %v clusters skipped as %s, got %v", expected, reason, value)
+		}
+	}
+}
+
+func TestRecordVersionMetrics(t *testing.T) {
+	recordVersionMetrics(v1alpha1.ManagedHubInventoryStatus{
+		Hubs: []v1alpha1.ManagedHub{
+			{Name: "cluster1", Version: "2.4.1", InstalledCSV: "advanced-cluster-management.v2.4.1"},
+			{Name: "cluster2", Version: "2.4.1", InstalledCSV: "advanced-cluster-management.v2.4.1"},
+			{Name: "cluster3", Version: "2.5.0", InstalledCSV: "advanced-cluster-management.v2.5.0"},
+		},
+	})
+	recordVersionMetrics(v1alpha1.ManagedHubInventoryStatus{
+		Hubs: []v1alpha1.ManagedHub{
+			{Name: "cluster1", Version: "2.5.0", InstalledCSV: "advanced-cluster-management.v2.5.0"},
+			{Name: "cluster2", Version: "2.5.0", InstalledCSV: "advanced-cluster-management.v2.5.0"},
+			{Name: "cluster3", Version: "2.5.0", InstalledCSV: "advanced-cluster-management.v2.5.0"},
+			{Name: "cluster4"},
+		},
+	})
+
+	for labels, expected := range map[[2]string]float64{
+		{"2.5.0", "advanced-cluster-management.v2.5.0"}: 3,
+		{"", ""}: 1,
+		// the versions no longer running are dropped
+		{"2.4.1", "advanced-cluster-management.v2.4.1"}: 0,
+	} {
+		value, err := testutil.GetGaugeMetricValue(metrics.HubVersions.WithLabelValues(labels[0], labels[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Errorf("expected %v hubs running %v, got %v", expected, labels, value)
+		}
+	}
+}
+
+func TestSetCSVs(t *testing.T) {
+	subscription := cluster.CreateSubManifestwork("cluster1", cluster.DefaultHubConfig())
+	csv, currentCSV := "advanced-cluster-management.v2.4.1", "advanced-cluster-management.v2.4.2"
+	subscription.Status.ResourceStatus.Manifests = []workv1.ManifestCondition{{
+		ResourceMeta: workv1.ManifestResourceMeta{Kind: "Subscription"},
+		StatusFeedbacks: workv1.StatusFeedbackResult{Values: []workv1.FeedbackValue{
+			{
+				Name:  cluster.SUBSCRIPTION_INSTALLED_CSV_FEEDBACK,
+				Value: workv1.FieldValue{Type: workv1.String, String: &csv},
+			},
+			{
+				Name:  cluster.SUBSCRIPTION_CURRENT_CSV_FEEDBACK,
+				Value: workv1.FieldValue{Type: workv1.String, String: &currentCSV},
+			},
+		}},
+	}}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(subscription); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := &inventoryController{workLister: workv1listers.NewManifestWorkLister(indexer)}
+
+	hubs := []v1alpha1.ManagedHub{{Name: "cluster1"}, {Name: "cluster2"}}
+	if err := ctrl.setCSVs(hubs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hubs[0].InstalledCSV != csv || hubs[0].CurrentCSV != currentCSV || hubs[1].InstalledCSV != "" || hubs[1].CurrentCSV != "" {
+		t.Errorf("unexpected CSVs %v", hubs)
+	}
+}
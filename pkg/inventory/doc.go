@@ -0,0 +1,3 @@
+// package inventory contains the hub-side controller maintaining the fleet inventory of the
+// managed hubs.
+package inventory
@@ -0,0 +1,116 @@
+package inventory
+
+import (
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/apis/v1alpha1"
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+	"github.com/stolostron/hub-cluster-controller/pkg/metrics"
+)
+
+// the types of version drift of the managed hubs
+const (
+	DriftMinorVersions  = "MinorVersions"
+	DriftReleasesBehind = "ReleasesBehind"
+)
+
+// minorVersion is the major and minor version of a hub, such as 2.4 for 2.4.1
+type minorVersion struct {
+	major, minor int
+}
+
+// channelPrefixes are the prefixes of the channels of the product flavors, followed by their version
+var channelPrefixes = []string{"release-", "community-"}
+
+// parseChannelVersion parses the major and minor version of a channel, such as release-2.4 or
+// community-2.5. The channels without known prefix, such as stable, have no version.
+func parseChannelVersion(channel string) (minorVersion, bool) {
+	for _, prefix := range channelPrefixes {
+		if strings.HasPrefix(channel, prefix) {
+			return parseMinorVersion(strings.TrimPrefix(channel, prefix))
+		}
+	}
+	return minorVersion{}, false
+}
+
+// parseMinorVersion parses the major and minor version of a hub version, such as 2.4.1, v2.6 or the
+// pre-release 2.5.0-rc1.
+func parseMinorVersion(version string) (minorVersion, bool) {
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return minorVersion{}, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return minorVersion{}, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return minorVersion{}, false
+	}
+	return minorVersion{major: major, minor: minor}, true
+}
+
+// behindChannel returns true if the version lags the channel version by more than the tolerated
+// number of minor releases. The minor releases of the previous major versions are unknown, so a
+// hub on an older major version is always behind.
+func behindChannel(version, channel minorVersion, tolerance int) bool {
+	if version.major != channel.major {
+		return version.major < channel.major
+	}
+	return channel.minor-version.minor > tolerance
+}
+
+// recordDriftMetrics sets the number of minor versions run by the managed hubs and the number of
+// hubs lagging the channel of their subscription, resolved through the product flavor of each hub,
+// and reports a drift when they exceed the tolerance of the configuration. The hubs not reporting
+// their version yet are ignored.
+func recordDriftMetrics(status v1alpha1.ManagedHubInventoryStatus, managedClusters []*clusterv1.ManagedCluster,
+	config *cluster.HubConfig) {
+	clustersByName := make(map[string]*clusterv1.ManagedCluster, len(managedClusters))
+	for _, managedCluster := range managedClusters {
+		clustersByName[managedCluster.Name] = managedCluster
+	}
+	versions := map[minorVersion]bool{}
+	lagging := 0
+	for _, hub := range status.Hubs {
+		version, ok := parseMinorVersion(hub.Version)
+		if !ok {
+			continue
+		}
+		versions[version] = true
+		managedCluster, ok := clustersByName[hub.Name]
+		if !ok {
+			managedCluster = &clusterv1.ManagedCluster{}
+		}
+		channel, ok := parseChannelVersion(cluster.Channel(managedCluster, config))
+		if ok && behindChannel(version, channel, config.MaxReleasesBehind) {
+			lagging++
+		}
+	}
+	metrics.HubMinorVersions.Set(float64(len(versions)))
+	metrics.HubsBehindChannel.Set(float64(lagging))
+
+	spreadDrift := config.MaxMinorVersions > 0 && len(versions) > config.MaxMinorVersions
+	lagDrift := config.MaxReleasesBehind > 0 && lagging > 0
+	if spreadDrift || lagDrift {
+		klog.V(2).Infof("managed hubs drifting: %d minor versions, %d hubs behind their channel",
+			len(versions), lagging)
+	}
+	metrics.VersionDrift.WithLabelValues(DriftMinorVersions).Set(boolToFloat(spreadDrift))
+	metrics.VersionDrift.WithLabelValues(DriftReleasesBehind).Set(boolToFloat(lagDrift))
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
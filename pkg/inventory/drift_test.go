@@ -0,0 +1,131 @@
+package inventory
+
+import (
+	"testing"
+
+	componentmetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/apis/v1alpha1"
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+	"github.com/stolostron/hub-cluster-controller/pkg/metrics"
+)
+
+func TestParseMinorVersion(t *testing.T) {
+	cases := map[string]struct {
+		expected minorVersion
+		ok       bool
+	}{
+		"2.4.1":       {minorVersion{2, 4}, true},
+		"v2.6":        {minorVersion{2, 6}, true},
+		"2.5.0-rc1":   {minorVersion{2, 5}, true},
+		"2.6.0+build": {minorVersion{2, 6}, true},
+		"":            {minorVersion{}, false},
+		"stable":      {minorVersion{}, false},
+		"2.x":         {minorVersion{}, false},
+	}
+	for version, c := range cases {
+		parsed, ok := parseMinorVersion(version)
+		if ok != c.ok || parsed != c.expected {
+			t.Errorf("expected %v %v for %q, got %v %v", c.expected, c.ok, version, parsed, ok)
+		}
+	}
+}
+
+func TestParseChannelVersion(t *testing.T) {
+	cases := map[string]struct {
+		expected minorVersion
+		ok       bool
+	}{
+		"release-2.5":   {minorVersion{2, 5}, true},
+		"community-2.6": {minorVersion{2, 6}, true},
+		"stable":        {minorVersion{}, false},
+		"fast-2.5":      {minorVersion{}, false},
+	}
+	for channel, c := range cases {
+		parsed, ok := parseChannelVersion(channel)
+		if ok != c.ok || parsed != c.expected {
+			t.Errorf("expected %v %v for %q, got %v %v", c.expected, c.ok, channel, parsed, ok)
+		}
+	}
+}
+
+func TestBehindChannel(t *testing.T) {
+	channel := minorVersion{2, 5}
+	for _, c := range []struct {
+		version   minorVersion
+		tolerance int
+		expected  bool
+	}{
+		{minorVersion{2, 5}, 0, false},
+		{minorVersion{2, 6}, 0, false},
+		{minorVersion{2, 4}, 0, true},
+		{minorVersion{2, 4}, 1, false},
+		{minorVersion{2, 2}, 2, true},
+		{minorVersion{1, 9}, 5, true},
+		{minorVersion{3, 0}, 0, false},
+	} {
+		if behind := behindChannel(c.version, channel, c.tolerance); behind != c.expected {
+			t.Errorf("expected %v behind the channel with tolerance %d to be %v", c.version, c.tolerance, c.expected)
+		}
+	}
+}
+
+func TestRecordDriftMetrics(t *testing.T) {
+	status := v1alpha1.ManagedHubInventoryStatus{
+		Hubs: []v1alpha1.ManagedHub{
+			{Name: "cluster1", Version: "2.3.2"},
+			{Name: "cluster2", Version: "2.4.1"},
+			{Name: "cluster3", Version: "2.5.0-rc1"},
+			{Name: "cluster4", Version: "2.5.1"},
+			{Name: "cluster5"},
+			{Name: "cluster6", Version: "2.5.0"},
+		},
+	}
+	// cluster6 runs the community operator, compared with the community channel
+	community := newManagedCluster("cluster6", nil)
+	community.Annotations = map[string]string{cluster.HOH_FLAVOR_ANNOTATION: cluster.FlavorCommunity}
+	managedClusters := []*clusterv1.ManagedCluster{newManagedCluster("cluster1", nil), community}
+
+	cases := []struct {
+		name              string
+		maxMinorVersions  int
+		maxReleasesBehind int
+		behind            float64
+		spreadDrift       float64
+		lagDrift          float64
+	}{
+		{name: "not checked", behind: 3},
+		{name: "tolerated", maxMinorVersions: 3, maxReleasesBehind: 2},
+		{name: "drifting", maxMinorVersions: 2, maxReleasesBehind: 1, behind: 1, spreadDrift: 1, lagDrift: 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := cluster.DefaultHubConfig()
+			config.Channel = "release-2.5"
+			config.CommunityChannel = "community-2.6"
+			config.MaxMinorVersions = c.maxMinorVersions
+			config.MaxReleasesBehind = c.maxReleasesBehind
+			recordDriftMetrics(status, managedClusters, config)
+
+			for _, m := range []struct {
+				gauge    componentmetrics.GaugeMetric
+				expected float64
+			}{
+				{metrics.HubMinorVersions, 3},
+				{metrics.HubsBehindChannel, c.behind},
+				{metrics.VersionDrift.WithLabelValues(DriftMinorVersions), c.spreadDrift},
+				{metrics.VersionDrift.WithLabelValues(DriftReleasesBehind), c.lagDrift},
+			} {
+				value, err := testutil.GetGaugeMetricValue(m.gauge)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if value != m.expected {
+					t.Errorf("expected %v, got %v", m.expected, value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// LeaderElectionOptions holds the leader election settings of the controller, so that only one of
+// several replicas writes the manifestworks at a time. Empty values use the library-go defaults,
+// that is a lock named hub-cluster-controller-lock in the controller namespace, and a lease
+// duration, renew deadline and retry period of 137s, 107s and 26s.
+type LeaderElectionOptions struct {
+	LeaderElect   bool
+	Namespace     string
+	Name          string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// AddFlags registers the leader election flags
+func (o *LeaderElectionOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.LeaderElect, "leader-elect", o.LeaderElect,
+		"Elect a leader among the replicas of the controller before running the controllers.")
+	flags.StringVar(&o.Namespace, "leader-election-namespace", o.Namespace,
+		"The namespace of the leader election lock. Defaults to the controller namespace.")
+	flags.StringVar(&o.Name, "leader-election-name", o.Name,
+		"The name of the leader election lock. Defaults to hub-cluster-controller-lock.")
+	flags.DurationVar(&o.LeaseDuration, "leader-election-lease-duration", o.LeaseDuration,
+		"The duration non-leader replicas wait before acquiring a lease that is not renewed. Defaults to 137s.")
+	flags.DurationVar(&o.RenewDeadline, "leader-election-renew-deadline", o.RenewDeadline,
+		"The duration the leader retries to renew its lease before giving up leadership. Defaults to 107s.")
+	flags.DurationVar(&o.RetryPeriod, "leader-election-retry-period", o.RetryPeriod,
+		"The duration between the attempts to acquire or renew the lease. Defaults to 26s.")
+}
+
+// Validate checks the lease durations are consistent with each other
+func (o *LeaderElectionOptions) Validate() error {
+	if o.LeaseDuration < 0 || o.RenewDeadline < 0 || o.RetryPeriod < 0 {
+		return fmt.Errorf("the leader election durations must not be negative")
+	}
+	if o.LeaseDuration > 0 && o.RenewDeadline > 0 && o.RenewDeadline >= o.LeaseDuration {
+		return fmt.Errorf("the leader election renew deadline %s must be shorter than the lease duration %s",
+			o.RenewDeadline, o.LeaseDuration)
+	}
+	if o.RenewDeadline > 0 && o.RetryPeriod > 0 && o.RetryPeriod >= o.RenewDeadline {
+		return fmt.Errorf("the leader election retry period %s must be shorter than the renew deadline %s",
+			o.RetryPeriod, o.RenewDeadline)
+	}
+	return nil
+}
+
+// leaderElectionConfig returns the leaderElection stanza of the library-go operator config, or nil
+// if nothing is overridden.
+func (o *LeaderElectionOptions) leaderElectionConfig() map[string]interface{} {
+	config := map[string]interface{}{}
+	if o.Namespace != "" {
+		config["namespace"] = o.Namespace
+	}
+	if o.Name != "" {
+		config["name"] = o.Name
+	}
+	for key, duration := range map[string]time.Duration{
+		"leaseDuration": o.LeaseDuration,
+		"renewDeadline": o.RenewDeadline,
+		"retryPeriod":   o.RetryPeriod,
+	} {
+		if duration > 0 {
+			config[key] = duration.String()
+		}
+	}
+	if len(config) == 0 {
+		return nil
+	}
+	return config
+}
+
+// injectLeaderElectionConfig merges the leader election settings into the operator config file, since
+// library-go only reads them from the file given by the --config flag. The merged config is written to
+// a temporary file which replaces the --config flag value.
+func (o *LeaderElectionOptions) injectLeaderElectionConfig(flags *pflag.FlagSet) error {
+	leaderElection := o.leaderElectionConfig()
+	if leaderElection == nil {
+		return nil
+	}
+
+	config := map[string]interface{}{
+		"apiVersion": "operator.openshift.io/v1alpha1",
+		"kind":       "GenericOperatorConfig",
+	}
+	if configFile := flags.Lookup("config").Value.String(); configFile != "" {
+		content, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return fmt.Errorf("failed to parse the config file %s: %v", configFile, err)
+		}
+	}
+	existing, _ := config["leaderElection"].(map[string]interface{})
+	if existing == nil {
+		existing = map[string]interface{}{}
+	}
+	for key, value := range leaderElection {
+		existing[key] = value
+	}
+	config["leaderElection"] = existing
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile("", "hub-cluster-controller-config-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return flags.Set("config", file.Name())
+}
@@ -0,0 +1,118 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+func TestLeaderElectionOptionsValidate(t *testing.T) {
+	cases := []struct {
+		name          string
+		options       LeaderElectionOptions
+		expectedError bool
+	}{
+		{
+			name:    "defaults",
+			options: LeaderElectionOptions{LeaderElect: true},
+		},
+		{
+			name: "consistent durations",
+			options: LeaderElectionOptions{
+				LeaseDuration: time.Minute, RenewDeadline: 40 * time.Second, RetryPeriod: 10 * time.Second,
+			},
+		},
+		{
+			name:          "renew deadline longer than the lease",
+			options:       LeaderElectionOptions{LeaseDuration: time.Minute, RenewDeadline: 2 * time.Minute},
+			expectedError: true,
+		},
+		{
+			name:          "retry period longer than the renew deadline",
+			options:       LeaderElectionOptions{RenewDeadline: time.Minute, RetryPeriod: time.Minute},
+			expectedError: true,
+		},
+		{
+			name:          "negative duration",
+			options:       LeaderElectionOptions{RetryPeriod: -time.Second},
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.options.Validate()
+			if c.expectedError && err == nil {
+				t.Errorf("expected error")
+			}
+			if !c.expectedError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestInjectLeaderElectionConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(configFile, []byte(`apiVersion: operator.openshift.io/v1alpha1
+kind: GenericOperatorConfig
+servingInfo:
+  bindAddress: ":8443"
+leaderElection:
+  retryPeriod: 10s
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	configFlag := ""
+	flags.StringVar(&configFlag, "config", "", "")
+	if err := flags.Set("config", configFile); err != nil {
+		t.Fatal(err)
+	}
+
+	options := &LeaderElectionOptions{LeaderElect: true, Namespace: "hoh", LeaseDuration: time.Minute}
+	if err := options.injectLeaderElectionConfig(flags); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if configFlag == configFile {
+		t.Fatalf("expected the config flag to be replaced")
+	}
+	defer os.Remove(configFlag)
+
+	content, err := ioutil.ReadFile(configFlag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config["servingInfo"].(map[string]interface{})["bindAddress"] != ":8443" {
+		t.Errorf("expected the serving info to be kept, got %v", config["servingInfo"])
+	}
+	leaderElection := config["leaderElection"].(map[string]interface{})
+	if leaderElection["namespace"] != "hoh" || leaderElection["leaseDuration"] != "1m0s" ||
+		leaderElection["retryPeriod"] != "10s" {
+		t.Errorf("unexpected leader election config %v", leaderElection)
+	}
+}
+
+func TestInjectLeaderElectionConfigWithDefaults(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	configFlag := ""
+	flags.StringVar(&configFlag, "config", "", "")
+
+	options := &LeaderElectionOptions{LeaderElect: true}
+	if err := options.injectLeaderElectionConfig(flags); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if configFlag != "" {
+		t.Errorf("expected the config flag not to be set, got %q", configFlag)
+	}
+}
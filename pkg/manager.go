@@ -2,41 +2,249 @@ package pkg
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stolostron/hub-cluster-controller/pkg/version"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	addonv1alpha1client "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addonv1alpha1informers "open-cluster-management.io/api/client/addon/informers/externalversions"
 	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	workv1client "open-cluster-management.io/api/client/work/clientset/versioned"
 	workv1informers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
 
+	"github.com/stolostron/hub-cluster-controller/pkg/apis/v1alpha1"
 	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+	"github.com/stolostron/hub-cluster-controller/pkg/dryrun"
+	"github.com/stolostron/hub-cluster-controller/pkg/inventory"
+	"github.com/stolostron/hub-cluster-controller/pkg/policy"
+	"github.com/stolostron/hub-cluster-controller/pkg/tracing"
 )
 
 var ResyncInterval = 5 * time.Minute
 
+// deployment modes of the hubs
+const (
+	// DeploymentModeManifestWork installs the hubs with manifestworks rendered for each managed cluster
+	DeploymentModeManifestWork = "ManifestWork"
+	// DeploymentModePolicy enforces the hubs with governance policies bound to the managed hubs
+	DeploymentModePolicy = "Policy"
+	// DeploymentModeManifestWorkReplicaSet installs the hubs with ManifestWorkReplicaSets fanned out
+	// to the managed hubs by the work API
+	DeploymentModeManifestWorkReplicaSet = "ManifestWorkReplicaSet"
+)
+
+// HubControllerOptions holds configuration for the hub cluster controller
+type HubControllerOptions struct {
+	InstallTimeout time.Duration
+	ResyncInterval time.Duration
+	MaxRetries     int
+	ParkedCooldown time.Duration
+
+	OperatorRecheckInterval time.Duration
+	ProfilingBindAddress    string
+	KubeAPIQPS              float32
+	KubeAPIBurst            int
+	Workers                 int
+	ShardCount              int
+	ShardIndex              int
+	DeploymentMode          string
+	DryRun                  bool
+	ManifestTemplateDir     string
+	WorkNamePrefix          string
+	WorkNameSuffix          string
+	ClusterProxyURL         string
+	ClusterProxyCAFile      string
+	HealthCheckInterval     time.Duration
+	EventCoalescingWindow   time.Duration
+	StuckWorkTimeout        time.Duration
+	FeedbackMissingRechecks int
+
+	LeaderElection LeaderElectionOptions
+	Tracing        tracing.Options
+}
+
+// NewHubControllerOptions returns a HubControllerOptions with default values
+func NewHubControllerOptions() *HubControllerOptions {
+	return &HubControllerOptions{
+		InstallTimeout: time.Hour,
+		ResyncInterval: ResyncInterval,
+		MaxRetries:     10,
+		ParkedCooldown: 6 * time.Hour,
+
+		OperatorRecheckInterval: time.Minute,
+		KubeAPIQPS:              100,
+		KubeAPIBurst:            200,
+		Workers:                 1,
+		ShardCount:              1,
+		DeploymentMode:          DeploymentModeManifestWork,
+		HealthCheckInterval:     10 * time.Minute,
+		EventCoalescingWindow:   5 * time.Second,
+		FeedbackMissingRechecks: 10,
+
+		LeaderElection: LeaderElectionOptions{LeaderElect: true},
+		Tracing:        tracing.Options{SamplingRatio: 1},
+	}
+}
+
+// AddFlags registers flags for the hub cluster controller
+func (o *HubControllerOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.DurationVar(&o.InstallTimeout, "install-timeout", o.InstallTimeout,
+		"The time a hub may take to install before it is reported as degraded. Set to 0 to disable the timeout.")
+	flags.DurationVar(&o.ResyncInterval, "resync-interval", o.ResyncInterval,
+		"The interval to resync all managed hubs to correct drift without informer events. Set to 0 to disable the resync.")
+	flags.IntVar(&o.MaxRetries, "max-retries", o.MaxRetries,
+		"The number of failed syncs after which a managed hub is parked until its desired state changes. Set to 0 to retry forever.")
+	flags.DurationVar(&o.ParkedCooldown, "parked-cooldown", o.ParkedCooldown,
+		"The time after which a parked managed hub is retried once, and parked again if it still fails. Set to 0 to only retry it when its desired state changes.")
+	flags.DurationVar(&o.OperatorRecheckInterval, "operator-recheck-interval", o.OperatorRecheckInterval,
+		"The interval to recheck a managed hub while waiting for its operator subscription to reach AtLatestKnown. Set to 0 to only rely on status events.")
+	flags.IntVar(&o.FeedbackMissingRechecks, "feedback-missing-rechecks", o.FeedbackMissingRechecks,
+		"The number of operator rechecks the subscription manifestwork may be applied without reporting the state of the operator subscription before the hub is reported as degraded, falling back to resyncs if the recheck is disabled. Set to 0 to wait forever.")
+	flags.DurationVar(&o.EventCoalescingWindow, "event-coalescing-window", o.EventCoalescingWindow,
+		"The minimum interval between two syncs of a managed hub triggered by the status updates of its manifestworks, the updates received meanwhile are collapsed into one sync. Set to 0 to sync on every update.")
+	flags.DurationVar(&o.StuckWorkTimeout, "stuck-work-timeout", o.StuckWorkTimeout,
+		"The time the hub manifestworks of a deleted managed cluster may be stuck in deletion before their finalizers are removed, so the managed cluster namespace is cleaned up. Set to 0 to never remove them.")
+	flags.IntVar(&o.Workers, "workers", o.Workers,
+		"The number of concurrent sync workers of each controller. A managed hub is never synced by two workers at once.")
+	flags.IntVar(&o.ShardCount, "shard-count", o.ShardCount,
+		"The number of shards the managed hubs are partitioned into, each shard reconciled by its own replicas.")
+	flags.IntVar(&o.ShardIndex, "shard-index", o.ShardIndex,
+		"The shard of the managed hubs reconciled by this replica, between 0 and --shard-count - 1.")
+	flags.StringVar(&o.DeploymentMode, "deployment-mode", o.DeploymentMode,
+		"How the hubs are deployed, ManifestWork to install them with manifestworks, Policy to enforce them with governance policies, or ManifestWorkReplicaSet to fan them out with ManifestWorkReplicaSets.")
+	flags.BoolVar(&o.DryRun, "dry-run", o.DryRun,
+		"Log the writes of the controller and send them as server-side dry runs, without changing the fleet. The events are still recorded.")
+	flags.StringVar(&o.ManifestTemplateDir, "manifest-template-dir", o.ManifestTemplateDir,
+		"The directory of the subscription.yaml and mch.yaml templates replacing the built-in operator subscription and MultiClusterHub, such as a mounted ConfigMap.")
+	flags.StringVar(&o.WorkNamePrefix, "work-name-prefix", o.WorkNamePrefix,
+		"The prefix of the names of the hub manifestworks created by the controller, <prefix><cluster>-hoh-hub-cluster-<type><suffix>. The existing manifestworks are found by their labels and keep their name.")
+	flags.StringVar(&o.WorkNameSuffix, "work-name-suffix", o.WorkNameSuffix,
+		"The suffix of the names of the hub manifestworks created by the controller.")
+	flags.StringVar(&o.ClusterProxyURL, "cluster-proxy-url", o.ClusterProxyURL,
+		"The URL of the cluster-proxy user server the health of the managed hubs is read through when the managedServiceAccount hub configuration is enabled, the managed clusters are queried at <cluster-proxy-url>/<cluster>.")
+	flags.StringVar(&o.ClusterProxyCAFile, "cluster-proxy-ca-file", o.ClusterProxyCAFile,
+		"The CA bundle verifying the certificate of the cluster-proxy user server.")
+	flags.DurationVar(&o.HealthCheckInterval, "health-check-interval", o.HealthCheckInterval,
+		"The interval the health of the managed hubs is read through the cluster-proxy. Set to 0 to only read it on the changes of the hubs.")
+	flags.Float32Var(&o.KubeAPIQPS, "kube-api-qps", o.KubeAPIQPS,
+		"The QPS of the clients talking to the kube-apiserver.")
+	flags.IntVar(&o.KubeAPIBurst, "kube-api-burst", o.KubeAPIBurst,
+		"The burst of the clients talking to the kube-apiserver.")
+	flags.StringVar(&o.ProfilingBindAddress, "profiling-bind-address", o.ProfilingBindAddress,
+		"The address to serve the pprof handlers on, for example localhost:6060. Profiling is disabled if empty.")
+	flags.StringVar(&o.Tracing.Endpoint, "tracing-endpoint", o.Tracing.Endpoint,
+		"The host:port of the OTLP gRPC collector to export the traces of the sync loops to. Tracing is disabled if empty.")
+	flags.BoolVar(&o.Tracing.Insecure, "tracing-insecure", o.Tracing.Insecure,
+		"Connect to the OTLP collector without TLS.")
+	flags.Float64Var(&o.Tracing.SamplingRatio, "tracing-sampling-ratio", o.Tracing.SamplingRatio,
+		"The fraction of the sync loops traced, between 0 and 1.")
+	o.LeaderElection.AddFlags(flags)
+}
+
 func NewController() *cobra.Command {
-	cmd := controllercmd.
-		NewControllerCommandConfig("hub-cluster-controller", version.Get(), runControllerManager).
-		NewCommand()
+	opts := NewHubControllerOptions()
+	cmdConfig := controllercmd.NewControllerCommandConfig("hub-cluster-controller", version.Get(), opts.RunControllerManager)
+	cmd := cmdConfig.NewCommand()
 	cmd.Use = "controller"
 	cmd.Short = "Start the Hub Cluster Controller"
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := opts.LeaderElection.Validate(); err != nil {
+			return err
+		}
+		if err := opts.Tracing.Validate(); err != nil {
+			return err
+		}
+		if opts.Workers < 1 {
+			return fmt.Errorf("--workers must be at least 1")
+		}
+		if opts.KubeAPIQPS <= 0 || opts.KubeAPIBurst <= 0 {
+			return fmt.Errorf("--kube-api-qps and --kube-api-burst must be positive")
+		}
+		if opts.ShardCount < 1 || opts.ShardIndex < 0 || opts.ShardIndex >= opts.ShardCount {
+			return fmt.Errorf("--shard-index must be between 0 and --shard-count - 1, and --shard-count at least 1")
+		}
+		if err := (cluster.WorkNaming{Prefix: opts.WorkNamePrefix, Suffix: opts.WorkNameSuffix}).Validate(); err != nil {
+			return err
+		}
+		switch opts.DeploymentMode {
+		case DeploymentModeManifestWork, DeploymentModePolicy, DeploymentModeManifestWorkReplicaSet:
+		default:
+			return fmt.Errorf("--deployment-mode must be %s, %s or %s", DeploymentModeManifestWork,
+				DeploymentModePolicy, DeploymentModeManifestWorkReplicaSet)
+		}
+		if opts.LeaderElection.Name == "" && (opts.DryRun || opts.ShardCount > 1) {
+			// a dry run controller runs next to the controller of the fleet, it elects its own leader
+			lock := "hub-cluster-controller-lock"
+			if opts.DryRun {
+				lock = "hub-cluster-controller-dry-run-lock"
+			}
+			// the replicas of each shard elect their own leader
+			if opts.ShardCount > 1 {
+				lock = fmt.Sprintf("%s-shard-%d", lock, opts.ShardIndex)
+			}
+			opts.LeaderElection.Name = lock
+		}
+		cmdConfig.DisableLeaderElection = !opts.LeaderElection.LeaderElect
+		return opts.LeaderElection.injectLeaderElectionConfig(cmd.Flags())
+	}
 
+	opts.AddFlags(cmd.Flags())
 	return cmd
 }
 
-// runControllerManager starts the controllers on hub to manage spoke cluster registration.
-func runControllerManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
-	// If qps in kubconfig is not set, increase the qps and burst to enhance the ability of kube client to handle
-	// requests in concurrent
-	// TODO: Use ClientConnectionOverrides flags to change qps/burst when library-go exposes them in the future
+// RunControllerManager starts the controllers on hub to manage spoke cluster registration.
+func (o *HubControllerOptions) RunControllerManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
+	if o.ManifestTemplateDir != "" {
+		if err := cluster.LoadManifestTemplates(o.ManifestTemplateDir); err != nil {
+			return err
+		}
+	}
+	if o.ProfilingBindAddress != "" {
+		if err := startProfiling(ctx, o.ProfilingBindAddress); err != nil {
+			return err
+		}
+	}
+	handleVerbositySignals(ctx)
+
+	// the client-go default qps and burst are too low to handle the requests of large fleets
 	kubeConfig := rest.CopyConfig(controllerContext.KubeConfig)
-	if kubeConfig.QPS == 0.0 {
-		kubeConfig.QPS = 100.0
-		kubeConfig.Burst = 200
+	kubeConfig.QPS = o.KubeAPIQPS
+	kubeConfig.Burst = o.KubeAPIBurst
+
+	if o.Tracing.Endpoint != "" {
+		shutdown, err := tracing.Setup(ctx, o.Tracing, version.Get().GitVersion)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			// the controller context is done already, give the pending spans some time to be exported
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdown(shutdownCtx); err != nil {
+				klog.Errorf("Failed to flush the traces: %v", err)
+			}
+		}()
+		tracing.WrapConfig(kubeConfig)
+	}
+	if o.DryRun {
+		klog.Infof("Running in dry run mode, the writes are logged and not persisted")
+		dryrun.WrapConfig(kubeConfig)
 	}
 
 	clusterClient, err := clusterv1client.NewForConfig(kubeConfig)
@@ -49,21 +257,237 @@ func runControllerManager(ctx context.Context, controllerContext *controllercmd.
 		return err
 	}
 
+	addOnClient, err := addonv1alpha1client.NewForConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
+
 	clusterInformers := clusterv1informers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
 	workInformers := workv1informers.NewSharedInformerFactory(workClient, 10*time.Minute)
+	// only watch the addons of the agents created by the controller
+	addOnInformers := addonv1alpha1informers.NewSharedInformerFactoryWithOptions(addOnClient, 10*time.Minute,
+		addonv1alpha1informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = cluster.MANAGED_BY_SELECTOR
+		}))
+	// register the stripping informers before the informers are requested by the controllers, so
+	// the factories share them
+	clusterInformers.InformerFor(&clusterv1.ManagedCluster{}, newManagedClusterInformer)
+	workInformers.InformerFor(&workv1.ManifestWork{}, newManifestWorkInformer)
+	dynamicInformers := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 10*time.Minute)
+	// the MultiClusterHubOverrides are only watched when their CRD is installed, the hubs are then
+	// only overridden by their annotations
+	var overrideInformer informers.GenericInformer
+	served, err := isResourceServed(kubeClient.Discovery(), v1alpha1.MultiClusterHubOverridesResource)
+	if err != nil {
+		return err
+	}
+	if served {
+		overrideInformer = dynamicInformers.ForResource(v1alpha1.MultiClusterHubOverridesResource)
+	} else {
+		klog.Infof("The %s are not served, the hubs are only overridden by their annotations",
+			v1alpha1.MultiClusterHubOverridesResource.Resource)
+	}
+	// the Restores are only watched when the cluster backup operator is installed, a missing resource
+	// would block the start of the controllers
+	restoreInformers := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, 10*time.Minute,
+		cluster.RESTORE_NAMESPACE, nil)
+	var restoreInformer informers.GenericInformer
+	served, err = isResourceServed(kubeClient.Discovery(), cluster.RestoresResource)
+	if err != nil {
+		return err
+	}
+	if served {
+		restoreInformer = restoreInformers.ForResource(cluster.RestoresResource)
+	} else {
+		klog.Infof("The %s are not served, the controller does not pause during the restores", cluster.RestoresResource.Resource)
+	}
+	// the MultiClusterHubs with an ambiguous status feedback are only verified when the views are served
+	var viewClient dynamic.NamespaceableResourceInterface
+	served, err = isResourceServed(kubeClient.Discovery(), cluster.ManagedClusterViewsResource)
+	if err != nil {
+		return err
+	}
+	if served {
+		viewClient = dynamicClient.Resource(cluster.ManagedClusterViewsResource)
+	}
+	// the managed serviceaccounts reading the health of the hubs are only created when they are served
+	var msaClient dynamic.NamespaceableResourceInterface
+	served, err = isResourceServed(kubeClient.Discovery(), cluster.ManagedServiceAccountsResource)
+	if err != nil {
+		return err
+	}
+	if served {
+		msaClient = dynamicClient.Resource(cluster.ManagedServiceAccountsResource)
+	}
+	// only watch the hub configuration in the controller namespace
+	kubeInformers := informers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute,
+		informers.WithNamespace(controllerContext.OperatorNamespace))
+
+	controllerOptions := cluster.ControllerOptions{
+		InstallTimeout:          o.InstallTimeout,
+		ResyncInterval:          o.ResyncInterval,
+		MaxRetries:              o.MaxRetries,
+		ParkedCooldown:          o.ParkedCooldown,
+		OperatorRecheckInterval: o.OperatorRecheckInterval,
+		ConfigNamespace:         controllerContext.OperatorNamespace,
+		ShardCount:              o.ShardCount,
+		ShardIndex:              o.ShardIndex,
+		DryRun:                  o.DryRun,
+		ClusterProxyURL:         o.ClusterProxyURL,
+		ClusterProxyCAFile:      o.ClusterProxyCAFile,
+		HealthCheckInterval:     o.HealthCheckInterval,
+		EventCoalescingWindow:   o.EventCoalescingWindow,
+		StuckWorkTimeout:        o.StuckWorkTimeout,
+		FeedbackMissingRechecks: o.FeedbackMissingRechecks,
+		WorkNaming:              cluster.WorkNaming{Prefix: o.WorkNamePrefix, Suffix: o.WorkNameSuffix},
+	}
+	clusterRecorder, stopRecording := cluster.NewClusterEventRecorder(kubeClient)
+	defer stopRecording()
 
-	hubClusterController := cluster.NewHubClusterController(
+	subscriptionController := cluster.NewSubscriptionController(
+		clusterClient.ClusterV1(),
+		workClient.WorkV1(),
+		clusterInformers.Cluster().V1().ManagedClusters(),
+		workInformers.Work().V1().ManifestWorks(),
+		kubeInformers.Core().V1().ConfigMaps(),
+		kubeInformers.Core().V1().Secrets(),
+		overrideInformer,
+		restoreInformer,
+		controllerOptions,
+		controllerContext.EventRecorder,
+		clusterRecorder,
+	)
+	mchController := cluster.NewMCHController(
+		clusterClient.ClusterV1(),
 		workClient.WorkV1(),
 		clusterInformers.Cluster().V1().ManagedClusters(),
 		workInformers.Work().V1().ManifestWorks(),
+		kubeInformers.Core().V1().ConfigMaps(),
+		kubeInformers.Core().V1().Secrets(),
+		overrideInformer,
+		restoreInformer,
+		controllerOptions,
+		controllerContext.EventRecorder,
+		clusterRecorder,
+	)
+	agentController := cluster.NewAgentController(
+		clusterClient.ClusterV1(),
+		workClient.WorkV1(),
+		addOnClient.AddonV1alpha1(),
+		clusterInformers.Cluster().V1().ManagedClusters(),
+		workInformers.Work().V1().ManifestWorks(),
+		addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+		kubeInformers.Core().V1().ConfigMaps(),
+		kubeInformers.Core().V1().Secrets(),
+		overrideInformer,
+		restoreInformer,
+		controllerOptions,
+		controllerContext.EventRecorder,
+		clusterRecorder,
+	)
+	statusController := cluster.NewStatusController(
+		clusterClient.ClusterV1(),
+		workClient.WorkV1(),
+		clusterInformers.Cluster().V1().ManagedClusters(),
+		workInformers.Work().V1().ManifestWorks(),
+		kubeInformers.Core().V1().ConfigMaps(),
+		kubeInformers.Core().V1().Secrets(),
+		overrideInformer,
+		restoreInformer,
+		viewClient,
+		controllerOptions,
+		controllerContext.EventRecorder,
+		clusterRecorder,
+	)
+	healthController := cluster.NewHealthController(
+		clusterClient.ClusterV1(),
+		workClient.WorkV1(),
+		kubeClient.CoreV1(),
+		clusterInformers.Cluster().V1().ManagedClusters(),
+		workInformers.Work().V1().ManifestWorks(),
+		kubeInformers.Core().V1().ConfigMaps(),
+		kubeInformers.Core().V1().Secrets(),
+		overrideInformer,
+		restoreInformer,
+		msaClient,
+		controllerOptions,
+		controllerContext.EventRecorder,
+		clusterRecorder,
+	)
+
+	inventoryController := inventory.NewInventoryController(
+		dynamicClient,
+		clusterInformers.Cluster().V1().ManagedClusters(),
+		workInformers.Work().V1().ManifestWorks(),
+		kubeInformers.Core().V1().ConfigMaps(),
+		controllerContext.OperatorNamespace,
+		controllerContext.EventRecorder,
+	)
+	policyController := policy.NewPolicyController(
+		dynamicClient,
+		kubeInformers.Core().V1().ConfigMaps(),
+		controllerContext.OperatorNamespace,
+		controllerContext.EventRecorder,
+	)
+	replicaSetController := policy.NewManifestWorkReplicaSetController(
+		dynamicClient,
+		kubeInformers.Core().V1().ConfigMaps(),
+		controllerContext.OperatorNamespace,
 		controllerContext.EventRecorder,
 	)
 
 	go clusterInformers.Start(ctx.Done())
 	go workInformers.Start(ctx.Done())
+	go addOnInformers.Start(ctx.Done())
+	go kubeInformers.Start(ctx.Done())
+	go dynamicInformers.Start(ctx.Done())
+	go restoreInformers.Start(ctx.Done())
 
-	go hubClusterController.Run(ctx, 1)
+	// the policies and replica sets are shared by the whole fleet, one worker of the first shard is enough
+	switch {
+	case o.DeploymentMode == DeploymentModePolicy && o.ShardIndex == 0:
+		go policyController.Run(ctx, 1)
+	case o.DeploymentMode == DeploymentModeManifestWorkReplicaSet && o.ShardIndex == 0:
+		go replicaSetController.Run(ctx, 1)
+	case o.DeploymentMode == DeploymentModeManifestWork:
+		go subscriptionController.Run(ctx, o.Workers)
+		go mchController.Run(ctx, o.Workers)
+		go agentController.Run(ctx, o.Workers)
+		go statusController.Run(ctx, o.Workers)
+		go healthController.Run(ctx, o.Workers)
+	}
+	// the inventory is a single object of the whole fleet, one worker of the first shard is enough
+	if o.ShardIndex == 0 {
+		go inventoryController.Run(ctx, 1)
+	}
 
 	<-ctx.Done()
 	return nil
 }
+
+// isResourceServed returns true if the kube-apiserver serves the resource.
+func isResourceServed(client discovery.DiscoveryInterface, resource schema.GroupVersionResource) (bool, error) {
+	resources, err := client.ServerResourcesForGroupVersion(resource.GroupVersion().String())
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == resource.Resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
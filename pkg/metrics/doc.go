@@ -0,0 +1,3 @@
+// package metrics contains the prometheus metrics of the hub installation lifecycle, they are
+// served on the /metrics endpoint of the controller.
+package metrics
@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	// export the depth, latency, work duration and retries of the controller queues, by queue name
+	_ "k8s.io/component-base/metrics/prometheus/workqueue"
+)
+
+const namespace = "open_cluster_management_hub_controller"
+
+var (
+	// HubsInstalled counts the hubs whose MultiClusterHub reached Running
+	HubsInstalled = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Namespace: namespace,
+			Name:      "hubs_installed_total",
+			Help:      "Number of managed hubs whose MultiClusterHub reached the Running phase.",
+		},
+	)
+	// HubsFailed counts the hubs turning degraded, by reason of the HubDegraded condition
+	HubsFailed = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace: namespace,
+			Name:      "hubs_failed_total",
+			Help:      "Number of managed hubs turning degraded, by reason.",
+		},
+		[]string{"reason"},
+	)
+	// HubInstallDuration observes the time from the creation of the subscription manifestwork to the
+	// MultiClusterHub reaching Running
+	HubInstallDuration = metrics.NewHistogram(
+		&metrics.HistogramOpts{
+			Namespace: namespace,
+			Name:      "hub_install_duration_seconds",
+			Help:      "Time from the creation of the hub manifestworks to the MultiClusterHub reaching the Running phase.",
+			// 1 minute to about 4 hours
+			Buckets: metrics.ExponentialBuckets(60, 2, 9),
+		},
+	)
+	// ManagedHubs is the current number of managed hubs, by phase
+	ManagedHubs = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace: namespace,
+			Name:      "managed_hubs",
+			Help:      "Number of managed hubs, by phase.",
+		},
+		[]string{"phase"},
+	)
+	// HubVersions is the current number of managed hubs, by version of the MultiClusterHub and CSV of
+	// the operator
+	HubVersions = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace: namespace,
+			Name:      "hub_versions",
+			Help:      "Number of managed hubs, by version of the MultiClusterHub and installed CSV of the operator.",
+		},
+		[]string{"version", "csv"},
+	)
+	// HubMinorVersions is the current number of distinct minor versions run by the managed hubs
+	HubMinorVersions = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace: namespace,
+			Name:      "hub_minor_versions",
+			Help:      "Number of distinct minor versions run by the managed hubs.",
+		},
+	)
+	// HubsBehindChannel is the current number of managed hubs lagging the channel of the subscription
+	// by more than the tolerated number of minor releases
+	HubsBehindChannel = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace: namespace,
+			Name:      "hubs_behind_channel",
+			Help:      "Number of managed hubs lagging the channel of the subscription by more than the tolerated number of releases.",
+		},
+	)
+	// VersionDrift is 1 while the managed hubs drift beyond the configured tolerance, by type of drift
+	VersionDrift = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace: namespace,
+			Name:      "version_drift",
+			Help:      "Whether the managed hubs drift beyond the configured tolerance, by type.",
+		},
+		[]string{"type"},
+	)
+	// SkippedClusters is the current number of managed clusters whose hub is not installed or not
+	// reconciled, by reason
+	SkippedClusters = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace: namespace,
+			Name:      "skipped_clusters",
+			Help:      "Number of managed clusters whose hub is not installed or not reconciled, by reason.",
+		},
+		[]string{"reason"},
+	)
+	// ReconcileErrors counts the failed reconciles, by controller and managed cluster
+	ReconcileErrors = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconcile_errors_total",
+			Help:      "Number of failed reconciles of the managed hubs, by controller and managed cluster.",
+		},
+		[]string{"controller", "cluster"},
+	)
+	// SyncRetries counts the managed hubs requeued with a backoff after a failed reconcile, by
+	// controller. The backoff does not go through the rate limiter of the queue, so these retries
+	// are not counted by the workqueue retries metric.
+	SyncRetries = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace: namespace,
+			Name:      "sync_retries_total",
+			Help:      "Number of managed hubs requeued with a backoff after a failed reconcile, by controller.",
+		},
+		[]string{"controller"},
+	)
+	// ParkedHubs counts the managed hubs parked after their retry budget is exhausted, by controller
+	ParkedHubs = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace: namespace,
+			Name:      "parked_hubs_total",
+			Help:      "Number of managed hubs parked after exhausting their retries, by controller.",
+		},
+		[]string{"controller"},
+	)
+	// ResourceRepairs counts the hub resources found deleted on the managed clusters while their
+	// manifestwork still exists, and reapplied, by kind
+	ResourceRepairs = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace: namespace,
+			Name:      "resource_repairs_total",
+			Help:      "Number of hub resources found deleted on the managed clusters and reapplied, by kind.",
+		},
+		[]string{"kind"},
+	)
+	// CoalescedSyncs counts the syncs of the managed hubs delayed to the end of the coalescing window
+	// of their manifestwork events, by controller
+	CoalescedSyncs = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace: namespace,
+			Name:      "coalesced_syncs_total",
+			Help:      "Number of syncs of the managed hubs delayed to collapse bursts of manifestwork events, by controller.",
+		},
+		[]string{"controller"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(HubsInstalled, HubsFailed, HubInstallDuration, ManagedHubs, HubVersions,
+		HubMinorVersions, HubsBehindChannel, VersionDrift, SkippedClusters, ReconcileErrors, SyncRetries, ParkedHubs, ResourceRepairs, CoalescedSyncs)
+}
+
+// ObserveHubInstalled records a hub reaching Running, installing since the given time.
+func ObserveHubInstalled(installStart, now time.Time) {
+	HubsInstalled.Inc()
+	if !installStart.IsZero() {
+		HubInstallDuration.Observe(now.Sub(installStart).Seconds())
+	}
+}
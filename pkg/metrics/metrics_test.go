@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"testing"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestWorkqueueMetrics(t *testing.T) {
+	queue := workqueue.NewNamed("TestController")
+	defer queue.ShutDown()
+	queue.Add("cluster1")
+	queue.Add("cluster2")
+
+	families, err := legacyregistry.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "workqueue_depth" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == "TestController" {
+					if metric.GetGauge().GetValue() != 2 {
+						t.Errorf("expected a queue depth of 2, got %v", metric.GetGauge().GetValue())
+					}
+					return
+				}
+			}
+		}
+	}
+	t.Errorf("expected the depth of the TestController queue to be exported")
+}
@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+// renderFunc renders the objects deploying the hub configuration on the fleet in the given namespace
+type renderFunc func(namespace string, config *cluster.HubConfig) ([]*unstructured.Unstructured, error)
+
+// policyController renders the objects deploying the operator subscription and the MultiClusterHub
+// of the hub configuration on the managed hubs selected by a Placement, in the controller namespace.
+type policyController struct {
+	dynamicClient dynamic.Interface
+	configLister  corev1listers.ConfigMapNamespaceLister
+	namespace     string
+	render        renderFunc
+}
+
+// NewPolicyController creates a new hub policy controller, enforcing the hubs with Policies bound to
+// the Placement of the managed hubs
+func NewPolicyController(
+	dynamicClient dynamic.Interface,
+	configMapInformer corev1informers.ConfigMapInformer,
+	namespace string,
+	recorder events.Recorder) factory.Controller {
+	return newController("HubPolicyController", dynamicClient, configMapInformer, namespace,
+		cluster.RenderPolicies, recorder)
+}
+
+// NewManifestWorkReplicaSetController creates a new hub ManifestWorkReplicaSet controller, fanning
+// the manifestworks of the hubs out to the Placement of the managed hubs
+func NewManifestWorkReplicaSetController(
+	dynamicClient dynamic.Interface,
+	configMapInformer corev1informers.ConfigMapInformer,
+	namespace string,
+	recorder events.Recorder) factory.Controller {
+	return newController("HubManifestWorkReplicaSetController", dynamicClient, configMapInformer, namespace,
+		cluster.RenderManifestWorkReplicaSets, recorder)
+}
+
+func newController(name string, dynamicClient dynamic.Interface, configMapInformer corev1informers.ConfigMapInformer,
+	namespace string, render renderFunc, recorder events.Recorder) factory.Controller {
+	c := &policyController{
+		dynamicClient: dynamicClient,
+		configLister:  configMapInformer.Lister().ConfigMaps(namespace),
+		namespace:     namespace,
+		render:        render,
+	}
+	return factory.New().
+		WithInformers(configMapInformer.Informer()).
+		WithSync(c.sync).
+		ToController(name, recorder)
+}
+
+func (c *policyController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	configMap, err := c.configLister.Get(cluster.HUB_CONFIG_NAME)
+	if errors.IsNotFound(err) {
+		configMap = nil
+	} else if err != nil {
+		return err
+	}
+	config, err := cluster.ParseHubConfig(configMap)
+	if err != nil {
+		return fmt.Errorf("invalid hub configuration %s: %v", cluster.HUB_CONFIG_NAME, err)
+	}
+	if config.Paused {
+		klog.V(2).Infof("the hub configuration is paused, not updating the fleet")
+		return nil
+	}
+
+	objects, err := c.render(c.namespace, config)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := c.apply(ctx, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply creates the object or updates it if its content is changed
+func (c *policyController) apply(ctx context.Context, desired *unstructured.Unstructured) error {
+	client := c.dynamicClient.Resource(resourceOf(desired)).Namespace(desired.GetNamespace())
+	existing, err := client.Get(ctx, desired.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		klog.V(2).Infof("creating %s %s/%s", desired.GetKind(), desired.GetNamespace(), desired.GetName())
+		_, err = client.Create(ctx, desired, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	updated := existing.DeepCopy()
+	for key, value := range desired.Object {
+		if key != "metadata" {
+			updated.Object[key] = value
+		}
+	}
+	labels := updated.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for key, value := range desired.GetLabels() {
+		labels[key] = value
+	}
+	updated.SetLabels(labels)
+	if equality.Semantic.DeepEqual(existing.Object, updated.Object) {
+		return nil
+	}
+	klog.V(2).Infof("updating %s %s/%s", desired.GetKind(), desired.GetNamespace(), desired.GetName())
+	_, err = client.Update(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+func resourceOf(obj *unstructured.Unstructured) schema.GroupVersionResource {
+	switch obj.GetKind() {
+	case "Policy":
+		return cluster.PoliciesResource
+	case "PlacementBinding":
+		return cluster.PlacementBindingsResource
+	case "ManifestWorkReplicaSet":
+		return cluster.ManifestWorkReplicaSetsResource
+	default:
+		return cluster.PlacementsResource
+	}
+}
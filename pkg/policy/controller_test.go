@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+	testinghelpers "github.com/stolostron/hub-cluster-controller/pkg/helpers/testing"
+)
+
+func newPolicyConfigLister(t *testing.T, data map[string]string) corev1listers.ConfigMapNamespaceLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: cluster.HUB_CONFIG_NAME, Namespace: "test"},
+		Data:       data,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return corev1listers.NewConfigMapLister(indexer).ConfigMaps("test")
+}
+
+func newPolicyController(t *testing.T, data map[string]string) (*policyController, *dynamicfake.FakeDynamicClient) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	return &policyController{
+		dynamicClient: dynamicClient,
+		configLister:  newPolicyConfigLister(t, data),
+		namespace:     "test",
+		render:        cluster.RenderPolicies,
+	}, dynamicClient
+}
+
+func TestSync(t *testing.T) {
+	ctrl, dynamicClient := newPolicyController(t, map[string]string{cluster.HUB_CONFIG_CHANNEL_KEY: "release-2.5"})
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "key")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a get and a create of each object, then a single get of each on the second sync
+	if len(dynamicClient.Actions()) != 12 {
+		t.Fatalf("expected 12 actions, got %v", dynamicClient.Actions())
+	}
+	for _, name := range []string{cluster.HOH_HUB_CLUSTER_SUBSCRIPTION, cluster.HOH_HUB_CLUSTER_MCH} {
+		if _, err := dynamicClient.Resource(cluster.PoliciesResource).Namespace("test").
+			Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
+			t.Errorf("expected the policy %s, got %v", name, err)
+		}
+	}
+	if _, err := dynamicClient.Resource(cluster.PlacementBindingsResource).Namespace("test").
+		Get(context.TODO(), cluster.HOH_HUB_CLUSTER_PLACEMENT_BINDING, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the placement binding, got %v", err)
+	}
+}
+
+func TestSyncUpdate(t *testing.T) {
+	ctrl, dynamicClient := newPolicyController(t, map[string]string{cluster.HUB_CONFIG_EXCLUDED_CLUSTERS_KEY: "cluster1"})
+	syncCtx := testinghelpers.NewFakeSyncContext(t, "key")
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctrl.configLister = newPolicyConfigLister(t, map[string]string{cluster.HUB_CONFIG_EXCLUDED_CLUSTERS_KEY: "cluster2"})
+	dynamicClient.ClearActions()
+	if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// only the placement is changed
+	if len(dynamicClient.Actions()) != 5 || dynamicClient.Actions()[3].GetVerb() != "update" {
+		t.Fatalf("expected the placement to be updated, got %v", dynamicClient.Actions())
+	}
+	placement, err := dynamicClient.Resource(cluster.PlacementsResource).Namespace("test").
+		Get(context.TODO(), cluster.HOH_HUB_CLUSTER_PLACEMENT, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	predicates, _, _ := unstructured.NestedSlice(placement.Object, "spec", "predicates")
+	expressions, _, _ := unstructured.NestedSlice(predicates[0].(map[string]interface{}),
+		"requiredClusterSelector", "labelSelector", "matchExpressions")
+	if values := expressions[1].(map[string]interface{})["values"].([]interface{}); values[1] != "cluster2" {
+		t.Errorf("expected cluster2 to be excluded, got %v", values)
+	}
+}
+
+func TestSyncPaused(t *testing.T) {
+	ctrl, dynamicClient := newPolicyController(t, map[string]string{cluster.HUB_CONFIG_PAUSED_KEY: "true"})
+	if err := ctrl.sync(context.TODO(), testinghelpers.NewFakeSyncContext(t, "key")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dynamicClient.Actions()) != 0 {
+		t.Errorf("expected no action while paused, got %v", dynamicClient.Actions())
+	}
+}
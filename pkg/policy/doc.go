@@ -0,0 +1,4 @@
+// package policy contains the hub-side controllers deploying the managed hubs fleet-wide, through
+// governance policies or ManifestWorkReplicaSets bound to a Placement, instead of manifestworks
+// rendered for each managed cluster.
+package policy
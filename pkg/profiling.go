@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// startProfiling serves the pprof handlers on the given address until the context is done.
+func startProfiling(ctx context.Context, bindAddress string) error {
+	listener, err := net.Listen("tcp", bindAddress)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Serving pprof on %s", listener.Addr())
+
+	server := &http.Server{Handler: profilingHandler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Failed to shut down the pprof server: %v", err)
+		}
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("Failed to serve pprof: %v", err)
+		}
+	}()
+	return nil
+}
+
+func profilingHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
@@ -0,0 +1,23 @@
+package pkg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProfilingHandler(t *testing.T) {
+	server := httptest.NewServer(profilingHandler())
+	defer server.Close()
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/cmdline"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status 200 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+// RenderOptions holds configuration for the rendering of the hub manifestworks
+type RenderOptions struct {
+	ClusterName         string
+	Labels              map[string]string
+	Annotations         map[string]string
+	Claims              map[string]string
+	ConfigFile          string
+	ManifestTemplateDir string
+	WorkNamePrefix      string
+	WorkNameSuffix      string
+}
+
+// NewRenderOptions returns a RenderOptions with default values
+func NewRenderOptions() *RenderOptions {
+	return &RenderOptions{}
+}
+
+// AddFlags registers flags for the rendering of the hub manifestworks
+func (o *RenderOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.ClusterName, "cluster", o.ClusterName, "The name of the managed cluster to render the manifestworks of.")
+	flags.StringToStringVar(&o.Labels, "labels", o.Labels,
+		"The labels of the managed cluster, for example hoh-size=small.")
+	flags.StringToStringVar(&o.Annotations, "annotations", o.Annotations,
+		"The annotations of the managed cluster, for example hoh-catalog-source=pre-release.")
+	flags.StringToStringVar(&o.Claims, "claims", o.Claims,
+		"The ClusterClaims of the managed cluster, for example region.open-cluster-management.io=us-east-1.")
+	flags.StringVar(&o.ConfigFile, "config", o.ConfigFile,
+		"The YAML file of the hub-cluster-controller-config ConfigMap, the default configuration is used if empty.")
+	flags.StringVar(&o.ManifestTemplateDir, "manifest-template-dir", o.ManifestTemplateDir,
+		"The directory of the subscription.yaml and mch.yaml templates of the controller, the built-in manifests are used if empty.")
+	flags.StringVar(&o.WorkNamePrefix, "work-name-prefix", o.WorkNamePrefix,
+		"The prefix of the names of the manifestworks, as set on the controller.")
+	flags.StringVar(&o.WorkNameSuffix, "work-name-suffix", o.WorkNameSuffix,
+		"The suffix of the names of the manifestworks, as set on the controller.")
+}
+
+func NewRender() *cobra.Command {
+	opts := NewRenderOptions()
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Print the subscription and mch manifestworks the controller creates for a managed cluster",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if opts.ClusterName == "" {
+				return fmt.Errorf("--cluster is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.Render(cmd.OutOrStdout())
+		},
+	}
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// Render writes the hub manifestworks of the managed cluster as a YAML stream.
+func (o *RenderOptions) Render(out io.Writer) error {
+	naming := cluster.WorkNaming{Prefix: o.WorkNamePrefix, Suffix: o.WorkNameSuffix}
+	if err := naming.Validate(); err != nil {
+		return err
+	}
+	if o.ManifestTemplateDir != "" {
+		if err := cluster.LoadManifestTemplates(o.ManifestTemplateDir); err != nil {
+			return err
+		}
+	}
+	configMap, err := readConfigFile(o.ConfigFile)
+	if err != nil {
+		return err
+	}
+	config, err := cluster.ParseHubConfig(configMap)
+	if err != nil {
+		return err
+	}
+
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        o.ClusterName,
+			Labels:      o.Labels,
+			Annotations: o.Annotations,
+		},
+	}
+	for name, value := range o.Claims {
+		managedCluster.Status.ClusterClaims = append(managedCluster.Status.ClusterClaims,
+			clusterv1.ManagedClusterClaim{Name: name, Value: value})
+	}
+	works, err := cluster.RenderManifestWorks(managedCluster, config, naming)
+	if err != nil {
+		return err
+	}
+	for _, work := range works {
+		data, err := yaml.Marshal(work)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readConfigFile reads the hub configuration ConfigMap from a YAML file, it returns nil if the file
+// is empty for the default configuration.
+func readConfigFile(file string) (*corev1.ConfigMap, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := yaml.Unmarshal(data, configMap); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", file, err)
+	}
+	return configMap, nil
+}
@@ -0,0 +1,51 @@
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: hub-cluster-controller-config
+data:
+  channel: release-2.5
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	opts := &RenderOptions{
+		ClusterName: "cluster1",
+		Annotations: map[string]string{"hoh-catalog-source": "pre-release"},
+		ConfigFile:  configFile,
+	}
+	out := &bytes.Buffer{}
+	if err := opts.Render(out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rendered := out.String()
+	if documents := strings.Count(rendered, "---\n"); documents != 2 {
+		t.Errorf("expected the subscription and mch manifestworks, got %d documents", documents)
+	}
+	for _, expected := range []string{
+		"name: cluster1-hoh-hub-cluster-subscription",
+		"name: cluster1-hoh-hub-cluster-mch",
+		"channel: release-2.5",
+		"source: pre-release",
+		"kind: MultiClusterHub",
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("expected %q in the rendered manifestworks:\n%s", expected, rendered)
+		}
+	}
+
+	opts.Annotations = map[string]string{"mch": "{"}
+	if err := opts.Render(&bytes.Buffer{}); err == nil {
+		t.Errorf("expected an invalid mch annotation to be reported")
+	}
+}
@@ -0,0 +1,136 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+	"github.com/stolostron/hub-cluster-controller/pkg/inventory"
+)
+
+// errorConditions are the conditions of the managed clusters reporting why a hub is not installed
+// or no longer retried, besides the degraded one, in the order they are reported as last error
+var errorConditions = []string{
+	cluster.HubConditionOperatorParked,
+	cluster.HubConditionMCHParked,
+	cluster.HubConditionAgentParked,
+	cluster.HubConditionMCHInvalid,
+	cluster.HubConditionArchitectureUnsupported,
+	cluster.HubConditionHyperShiftUnsupported,
+}
+
+// StatusOptions holds configuration for the fleet status summary
+type StatusOptions struct {
+	Kubeconfig string
+}
+
+// NewStatusOptions returns a StatusOptions with default values
+func NewStatusOptions() *StatusOptions {
+	return &StatusOptions{}
+}
+
+// AddFlags registers flags for the fleet status summary
+func (o *StatusOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig,
+		"The kubeconfig of the hub of hubs, the in-cluster configuration is used if empty.")
+}
+
+func NewStatus() *cobra.Command {
+	opts := NewStatusOptions()
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Print the installation status of the managed hubs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeConfig, err := clientcmd.BuildConfigFromFlags("", opts.Kubeconfig)
+			if err != nil {
+				return err
+			}
+			clusterClient, err := clusterv1client.NewForConfig(kubeConfig)
+			if err != nil {
+				return err
+			}
+			workClient, err := workv1client.NewForConfig(kubeConfig)
+			if err != nil {
+				return err
+			}
+			return PrintStatus(cmd.Context(), clusterClient, workClient, cmd.OutOrStdout())
+		},
+	}
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// PrintStatus writes a table of the managed hubs with their phase, the CSV installed by the operator
+// subscription, the version of the MultiClusterHub and the last error, as seen by the controller.
+func PrintStatus(ctx context.Context, clusterClient clusterv1client.Interface, workClient workv1client.Interface,
+	out io.Writer) error {
+	managedClusters, err := clusterClient.ClusterV1().ManagedClusters().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	// the hosted managed clusters have their manifestworks in the hosting cluster namespace, list them
+	// all at once rather than per managed cluster
+	works, err := workClient.WorkV1().ManifestWorks(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: cluster.MANAGED_BY_LABEL + "=" + cluster.MANAGED_BY_VALUE,
+	})
+	if err != nil {
+		return err
+	}
+	// the manifestworks by managed cluster and type, whatever their naming scheme
+	worksByType := map[string]*workv1.ManifestWork{}
+	for i := range works.Items {
+		for _, workType := range []string{cluster.HOH_HUB_CLUSTER_SUBSCRIPTION, cluster.HOH_HUB_CLUSTER_MCH} {
+			if cluster.IsWorkOfType(&works.Items[i], workType) {
+				worksByType[cluster.WorkManagedCluster(&works.Items[i])+"/"+workType] = &works.Items[i]
+			}
+		}
+	}
+
+	clusters := make([]*clusterv1.ManagedCluster, 0, len(managedClusters.Items))
+	conditions := map[string][]metav1.Condition{}
+	for i := range managedClusters.Items {
+		clusters = append(clusters, &managedClusters.Items[i])
+		conditions[managedClusters.Items[i].Name] = managedClusters.Items[i].Status.Conditions
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tPHASE\tINSTALLED CSV\tMCH VERSION\tLAST ERROR")
+	for _, hub := range inventory.BuildInventoryStatus(clusters).Hubs {
+		lastError := hub.LastError
+		for _, conditionType := range errorConditions {
+			if lastError != "" {
+				break
+			}
+			if condition := meta.FindStatusCondition(conditions[hub.Name], conditionType); condition != nil &&
+				condition.Status == metav1.ConditionTrue {
+				lastError = condition.Message
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", hub.Name, hub.Phase,
+			valueOrNone(cluster.GetFeedbackValue(worksByType[hub.Name+"/"+cluster.HOH_HUB_CLUSTER_SUBSCRIPTION],
+				"Subscription", cluster.SUBSCRIPTION_INSTALLED_CSV_FEEDBACK)),
+			valueOrNone(cluster.GetFeedbackValue(worksByType[hub.Name+"/"+cluster.HOH_HUB_CLUSTER_MCH],
+				"MultiClusterHub", cluster.MCH_VERSION_FEEDBACK)),
+			valueOrNone(lastError))
+	}
+	return w.Flush()
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+func newFeedbackWork(namespace, name, kind, feedback, value string) *workv1.ManifestWork {
+	return &workv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      namespace + "-" + name,
+			Namespace: namespace,
+			Labels:    map[string]string{cluster.MANAGED_BY_LABEL: cluster.MANAGED_BY_VALUE},
+		},
+		Status: workv1.ManifestWorkStatus{
+			ResourceStatus: workv1.ManifestResourceStatus{
+				Manifests: []workv1.ManifestCondition{{
+					ResourceMeta: workv1.ManifestResourceMeta{Kind: kind},
+					StatusFeedbacks: workv1.StatusFeedbackResult{
+						Values: []workv1.FeedbackValue{{
+							Name:  feedback,
+							Value: workv1.FieldValue{Type: workv1.String, String: &value},
+						}},
+					},
+				}},
+			},
+		},
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	clusterClient := clusterfake.NewSimpleClientset(
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1"},
+			Status: clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{
+				{Type: cluster.HubConditionInstalled, Status: metav1.ConditionTrue},
+			}},
+		},
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster2"},
+			Status: clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{
+				{Type: cluster.HubConditionInstalling, Status: metav1.ConditionTrue},
+				{Type: cluster.HubConditionOperatorParked, Status: metav1.ConditionTrue, Message: "giving up"},
+			}},
+		},
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster3", Labels: map[string]string{cluster.HOH_LABEL: cluster.HOH_LABEL_DISABLED}},
+		},
+	)
+	workClient := workfake.NewSimpleClientset(
+		newFeedbackWork("cluster1", cluster.HOH_HUB_CLUSTER_SUBSCRIPTION, "Subscription",
+			cluster.SUBSCRIPTION_INSTALLED_CSV_FEEDBACK, "advanced-cluster-management.v2.5.0"),
+		newFeedbackWork("cluster1", cluster.HOH_HUB_CLUSTER_MCH, "MultiClusterHub",
+			cluster.MCH_VERSION_FEEDBACK, "2.5.0"),
+	)
+
+	out := &bytes.Buffer{}
+	if err := PrintStatus(context.TODO(), clusterClient, workClient, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 managed hubs, got:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "cluster1 Installed advanced-cluster-management.v2.5.0 2.5.0 <none>" {
+		t.Errorf("unexpected status of cluster1: %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "cluster2 Installing <none> <none> giving up" {
+		t.Errorf("unexpected status of cluster2: %q", lines[2])
+	}
+}
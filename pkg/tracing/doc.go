@@ -0,0 +1,3 @@
+// package tracing sets up the OpenTelemetry tracing of the sync loops, exported to an OTLP
+// collector.
+package tracing
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
+)
+
+// tracerName is the name of the tracer of the sync loops
+const tracerName = "github.com/stolostron/hub-cluster-controller"
+
+// Options holds the tracing settings. Tracing is disabled if no endpoint is set.
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector
+	Endpoint string
+	// Insecure disables the TLS of the connection to the collector
+	Insecure bool
+	// SamplingRatio is the fraction of the sync loops traced
+	SamplingRatio float64
+}
+
+// Validate checks the sampling ratio is a fraction
+func (o *Options) Validate() error {
+	if o.SamplingRatio < 0 || o.SamplingRatio > 1 {
+		return fmt.Errorf("the tracing sampling ratio %v must be between 0 and 1", o.SamplingRatio)
+	}
+	return nil
+}
+
+// Setup installs the global tracer provider exporting the spans to the OTLP collector. It returns
+// a function flushing the pending spans and stopping the exporter.
+func Setup(ctx context.Context, options Options, serviceVersion string) (func(context.Context) error, error) {
+	exporter, err := otlp.NewExporter(ctx, newDriver(options))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SamplingRatio))),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(
+			semconv.ServiceNameKey.String("hub-cluster-controller"),
+			semconv.ServiceVersionKey.String(serviceVersion),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+func newDriver(options Options) otlp.ProtocolDriver {
+	driverOptions := []otlpgrpc.Option{otlpgrpc.WithEndpoint(options.Endpoint)}
+	if options.Insecure {
+		driverOptions = append(driverOptions, otlpgrpc.WithInsecure())
+	}
+	return otlpgrpc.NewDriver(driverOptions...)
+}
+
+// WrapConfig traces the requests of the clients created from the config, the spans of the API
+// calls are children of the sync span of the request context.
+func WrapConfig(config *rest.Config) {
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return otelhttp.NewTransport(rt)
+	})
+}
+
+// StartSync starts the span of a sync of the managed cluster by the controller of the given phase.
+func StartSync(ctx context.Context, phase, managedClusterName string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, phase+".sync", trace.WithAttributes(
+		attribute.String("cluster", managedClusterName),
+		attribute.String("phase", phase),
+	))
+}
+
+// EndSync ends the span of a sync, recording the error if the sync failed.
+func EndSync(span trace.Span, err error) {
+	if err != nil {
+		recordError(span, err)
+	}
+	span.End()
+}
+
+// RecordError records a failed reconcile on the span of the context, for the failures retried with
+// a backoff rather than returned by the sync.
+func RecordError(ctx context.Context, err error) {
+	recordError(trace.SpanFromContext(ctx), err)
+}
+
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
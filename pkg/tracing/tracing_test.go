@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSyncSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	ctx, span := StartSync(context.TODO(), "MCHController", "cluster1")
+	RecordError(ctx, fmt.Errorf("failed to apply"))
+	EndSync(span, nil)
+
+	_, span = StartSync(context.TODO(), "MCHController", "cluster2")
+	EndSync(span, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "MCHController.sync" {
+		t.Errorf("expected span MCHController.sync, got %s", spans[0].Name)
+	}
+	attributes := map[string]string{}
+	for _, attribute := range spans[0].Attributes {
+		attributes[string(attribute.Key)] = attribute.Value.AsString()
+	}
+	if attributes["cluster"] != "cluster1" || attributes["phase"] != "MCHController" {
+		t.Errorf("unexpected attributes %v", attributes)
+	}
+	if spans[0].StatusCode != codes.Error || spans[1].StatusCode == codes.Error {
+		t.Errorf("expected only the failed sync to have an error status, got %v and %v",
+			spans[0].StatusCode, spans[1].StatusCode)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for ratio, valid := range map[float64]bool{-0.1: false, 0: true, 0.5: true, 1: true, 1.5: false} {
+		err := (&Options{SamplingRatio: ratio}).Validate()
+		if valid && err != nil {
+			t.Errorf("unexpected error for ratio %v: %v", ratio, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("expected error for ratio %v", ratio)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/clientcmd"
+	addonv1alpha1client "open-cluster-management.io/api/client/addon/clientset/versioned"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+// UninstallOptions holds configuration for the uninstallation of the hubs
+type UninstallOptions struct {
+	Kubeconfig   string
+	Timeout      time.Duration
+	PollInterval time.Duration
+	// MaxUnconfirmed is the number of hubs uninstalled at once without confirmation
+	MaxUnconfirmed int
+	// Confirm confirms the uninstallation of more than MaxUnconfirmed hubs at once
+	Confirm bool
+}
+
+// NewUninstallOptions returns an UninstallOptions with default values
+func NewUninstallOptions() *UninstallOptions {
+	return &UninstallOptions{
+		Timeout:        30 * time.Minute,
+		PollInterval:   5 * time.Second,
+		MaxUnconfirmed: 3,
+	}
+}
+
+// AddFlags registers flags for the uninstallation of the hubs
+func (o *UninstallOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig,
+		"The kubeconfig of the hub of hubs, the in-cluster configuration is used if empty.")
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout,
+		"The time to wait for the hub of each managed cluster to be uninstalled.")
+	flags.DurationVar(&o.PollInterval, "poll-interval", o.PollInterval,
+		"The interval to check the deletion of the hub manifestworks.")
+	flags.IntVar(&o.MaxUnconfirmed, "max-unconfirmed", o.MaxUnconfirmed,
+		"The number of hubs uninstalled at once without --confirm, so a wrong list of managed clusters does not uninstall the hubs of the fleet.")
+	flags.BoolVar(&o.Confirm, "confirm", o.Confirm,
+		"Confirm the uninstallation of more than --max-unconfirmed hubs at once.")
+}
+
+// Validate returns an error if the options are invalid, or if more than MaxUnconfirmed hubs are
+// uninstalled at once without confirmation.
+func (o *UninstallOptions) Validate(managedClusterNames []string) error {
+	if o.Timeout <= 0 || o.PollInterval <= 0 {
+		return fmt.Errorf("--timeout and --poll-interval must be positive")
+	}
+	if o.MaxUnconfirmed < 0 {
+		return fmt.Errorf("--max-unconfirmed must not be negative")
+	}
+	if len(managedClusterNames) > o.MaxUnconfirmed && !o.Confirm {
+		return fmt.Errorf("refusing to uninstall %d hubs at once without --confirm, more than --max-unconfirmed=%d",
+			len(managedClusterNames), o.MaxUnconfirmed)
+	}
+	return nil
+}
+
+func NewUninstall() *cobra.Command {
+	opts := NewUninstallOptions()
+	cmd := &cobra.Command{
+		Use:   "uninstall CLUSTER...",
+		Short: "Uninstall the hubs of the given managed clusters and wait until they are removed",
+		Args:  cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return opts.Validate(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return opts.Uninstall(ctx, cmd.OutOrStdout(), args)
+		},
+	}
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// Uninstall uninstalls the hubs of the managed clusters one after the other, it stops at the first
+// managed cluster failing to be uninstalled.
+func (o *UninstallOptions) Uninstall(ctx context.Context, out io.Writer, managedClusterNames []string) error {
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", o.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	clusterClient, err := clusterv1client.NewForConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
+	workClient, err := workv1client.NewForConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
+	addOnClient, err := addonv1alpha1client.NewForConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	uninstaller := cluster.NewUninstaller(clusterClient.ClusterV1(), workClient.WorkV1(), addOnClient.AddonV1alpha1(),
+		o.PollInterval)
+	for _, managedClusterName := range managedClusterNames {
+		clusterCtx, cancel := context.WithTimeout(ctx, o.Timeout)
+		err := uninstaller.Uninstall(clusterCtx, managedClusterName)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to uninstall the hub of managed cluster %s: %v", managedClusterName, err)
+		}
+		fmt.Fprintf(out, "Uninstalled the hub of managed cluster %s\n", managedClusterName)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package pkg
+
+import "testing"
+
+func TestUninstallValidate(t *testing.T) {
+	cases := []struct {
+		name          string
+		clusters      []string
+		confirm       bool
+		expectedError bool
+	}{
+		{
+			name:     "below the confirmation threshold",
+			clusters: []string{"cluster1", "cluster2", "cluster3"},
+		},
+		{
+			name:          "above the confirmation threshold",
+			clusters:      []string{"cluster1", "cluster2", "cluster3", "cluster4"},
+			expectedError: true,
+		},
+		{
+			name:     "confirmed",
+			clusters: []string{"cluster1", "cluster2", "cluster3", "cluster4"},
+			confirm:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := NewUninstallOptions()
+			opts.Confirm = c.confirm
+			if err := opts.Validate(c.clusters); c.expectedError != (err != nil) {
+				t.Errorf("expected error %v, got %v", c.expectedError, err)
+			}
+		})
+	}
+}
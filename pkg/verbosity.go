@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"k8s.io/klog/v2"
+)
+
+// maxVerbosity caps the log verbosity raised by the signals
+const maxVerbosity = 10
+
+// handleVerbositySignals raises the log verbosity by one level on SIGUSR1 and restores the verbosity
+// of the flags on SIGUSR2 until the context is done, so a stuck fleet is debugged without restarting
+// the controller and losing its in-memory state.
+func handleVerbositySignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	initial := currentVerbosity()
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-signals:
+				level := nextVerbosity(sig, currentVerbosity(), initial)
+				if err := setVerbosity(level); err != nil {
+					klog.Errorf("Failed to set the log verbosity to %d: %v", level, err)
+					continue
+				}
+				klog.Infof("Set the log verbosity to %d on %s", level, sig)
+			}
+		}
+	}()
+}
+
+// nextVerbosity returns the log verbosity to set on the signal
+func nextVerbosity(sig os.Signal, current, initial klog.Level) klog.Level {
+	if sig == syscall.SIGUSR2 {
+		return initial
+	}
+	if current >= maxVerbosity {
+		return maxVerbosity
+	}
+	return current + 1
+}
+
+// currentVerbosity returns the log verbosity, klog has no getter of its verbosity
+func currentVerbosity() klog.Level {
+	level := klog.Level(0)
+	for level < maxVerbosity && klog.V(level+1).Enabled() {
+		level++
+	}
+	return level
+}
+
+// setVerbosity sets the log verbosity, as the -v flag does
+func setVerbosity(level klog.Level) error {
+	var verbosity klog.Level
+	return verbosity.Set(strconv.Itoa(int(level)))
+}
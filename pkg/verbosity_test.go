@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"syscall"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+func TestNextVerbosity(t *testing.T) {
+	cases := []struct {
+		name     string
+		sig      syscall.Signal
+		current  klog.Level
+		expected klog.Level
+	}{
+		{name: "raise", sig: syscall.SIGUSR1, current: 2, expected: 3},
+		{name: "capped", sig: syscall.SIGUSR1, current: maxVerbosity, expected: maxVerbosity},
+		{name: "restore", sig: syscall.SIGUSR2, current: 6, expected: 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if level := nextVerbosity(c.sig, c.current, 2); level != c.expected {
+				t.Errorf("expected verbosity %d, got %d", c.expected, level)
+			}
+		})
+	}
+}
+
+func TestSetVerbosity(t *testing.T) {
+	initial := currentVerbosity()
+	defer func() {
+		if err := setVerbosity(initial); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := setVerbosity(initial + 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if level := currentVerbosity(); level != initial+2 {
+		t.Errorf("expected verbosity %d, got %d", initial+2, level)
+	}
+}
@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/webhook"
+)
+
+// WebhookOptions holds configuration for the admission webhook server
+type WebhookOptions struct {
+	Port       int
+	CertDir    string
+	Kubeconfig string
+
+	Webhook webhook.Options
+}
+
+// NewWebhookOptions returns a WebhookOptions with default values
+func NewWebhookOptions() *WebhookOptions {
+	return &WebhookOptions{
+		Port:    9443,
+		CertDir: "/var/run/secrets/serving-cert",
+	}
+}
+
+// AddFlags registers flags for the admission webhook server
+func (o *WebhookOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.IntVar(&o.Port, "port", o.Port, "The port to serve the admission webhooks on.")
+	flags.StringVar(&o.CertDir, "cert-dir", o.CertDir,
+		"The directory of the tls.crt and tls.key serving certificate of the admission webhooks.")
+	flags.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig,
+		"The kubeconfig of the hub the webhooks check the manifestworks on, the in-cluster configuration is used if empty.")
+	flags.StringVar(&o.Webhook.DefaultHOHLabel, "default-hoh-label", o.Webhook.DefaultHOHLabel,
+		"The hoh label set on the new managed clusters without it, for example disabled to only install hubs on the labeled managed clusters. The label is not defaulted if empty.")
+}
+
+func NewWebhook() *cobra.Command {
+	opts := NewWebhookOptions()
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Start the admission webhooks validating and defaulting the managed clusters",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if errs := validation.IsValidLabelValue(opts.Webhook.DefaultHOHLabel); len(errs) > 0 {
+				return fmt.Errorf("invalid --default-hoh-label: %s", strings.Join(errs, ", "))
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return opts.RunWebhook(ctx)
+		},
+	}
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// RunWebhook serves the admission webhooks until the context is done.
+func (o *WebhookOptions) RunWebhook(ctx context.Context) error {
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", o.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	workClient, err := workv1client.NewForConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", o.Port),
+		Handler:           webhook.NewHandler(o.Webhook, workClient.WorkV1()),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Failed to shut down the webhook server: %v", err)
+		}
+	}()
+
+	klog.Infof("Serving the admission webhooks on %s", server.Addr)
+	err = server.ListenAndServeTLS(filepath.Join(o.CertDir, "tls.crt"), filepath.Join(o.CertDir, "tls.key"))
+	if err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
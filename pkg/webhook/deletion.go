@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	workclientv1 "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+// VALIDATE_MANAGED_CLUSTER_DELETION_PATH is the path the deletions of the managed clusters are
+// validated on
+const VALIDATE_MANAGED_CLUSTER_DELETION_PATH = "/validate-managedcluster-deletions"
+
+// ValidateManagedClusterDeletion returns the admission function rejecting the deletion of the managed
+// clusters whose hub manifestworks still exist, so a managed cluster serving as a managed hub is not
+// detached by accident. The deletion is allowed once the manifestworks are being deleted, or are
+// orphaned intentionally with the Orphan propagation policy so the hub is left on the managed cluster.
+func ValidateManagedClusterDeletion(workClient workclientv1.WorkV1Interface) admitFunc {
+	return func(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+		if request.Operation != admissionv1.Delete {
+			return allow()
+		}
+		works, err := workClient.ManifestWorks(request.Name).List(ctx, metav1.ListOptions{
+			LabelSelector: cluster.MANAGED_BY_SELECTOR,
+		})
+		if err != nil {
+			return deny(metav1.StatusReasonInternalError, http.StatusInternalServerError,
+				fmt.Sprintf("failed to list the hub manifestworks: %v", err))
+		}
+
+		var remaining []string
+		for _, work := range works.Items {
+			if work.DeletionTimestamp != nil || isOrphaned(&work) {
+				continue
+			}
+			remaining = append(remaining, work.Name)
+		}
+		if len(remaining) == 0 {
+			return allow()
+		}
+		return deny(metav1.StatusReasonForbidden, http.StatusForbidden, fmt.Sprintf(
+			"the managed cluster %s is a managed hub, delete its manifestworks %s or set their deleteOption "+
+				"propagationPolicy to Orphan before deleting it", request.Name, strings.Join(remaining, ", ")))
+	}
+}
+
+// isOrphaned returns true if the resources of the manifestwork are left on the managed cluster when
+// it is deleted
+func isOrphaned(work *workv1.ManifestWork) bool {
+	return work.Spec.DeleteOption != nil && work.Spec.DeleteOption.PropagationPolicy == workv1.DeletePropagationPolicyTypeOrphan
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+func TestValidateManagedClusterDeletion(t *testing.T) {
+	newWork := func(deleteOption *workv1.DeleteOption, deleting bool) *workv1.ManifestWork {
+		work := cluster.CreateSubManifestwork("cluster1", cluster.DefaultHubConfig())
+		work.Spec.DeleteOption = deleteOption
+		if deleting {
+			now := metav1.Now()
+			work.DeletionTimestamp = &now
+		}
+		return work
+	}
+	cases := []struct {
+		name      string
+		operation admissionv1.Operation
+		works     []runtime.Object
+		expected  bool
+	}{
+		{
+			name:      "no manifestwork",
+			operation: admissionv1.Delete,
+			expected:  true,
+		},
+		{
+			name:      "manifestwork remaining",
+			operation: admissionv1.Delete,
+			works:     []runtime.Object{newWork(nil, false)},
+			expected:  false,
+		},
+		{
+			name:      "manifestwork being deleted",
+			operation: admissionv1.Delete,
+			works:     []runtime.Object{newWork(nil, true)},
+			expected:  true,
+		},
+		{
+			name:      "manifestwork orphaned",
+			operation: admissionv1.Delete,
+			works: []runtime.Object{newWork(&workv1.DeleteOption{
+				PropagationPolicy: workv1.DeletePropagationPolicyTypeOrphan,
+			}, false)},
+			expected: true,
+		},
+		{
+			name:      "update",
+			operation: admissionv1.Update,
+			works:     []runtime.Object{newWork(nil, false)},
+			expected:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			workClient := workfake.NewSimpleClientset(c.works...)
+			response := ValidateManagedClusterDeletion(workClient.WorkV1())(context.TODO(), &admissionv1.AdmissionRequest{
+				Name:      "cluster1",
+				Operation: c.operation,
+			})
+			if response.Allowed != c.expected {
+				t.Errorf("expected allowed %v, got %v: %v", c.expected, response.Allowed, response.Result)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+// MUTATE_MANAGED_CLUSTER_PATH is the path the managed clusters are defaulted on
+const MUTATE_MANAGED_CLUSTER_PATH = "/mutate-managedclusters"
+
+// jsonPatchOperation is an operation of the JSON patch returned to the kube-apiserver
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// annotationMigration rewrites an annotation of a deprecated format to its current format, it
+// returns false if the annotation is already in the current format or can not be migrated.
+type annotationMigration struct {
+	annotation string
+	migrate    func(value string) (string, bool)
+}
+
+// annotationMigrations are the deprecated annotation formats rewritten by the mutating webhook
+var annotationMigrations = []annotationMigration{
+	{annotation: cluster.HOH_MCH_ANNOTATION, migrate: migrateMCHComponents},
+}
+
+// deprecatedMCHComponents are the boolean fields of the MultiClusterHub spec deprecated by the
+// spec.overrides.components list, by the name of the component they enable
+var deprecatedMCHComponents = []struct {
+	field     string
+	component string
+}{
+	{field: "enableClusterBackup", component: "cluster-backup"},
+	{field: "enableClusterProxyAddon", component: "cluster-proxy-addon"},
+}
+
+// migrateMCHComponents rewrites the deprecated component fields of the MultiClusterHub of the mch
+// annotation to their spec.overrides.components entry. A component already listed is kept and the
+// deprecated field dropped, as the operator prefers the list. The invalid MultiClusterHubs are left
+// to the validation.
+func migrateMCHComponents(value string) (string, bool) {
+	mch := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(value), &mch); err != nil {
+		return "", false
+	}
+	spec, ok := mch["spec"].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+
+	migrated := false
+	for _, deprecated := range deprecatedMCHComponents {
+		enabled, ok := spec[deprecated.field].(bool)
+		if !ok {
+			continue
+		}
+		overrides, ok := spec["overrides"].(map[string]interface{})
+		if !ok {
+			if spec["overrides"] != nil {
+				continue
+			}
+			overrides = map[string]interface{}{}
+			spec["overrides"] = overrides
+		}
+		components, ok := overrides["components"].([]interface{})
+		if !ok && overrides["components"] != nil {
+			continue
+		}
+		if !hasComponent(components, deprecated.component) {
+			overrides["components"] = append(components, map[string]interface{}{
+				"name":    deprecated.component,
+				"enabled": enabled,
+			})
+		}
+		delete(spec, deprecated.field)
+		migrated = true
+	}
+	if !migrated {
+		return "", false
+	}
+	raw, err := json.Marshal(mch)
+	if err != nil {
+		return "", false
+	}
+	return string(raw), true
+}
+
+// hasComponent returns true if the spec.overrides.components list has an entry of the component
+func hasComponent(components []interface{}, name string) bool {
+	for _, component := range components {
+		if entry, ok := component.(map[string]interface{}); ok && entry["name"] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultManagedCluster returns the admission function defaulting the hoh label of the new managed
+// clusters to the given value, unless it is empty, and rewriting the deprecated annotation formats.
+func DefaultManagedCluster(defaultHOHLabel string) admitFunc {
+	return func(_ context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+		managedCluster := &clusterv1.ManagedCluster{}
+		if err := json.Unmarshal(request.Object.Raw, managedCluster); err != nil {
+			return deny(metav1.StatusReasonBadRequest, http.StatusBadRequest, fmt.Sprintf("invalid managed cluster: %v", err))
+		}
+
+		var patch []jsonPatchOperation
+		if _, ok := managedCluster.Labels[cluster.HOH_LABEL]; !ok && defaultHOHLabel != "" &&
+			request.Operation == admissionv1.Create {
+			if managedCluster.Labels == nil {
+				patch = append(patch, jsonPatchOperation{Op: "add", Path: "/metadata/labels",
+					Value: map[string]string{cluster.HOH_LABEL: defaultHOHLabel}})
+			} else {
+				patch = append(patch, jsonPatchOperation{Op: "add", Path: "/metadata/labels/" + escapeJSONPointer(cluster.HOH_LABEL),
+					Value: defaultHOHLabel})
+			}
+		}
+		for _, migration := range annotationMigrations {
+			value, ok := managedCluster.Annotations[migration.annotation]
+			if !ok {
+				continue
+			}
+			if migrated, ok := migration.migrate(value); ok {
+				patch = append(patch, jsonPatchOperation{Op: "replace",
+					Path: "/metadata/annotations/" + escapeJSONPointer(migration.annotation), Value: migrated})
+			}
+		}
+		if len(patch) == 0 {
+			return allow()
+		}
+
+		patchBytes, err := json.Marshal(patch)
+		if err != nil {
+			return deny(metav1.StatusReasonInternalError, http.StatusInternalServerError, err.Error())
+		}
+		patchType := admissionv1.PatchTypeJSONPatch
+		return &admissionv1.AdmissionResponse{Allowed: true, Patch: patchBytes, PatchType: &patchType}
+	}
+}
+
+// escapeJSONPointer escapes a label or annotation key as a JSON pointer reference token
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+func TestDefaultManagedCluster(t *testing.T) {
+	cases := []struct {
+		name            string
+		operation       admissionv1.Operation
+		defaultHOHLabel string
+		labels          map[string]string
+		annotations     map[string]string
+		expectedPatch   []jsonPatchOperation
+	}{
+		{
+			name:      "no default",
+			operation: admissionv1.Create,
+		},
+		{
+			name:            "default label without labels",
+			operation:       admissionv1.Create,
+			defaultHOHLabel: "disabled",
+			expectedPatch: []jsonPatchOperation{
+				{Op: "add", Path: "/metadata/labels", Value: map[string]interface{}{"hoh": "disabled"}},
+			},
+		},
+		{
+			name:            "default label",
+			operation:       admissionv1.Create,
+			defaultHOHLabel: "disabled",
+			labels:          map[string]string{"vendor": "OpenShift"},
+			expectedPatch:   []jsonPatchOperation{{Op: "add", Path: "/metadata/labels/hoh", Value: "disabled"}},
+		},
+		{
+			name:            "label set",
+			operation:       admissionv1.Create,
+			defaultHOHLabel: "disabled",
+			labels:          map[string]string{"hoh": "enabled"},
+		},
+		{
+			name:            "label not defaulted on update",
+			operation:       admissionv1.Update,
+			defaultHOHLabel: "disabled",
+		},
+		{
+			name:        "deprecated mch components",
+			operation:   admissionv1.Update,
+			annotations: map[string]string{"mch": `{"spec":{"availabilityConfig":"Basic","enableClusterBackup":true}}`},
+			expectedPatch: []jsonPatchOperation{{Op: "replace", Path: "/metadata/annotations/mch",
+				Value: `{"spec":{"availabilityConfig":"Basic","overrides":{"components":[{"enabled":true,"name":"cluster-backup"}]}}}`}},
+		},
+		{
+			name:      "deprecated mch components with overrides",
+			operation: admissionv1.Update,
+			annotations: map[string]string{"mch": `{"spec":{"enableClusterBackup":true,"enableClusterProxyAddon":false,` +
+				`"overrides":{"components":[{"name":"cluster-backup","enabled":false}]}}}`},
+			expectedPatch: []jsonPatchOperation{{Op: "replace", Path: "/metadata/annotations/mch",
+				Value: `{"spec":{"overrides":{"components":[{"enabled":false,"name":"cluster-backup"},{"enabled":false,"name":"cluster-proxy-addon"}]}}}`}},
+		},
+		{
+			name:        "current mch components",
+			operation:   admissionv1.Update,
+			annotations: map[string]string{"mch": `{"spec":{"overrides":{"components":[{"name":"cluster-backup","enabled":true}]}}}`},
+		},
+		{
+			name:        "invalid mch",
+			operation:   admissionv1.Update,
+			annotations: map[string]string{"mch": `{"spec":`},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			object := newManagedClusterRaw(t, c.annotations)
+			if c.labels != nil {
+				managedCluster := map[string]interface{}{}
+				if err := json.Unmarshal(object.Raw, &managedCluster); err != nil {
+					t.Fatal(err)
+				}
+				managedCluster["metadata"].(map[string]interface{})["labels"] = c.labels
+				raw, err := json.Marshal(managedCluster)
+				if err != nil {
+					t.Fatal(err)
+				}
+				object.Raw = raw
+			}
+			response := DefaultManagedCluster(c.defaultHOHLabel)(context.TODO(), &admissionv1.AdmissionRequest{
+				Operation: c.operation,
+				Object:    object,
+			})
+			if !response.Allowed {
+				t.Fatalf("expected the managed cluster to be allowed, got %v", response.Result)
+			}
+
+			var patch []jsonPatchOperation
+			if response.Patch != nil {
+				if err := json.Unmarshal(response.Patch, &patch); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, _ := json.Marshal(c.expectedPatch)
+			actual, _ := json.Marshal(patch)
+			if string(expected) != string(actual) {
+				t.Errorf("expected the patch %s, got %s", expected, actual)
+			}
+		})
+	}
+}
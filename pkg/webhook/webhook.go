@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	workclientv1 "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/hub-cluster-controller/pkg/cluster"
+)
+
+// VALIDATE_MANAGED_CLUSTER_PATH is the path the managed clusters are validated on
+const VALIDATE_MANAGED_CLUSTER_PATH = "/validate-managedclusters"
+
+// maxReviewSize bounds the size of the admission reviews read from the kube-apiserver
+const maxReviewSize = 3 * 1024 * 1024
+
+// Options configures the admission webhooks
+type Options struct {
+	// DefaultHOHLabel is the hoh label set on the new managed clusters without it, the label is not
+	// defaulted if empty
+	DefaultHOHLabel string
+}
+
+// NewHandler returns the handler of the admission webhooks, the work client is used to check the
+// hub manifestworks of the managed clusters being deleted.
+func NewHandler(options Options, workClient workclientv1.WorkV1Interface) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(VALIDATE_MANAGED_CLUSTER_PATH, serveAdmission(ValidateManagedCluster))
+	mux.HandleFunc(MUTATE_MANAGED_CLUSTER_PATH, serveAdmission(DefaultManagedCluster(options.DefaultHOHLabel)))
+	mux.HandleFunc(VALIDATE_MANAGED_CLUSTER_DELETION_PATH, serveAdmission(ValidateManagedClusterDeletion(workClient)))
+	return mux
+}
+
+// admitFunc admits or rejects the object of an admission request
+type admitFunc func(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// serveAdmission decodes the admission review of the request and writes the response of admit.
+func serveAdmission(admit admitFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxReviewSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		review := &admissionv1.AdmissionReview{}
+		if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+			http.Error(w, fmt.Sprintf("invalid admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		response := admit(r.Context(), review.Request)
+		response.UID = review.Request.UID
+		review.Response = response
+		review.Request = nil
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			klog.Errorf("Failed to write the admission review: %v", err)
+		}
+	}
+}
+
+// ValidateManagedCluster rejects the managed clusters whose mch annotation is set or changed to a
+// MultiClusterHub not matching the MultiClusterHub schema. An unchanged annotation is not validated
+// again, so the managed clusters annotated before the webhook was installed can still be updated.
+func ValidateManagedCluster(_ context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	managedCluster := &clusterv1.ManagedCluster{}
+	if err := json.Unmarshal(request.Object.Raw, managedCluster); err != nil {
+		return deny(metav1.StatusReasonBadRequest, http.StatusBadRequest, fmt.Sprintf("invalid managed cluster: %v", err))
+	}
+	mch, ok := managedCluster.Annotations[cluster.HOH_MCH_ANNOTATION]
+	if !ok {
+		return allow()
+	}
+	if request.Operation == admissionv1.Update {
+		oldCluster := &clusterv1.ManagedCluster{}
+		if err := json.Unmarshal(request.OldObject.Raw, oldCluster); err == nil &&
+			oldCluster.Annotations[cluster.HOH_MCH_ANNOTATION] == mch {
+			return allow()
+		}
+	}
+	if err := cluster.ValidateMCH(mch); err != nil {
+		return deny(metav1.StatusReasonInvalid, http.StatusUnprocessableEntity,
+			fmt.Sprintf("invalid %s annotation: %v", cluster.HOH_MCH_ANNOTATION, err))
+	}
+	return allow()
+}
+
+func allow() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func deny(reason metav1.StatusReason, code int32, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  reason,
+			Code:    code,
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func newManagedClusterRaw(t *testing.T, annotations map[string]string) runtime.RawExtension {
+	raw, err := json.Marshal(&clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Annotations: annotations},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: raw}
+}
+
+func TestValidateManagedCluster(t *testing.T) {
+	valid := map[string]string{"mch": `{"spec":{"availabilityConfig":"Basic"}}`}
+	invalid := map[string]string{"mch": `{"spec":{"availabiltyConfig":"Basic"}}`}
+	cases := []struct {
+		name      string
+		operation admissionv1.Operation
+		object    map[string]string
+		oldObject map[string]string
+		expected  bool
+	}{
+		{
+			name:      "no annotation",
+			operation: admissionv1.Create,
+			expected:  true,
+		},
+		{
+			name:      "valid annotation",
+			operation: admissionv1.Create,
+			object:    valid,
+			expected:  true,
+		},
+		{
+			name:      "invalid annotation",
+			operation: admissionv1.Create,
+			object:    invalid,
+			expected:  false,
+		},
+		{
+			name:      "invalid annotation changed",
+			operation: admissionv1.Update,
+			object:    invalid,
+			oldObject: valid,
+			expected:  false,
+		},
+		{
+			name:      "invalid annotation unchanged",
+			operation: admissionv1.Update,
+			object:    invalid,
+			oldObject: invalid,
+			expected:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			request := &admissionv1.AdmissionRequest{
+				Operation: c.operation,
+				Object:    newManagedClusterRaw(t, c.object),
+			}
+			if c.operation == admissionv1.Update {
+				request.OldObject = newManagedClusterRaw(t, c.oldObject)
+			}
+			response := ValidateManagedCluster(context.TODO(), request)
+			if response.Allowed != c.expected {
+				t.Errorf("expected allowed %v, got %v: %v", c.expected, response.Allowed, response.Result)
+			}
+			if !response.Allowed && response.Result.Reason != metav1.StatusReasonInvalid {
+				t.Errorf("expected the reason Invalid, got %q", response.Result.Reason)
+			}
+		})
+	}
+}
+
+func TestServeAdmission(t *testing.T) {
+	server := httptest.NewServer(NewHandler(Options{}, workfake.NewSimpleClientset().WorkV1()))
+	defer server.Close()
+
+	review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID("review-uid"),
+			Operation: admissionv1.Create,
+			Object:    newManagedClusterRaw(t, map[string]string{"mch": "{"}),
+		},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(server.URL+VALIDATE_MANAGED_CLUSTER_PATH, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	actual := &admissionv1.AdmissionReview{}
+	if err := json.NewDecoder(resp.Body).Decode(actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.Response == nil || actual.Response.UID != "review-uid" || actual.Response.Allowed {
+		t.Errorf("expected the review to be rejected, got %v", actual.Response)
+	}
+}